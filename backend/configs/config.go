@@ -26,7 +26,74 @@ type Config struct {
 			KeyFile   string `mapstructure:"keyFile"`
 			EnableTLS bool   `mapstructure:"enableTLS"`
 		}
+		Poller struct {
+			Interval int `mapstructure:"interval"`
+			Pages    int `mapstructure:"pages"`
+			Limit    int `mapstructure:"limit"`
+		} `mapstructure:"poller"`
+		Dispatcher struct {
+			Interval int `mapstructure:"interval"`
+			Batch    int `mapstructure:"batch"`
+		} `mapstructure:"dispatcher"`
+		Jobs struct {
+			Workers  int `mapstructure:"workers"`
+			Interval int `mapstructure:"interval"`
+			Lease    int `mapstructure:"lease"`
+		} `mapstructure:"jobs"`
+		Relay struct {
+			Interval int `mapstructure:"interval"`
+			Batch    int `mapstructure:"batch"`
+		} `mapstructure:"relay"`
+		ShutdownTimeout int `mapstructure:"shutdownTimeout"`
 	} `mapstructure:"handlers"`
+	Services struct {
+		Cache struct {
+			TTL        int `mapstructure:"ttl"`
+			MaxEntries int `mapstructure:"maxEntries"`
+		} `mapstructure:"cache"`
+		Itinerary struct {
+			MinConnection  int            `mapstructure:"minConnection"`
+			MinConnections map[string]int `mapstructure:"minConnections"`
+			MaxLayover     int            `mapstructure:"maxLayover"`
+		} `mapstructure:"itinerary"`
+		Emissions struct {
+			FuelBurnFile    string  `mapstructure:"fuelBurnFile"`
+			LoadFactor      float64 `mapstructure:"loadFactor"`
+			DefaultAircraft string  `mapstructure:"defaultAircraft"`
+		} `mapstructure:"emissions"`
+		Webhooks struct {
+			MaxAttempts int `mapstructure:"maxAttempts"`
+			Backoff     int `mapstructure:"backoff"`
+			MaxBackoff  int `mapstructure:"maxBackoff"`
+			Timeout     int `mapstructure:"timeout"`
+		} `mapstructure:"webhooks"`
+		Jobs struct {
+			MaxAttempts int `mapstructure:"maxAttempts"`
+			Backoff     int `mapstructure:"backoff"`
+			MaxBackoff  int `mapstructure:"maxBackoff"`
+		} `mapstructure:"jobs"`
+		Ingestion struct {
+			StrictSchema bool `mapstructure:"strictSchema"`
+		} `mapstructure:"ingestion"`
+		Outbox struct {
+			Backoff    int `mapstructure:"backoff"`
+			MaxBackoff int `mapstructure:"maxBackoff"`
+			Timeout    int `mapstructure:"timeout"`
+			Webhook    struct {
+				URL string `mapstructure:"url"`
+			} `mapstructure:"webhook"`
+			File struct {
+				Path string `mapstructure:"path"`
+			} `mapstructure:"file"`
+			NATS struct {
+				URL     string `mapstructure:"url"`
+				Subject string `mapstructure:"subject"`
+			} `mapstructure:"nats"`
+		} `mapstructure:"outbox"`
+		Bulk struct {
+			MaxOperations int `mapstructure:"maxOperations"`
+		} `mapstructure:"bulk"`
+	} `mapstructure:"services"`
 	Repositories struct {
 		Postgres struct {
 			Host              string `mapstructure:"host"`
@@ -35,6 +102,8 @@ type Config struct {
 			DB                string `mapstructure:"db"`
 			SSLMODE           string `mapstructure:"SSLMODE"`
 			MAXCONWAITINGTIME int    `mapstructure:"MAXCONWAITINGTIME"`
+			AutoMigrate       bool   `mapstructure:"autoMigrate"`
+			BatchSize         int    `mapstructure:"batchSize"`
 		}
 	}
 }
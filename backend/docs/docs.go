@@ -51,7 +51,7 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "created_at": {
-                    "$ref": "#/definitions/structs.CustomTime"
+                    "$ref": "#/definitions/structs.Instant"
                 },
                 "iata_code": {
                     "type": "string"
@@ -68,7 +68,7 @@ const docTemplate = `{
                 }
             }
         },
-        "structs.CustomTime": {
+        "structs.Instant": {
             "type": "object",
             "properties": {
                 "time.Time": {
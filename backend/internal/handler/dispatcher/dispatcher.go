@@ -0,0 +1,43 @@
+package dispatcher
+
+import (
+	"context"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+)
+
+type Config struct {
+	interval time.Duration
+	batch    int
+}
+
+// NewConfig configures the webhook dispatcher: how often it looks for due
+// deliveries and how many it claims at a time. An interval of zero or less
+// disables it.
+func NewConfig(
+	interval time.Duration,
+	batch int,
+) Config {
+	if batch < 1 {
+		batch = 20
+	}
+	return Config{
+		interval: interval,
+		batch:    batch,
+	}
+}
+
+type Dispatcher interface {
+	Run() error
+	Shutdown(ctx context.Context) error
+}
+
+func New(config Config, s *service.Service) Dispatcher {
+	return &worker{
+		config:  config,
+		service: s,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
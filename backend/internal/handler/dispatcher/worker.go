@@ -0,0 +1,69 @@
+package dispatcher
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/FACorreiaa/aviatoon-tracker/pkg/logs"
+)
+
+// worker sends the webhook deliveries queued for flight changes.
+type worker struct {
+	config  Config
+	service *service.Service
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func (d *worker) Run() error {
+	defer close(d.done)
+	if d.config.interval <= 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-d.stop
+		cancel()
+	}()
+
+	ticker := time.NewTicker(d.config.interval)
+	defer ticker.Stop()
+	for {
+		d.cycle(ctx)
+		select {
+		case <-ticker.C:
+		case <-d.stop:
+			return nil
+		}
+	}
+}
+
+func (d *worker) Shutdown(ctx context.Context) error {
+	d.stopOnce.Do(func() { close(d.stop) })
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// cycle drains the due deliveries, a batch at a time.
+func (d *worker) cycle(ctx context.Context) {
+	for ctx.Err() == nil {
+		sent, err := d.service.Watch.Deliver(ctx, d.config.batch)
+		if err != nil {
+			logs.DefaultLogger.WithError(err).Error("Error delivering webhooks")
+			return
+		}
+		if sent < d.config.batch {
+			return
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+)
+
+// fakeWatch sends the deliveries of sent, one call at a time.
+type fakeWatch struct {
+	service.Watch
+	sent   []int
+	err    error
+	limits []int
+}
+
+func (f *fakeWatch) Deliver(_ context.Context, limit int) (int, error) {
+	f.limits = append(f.limits, limit)
+	if len(f.sent) == 0 {
+		return 0, f.err
+	}
+	n := f.sent[0]
+	f.sent = f.sent[1:]
+	return n, nil
+}
+
+func TestCycle(t *testing.T) {
+	tests := []struct {
+		name  string
+		sent  []int
+		err   error
+		calls int
+	}{
+		{"nothing due", nil, nil, 1},
+		{"partial batch", []int{3}, nil, 1},
+		{"full batches drain", []int{5, 5, 2}, nil, 3},
+		{"error stops the cycle", []int{5}, errors.New("db down"), 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			watch := &fakeWatch{sent: tt.sent, err: tt.err}
+			d := New(NewConfig(time.Second, 5), &service.Service{Watch: watch}).(*worker)
+			d.cycle(context.Background())
+			if len(watch.limits) != tt.calls {
+				t.Errorf("Deliver called %d times, want %d", len(watch.limits), tt.calls)
+			}
+			for _, limit := range watch.limits {
+				if limit != 5 {
+					t.Errorf("Deliver limit = %d, want the batch of 5", limit)
+				}
+			}
+		})
+	}
+}
+
+func TestCycleCancelled(t *testing.T) {
+	watch := &fakeWatch{sent: []int{5, 5, 5}}
+	d := New(NewConfig(time.Second, 5), &service.Service{Watch: watch}).(*worker)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d.cycle(ctx)
+	if len(watch.limits) != 0 {
+		t.Errorf("Deliver called %d times after cancellation", len(watch.limits))
+	}
+}
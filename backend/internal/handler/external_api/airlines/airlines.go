@@ -3,21 +3,29 @@ package airlines
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/google/uuid"
 	"log"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/conditional"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/history"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/imports"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/patch"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/render"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/trash"
 	internal_api "github.com/FACorreiaa/aviatoon-tracker/internal/handler/internalApi"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
 	"github.com/go-chi/chi/v5"
 )
 
-func NewCustomTime(t time.Time) structs.CustomTime {
-	return structs.CustomTime{Time: t}
-}
+const (
+	defaultLeaderboardLimit = 20
+	maxLeaderboardLimit     = 500
+)
 
 type Handler struct {
 	service *service.Service
@@ -30,41 +38,33 @@ func NewHandler(s *service.Service) *Handler {
 
 //Aircraft
 
-func (h *Handler) InsertAircraft(w http.ResponseWriter, r *http.Request) error {
-	apiResponse, err, _ := internal_api.FetchAviationStackData("aircraft_types")
-
-	if err != nil {
-		log.Printf("error getting .data: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
+// ImportAircrafts fetches the aircraft types of the upstream API and, unless
+// dryRun, stores them. Either way the import is recorded as an ingestion run.
+func (h *Handler) ImportAircrafts(ctx context.Context, dryRun bool) (structs.IngestionRun, error) {
+	return internal_api.Ingest(ctx, h.service.Ingestion, "aircraft", "aircraft_types", dryRun, decodeAircrafts,
+		h.service.Aircraft.DiffAircrafts, h.service.Aircraft.CreateAircrafts)
+}
 
+func decodeAircrafts(apiResponse []byte) ([]structs.Aircraft, error) {
 	var response structs.AircraftApiData
-	err = json.Unmarshal(apiResponse, &response)
-	if err != nil {
-		log.Printf("error unmarshaling API response: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-
+	if err := json.Unmarshal(apiResponse, &response); err != nil {
+		return nil, fmt.Errorf("error unmarshalling API response: %w", err)
 	}
 
+	createdAt := structs.NewInstant(time.Now())
+	aircrafts := make([]structs.Aircraft, 0, len(response.Data))
 	for _, a := range response.Data {
-		createdTime := time.Now()
-		createdAt := NewCustomTime(createdTime)
-
-		err := h.service.Aircraft.CreateAircraft(h.ctx, &structs.Aircraft{
-			ID:           a.ID,
+		aircrafts = append(aircrafts, structs.Aircraft{
+			ID:           uuid.New(),
 			IataCode:     a.IataCode,
 			AircraftName: a.AircraftName,
 			PlaneTypeId:  a.PlaneTypeId,
 			CreatedAt:    createdAt,
 			UpdatedAt:    nil,
 		})
-		if err != nil {
-			log.Printf("error creating aircrafts in database: %v", err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return err
-		}
 	}
-	return nil
+
+	return aircrafts, nil
 }
 
 func (h *Handler) CreateAircraft(w http.ResponseWriter, r *http.Request) {
@@ -104,49 +104,34 @@ func (h *Handler) CreateAircraft(w http.ResponseWriter, r *http.Request) {
 // @Description  Get aircraft
 // @Tags         aircrafts
 // @Accept       json
-// @Produce      json
+// @Produce      json,application/x-ndjson,text/csv,application/x-protobuf
+// @Param        format  query     string  false  "json, ndjson, csv or protobuf; overrides Accept"
 // @Success      200  {array}   structs.Aircraft
 // @Router       /api/v1/aircrafts [get]
 func (h *Handler) GetAircrafts(w http.ResponseWriter, r *http.Request) {
-	aircrafts, err := h.service.Aircraft.GetAircrafts(h.ctx)
-	if len(aircrafts) == 0 {
-		err := h.InsertAircraft(w, r)
-		if err != nil {
-			log.Printf("Error inserting aircraft: %v", err)
-
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-
-			return
-		}
-		aircrafts, err := h.service.Aircraft.GetAircrafts(h.ctx)
-
-		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("Invalid aircraft"))
-			return
-		}
-		// Write the list of countries to the response
-		err = json.NewEncoder(w).Encode(aircrafts)
-		if err != nil {
-			log.Printf("error encoding tax as JSON: %v", err)
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("Error encoding json"))
-			return
-		}
-
+	format, ok := render.Negotiate(w, r)
+	if !ok {
+		return
 	}
+
+	version, err := h.service.Version.GetVersion(h.ctx, "aircraft")
 	if err != nil {
-		log.Printf("Error fetching airline .data: %v", err)
+		log.Printf("Error fetching aircraft .data: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
 
-		// Write an error response to the client
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if version.Rows == 0 {
+		imports.Queue[structs.Aircraft](h.ctx, w, h.service, "aircraft", format)
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(aircrafts)
+	if conditional.Check(w, r, version.ETag(trash.Variant(r, string(format))), version.LastModified) {
+		return
+	}
+	render.Stream(w, format, func(fn func(structs.Aircraft) error) error {
+		return h.service.Aircraft.StreamAircrafts(trash.Context(h.ctx, r), fn)
+	})
 }
 
 func (h *Handler) GetAircraft(w http.ResponseWriter, r *http.Request) {
@@ -157,7 +142,13 @@ func (h *Handler) GetAircraft(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	aircraft, err := h.service.Aircraft.GetAircraft(h.ctx, id)
+	ctx, err := history.Context(trash.Context(h.ctx, r), r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	aircraft, err := h.service.Aircraft.GetAircraft(ctx, id)
 	if err != nil {
 		log.Printf("Error fetching aircraft .data: %v", err)
 
@@ -166,10 +157,7 @@ func (h *Handler) GetAircraft(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(aircraft)
+	conditional.WriteJSON(w, r, aircraft, conditional.LastModified(aircraft))
 }
 
 func (h *Handler) DeleteAircraft(w http.ResponseWriter, r *http.Request) {
@@ -181,12 +169,7 @@ func (h *Handler) DeleteAircraft(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.Aircraft.DeleteAircraft(h.ctx, id)
-	if err != nil {
-		log.Printf("Error deleting aircraft .data: %v", err)
-
-		// Write an error response to the client
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if !patch.Delete(h.ctx, w, r, h.service, "aircraft", id) {
 		return
 	}
 
@@ -195,6 +178,8 @@ func (h *Handler) DeleteAircraft(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("Aircraft deleted successfully"))
 }
 
+// UpdateAircraft replaces an aircraft with the body, in the shape GET returns.
+// Fields the body leaves out are cleared.
 func (h *Handler) UpdateAircraft(w http.ResponseWriter, r *http.Request) {
 	id, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
@@ -204,22 +189,27 @@ func (h *Handler) UpdateAircraft(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var updates map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !patch.Replace(h.ctx, w, r, h.service, "aircraft", id) {
 		return
 	}
-	updates["UpdatedAt"] = time.Now()
+	patch.Written(h.ctx, w, r, h.service.Aircraft.GetAircraft, id)
+}
 
-	if err := h.service.Aircraft.UpdateAircraft(h.ctx, id, updates); err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+// PatchAircraft applies a JSON Merge Patch or a JSON Patch to an aircraft as GET
+// returns it.
+func (h *Handler) PatchAircraft(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		// Handle the error for invalid UUID format
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid aircraft ID"))
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	//json.NewEncoder(w).Encode(taxs)
+	if !patch.Patch(h.ctx, w, r, h.service, "aircraft", id) {
+		return
+	}
+	patch.Written(h.ctx, w, r, h.service.Aircraft.GetAircraft, id)
 }
 
 func (h *Handler) GetAircraftCount(w http.ResponseWriter, r *http.Request) {
@@ -245,41 +235,33 @@ func (h *Handler) GetAircraftCount(w http.ResponseWriter, r *http.Request) {
 **	AIRLINE TAX **
 ******************/
 
-func (h *Handler) InsertTax(w http.ResponseWriter, r *http.Request) error {
-	apiResponse, err, _ := internal_api.FetchAviationStackData("taxes")
-
-	if err != nil {
-		log.Printf("error getting .data: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
+// ImportTaxes fetches the taxes of the upstream API and, unless dryRun, stores
+// them. Either way the import is recorded as an ingestion run.
+func (h *Handler) ImportTaxes(ctx context.Context, dryRun bool) (structs.IngestionRun, error) {
+	return internal_api.Ingest(ctx, h.service.Ingestion, "tax", "taxes", dryRun, decodeTaxes,
+		h.service.Tax.DiffTaxes, h.service.Tax.CreateTaxes)
+}
 
+func decodeTaxes(apiResponse []byte) ([]structs.Tax, error) {
 	var response structs.TaxApiData
-	err = json.Unmarshal(apiResponse, &response)
-	if err != nil {
-		log.Printf("error unmarshaling API response: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-
+	if err := json.Unmarshal(apiResponse, &response); err != nil {
+		return nil, fmt.Errorf("error unmarshalling API response: %w", err)
 	}
 
+	createdAt := structs.NewInstant(time.Now())
+	taxes := make([]structs.Tax, 0, len(response.Data))
 	for _, t := range response.Data {
-		createdTime := time.Now()
-		createdAt := NewCustomTime(createdTime)
-
-		err := h.service.Tax.CreateTax(h.ctx, &structs.Tax{
-			ID:        t.ID,
+		taxes = append(taxes, structs.Tax{
+			ID:        uuid.New(),
 			TaxId:     t.TaxId,
 			TaxName:   t.TaxName,
 			IataCode:  t.IataCode,
 			CreatedAt: createdAt,
 			UpdatedAt: nil,
 		})
-		if err != nil {
-			log.Printf("error creating tax in database: %v", err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return err
-		}
 	}
-	return nil
+
+	return taxes, nil
 }
 
 func (h *Handler) CreateTax(w http.ResponseWriter, r *http.Request) {
@@ -421,33 +403,29 @@ func (h *Handler) CreateTax(w http.ResponseWriter, r *http.Request) {
 // }
 
 func (h *Handler) GetTaxs(w http.ResponseWriter, r *http.Request) {
-	taxs, err := h.service.Tax.GetTaxs(h.ctx)
+	format, ok := render.Negotiate(w, r)
+	if !ok {
+		return
+	}
+
+	version, err := h.service.Version.GetVersion(h.ctx, "tax")
 	if err != nil {
 		log.Printf("Error fetching tax .data: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	if len(taxs) == 0 {
-		err := h.InsertTax(w, r)
-		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("Invalid tax"))
-			return
-		}
-
-		taxs, err = h.service.Tax.GetTaxs(h.ctx)
-		if err != nil {
-			log.Printf("Error fetching tax .data: %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte("Internal server error"))
-			return
-		}
+	if version.Rows == 0 {
+		imports.Queue[structs.Tax](h.ctx, w, h.service, "tax", format)
+		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(taxs)
+	if conditional.Check(w, r, version.ETag(trash.Variant(r, string(format))), version.LastModified) {
+		return
+	}
+	render.Stream(w, format, func(fn func(structs.Tax) error) error {
+		return h.service.Tax.StreamTaxes(trash.Context(h.ctx, r), fn)
+	})
 }
 
 func (h *Handler) GetTax(w http.ResponseWriter, r *http.Request) {
@@ -459,7 +437,13 @@ func (h *Handler) GetTax(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	taxs, err := h.service.Tax.GetTax(h.ctx, id)
+	ctx, err := history.Context(trash.Context(h.ctx, r), r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	taxs, err := h.service.Tax.GetTax(ctx, id)
 	if err != nil {
 		log.Printf("Error fetching airline .data: %v", err)
 
@@ -468,10 +452,7 @@ func (h *Handler) GetTax(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(taxs)
+	conditional.WriteJSON(w, r, taxs, conditional.LastModified(taxs))
 }
 
 func (h *Handler) DeleteTax(w http.ResponseWriter, r *http.Request) {
@@ -483,12 +464,7 @@ func (h *Handler) DeleteTax(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.Tax.DeleteTax(h.ctx, id)
-	if err != nil {
-		log.Printf("Error fetching airline .data: %v", err)
-
-		// Write an error response to the client
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if !patch.Delete(h.ctx, w, r, h.service, "tax", id) {
 		return
 	}
 
@@ -498,31 +474,38 @@ func (h *Handler) DeleteTax(w http.ResponseWriter, r *http.Request) {
 	//json.NewEncoder(w).Encode(taxs)
 }
 
+// UpdateTax replaces a tax with the body, in the shape GET returns.
+// Fields the body leaves out are cleared.
 func (h *Handler) UpdateTax(w http.ResponseWriter, r *http.Request) {
 	id, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
 		// Handle the error for invalid UUID format
 		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid aircraft ID"))
+		w.Write([]byte("Invalid tax ID"))
 		return
 	}
 
-	var updates map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !patch.Replace(h.ctx, w, r, h.service, "tax", id) {
 		return
 	}
-	updates["UpdatedAt"] = time.Now()
+	patch.Written(h.ctx, w, r, h.service.Tax.GetTax, id)
+}
 
-	if err := h.service.Tax.UpdateTax(h.ctx, id, updates); err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+// PatchTax applies a JSON Merge Patch or a JSON Patch to a tax as GET
+// returns it.
+func (h *Handler) PatchTax(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		// Handle the error for invalid UUID format
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid tax ID"))
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	//json.NewEncoder(w).Encode(taxs)
+	if !patch.Patch(h.ctx, w, r, h.service, "tax", id) {
+		return
+	}
+	patch.Written(h.ctx, w, r, h.service.Tax.GetTax, id)
 }
 
 func (h *Handler) GetTaxesCount(w http.ResponseWriter, r *http.Request) {
@@ -564,28 +547,33 @@ func (h *Handler) GetTaxesCount(w http.ResponseWriter, r *http.Request) {
 
 //Airline
 
-func (h *Handler) InsertAirlines(w http.ResponseWriter, r *http.Request) error {
-	apiResponse, err, _ := internal_api.FetchAviationStackData("airline")
+// ImportAirlines fetches the airlines of the upstream API and, unless dryRun, stores
+// them. Either way the import is recorded as an ingestion run.
+func (h *Handler) ImportAirlines(ctx context.Context, dryRun bool) (structs.IngestionRun, error) {
+	run, err := internal_api.Ingest(ctx, h.service.Ingestion, "airline", "airline", dryRun, decodeAirlines,
+		h.service.Airline.DiffAirlines, h.service.Airline.CreateAirlines)
+	if err != nil || dryRun {
+		return run, err
+	}
 
-	if err != nil {
-		log.Printf("error getting airline .data: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if _, err := h.service.Integrity.LinkReferences(ctx); err != nil {
+		log.Printf("error linking references: %v", err)
 	}
 
-	var response structs.AirlineApiData
-	err = json.Unmarshal(apiResponse, &response)
-	if err != nil {
-		log.Printf("error unmarshaling API response: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	return run, nil
+}
 
+func decodeAirlines(apiResponse []byte) ([]structs.Airline, error) {
+	var response structs.AirlineApiData
+	if err := json.Unmarshal(apiResponse, &response); err != nil {
+		return nil, fmt.Errorf("error unmarshalling API response: %w", err)
 	}
 
+	createdAt := structs.NewInstant(time.Now())
+	airlines := make([]structs.Airline, 0, len(response.Data))
 	for _, a := range response.Data {
-		createdTime := time.Now()
-		createdAt := NewCustomTime(createdTime)
-
-		err := h.service.Airline.CreateAirline(h.ctx, &structs.Airline{
-			ID:                   a.ID,
+		airlines = append(airlines, structs.Airline{
+			ID:                   uuid.New(),
 			FleetAverageAge:      a.FleetAverageAge,
 			AirlineId:            a.AirlineId,
 			Callsign:             a.Callsign,
@@ -603,13 +591,9 @@ func (h *Handler) InsertAirlines(w http.ResponseWriter, r *http.Request) error {
 			CreatedAt:            createdAt,
 			UpdatedAt:            nil,
 		})
-		if err != nil {
-			log.Printf("error creating aircrafts in database: %v", err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return err
-		}
 	}
-	return nil
+
+	return airlines, nil
 }
 
 func (h *Handler) CreateAirline(w http.ResponseWriter, r *http.Request) {
@@ -629,46 +613,29 @@ func (h *Handler) CreateAirline(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) GetAirlines(w http.ResponseWriter, r *http.Request) {
-	airlines, err := h.service.Airline.GetAirlines(h.ctx)
-	if len(airlines) == 0 {
-		err := h.InsertAirlines(w, r)
-		if err != nil {
-			log.Printf("Error inserting airline: %v", err)
-
-			// Write an error response to the client
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte("Internal server error"))
-			return
-		}
-		airlines, err := h.service.Airline.GetAirlines(h.ctx)
-
-		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("Invalid tax"))
-			return
-		}
-		// Write the list of countries to the response
-		err = json.NewEncoder(w).Encode(airlines)
-		if err != nil {
-			log.Printf("error encoding tax as JSON: %v", err)
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("Error encoding json"))
-			return
-		}
-
+	format, ok := render.Negotiate(w, r)
+	if !ok {
+		return
 	}
+
+	version, err := h.service.Version.GetVersion(h.ctx, "airline")
 	if err != nil {
 		log.Printf("Error fetching airline .data: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
 
-		// Write an error response to the client
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if version.Rows == 0 {
+		imports.Queue[structs.Airline](h.ctx, w, h.service, "airline", format)
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(airlines)
+	if conditional.Check(w, r, version.ETag(trash.Variant(r, string(format))), version.LastModified) {
+		return
+	}
+	render.Stream(w, format, func(fn func(structs.Airline) error) error {
+		return h.service.Airline.StreamAirlines(trash.Context(h.ctx, r), fn)
+	})
 }
 
 func (h *Handler) GetAirline(w http.ResponseWriter, r *http.Request) {
@@ -680,7 +647,13 @@ func (h *Handler) GetAirline(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	airlines, err := h.service.Airline.GetAirline(h.ctx, id)
+	ctx, err := history.Context(trash.Context(h.ctx, r), r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	airlines, err := h.service.Airline.GetAirline(ctx, id)
 	if err != nil {
 		log.Printf("Error fetching airline .data: %v", err)
 
@@ -689,10 +662,7 @@ func (h *Handler) GetAirline(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(airlines)
+	conditional.WriteJSON(w, r, airlines, conditional.LastModified(airlines))
 }
 
 func (h *Handler) DeleteAirline(w http.ResponseWriter, r *http.Request) {
@@ -704,12 +674,7 @@ func (h *Handler) DeleteAirline(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.Airline.DeleteAirline(h.ctx, id)
-	if err != nil {
-		log.Printf("Error fetching airline .data: %v", err)
-
-		// Write an error response to the client
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if !patch.Delete(h.ctx, w, r, h.service, "airline", id) {
 		return
 	}
 
@@ -719,31 +684,38 @@ func (h *Handler) DeleteAirline(w http.ResponseWriter, r *http.Request) {
 	//json.NewEncoder(w).Encode(taxs)
 }
 
+// UpdateAirline replaces an airline with the body, in the shape GET returns.
+// Fields the body leaves out are cleared.
 func (h *Handler) UpdateAirline(w http.ResponseWriter, r *http.Request) {
 	id, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
 		// Handle the error for invalid UUID format
 		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid aircraft ID"))
+		w.Write([]byte("Invalid airline ID"))
 		return
 	}
 
-	var updates map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !patch.Replace(h.ctx, w, r, h.service, "airline", id) {
 		return
 	}
-	updates["UpdatedAt"] = time.Now()
+	patch.Written(h.ctx, w, r, h.service.Airline.GetAirline, id)
+}
 
-	if err := h.service.Airline.UpdateAirline(h.ctx, id, updates); err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+// PatchAirline applies a JSON Merge Patch or a JSON Patch to an airline as GET
+// returns it.
+func (h *Handler) PatchAirline(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		// Handle the error for invalid UUID format
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid airline ID"))
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	//json.NewEncoder(w).Encode(taxs)
+	if !patch.Patch(h.ctx, w, r, h.service, "airline", id) {
+		return
+	}
+	patch.Written(h.ctx, w, r, h.service.Airline.GetAirline, id)
 }
 
 func (h *Handler) GetAirlineCount(w http.ResponseWriter, r *http.Request) {
@@ -766,19 +738,24 @@ func (h *Handler) GetAirlineCount(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) GetAirlinesCountry(w http.ResponseWriter, r *http.Request) {
-	airlines, err := h.service.Airline.GetAirlinesCountry(h.ctx)
+	format, ok := render.Negotiate(w, r)
+	if !ok {
+		return
+	}
+
+	version, err := h.service.Version.GetVersion(h.ctx, "airline", "city", "country")
 	if err != nil {
 		log.Printf("Error fetching airline from coutry .data: %v", err)
-
-		// Write an error response to the client
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(airlines)
+	if conditional.Check(w, r, version.ETag(string(format)), version.LastModified) {
+		return
+	}
+	render.Stream(w, format, func(fn func(structs.AirlineInfo) error) error {
+		return h.service.Airline.StreamAirlinesCountry(h.ctx, fn)
+	})
 }
 
 func (h *Handler) GetAirlineCountry(w http.ResponseWriter, r *http.Request) {
@@ -799,10 +776,7 @@ func (h *Handler) GetAirlineCountry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(airlines)
+	conditional.WriteJSON(w, r, airlines, time.Time{})
 }
 
 func (h *Handler) GetAirlineCountryName(w http.ResponseWriter, r *http.Request) {
@@ -816,10 +790,7 @@ func (h *Handler) GetAirlineCountryName(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(airline)
+	conditional.WriteJSON(w, r, airline, time.Time{})
 }
 
 func (h *Handler) GetAirlineCityName(w http.ResponseWriter, r *http.Request) {
@@ -833,10 +804,7 @@ func (h *Handler) GetAirlineCityName(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(airline)
+	conditional.WriteJSON(w, r, airline, time.Time{})
 }
 
 func (h *Handler) GetAirlineCountryCityName(w http.ResponseWriter, r *http.Request) {
@@ -851,36 +819,97 @@ func (h *Handler) GetAirlineCountryCityName(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(airline)
+	conditional.WriteJSON(w, r, airline, time.Time{})
 }
 
-//Airplane
-
-func (h *Handler) InsertAirplane(w http.ResponseWriter, r *http.Request) error {
-	apiResponse, err, _ := internal_api.FetchAviationStackData("airplanes")
+// GetAirlineFleet compares the reported fleet size and average age of an
+// airline with the airplanes matched to it by IATA/ICAO code, broken down by
+// age, model, engine type and status.
+func (h *Handler) GetAirlineFleet(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid airline ID", http.StatusBadRequest)
+		return
+	}
 
+	fleet, err := h.service.Airline.GetAirlineFleet(h.ctx, id)
 	if err != nil {
-		log.Printf("error getting airplane .data: %v", err)
+		log.Printf("Error fetching airline fleet: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	var response structs.AirplaneApiData
-	err = json.Unmarshal(apiResponse, &response)
-	// Replace "0000-00-00" datetime values with zero value of time.Time
+	conditional.WriteJSON(w, r, fleet, time.Time{})
+}
+
+var fleetLeaderboardOrders = map[string]bool{
+	"observed_fleet": true,
+	"reported_fleet": true,
+	"average_age":    true,
+	"youngest":       true,
+}
+
+// GetFleetLeaderboard ranks airlines by ?order= (observed_fleet, reported_fleet,
+// average_age or youngest), at most ?limit= of them.
+func (h *Handler) GetFleetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	order := r.URL.Query().Get("order")
+	if order == "" {
+		order = "observed_fleet"
+	}
+	if !fleetLeaderboardOrders[order] {
+		http.Error(w, "Invalid order", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultLeaderboardLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxLeaderboardLimit {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	leaderboard, err := h.service.Airline.GetFleetLeaderboard(h.ctx, order, limit)
 	if err != nil {
-		log.Printf("error unmarshalling API response: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Printf("Error fetching fleet leaderboard: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
 	}
 
-	for _, a := range response.Data {
-		createdTime := time.Now()
-		createdAt := NewCustomTime(createdTime)
+	conditional.WriteJSON(w, r, leaderboard, time.Time{})
+}
+
+//Airplane
+
+// ImportAirplanes fetches the airplanes of the upstream API and, unless dryRun, stores
+// them. Either way the import is recorded as an ingestion run.
+func (h *Handler) ImportAirplanes(ctx context.Context, dryRun bool) (structs.IngestionRun, error) {
+	run, err := internal_api.Ingest(ctx, h.service.Ingestion, "airplane", "airplanes", dryRun, decodeAirplanes,
+		h.service.Airplane.DiffAirplanes, h.service.Airplane.CreateAirplanes)
+	if err != nil || dryRun {
+		return run, err
+	}
+
+	if _, err := h.service.Integrity.LinkReferences(ctx); err != nil {
+		log.Printf("error linking references: %v", err)
+	}
+
+	return run, nil
+}
+
+func decodeAirplanes(apiResponse []byte) ([]structs.Airplane, error) {
+	var response structs.AirplaneApiData
+	if err := json.Unmarshal(apiResponse, &response); err != nil {
+		return nil, fmt.Errorf("error unmarshalling API response: %w", err)
+	}
 
-		err := h.service.Airplane.CreateAirplane(h.ctx, &structs.Airplane{
-			ID:                     a.ID,
+	createdAt := structs.NewInstant(time.Now())
+	airplanes := make([]structs.Airplane, 0, len(response.Data))
+	for _, a := range response.Data {
+		airplanes = append(airplanes, structs.Airplane{
+			ID:                     uuid.New(),
 			IataType:               a.IataType,
 			AirplaneId:             a.AirplaneId,
 			AirlineIataCode:        a.AirlineIataCode,
@@ -909,14 +938,9 @@ func (h *Handler) InsertAirplane(w http.ResponseWriter, r *http.Request) error {
 			CreatedAt:              createdAt,
 			UpdatedAt:              nil,
 		})
-
-		if err != nil {
-			log.Printf("error creating airplanes in database: %v", err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return err
-		}
 	}
-	return nil
+
+	return airplanes, nil
 }
 
 func (h *Handler) CreateAirplane(w http.ResponseWriter, r *http.Request) {
@@ -936,46 +960,29 @@ func (h *Handler) CreateAirplane(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) GetAirplanes(w http.ResponseWriter, r *http.Request) {
-	airplanes, err := h.service.Airplane.GetAirplanes(h.ctx)
-
-	if len(airplanes) == 0 {
-		err := h.InsertAirplane(w, r)
-		if err != nil {
-			log.Printf("Error inserting airplanes: %v", err)
-
-			// Write an error response to the client
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte("Internal server error"))
-			return
-		}
-
-		airplanes, err := h.service.Airplane.GetAirplanes(h.ctx)
-		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("Invalid tax"))
-			return
-		}
-		// Write the list of countries to the response
-		err = json.NewEncoder(w).Encode(airplanes)
-		if err != nil {
-			log.Printf("error encoding tax as JSON: %v", err)
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("Error encoding json"))
-			return
-		}
+	format, ok := render.Negotiate(w, r)
+	if !ok {
+		return
 	}
+
+	version, err := h.service.Version.GetVersion(h.ctx, "airplane")
 	if err != nil {
 		log.Printf("Error fetching airplanes .data: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
 
-		// Write an error response to the client
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if version.Rows == 0 {
+		imports.Queue[structs.Airplane](h.ctx, w, h.service, "airplane", format)
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(airplanes)
+	if conditional.Check(w, r, version.ETag(trash.Variant(r, string(format))), version.LastModified) {
+		return
+	}
+	render.Stream(w, format, func(fn func(structs.Airplane) error) error {
+		return h.service.Airplane.StreamAirplanes(trash.Context(h.ctx, r), fn)
+	})
 }
 
 func (h *Handler) GetAirplane(w http.ResponseWriter, r *http.Request) {
@@ -987,7 +994,13 @@ func (h *Handler) GetAirplane(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	airplane, err := h.service.Airplane.GetAirplane(h.ctx, id)
+	ctx, err := history.Context(trash.Context(h.ctx, r), r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	airplane, err := h.service.Airplane.GetAirplane(ctx, id)
 	if err != nil {
 		log.Printf("Error fetching airplanes .data: %v", err)
 
@@ -996,10 +1009,7 @@ func (h *Handler) GetAirplane(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(airplane)
+	conditional.WriteJSON(w, r, airplane, conditional.LastModified(airplane))
 }
 
 func (h *Handler) DeleteAirplane(w http.ResponseWriter, r *http.Request) {
@@ -1011,12 +1021,7 @@ func (h *Handler) DeleteAirplane(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.Airplane.DeleteAirplane(h.ctx, id)
-	if err != nil {
-		log.Printf("Error fetching airplanes .data: %v", err)
-
-		// Write an error response to the client
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if !patch.Delete(h.ctx, w, r, h.service, "airplane", id) {
 		return
 	}
 
@@ -1026,6 +1031,8 @@ func (h *Handler) DeleteAirplane(w http.ResponseWriter, r *http.Request) {
 	//json.NewEncoder(w).Encode(taxs)
 }
 
+// UpdateAirplane replaces an airplane with the body, in the shape GET returns.
+// Fields the body leaves out are cleared.
 func (h *Handler) UpdateAirplane(w http.ResponseWriter, r *http.Request) {
 	id, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
@@ -1035,22 +1042,27 @@ func (h *Handler) UpdateAirplane(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var updates map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !patch.Replace(h.ctx, w, r, h.service, "airplane", id) {
 		return
 	}
-	updates["UpdatedAt"] = time.Now()
+	patch.Written(h.ctx, w, r, h.service.Airplane.GetAirplane, id)
+}
 
-	if err := h.service.Airplane.UpdateAirplane(h.ctx, id, updates); err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+// PatchAirplane applies a JSON Merge Patch or a JSON Patch to an airplane as GET
+// returns it.
+func (h *Handler) PatchAirplane(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		// Handle the error for invalid UUID format
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid airplane ID"))
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	//json.NewEncoder(w).Encode(taxs)
+	if !patch.Patch(h.ctx, w, r, h.service, "airplane", id) {
+		return
+	}
+	patch.Written(h.ctx, w, r, h.service.Airplane.GetAirplane, id)
 }
 
 func (h *Handler) GetAirplaneCount(w http.ResponseWriter, r *http.Request) {
@@ -1073,19 +1085,24 @@ func (h *Handler) GetAirplaneCount(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) GetAirplaneAirline(w http.ResponseWriter, r *http.Request) {
-	airplane, err := h.service.Airplane.GetAirplaneAirline(h.ctx)
+	format, ok := render.Negotiate(w, r)
+	if !ok {
+		return
+	}
+
+	version, err := h.service.Version.GetVersion(h.ctx, "airplane", "airline")
 	if err != nil {
 		log.Printf("Error fetching airplanes .data: %v", err)
-
-		// Write an error response to the client
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(airplane)
+	if conditional.Check(w, r, version.ETag(string(format)), version.LastModified) {
+		return
+	}
+	render.Stream(w, format, func(fn func(structs.AirplaneInfo) error) error {
+		return h.service.Airplane.StreamAirplaneAirline(h.ctx, fn)
+	})
 }
 
 func (h *Handler) GetAirplanesFromAirlineName(w http.ResponseWriter, r *http.Request) {
@@ -1099,10 +1116,7 @@ func (h *Handler) GetAirplanesFromAirlineName(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(airplane)
+	conditional.WriteJSON(w, r, airplane, time.Time{})
 }
 
 func (h *Handler) GetAirplanesFromAirlineCountry(w http.ResponseWriter, r *http.Request) {
@@ -1116,8 +1130,5 @@ func (h *Handler) GetAirplanesFromAirlineCountry(w http.ResponseWriter, r *http.
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(airplane)
+	conditional.WriteJSON(w, r, airplane, time.Time{})
 }
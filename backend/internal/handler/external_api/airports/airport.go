@@ -3,10 +3,17 @@ package airports
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"time"
 
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/conditional"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/history"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/imports"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/patch"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/render"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/trash"
 	internal_api "github.com/FACorreiaa/aviatoon-tracker/internal/handler/internalApi"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
@@ -19,10 +26,6 @@ type Handler struct {
 	ctx     context.Context
 }
 
-func NewCustomTime(t time.Time) structs.CustomTime {
-	return structs.CustomTime{Time: t}
-}
-
 func NewHandler(s *service.Service) *Handler {
 	return &Handler{service: s, ctx: context.Background()}
 }
@@ -30,33 +33,39 @@ func NewHandler(s *service.Service) *Handler {
 /*****************
 ** AIRLINE AIRPLANE **
 ******************/
-func (h *Handler) InsertAirport(w http.ResponseWriter, r *http.Request) error {
-	apiResponse, err, _ := internal_api.FetchAviationStackData("airport")
+// ImportAirports fetches the airports of the upstream API and, unless dryRun, stores
+// them. Either way the import is recorded as an ingestion run.
+func (h *Handler) ImportAirports(ctx context.Context, dryRun bool) (structs.IngestionRun, error) {
+	run, err := internal_api.Ingest(ctx, h.service.Ingestion, "airport", "airport", dryRun, decodeAirports,
+		h.service.Airport.DiffAirports, h.service.Airport.CreateAirports)
+	if err != nil || dryRun {
+		return run, err
+	}
 
-	if err != nil {
-		log.Printf("error getting .data: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if _, err := h.service.Integrity.LinkReferences(ctx); err != nil {
+		log.Printf("error linking references: %v", err)
 	}
 
-	var response structs.AirportApiData
-	err = json.Unmarshal(apiResponse, &response)
+	return run, nil
+}
 
-	if err != nil {
-		log.Printf("error unmarshalling API response: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+func decodeAirports(apiResponse []byte) ([]structs.Airport, error) {
+	var response structs.AirportApiData
+	if err := json.Unmarshal(apiResponse, &response); err != nil {
+		return nil, fmt.Errorf("error unmarshalling API response: %w", err)
 	}
 
+	createdAt := structs.NewInstant(time.Now())
+	airports := make([]structs.Airport, 0, len(response.Data))
 	for _, a := range response.Data {
-		createdTime := time.Now()
-		createdAt := NewCustomTime(createdTime)
-		err := h.service.Airport.CreateAirport(h.ctx, &structs.Airport{
+		airports = append(airports, structs.Airport{
 			ID:           uuid.New(),
 			GMT:          a.GMT,
 			AirportId:    a.AirportId,
 			IataCode:     a.IataCode,
 			CityIataCode: a.CityIataCode,
 			IcaoCode:     a.IcaoCode,
-			CountryIso2:  a.CountryName,
+			CountryIso2:  a.CountryIso2,
 			GeonameId:    a.GeonameId,
 			Latitude:     a.Latitude,
 			Longitude:    a.Longitude,
@@ -67,15 +76,9 @@ func (h *Handler) InsertAirport(w http.ResponseWriter, r *http.Request) error {
 			CreatedAt:    createdAt,
 			UpdatedAt:    a.UpdatedAt,
 		})
-
-		if err != nil {
-			log.Printf("error creating Airport: %v", err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return err
-		}
 	}
 
-	return nil
+	return airports, nil
 }
 
 func (h *Handler) CreateAirport(w http.ResponseWriter, r *http.Request) {
@@ -95,45 +98,29 @@ func (h *Handler) CreateAirport(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) GetAirports(w http.ResponseWriter, r *http.Request) {
-	airports, err := h.service.Airport.GetAirports(h.ctx)
-
-	if len(airports) == 0 {
-		err := h.InsertAirport(w, r)
-		if err != nil {
-			log.Printf("Error inserting airport: %v", err)
-
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-
-			return
-		}
-		airports, err := h.service.Airport.GetAirports(h.ctx)
-
-		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("Invalid airport"))
-			return
-		}
-		err = json.NewEncoder(w).Encode(airports)
-		if err != nil {
-			log.Printf("error encoding airport as JSON: %v", err)
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("Error encoding json"))
-			return
-		}
+	format, ok := render.Negotiate(w, r)
+	if !ok {
+		return
 	}
 
+	version, err := h.service.Version.GetVersion(h.ctx, "airport")
 	if err != nil {
 		log.Printf("Error fetching airport .data: %v", err)
-
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
 
+	if version.Rows == 0 {
+		imports.Queue[structs.Airport](h.ctx, w, h.service, "airport", format)
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(airports)
+	if conditional.Check(w, r, version.ETag(trash.Variant(r, string(format))), version.LastModified) {
+		return
+	}
+	render.Stream(w, format, func(fn func(structs.Airport) error) error {
+		return h.service.Airport.StreamAirports(trash.Context(h.ctx, r), fn)
+	})
 }
 
 func (h *Handler) GetAirport(w http.ResponseWriter, r *http.Request) {
@@ -145,7 +132,13 @@ func (h *Handler) GetAirport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	airport, err := h.service.Airport.GetAirport(h.ctx, id)
+	ctx, err := history.Context(trash.Context(h.ctx, r), r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	airport, err := h.service.Airport.GetAirport(ctx, id)
 	if err != nil {
 		log.Printf("Error fetching airline .data: %v", err)
 
@@ -154,10 +147,7 @@ func (h *Handler) GetAirport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(airport)
+	conditional.WriteJSON(w, r, airport, conditional.LastModified(airport))
 }
 
 func (h *Handler) GetAirportCount(w http.ResponseWriter, r *http.Request) {
@@ -187,12 +177,7 @@ func (h *Handler) DeleteAirport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.Airport.DeleteAirport(h.ctx, id)
-	if err != nil {
-		log.Printf("Error fetching airport .data: %v", err)
-
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-
+	if !patch.Delete(h.ctx, w, r, h.service, "airport", id) {
 		return
 	}
 
@@ -201,45 +186,59 @@ func (h *Handler) DeleteAirport(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	//json.NewEncoder(w).Encode(airport)
 }
+
+// UpdateAirport replaces an airport with the body, in the shape GET returns.
+// Fields the body leaves out are cleared.
 func (h *Handler) UpdateAirport(w http.ResponseWriter, r *http.Request) {
 	id, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
 		// Handle the error for invalid UUID format
 		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid aircraft ID"))
+		w.Write([]byte("Invalid airport ID"))
 		return
 	}
 
-	var updates map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !patch.Replace(h.ctx, w, r, h.service, "airport", id) {
 		return
 	}
-	updates["UpdatedAt"] = time.Now()
+	patch.Written(h.ctx, w, r, h.service.Airport.GetAirport, id)
+}
 
-	if err := h.service.Airport.UpdateAirport(h.ctx, id, updates); err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+// PatchAirport applies a JSON Merge Patch or a JSON Patch to an airport as GET
+// returns it.
+func (h *Handler) PatchAirport(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		// Handle the error for invalid UUID format
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid airport ID"))
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	if !patch.Patch(h.ctx, w, r, h.service, "airport", id) {
+		return
+	}
+	patch.Written(h.ctx, w, r, h.service.Airport.GetAirport, id)
 }
 func (h *Handler) GetCitiesAirport(w http.ResponseWriter, r *http.Request) {
-	airportInfo, err := h.service.Airport.GetCitiesAirports(h.ctx)
+	format, ok := render.Negotiate(w, r)
+	if !ok {
+		return
+	}
+
+	version, err := h.service.Version.GetVersion(h.ctx, "airport", "city")
 	if err != nil {
 		log.Printf("Error fetching airport .data: %v", err)
-
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
-
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(airportInfo)
+	if conditional.Check(w, r, version.ETag(string(format)), version.LastModified) {
+		return
+	}
+	render.Stream(w, format, func(fn func(structs.AirportInfo) error) error {
+		return h.service.Airport.StreamCitiesAirports(h.ctx, fn)
+	})
 }
 
 func (h *Handler) GetCityNameAirport(w http.ResponseWriter, r *http.Request) {
@@ -254,10 +253,7 @@ func (h *Handler) GetCityNameAirport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(airport)
+	conditional.WriteJSON(w, r, airport, time.Time{})
 }
 
 func (h *Handler) GetCountryNameAirport(w http.ResponseWriter, r *http.Request) {
@@ -272,10 +268,7 @@ func (h *Handler) GetCountryNameAirport(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(airportInfo)
+	conditional.WriteJSON(w, r, airportInfo, time.Time{})
 }
 
 func (h *Handler) GetCityNameAirportAlternative(w http.ResponseWriter, r *http.Request) {
@@ -290,10 +283,7 @@ func (h *Handler) GetCityNameAirportAlternative(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(airportInfo)
+	conditional.WriteJSON(w, r, airportInfo, time.Time{})
 }
 
 func (h *Handler) GetCityIataCodeAirport(w http.ResponseWriter, r *http.Request) {
@@ -307,8 +297,5 @@ func (h *Handler) GetCityIataCodeAirport(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(airplane)
+	conditional.WriteJSON(w, r, airplane, time.Time{})
 }
@@ -0,0 +1,113 @@
+package airports
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/conditional"
+	internal_api "github.com/FACorreiaa/aviatoon-tracker/internal/handler/internalApi"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/board"
+	"github.com/go-chi/chi/v5"
+)
+
+// boardRefresh is how often a board asks the upstream flights endpoint for
+// fresh data; kiosks poll far more often than that.
+const boardRefresh = time.Minute
+
+// boardFetchTimeout is how long a board request waits for upstream before
+// it is served from what is stored.
+const boardFetchTimeout = 5 * time.Second
+
+const boardTimeLayout = "2006-01-02T15:04"
+
+var (
+	boardMu      sync.Mutex
+	boardFetched = make(map[string]time.Time)
+)
+
+func (h *Handler) GetDepartures(w http.ResponseWriter, r *http.Request) {
+	h.getBoard(w, r, board.Departures, "dep_iata")
+}
+
+func (h *Handler) GetArrivals(w http.ResponseWriter, r *http.Request) {
+	h.getBoard(w, r, board.Arrivals, "arr_iata")
+}
+
+func (h *Handler) getBoard(w http.ResponseWriter, r *http.Request, direction string, param string) {
+	iata := strings.ToUpper(chi.URLParam(r, "id"))
+	if len(iata) != 3 {
+		http.Error(w, "Invalid airport IATA code", http.StatusBadRequest)
+		return
+	}
+
+	var from, to time.Time
+	if v := r.URL.Query().Get("from"); v != "" {
+		t, err := time.Parse(boardTimeLayout, v)
+		if err != nil {
+			http.Error(w, "Invalid from, expected YYYY-MM-DDTHH:MM", http.StatusBadRequest)
+			return
+		}
+		from = t
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		t, err := time.Parse(boardTimeLayout, v)
+		if err != nil {
+			http.Error(w, "Invalid to, expected YYYY-MM-DDTHH:MM", http.StatusBadRequest)
+			return
+		}
+		to = t
+	}
+	if from.IsZero() != to.IsZero() {
+		http.Error(w, "from and to must be given together", http.StatusBadRequest)
+		return
+	}
+	if !from.IsZero() && !to.After(from) {
+		http.Error(w, "to must be after from", http.StatusBadRequest)
+		return
+	}
+
+	h.refreshBoard(r.Context(), iata, direction, param)
+
+	result, err := h.service.Board.GetBoard(r.Context(), iata, direction, from, to)
+	if errors.Is(err, board.ErrUnknownAirport) {
+		http.Error(w, "Airport not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching %s board: %v", direction, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	conditional.WriteJSON(w, r, result, time.Time{})
+}
+
+// refreshBoard records the airport's current flights from upstream, at most
+// once per boardRefresh, waiting for upstream at most boardFetchTimeout. A
+// failure only means the board is served from what is already stored.
+func (h *Handler) refreshBoard(ctx context.Context, iata string, direction string, param string) {
+	key := direction + ":" + iata
+	boardMu.Lock()
+	if time.Since(boardFetched[key]) < boardRefresh {
+		boardMu.Unlock()
+		return
+	}
+	boardFetched[key] = time.Now()
+	boardMu.Unlock()
+
+	fetchCtx, cancel := context.WithTimeout(ctx, boardFetchTimeout)
+	defer cancel()
+	flights, err := internal_api.FetchFlights(fetchCtx, param+"="+iata, "limit=100")
+	if err != nil {
+		log.Printf("Error fetching %s for %s: %v", direction, iata, err)
+		return
+	}
+	if _, err := h.service.Flight.RecordFlights(ctx, flights); err != nil {
+		log.Printf("Error recording %s for %s: %v", direction, iata, err)
+	}
+}
@@ -0,0 +1,168 @@
+// Package auth authenticates API users with the HS256 access tokens made by
+// utils.GenerateNewJWTAccessToken, signed with JWT_SECRET_KEY.
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+type contextKey struct{}
+
+// RoleAdmin is the credential set on the access tokens of administrators.
+const RoleAdmin = "admin"
+
+var errNoSecret = errors.New("JWT_SECRET_KEY is not set")
+
+// identity is who a valid access token was issued to.
+type identity struct {
+	id    uuid.UUID
+	admin bool
+}
+
+// Required rejects requests without a valid bearer token and makes the user
+// ID of the token available to UserID.
+func Required(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := bearer(r)
+		if token == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "Missing bearer token", http.StatusUnauthorized)
+			return
+		}
+
+		who, err := parse(token)
+		if errors.Is(err, errNoSecret) {
+			http.Error(w, "Authentication is not configured", http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, who)))
+	})
+}
+
+// Admin rejects the requests of users who are not administrators. It goes
+// after Required.
+func Admin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsAdmin(r) {
+			http.Error(w, "Administrator access required", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// UserID returns the authenticated user of a request that went through
+// Required.
+func UserID(r *http.Request) uuid.UUID {
+	who, _ := r.Context().Value(contextKey{}).(identity)
+	return who.id
+}
+
+// IsAdmin reports whether a request carries the valid token of an
+// administrator. Routes open to everyone use it for the options only
+// administrators have; it reads the token itself when Required did not.
+func IsAdmin(r *http.Request) bool {
+	if who, ok := r.Context().Value(contextKey{}).(identity); ok {
+		return who.admin
+	}
+	token := bearer(r)
+	if token == "" {
+		return false
+	}
+	who, err := parse(token)
+	return err == nil && who.admin
+}
+
+// Configured reports whether tokens can be issued and checked.
+func Configured() bool {
+	return os.Getenv("JWT_SECRET_KEY") != ""
+}
+
+// Credentials are the credentials of the access tokens issued to admin or
+// not.
+func Credentials(admin bool) []string {
+	if admin {
+		return []string{RoleAdmin}
+	}
+	return nil
+}
+
+func bearer(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	token := strings.TrimPrefix(header, "Bearer ")
+	if token == header {
+		return ""
+	}
+	return token
+}
+
+func parse(token string) (identity, error) {
+	secret := os.Getenv("JWT_SECRET_KEY")
+	if secret == "" {
+		return identity{}, errNoSecret
+	}
+
+	parsed, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		return []byte(secret), nil
+	})
+	if err != nil {
+		return identity{}, err
+	}
+
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok {
+		return identity{}, errors.New("unexpected claims")
+	}
+	// The generator sets "expires" rather than the registered "exp" claim.
+	expires, ok := claims["expires"].(float64)
+	if !ok || time.Now().Unix() > int64(expires) {
+		return identity{}, errors.New("token expired")
+	}
+	id, _ := claims["id"].(string)
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return identity{}, err
+	}
+	admin, _ := claims[RoleAdmin].(bool)
+	return identity{id: userID, admin: admin}, nil
+}
+
+// CalendarKey is the key of a user's calendar feed URL. Calendar apps
+// cannot send a bearer token, so the feed is authorised by this HMAC of the
+// user ID instead; it stays valid for as long as JWT_SECRET_KEY does.
+func CalendarKey(userID uuid.UUID) (string, error) {
+	secret := os.Getenv("JWT_SECRET_KEY")
+	if secret == "" {
+		return "", errNoSecret
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("calendar:" + userID.String()))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// ValidCalendarKey reports whether key is the calendar key of userID.
+func ValidCalendarKey(userID uuid.UUID, key string) bool {
+	want, err := CalendarKey(userID)
+	return err == nil && hmac.Equal([]byte(key), []byte(want))
+}
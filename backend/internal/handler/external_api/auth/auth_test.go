@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/utils"
+	"github.com/google/uuid"
+)
+
+func token(t *testing.T, id uuid.UUID, admin bool) string {
+	t.Helper()
+	token, err := utils.GenerateNewJWTAccessToken(Credentials(admin), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestAdmin(t *testing.T) {
+	t.Setenv("JWT_SECRET_KEY", "secret")
+	id := uuid.New()
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"not a bearer token", "Basic " + token(t, id, true), http.StatusUnauthorized},
+		{"invalid token", "Bearer x.y.z", http.StatusUnauthorized},
+		{"user", "Bearer " + token(t, id, false), http.StatusForbidden},
+		{"administrator", "Bearer " + token(t, id, true), http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got uuid.UUID
+			handler := Required(Admin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = UserID(r)
+				w.WriteHeader(http.StatusNoContent)
+			})))
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			if tt.want == http.StatusNoContent && got != id {
+				t.Errorf("UserID = %s, want %s", got, id)
+			}
+		})
+	}
+}
+
+func TestIsAdmin(t *testing.T) {
+	t.Setenv("JWT_SECRET_KEY", "secret")
+	admin, user := token(t, uuid.New(), true), token(t, uuid.New(), false)
+
+	tests := []struct {
+		authorization string
+		want          bool
+	}{
+		{"", false},
+		{"Bearer " + user, false},
+		{"Bearer " + admin, true},
+		{"Bearer " + admin + "x", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Authorization", tt.authorization)
+		if got := IsAdmin(r); got != tt.want {
+			t.Errorf("IsAdmin(%q) = %v, want %v", tt.authorization, got, tt.want)
+		}
+	}
+
+	// A token signed with another secret is no administrator's.
+	t.Setenv("JWT_SECRET_KEY", "other")
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+admin)
+	if IsAdmin(r) {
+		t.Error("IsAdmin accepted a token signed with another secret")
+	}
+}
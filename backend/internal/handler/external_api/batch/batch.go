@@ -0,0 +1,101 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/batch"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+)
+
+// maxBodyBytes bounds the body of a bulk write, well above what the largest
+// batch of operations takes.
+const maxBodyBytes = 4 << 20
+
+type Handler struct {
+	service *service.Service
+	ctx     context.Context
+}
+
+func NewHandler(s *service.Service) *Handler {
+	return &Handler{service: s, ctx: context.Background()}
+}
+
+// Apply answers POST /{resource}/bulk with {"mode": "atomic"|"partial",
+// "operations": [{"op", "id", "data"}, ...]} and the result of every
+// operation. It is 200 when every operation was applied, 207 when a partial
+// batch applied only some, 409 when an atomic batch was rolled back and 422
+// when an operation is invalid, in which case nothing was written. A body
+// over maxBodyBytes is 413. The references of the written rows are relinked
+// afterwards.
+func (h *Handler) Apply(resource string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req structs.BulkRequest
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+		decoder.UseNumber()
+		if err := decoder.Decode(&req); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		result, err := h.service.Batch.Apply(h.ctx, resource, req)
+		switch {
+		case err == nil:
+		case errors.Is(err, batch.ErrInvalid):
+			writeJSON(w, http.StatusUnprocessableEntity, result)
+			return
+		case errors.Is(err, batch.ErrInvalidMode), errors.Is(err, batch.ErrNoOperations):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, batch.ErrTooMany):
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		default:
+			log.Printf("Error applying %s bulk write: %v", resource, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if ids := applied(result); len(ids) > 0 {
+			if _, err := h.service.Integrity.LinkRows(h.ctx, resource, ids); err != nil {
+				log.Printf("error linking references: %v", err)
+			}
+		}
+
+		status := http.StatusOK
+		if result.Failed > 0 {
+			status = http.StatusMultiStatus
+			if result.Mode == structs.BulkAtomic {
+				status = http.StatusConflict
+			}
+		}
+		writeJSON(w, status, result)
+	}
+}
+
+// applied lists the rows the operations of result wrote.
+func applied(result structs.BulkResult) []uuid.UUID {
+	var ids []uuid.UUID
+	for _, item := range result.Results {
+		if item.ID != nil && item.Status >= 200 && item.Status <= 299 {
+			ids = append(ids, *item.ID)
+		}
+	}
+	return ids
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,29 @@
+package batch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestApplyBody(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"not json", "{", http.StatusBadRequest},
+		{"too large", `{"mode":"atomic","operations":[` + strings.Repeat(`{"op":"delete"},`, maxBodyBytes/16) + `]}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/tax/bulk", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			NewHandler(nil).Apply("tax")(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
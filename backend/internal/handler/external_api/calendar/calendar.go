@@ -0,0 +1,90 @@
+package calendar
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/auth"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/conditional"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	service *service.Service
+	ctx     context.Context
+}
+
+func NewHandler(s *service.Service) *Handler {
+	return &Handler{service: s, ctx: context.Background()}
+}
+
+// GetFlightCalendar serves the recorded legs of {flight_iata} as an
+// iCalendar feed.
+func (h *Handler) GetFlightCalendar(w http.ResponseWriter, r *http.Request) {
+	cal, err := h.service.Calendar.FlightCalendar(h.ctx, chi.URLParam(r, "flight_iata"))
+	if err != nil {
+		log.Printf("Error fetching flight calendar: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	write(w, r, cal)
+}
+
+// GetCalendarURL returns the address of the authenticated user's feed of
+// followed flights, to paste into a calendar app.
+func (h *Handler) GetCalendarURL(w http.ResponseWriter, r *http.Request) {
+	id := auth.UserID(r)
+	key, err := auth.CalendarKey(id)
+	if err != nil {
+		log.Printf("Error creating calendar key: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	conditional.WriteJSON(w, r, map[string]string{
+		"url": "/api/v1/calendars/" + id.String() + ".ics?key=" + key,
+	}, time.Time{})
+}
+
+// GetUserCalendar serves the followed flights of {user_id} as an iCalendar
+// feed. It is authorised by the ?key= of the URL from GetCalendarURL.
+func (h *Handler) GetUserCalendar(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "user_id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if !auth.ValidCalendarKey(id, r.URL.Query().Get("key")) {
+		http.Error(w, "Invalid calendar key", http.StatusForbidden)
+		return
+	}
+
+	cal, err := h.service.Calendar.UserCalendar(h.ctx, id)
+	if err != nil {
+		log.Printf("Error fetching user calendar: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	write(w, r, cal)
+}
+
+// write sends a calendar with an ETag, so subscribed apps polling an
+// unchanged feed get a 304.
+func write(w http.ResponseWriter, r *http.Request, cal structs.Calendar) {
+	body := encode(cal)
+	sum := sha256.Sum256(body)
+	if conditional.Check(w, r, `"`+hex.EncodeToString(sum[:16])+`"`, time.Time{}) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="`+cal.Name+`.ics"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
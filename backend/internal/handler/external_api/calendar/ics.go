@@ -0,0 +1,293 @@
+package calendar
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/localtime"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+const (
+	localLayout = "20060102T150405"
+	utcLayout   = "20060102T150405Z"
+	// maxLine is the longest content line RFC 5545 allows, in octets.
+	maxLine = 75
+)
+
+// encoder writes an iCalendar (RFC 5545) stream.
+type encoder struct {
+	buf bytes.Buffer
+}
+
+// line writes a content line, folding it at 75 octets without splitting a
+// UTF-8 sequence.
+func (e *encoder) line(name string, value string) {
+	l := name + ":" + value
+	for first := true; ; first = false {
+		limit := maxLine
+		if !first {
+			e.buf.WriteByte(' ')
+			limit--
+		}
+		if len(l) <= limit {
+			e.buf.WriteString(l)
+			e.buf.WriteString("\r\n")
+			return
+		}
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(l[cut]) {
+			cut--
+		}
+		e.buf.WriteString(l[:cut])
+		e.buf.WriteString("\r\n")
+		l = l[cut:]
+	}
+}
+
+// text escapes a TEXT value.
+func text(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// encode renders a calendar. Times carry the TZID of their airport, with a
+// VTIMEZONE for every zone used; times at airports of unknown zone are
+// floating.
+func encode(cal structs.Calendar) []byte {
+	e := &encoder{}
+	e.line("BEGIN", "VCALENDAR")
+	e.line("VERSION", "2.0")
+	e.line("PRODID", "-//aviatoon-tracker//flights//EN")
+	e.line("CALSCALE", "GREGORIAN")
+	e.line("METHOD", "PUBLISH")
+	e.line("X-WR-CALNAME", text(cal.Name))
+	e.line("REFRESH-INTERVAL;VALUE=DURATION", "PT1H")
+	e.line("X-PUBLISHED-TTL", "PT1H")
+
+	for _, span := range zoneSpans(cal.Events) {
+		e.vtimezone(span.zone, span.from, span.to)
+	}
+	for _, ev := range cal.Events {
+		e.vevent(ev)
+	}
+
+	e.line("END", "VCALENDAR")
+	return e.buf.Bytes()
+}
+
+func (e *encoder) vevent(ev structs.CalendarEvent) {
+	f := ev.Flight
+	e.line("BEGIN", "VEVENT")
+	e.line("UID", f.ID.String()+"@aviatoon-tracker")
+	e.line("SEQUENCE", strconv.Itoa(f.Sequence))
+	e.line("DTSTAMP", f.LastSeen.UTC().Format(utcLayout))
+	e.line("LAST-MODIFIED", f.LastSeen.UTC().Format(utcLayout))
+	e.time("DTSTART", *ev.Start, ev.StartZone)
+	if ev.End != nil && instant(*ev.End, ev.EndZone).After(instant(*ev.Start, ev.StartZone)) {
+		e.time("DTEND", *ev.End, ev.EndZone)
+	}
+
+	e.line("SUMMARY", text(fmt.Sprintf("%s %s → %s", f.FlightIata, f.Departure.Iata, f.Arrival.Iata)))
+	e.line("LOCATION", text(airportName(ev.DepartureName, f.Departure.Iata)))
+	if ev.Departure.HasCoordinates() {
+		e.line("GEO", geo(ev.Departure))
+	}
+	e.line("DESCRIPTION", text(description(ev)))
+	if f.Status == structs.Cancelled {
+		e.line("STATUS", "CANCELLED")
+	} else {
+		e.line("STATUS", "CONFIRMED")
+	}
+	e.line("TRANSP", "OPAQUE")
+
+	// RFC 9073 locations, for clients that show both ends.
+	e.vlocation(f.ID.String()+"-departure", ev.DepartureName, f.Departure.Iata, ev.Departure)
+	e.vlocation(f.ID.String()+"-arrival", ev.ArrivalName, f.Arrival.Iata, ev.Arrival)
+	e.line("END", "VEVENT")
+}
+
+func (e *encoder) vlocation(uid string, name string, iata string, position structs.AirportPosition) {
+	e.line("BEGIN", "VLOCATION")
+	e.line("UID", uid)
+	e.line("NAME", text(airportName(name, iata)))
+	e.line("LOCATION-TYPE", "airport")
+	if position.HasCoordinates() {
+		e.line("GEO", geo(position))
+	}
+	e.line("END", "VLOCATION")
+}
+
+// time writes a stored wall-clock time with the TZID of zone, or floating.
+func (e *encoder) time(name string, t time.Time, zone *time.Location) {
+	if zone == nil {
+		e.line(name, t.UTC().Format(localLayout))
+		return
+	}
+	e.line(name+";TZID="+zone.String(), t.UTC().Format(localLayout))
+}
+
+// vtimezone describes zone between from and to: the observance in effect at
+// from and every offset change up to to, found by probing the zone database
+// a day at a time.
+func (e *encoder) vtimezone(zone *time.Location, from time.Time, to time.Time) {
+	e.line("BEGIN", "VTIMEZONE")
+	e.line("TZID", zone.String())
+
+	name, offset := from.In(zone).Zone()
+	e.observance(from.In(zone).IsDST(), time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC), offset, offset, name)
+	for t := from; t.Before(to); {
+		next := t.Add(24 * time.Hour)
+		if _, o := next.In(zone).Zone(); o == offset {
+			t = next
+			continue
+		}
+		// Narrow down to the second the offset changes.
+		lo, hi := t, next
+		for hi.Sub(lo) > time.Second {
+			mid := lo.Add(hi.Sub(lo) / 2)
+			if _, o := mid.In(zone).Zone(); o == offset {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		newName, newOffset := hi.In(zone).Zone()
+		onset := hi.Add(time.Duration(offset) * time.Second).UTC()
+		e.observance(hi.In(zone).IsDST(), onset, offset, newOffset, newName)
+		offset, t = newOffset, hi
+	}
+	e.line("END", "VTIMEZONE")
+}
+
+func (e *encoder) observance(dst bool, onset time.Time, from int, to int, name string) {
+	kind := "STANDARD"
+	if dst {
+		kind = "DAYLIGHT"
+	}
+	e.line("BEGIN", kind)
+	e.line("DTSTART", onset.Format(localLayout))
+	e.line("TZOFFSETFROM", utcOffset(from))
+	e.line("TZOFFSETTO", utcOffset(to))
+	e.line("TZNAME", text(name))
+	e.line("END", kind)
+}
+
+type zoneSpan struct {
+	zone     *time.Location
+	from, to time.Time
+}
+
+// zoneSpans lists the zones the events use and the period each has to
+// describe, with a day of margin on both sides.
+func zoneSpans(events []structs.CalendarEvent) []zoneSpan {
+	var spans []zoneSpan
+	index := make(map[string]int)
+	add := func(t *time.Time, zone *time.Location) {
+		if t == nil || zone == nil {
+			return
+		}
+		at := instant(*t, zone)
+		i, ok := index[zone.String()]
+		if !ok {
+			index[zone.String()] = len(spans)
+			spans = append(spans, zoneSpan{zone: zone, from: at, to: at})
+			return
+		}
+		if at.Before(spans[i].from) {
+			spans[i].from = at
+		}
+		if at.After(spans[i].to) {
+			spans[i].to = at
+		}
+	}
+	for _, ev := range events {
+		add(ev.Start, ev.StartZone)
+		add(ev.End, ev.EndZone)
+	}
+	for i := range spans {
+		spans[i].from = spans[i].from.Add(-24 * time.Hour)
+		spans[i].to = spans[i].to.Add(24 * time.Hour)
+	}
+	return spans
+}
+
+// instant is the moment a stored wall-clock time stands for; floating times
+// are read as UTC, which is good enough to order them.
+func instant(t time.Time, zone *time.Location) time.Time {
+	if zone == nil {
+		return t.UTC()
+	}
+	return localtime.At(t, zone)
+}
+
+func utcOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign, seconds = "-", -seconds
+	}
+	s := fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+	if seconds%60 != 0 {
+		s += fmt.Sprintf("%02d", seconds%60)
+	}
+	return s
+}
+
+func geo(p structs.AirportPosition) string {
+	return strconv.FormatFloat(p.Latitude, 'f', 6, 64) + ";" + strconv.FormatFloat(p.Longitude, 'f', 6, 64)
+}
+
+func airportName(name string, iata string) string {
+	if name == "" {
+		return iata
+	}
+	return name + " (" + iata + ")"
+}
+
+func description(ev structs.CalendarEvent) string {
+	f := ev.Flight
+	lines := []string{
+		fmt.Sprintf("%s from %s to %s", f.FlightIata, airportName(ev.DepartureName, f.Departure.Iata), airportName(ev.ArrivalName, f.Arrival.Iata)),
+	}
+	if f.AirlineName != "" {
+		lines = append(lines, "Airline: "+f.AirlineName)
+	}
+	if f.Codeshare != nil {
+		lines = append(lines, "Operated as "+f.Codeshare.FlightIata)
+	}
+	if f.Status != "" {
+		lines = append(lines, "Status: "+string(f.Status))
+	}
+	lines = append(lines, endpointLines("Departure", f.Departure)...)
+	lines = append(lines, endpointLines("Arrival", f.Arrival)...)
+	return strings.Join(lines, "\n")
+}
+
+func endpointLines(label string, e structs.FlightEndpoint) []string {
+	var parts []string
+	if e.Scheduled != nil {
+		parts = append(parts, "scheduled "+e.Scheduled.UTC().Format("15:04"))
+	}
+	if e.Estimated != nil {
+		parts = append(parts, "estimated "+e.Estimated.UTC().Format("15:04"))
+	}
+	if e.Actual != nil {
+		parts = append(parts, "actual "+e.Actual.UTC().Format("15:04"))
+	}
+	if e.Terminal != "" {
+		parts = append(parts, "terminal "+e.Terminal)
+	}
+	if e.Gate != "" {
+		parts = append(parts, "gate "+e.Gate)
+	}
+	if e.Delay != nil && *e.Delay > 0 {
+		parts = append(parts, fmt.Sprintf("delay %d min", *e.Delay))
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	return []string{label + ": " + strings.Join(parts, ", ")}
+}
@@ -0,0 +1,130 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestLineFolding(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"short", "TP1350 LIS → LHR"},
+		{"exactly one line", strings.Repeat("a", maxLine-len("DESCRIPTION:"))},
+		{"one octet over", strings.Repeat("a", maxLine-len("DESCRIPTION:")+1)},
+		{"long ascii", strings.Repeat("abcdefghij", 30)},
+		{"multibyte", strings.Repeat("→ São Paulo–Guarulhos ", 20)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &encoder{}
+			e.line("DESCRIPTION", tt.value)
+			out := e.buf.String()
+
+			if !strings.HasSuffix(out, "\r\n") {
+				t.Fatalf("%q does not end with CRLF", out)
+			}
+			lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+			for i, l := range lines {
+				if len(l) > maxLine {
+					t.Errorf("line %d is %d octets", i, len(l))
+				}
+				if i > 0 && !strings.HasPrefix(l, " ") {
+					t.Errorf("continuation line %d does not start with a space", i)
+				}
+				if !utf8.ValidString(l) {
+					t.Errorf("line %d splits a UTF-8 sequence: %q", i, l)
+				}
+			}
+			// Unfolding removes every CRLF followed by a space.
+			if got := strings.ReplaceAll(strings.TrimSuffix(out, "\r\n"), "\r\n ", ""); got != "DESCRIPTION:"+tt.value {
+				t.Errorf("unfolded %q", got)
+			}
+		})
+	}
+}
+
+func TestText(t *testing.T) {
+	got := text("a\\b;c,d\ne\r\nf")
+	if want := `a\\b\;c\,d\ne\nf`; got != want {
+		t.Errorf("text = %s, want %s", got, want)
+	}
+}
+
+func TestUTCOffset(t *testing.T) {
+	tests := []struct {
+		seconds int
+		want    string
+	}{
+		{0, "+0000"},
+		{3600, "+0100"},
+		{-5 * 3600, "-0500"},
+		{5*3600 + 45*60, "+0545"},
+		{-(9*3600 + 30*60), "-0930"},
+		{-(36*60 + 45), "-003645"},
+	}
+	for _, tt := range tests {
+		if got := utcOffset(tt.seconds); got != tt.want {
+			t.Errorf("utcOffset(%d) = %s, want %s", tt.seconds, got, tt.want)
+		}
+	}
+}
+
+func TestVTimezone(t *testing.T) {
+	lisbon, err := time.LoadLocation("Europe/Lisbon")
+	if err != nil {
+		t.Skipf("no zone database: %v", err)
+	}
+
+	e := &encoder{}
+	from := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 11, 30, 0, 0, 0, 0, time.UTC)
+	e.vtimezone(lisbon, from, to)
+
+	want := strings.Join([]string{
+		"BEGIN:VTIMEZONE",
+		"TZID:Europe/Lisbon",
+		"BEGIN:STANDARD",
+		"DTSTART:19700101T000000",
+		"TZOFFSETFROM:+0000",
+		"TZOFFSETTO:+0000",
+		"TZNAME:WET",
+		"END:STANDARD",
+		"BEGIN:DAYLIGHT",
+		"DTSTART:20230326T010000",
+		"TZOFFSETFROM:+0000",
+		"TZOFFSETTO:+0100",
+		"TZNAME:WEST",
+		"END:DAYLIGHT",
+		"BEGIN:STANDARD",
+		"DTSTART:20231029T020000",
+		"TZOFFSETFROM:+0100",
+		"TZOFFSETTO:+0000",
+		"TZNAME:WET",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+	}, "\r\n") + "\r\n"
+	if got := e.buf.String(); got != want {
+		t.Errorf("vtimezone =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestVTimezoneWithoutChanges(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("no zone database: %v", err)
+	}
+
+	e := &encoder{}
+	e.vtimezone(tokyo, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC))
+	got := e.buf.String()
+	if strings.Count(got, "BEGIN:STANDARD") != 1 || strings.Contains(got, "DAYLIGHT") {
+		t.Errorf("vtimezone of a zone without changes =\n%s", got)
+	}
+	if !strings.Contains(got, "TZOFFSETTO:+0900\r\n") {
+		t.Errorf("vtimezone of Asia/Tokyo has no +0900 offset:\n%s", got)
+	}
+}
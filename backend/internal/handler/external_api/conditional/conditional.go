@@ -0,0 +1,147 @@
+package conditional
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Versioned is implemented by rows carrying created_at/updated_at.
+type Versioned interface {
+	LastModified() time.Time
+}
+
+// LastModified returns the most recent modification time of rows.
+func LastModified[T Versioned](rows ...T) time.Time {
+	var last time.Time
+	for _, row := range rows {
+		if t := row.LastModified(); t.After(last) {
+			last = t
+		}
+	}
+	return last
+}
+
+// ETag is a strong validator over the JSON representation of v, the same
+// bytes WriteJSON sends.
+func ETag(v any) (string, []byte, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return "", nil, err
+	}
+	body = append(body, '\n')
+
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, body, nil
+}
+
+// WriteJSON writes v with ETag and, when lastModified is set, Last-Modified
+// headers, answering 304 when the client copy is still current. Joined views
+// pass a zero lastModified: the newest row of one table says nothing about
+// the rows it was joined with.
+func WriteJSON(w http.ResponseWriter, r *http.Request, v any, lastModified time.Time) {
+	etag, body, err := ETag(v)
+	if err != nil {
+		log.Printf("error encoding response as JSON: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if Check(w, r, etag, lastModified) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// Check sets the validators of a response that is about to be written and
+// answers 304 itself, returning true, when the client copy is still current.
+// Streamed lists use it with a validator computed before the first row.
+func Check(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	w.Header().Set("ETag", etag)
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if notModified(r, etag, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// ErrModified is returned by the check of Match when the row is no longer
+// the one the If-Match of the request names.
+var ErrModified = errors.New("resource was modified")
+
+// Match enforces optimistic concurrency on writes: the request must carry
+// an If-Match matching the current representation, which the write reads
+// in its own transaction, under a lock, and checks there. The check
+// returns ErrModified, and sets the current ETag on w, when the If-Match of
+// r does not match. Match writes 428 and returns false when r carries no
+// If-Match.
+func Match(w http.ResponseWriter, r *http.Request) (func(current any) error, bool) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		http.Error(w, "If-Match header is required", http.StatusPreconditionRequired)
+		return nil, false
+	}
+
+	return func(current any) error {
+		etag, _, err := ETag(current)
+		if err != nil {
+			return err
+		}
+		if !matches(header, etag, false) {
+			w.Header().Set("ETag", etag)
+			return ErrModified
+		}
+		return nil
+	}, true
+}
+
+// notModified follows RFC 9110: If-None-Match wins over If-Modified-Since.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		return matches(header, etag, true)
+	}
+	if header := r.Header.Get("If-Modified-Since"); header != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(header)
+		if err != nil {
+			return false
+		}
+		return !lastModified.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// matches reports whether etag is in the comma separated header list. Weak
+// comparison ignores W/ prefixes and is what If-None-Match uses.
+func matches(header string, etag string, weak bool) bool {
+	if weak {
+		etag = strings.TrimPrefix(etag, "W/")
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
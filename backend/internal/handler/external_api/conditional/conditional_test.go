@@ -0,0 +1,126 @@
+package conditional
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type row struct {
+	Name string `json:"name"`
+}
+
+func etag(t *testing.T, v any) string {
+	t.Helper()
+	tag, _, err := ETag(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tag
+}
+
+func TestETag(t *testing.T) {
+	a, b := etag(t, row{"a"}), etag(t, row{"b"})
+	if a == b {
+		t.Errorf("different rows share the ETag %s", a)
+	}
+	if again := etag(t, row{"a"}); again != a {
+		t.Errorf("ETag = %s, then %s", a, again)
+	}
+	if a[0] != '"' || a[len(a)-1] != '"' {
+		t.Errorf("ETag %s is not quoted", a)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		etag   string
+		weak   bool
+		want   bool
+	}{
+		{`"a"`, `"a"`, false, true},
+		{`"b"`, `"a"`, false, false},
+		{`"b", "a"`, `"a"`, false, true},
+		{`*`, `"a"`, false, true},
+		{`W/"a"`, `"a"`, false, false},
+		{`W/"a"`, `"a"`, true, true},
+		{`"a"`, `W/"a"`, true, true},
+	}
+	for _, tt := range tests {
+		if got := matches(tt.header, tt.etag, tt.weak); got != tt.want {
+			t.Errorf("matches(%s, %s, %v) = %v, want %v", tt.header, tt.etag, tt.weak, got, tt.want)
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	modified := time.Date(2023, 4, 19, 6, 25, 0, 0, time.UTC)
+	current := etag(t, row{"a"})
+
+	tests := []struct {
+		name   string
+		method string
+		header http.Header
+		want   int
+	}{
+		{"no validators", http.MethodGet, http.Header{}, http.StatusOK},
+		{"current etag", http.MethodGet, http.Header{"If-None-Match": {current}}, http.StatusNotModified},
+		{"weak current etag", http.MethodGet, http.Header{"If-None-Match": {"W/" + current}}, http.StatusNotModified},
+		{"stale etag", http.MethodGet, http.Header{"If-None-Match": {`"stale"`}}, http.StatusOK},
+		{"not modified since", http.MethodGet, http.Header{"If-Modified-Since": {modified.Format(http.TimeFormat)}}, http.StatusNotModified},
+		{"modified since", http.MethodGet, http.Header{"If-Modified-Since": {modified.Add(-time.Second).Format(http.TimeFormat)}}, http.StatusOK},
+		{"etag wins over date", http.MethodGet, http.Header{
+			"If-None-Match":     {`"stale"`},
+			"If-Modified-Since": {modified.Format(http.TimeFormat)},
+		}, http.StatusOK},
+		{"only reads", http.MethodPost, http.Header{"If-None-Match": {current}}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/", nil)
+			r.Header = tt.header
+			w := httptest.NewRecorder()
+			WriteJSON(w, r, row{"a"}, modified)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			if got := w.Header().Get("ETag"); got != current {
+				t.Errorf("ETag = %s, want %s", got, current)
+			}
+			if got := w.Header().Get("Last-Modified"); got != modified.Format(http.TimeFormat) {
+				t.Errorf("Last-Modified = %s", got)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPut, "/", nil)
+	w := httptest.NewRecorder()
+	if _, ok := Match(w, r); ok || w.Code != http.StatusPreconditionRequired {
+		t.Fatalf("Match without If-Match = %v, status %d", ok, w.Code)
+	}
+
+	r.Header.Set("If-Match", etag(t, row{"a"}))
+	w = httptest.NewRecorder()
+	check, ok := Match(w, r)
+	if !ok {
+		t.Fatal("Match refused an If-Match")
+	}
+	if err := check(row{"a"}); err != nil {
+		t.Errorf("check of the current row = %v", err)
+	}
+	if w.Header().Get("ETag") != "" {
+		t.Errorf("ETag set on a match")
+	}
+	if err := check(row{"b"}); !errors.Is(err, ErrModified) {
+		t.Errorf("check of a modified row = %v, want %v", err, ErrModified)
+	}
+	if got, want := w.Header().Get("ETag"), etag(t, row{"b"}); got != want {
+		t.Errorf("ETag = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,64 @@
+package emissions
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/conditional"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/emissions"
+)
+
+type Handler struct {
+	service *service.Service
+	ctx     context.Context
+}
+
+func NewHandler(s *service.Service) *Handler {
+	return &Handler{service: s, ctx: context.Background()}
+}
+
+// GetEmissions estimates CO2 for a route (?from=&to=, optionally
+// &aircraft=), a flight number (?flight=) or an airline's flights
+// (?airline=). Flight and airline estimates can be narrowed to ?date=.
+func (h *Handler) GetEmissions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var date time.Time
+	if v := query.Get("date"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		date = d
+	}
+
+	var result interface{}
+	var err error
+	switch {
+	case query.Get("from") != "" && query.Get("to") != "":
+		result, err = h.service.Emissions.EstimateRoute(h.ctx, query.Get("from"), query.Get("to"), query.Get("aircraft"))
+	case query.Get("flight") != "":
+		result, err = h.service.Emissions.EstimateFlight(h.ctx, query.Get("flight"), date)
+	case query.Get("airline") != "":
+		result, err = h.service.Emissions.EstimateAirline(h.ctx, query.Get("airline"), date)
+	default:
+		http.Error(w, "Expected from and to, flight or airline", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, emissions.ErrUnknownAirport) {
+		http.Error(w, "Unknown airport or airport without coordinates", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error estimating emissions: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	conditional.WriteJSON(w, r, result, time.Time{})
+}
@@ -0,0 +1,96 @@
+package flights
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/conditional"
+	internal_api "github.com/FACorreiaa/aviatoon-tracker/internal/handler/internalApi"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// upstreamParams are the query parameters passed on to the upstream flights
+// endpoint.
+var upstreamParams = []string{
+	"flight_date", "flight_status", "flight_iata", "flight_icao", "airline_iata", "airline_icao",
+	"dep_iata", "dep_icao", "arr_iata", "arr_icao", "limit", "offset",
+}
+
+type Handler struct {
+	service *service.Service
+	ctx     context.Context
+}
+
+func NewHandler(s *service.Service) *Handler {
+	return &Handler{service: s, ctx: context.Background()}
+}
+
+// GetFlights fetches flights from the upstream flights endpoint. With
+// ?dedupe=true every physical flight is returned once, under its operating
+// flight number, with the marketing numbers sold on it.
+func (h *Handler) GetFlights(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	dedupe := false
+	if v := query.Get("dedupe"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "Invalid dedupe, expected true or false", http.StatusBadRequest)
+			return
+		}
+		dedupe = b
+	}
+
+	var params []string
+	for _, name := range upstreamParams {
+		if v := query.Get(name); v != "" {
+			params = append(params, name+"="+v)
+		}
+	}
+
+	flights, err := internal_api.FetchFlights(r.Context(), params...)
+	if err != nil {
+		log.Printf("Error fetching flights: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !dedupe {
+		conditional.WriteJSON(w, r, flights, time.Time{})
+		return
+	}
+
+	operated, err := h.service.Flight.Dedupe(h.ctx, flights)
+	if err != nil {
+		log.Printf("Error deduplicating flights: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	conditional.WriteJSON(w, r, operated, time.Time{})
+}
+
+// GetCodeshares shows the operating flight behind {flight_iata} and every
+// flight number sold on it, from the stored flights of ?date= (default
+// today).
+func (h *Handler) GetCodeshares(w http.ResponseWriter, r *http.Request) {
+	date := time.Now().UTC().Truncate(24 * time.Hour)
+	if v := r.URL.Query().Get("date"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		date = d
+	}
+
+	codeshares, err := h.service.Flight.GetCodeshares(h.ctx, chi.URLParam(r, "flight_iata"), date)
+	if err != nil {
+		log.Printf("Error fetching codeshares: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	conditional.WriteJSON(w, r, codeshares, time.Time{})
+}
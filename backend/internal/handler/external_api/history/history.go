@@ -0,0 +1,78 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/conditional"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/trash"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/history"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+type Handler struct {
+	service *service.Service
+	ctx     context.Context
+}
+
+func NewHandler(s *service.Service) *Handler {
+	return &Handler{service: s, ctx: context.Background()}
+}
+
+// Context returns ctx set to read the version a row had at ?as_of=, a date
+// or a time, when the request gives one.
+func Context(ctx context.Context, r *http.Request) (context.Context, error) {
+	asOf, err := structs.ParseTime(r.URL.Query().Get("as_of"))
+	if err != nil {
+		return ctx, errors.New("invalid as_of, expected a date or an RFC 3339 time")
+	}
+	if asOf.IsZero() {
+		return ctx, nil
+	}
+	return structs.WithAsOf(ctx, asOf), nil
+}
+
+// GetHistory answers GET /{resource}/{id}/history with the latest versions
+// of a row of the given table, newest first, up to ?limit=. The history of
+// a soft deleted or purged row is only shown to administrators asking for
+// it with ?include_deleted=true.
+func (h *Handler) GetHistory(resource string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid ID", http.StatusBadRequest)
+			return
+		}
+		limit := defaultHistoryLimit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			limit, err = strconv.Atoi(v)
+			if err != nil || limit < 1 || limit > maxHistoryLimit {
+				http.Error(w, "Invalid limit", http.StatusBadRequest)
+				return
+			}
+		}
+
+		entries, err := h.service.History.GetHistory(trash.Context(h.ctx, r), resource, id, limit)
+		switch {
+		case err == nil:
+			conditional.WriteJSON(w, r, entries, time.Time{})
+		case errors.Is(err, history.ErrNotFound):
+			http.Error(w, "Not found", http.StatusNotFound)
+		default:
+			log.Printf("Error fetching %s history: %v", resource, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package imports
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/render"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+// Queue answers a list of a reference table that was never imported. It
+// queues the import of resource for the job workers, or finds the one
+// already queued, and answers 202 with the location of the job and an
+// empty list in format.
+func Queue[T any](ctx context.Context, w http.ResponseWriter, s *service.Service, resource string, format render.Format) {
+	p := structs.ImportJob{Resource: resource}
+	j, _, err := s.Job.Enqueue(ctx, structs.ImportKind, p.Key(), p)
+	if err != nil {
+		log.Printf("Error queueing %s import: %v", resource, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", render.ContentType(format))
+	w.Header().Set("Location", "/api/v1/jobs/"+j.ID.String())
+	w.WriteHeader(http.StatusAccepted)
+	err = render.Encode(w, format, func(func(T) error) error { return nil })
+	if err != nil {
+		log.Printf("Error writing empty %s list: %v", resource, err)
+	}
+}
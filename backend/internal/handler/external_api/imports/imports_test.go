@@ -0,0 +1,46 @@
+package imports
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/render"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+)
+
+type fakeJob struct {
+	service.Job
+	kind, key string
+	payload   interface{}
+}
+
+func (f *fakeJob) Enqueue(ctx context.Context, kind string, key string, payload interface{}) (structs.Job, bool, error) {
+	f.kind, f.key, f.payload = kind, key, payload
+	return structs.Job{ID: uuid.MustParse("6f1c2a3e-9a4b-4f6d-8e2a-1b3c4d5e6f70")}, true, nil
+}
+
+func TestQueue(t *testing.T) {
+	jobs := &fakeJob{}
+	w := httptest.NewRecorder()
+	Queue[structs.Airport](context.Background(), w, &service.Service{Job: jobs}, "airport", render.JSON)
+
+	if w.Code != http.StatusAccepted {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusAccepted)
+	}
+	if got := w.Header().Get("Location"); got != "/api/v1/jobs/6f1c2a3e-9a4b-4f6d-8e2a-1b3c4d5e6f70" {
+		t.Errorf("Location = %q", got)
+	}
+	if got := w.Body.String(); got != "[]\n" {
+		t.Errorf("body = %q, want an empty list", got)
+	}
+	if jobs.kind != "import" || jobs.key != "import:airport" {
+		t.Errorf("queued %s with key %s, want import with key import:airport", jobs.kind, jobs.key)
+	}
+	if p, ok := jobs.payload.(structs.ImportJob); !ok || p.Resource != "airport" || p.DryRun {
+		t.Errorf("payload = %#v", jobs.payload)
+	}
+}
@@ -0,0 +1,181 @@
+package ingestions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/conditional"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/worker"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/ingestion"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultRunLimit = 50
+	maxRunLimit     = 500
+)
+
+type Handler struct {
+	service *service.Service
+	ctx     context.Context
+}
+
+func NewHandler(s *service.Service) *Handler {
+	return &Handler{service: s, ctx: context.Background()}
+}
+
+// CreateIngestion queues an import of {"dataset", "dry_run"} on the job
+// workers and answers with the job; the run shows up once a worker starts
+// it.
+func (h *Handler) CreateIngestion(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Dataset string `json:"dataset"`
+		DryRun  bool   `json:"dry_run"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	payload := structs.ImportJob{Resource: body.Dataset, DryRun: body.DryRun}
+	raw, _ := json.Marshal(payload)
+	key, err := worker.Check(worker.KindImport, raw)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	j, created, err := h.service.Job.Enqueue(h.ctx, worker.KindImport, key, payload)
+	if err != nil {
+		log.Printf("Error queueing ingestion: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Location", "/api/v1/jobs/"+j.ID.String())
+	status := http.StatusAccepted
+	if !created {
+		status = http.StatusOK
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(j)
+}
+
+// GetIngestions lists the latest runs, filtered by ?dataset= and ?dry_run=.
+func (h *Handler) GetIngestions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := structs.IngestionFilter{Dataset: query.Get("dataset"), Limit: defaultRunLimit}
+	if v := query.Get("dry_run"); v != "" {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "Invalid dry_run", http.StatusBadRequest)
+			return
+		}
+		filter.DryRun = &dryRun
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxRunLimit {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	runs, err := h.service.Ingestion.GetRuns(h.ctx, filter)
+	if !ok(w, err, "fetching ingestion runs") {
+		return
+	}
+	conditional.WriteJSON(w, r, runs, time.Time{})
+}
+
+// GetIngestion returns a run with its counts and errors.
+func (h *Handler) GetIngestion(w http.ResponseWriter, r *http.Request) {
+	id, valid := idParam(w, r)
+	if !valid {
+		return
+	}
+
+	run, err := h.service.Ingestion.GetRun(h.ctx, id)
+	if !ok(w, err, "fetching ingestion run") {
+		return
+	}
+	conditional.WriteJSON(w, r, run, time.Time{})
+}
+
+// GetIngestionDiff returns the field-level diff a run computed between the
+// fetched dataset and the table.
+func (h *Handler) GetIngestionDiff(w http.ResponseWriter, r *http.Request) {
+	id, valid := idParam(w, r)
+	if !valid {
+		return
+	}
+
+	diff, err := h.service.Ingestion.GetRunDiff(h.ctx, id)
+	if !ok(w, err, "fetching ingestion diff") {
+		return
+	}
+	conditional.WriteJSON(w, r, diff, time.Time{})
+}
+
+// GetIngestionDrift lists the fields of the payload of a run that did not
+// match the expected schema of its endpoint.
+func (h *Handler) GetIngestionDrift(w http.ResponseWriter, r *http.Request) {
+	id, valid := idParam(w, r)
+	if !valid {
+		return
+	}
+
+	drift, err := h.service.Ingestion.GetRunDrift(h.ctx, id)
+	if !ok(w, err, "fetching schema drift") {
+		return
+	}
+	conditional.WriteJSON(w, r, drift, time.Time{})
+}
+
+// GetSchema returns the schema expected from an upstream endpoint.
+func (h *Handler) GetSchema(w http.ResponseWriter, r *http.Request) {
+	schema, err := h.service.Ingestion.GetSchema(h.ctx, chi.URLParam(r, "endpoint"))
+	if !ok(w, err, "fetching upstream schema") {
+		return
+	}
+	conditional.WriteJSON(w, r, schema, time.Time{})
+}
+
+// DeleteSchema forgets the schema expected from an upstream endpoint, to
+// accept a change upstream: the next import learns it again.
+func (h *Handler) DeleteSchema(w http.ResponseWriter, r *http.Request) {
+	if !ok(w, h.service.Ingestion.DeleteSchema(h.ctx, chi.URLParam(r, "endpoint")), "deleting upstream schema") {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func ok(w http.ResponseWriter, err error, action string) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, ingestion.ErrNotFound):
+		http.Error(w, "Not found", http.StatusNotFound)
+	default:
+		log.Printf("Error %s: %v", action, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+	return false
+}
+
+func idParam(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return id, true
+}
@@ -0,0 +1,62 @@
+package integrity
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+)
+
+const defaultSampleLimit = 20
+
+type Handler struct {
+	service *service.Service
+	ctx     context.Context
+}
+
+func NewHandler(s *service.Service) *Handler {
+	return &Handler{service: s, ctx: context.Background()}
+}
+
+// GetIntegrityReport lists, per relationship, how many rows are linked and
+// which rows carry a code that matches nothing. ?limit= caps the samples.
+func (h *Handler) GetIntegrityReport(w http.ResponseWriter, r *http.Request) {
+	limit := defaultSampleLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	report, err := h.service.Integrity.GetIntegrityReport(h.ctx, limit)
+	if err != nil {
+		log.Printf("Error building integrity report: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// LinkReferences resolves any codes left unlinked, e.g. after countries were
+// imported after the airlines that reference them.
+func (h *Handler) LinkReferences(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.Integrity.LinkReferences(h.ctx)
+	if err != nil {
+		log.Printf("Error linking references: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
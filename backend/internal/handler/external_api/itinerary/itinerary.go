@@ -0,0 +1,93 @@
+package itinerary
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/conditional"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+const (
+	defaultMaxStops = 1
+	maxMaxStops     = 3
+	defaultLimit    = 10
+	maxLimit        = 50
+)
+
+var rankings = map[string]bool{
+	"duration": true,
+	"stops":    true,
+	"detour":   true,
+}
+
+type Handler struct {
+	service *service.Service
+	ctx     context.Context
+}
+
+func NewHandler(s *service.Service) *Handler {
+	return &Handler{service: s, ctx: context.Background()}
+}
+
+// FindItineraries answers ?from=&to=&date=YYYY-MM-DD with connecting
+// itineraries of at most ?max_stops= stops, ranked by ?sort= (duration,
+// stops or detour).
+func (h *Handler) FindItineraries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := structs.ItineraryQuery{
+		From:     query.Get("from"),
+		To:       query.Get("to"),
+		MaxStops: defaultMaxStops,
+		Sort:     query.Get("sort"),
+		Limit:    defaultLimit,
+	}
+	if len(q.From) != 3 || len(q.To) != 3 || q.From == q.To {
+		http.Error(w, "from and to must be two different IATA airport codes", http.StatusBadRequest)
+		return
+	}
+
+	date, err := time.Parse("2006-01-02", query.Get("date"))
+	if err != nil {
+		http.Error(w, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	q.Date = date
+
+	if v := query.Get("max_stops"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxMaxStops {
+			http.Error(w, "Invalid max_stops", http.StatusBadRequest)
+			return
+		}
+		q.MaxStops = n
+	}
+	if q.Sort == "" {
+		q.Sort = "duration"
+	}
+	if !rankings[q.Sort] {
+		http.Error(w, "Invalid sort", http.StatusBadRequest)
+		return
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxLimit {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		q.Limit = n
+	}
+
+	itineraries, err := h.service.Itinerary.FindItineraries(h.ctx, q)
+	if err != nil {
+		log.Printf("Error finding itineraries: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	conditional.WriteJSON(w, r, itineraries, time.Time{})
+}
@@ -0,0 +1,208 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/auth"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/conditional"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/worker"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/job"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultJobLimit = 50
+	maxJobLimit     = 500
+)
+
+// adminKinds are the kinds of job only administrators may queue: imports
+// spend the upstream API quota and purges destroy rows.
+var adminKinds = map[string]bool{
+	worker.KindImport: true,
+	worker.KindPurge:  true,
+}
+
+var jobStatuses = map[string]bool{
+	structs.JobQueued:    true,
+	structs.JobRunning:   true,
+	structs.JobSucceeded: true,
+	structs.JobFailed:    true,
+	structs.JobCancelled: true,
+}
+
+type Handler struct {
+	service *service.Service
+	ctx     context.Context
+}
+
+func NewHandler(s *service.Service) *Handler {
+	return &Handler{service: s, ctx: context.Background()}
+}
+
+// CreateJob queues {"kind", "payload"} for the job workers. An import or
+// rebuild that is already queued or running is returned instead of being
+// queued again. Only administrators may queue an import or a purge.
+func (h *Handler) CreateJob(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Kind    string          `json:"kind"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if adminKinds[body.Kind] && !auth.IsAdmin(r) {
+		http.Error(w, "Administrator access required", http.StatusForbidden)
+		return
+	}
+	key, err := worker.Check(body.Kind, body.Payload)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(body.Payload) == 0 {
+		body.Payload = json.RawMessage("{}")
+	}
+
+	j, created, err := h.service.Job.Enqueue(h.ctx, body.Kind, key, body.Payload)
+	if !ok(w, err, "queueing job") {
+		return
+	}
+	w.Header().Set("Location", "/api/v1/jobs/"+j.ID.String())
+	status := http.StatusAccepted
+	if !created {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, j)
+}
+
+// GetJobs lists the latest jobs, filtered by ?kind= and ?status=.
+func (h *Handler) GetJobs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := structs.JobFilter{Kind: query.Get("kind"), Status: query.Get("status"), Limit: defaultJobLimit}
+	if filter.Status != "" && !jobStatuses[filter.Status] {
+		http.Error(w, "Invalid status, expected queued, running, succeeded, failed or cancelled", http.StatusBadRequest)
+		return
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxJobLimit {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	jobs, err := h.service.Job.GetJobs(h.ctx, filter)
+	if !ok(w, err, "fetching jobs") {
+		return
+	}
+	conditional.WriteJSON(w, r, jobs, time.Time{})
+}
+
+// GetJob returns the status of a job, with its result once it succeeded.
+func (h *Handler) GetJob(w http.ResponseWriter, r *http.Request) {
+	id, valid := idParam(w, r)
+	if !valid {
+		return
+	}
+
+	j, err := h.service.Job.GetJob(h.ctx, id)
+	if !ok(w, err, "fetching job") {
+		return
+	}
+	conditional.WriteJSON(w, r, j, time.Time{})
+}
+
+// GetJobOutput downloads the file built by a succeeded export.
+func (h *Handler) GetJobOutput(w http.ResponseWriter, r *http.Request) {
+	id, valid := idParam(w, r)
+	if !valid {
+		return
+	}
+
+	out, err := h.service.Job.GetJobOutput(h.ctx, id)
+	if !ok(w, err, "fetching job output") {
+		return
+	}
+	w.Header().Set("Content-Type", out.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(out.Size, 10))
+	w.WriteHeader(http.StatusOK)
+	err = h.service.Job.StreamJobOutput(h.ctx, id, func(chunk []byte) error {
+		_, err := w.Write(chunk)
+		return err
+	})
+	if err != nil {
+		// The status is out, so the download is cut short rather than
+		// passed off as complete.
+		log.Printf("Error streaming job output: %v", err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// CancelJob cancels a job that has not started yet.
+func (h *Handler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	id, valid := idParam(w, r)
+	if !valid {
+		return
+	}
+
+	if !ok(w, h.service.Job.CancelJob(h.ctx, id), "cancelling job") {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RetryJob queues a failed or cancelled job again.
+func (h *Handler) RetryJob(w http.ResponseWriter, r *http.Request) {
+	id, valid := idParam(w, r)
+	if !valid {
+		return
+	}
+
+	if !ok(w, h.service.Job.RetryJob(h.ctx, id), "retrying job") {
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ok answers the errors of the job service and reports whether there was
+// none.
+func ok(w http.ResponseWriter, err error, action string) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, job.ErrNotFound):
+		http.Error(w, "Not found", http.StatusNotFound)
+	case errors.Is(err, job.ErrActiveKey):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("Error %s: %v", action, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+	return false
+}
+
+func idParam(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,77 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/auth"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/utils"
+	"github.com/google/uuid"
+)
+
+type fakeJob struct {
+	service.Job
+	queued []string
+}
+
+func (f *fakeJob) Enqueue(_ context.Context, kind string, _ string, _ interface{}) (structs.Job, bool, error) {
+	f.queued = append(f.queued, kind)
+	return structs.Job{ID: uuid.New(), Kind: kind}, true, nil
+}
+
+func TestCreateJob(t *testing.T) {
+	t.Setenv("JWT_SECRET_KEY", "secret")
+	user, err := utils.GenerateNewJWTAccessToken(auth.Credentials(false), uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, err := utils.GenerateNewJWTAccessToken(auth.Credentials(true), uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		body  string
+		want  int
+	}{
+		{"import by a user", user, `{"kind":"import","payload":{"resource":"airport"}}`, http.StatusForbidden},
+		{"purge by a user", user, `{"kind":"trash.purge","payload":{}}`, http.StatusForbidden},
+		{"export by a user", user, `{"kind":"export","payload":{"resource":"airport"}}`, http.StatusAccepted},
+		{"import by an administrator", admin, `{"kind":"import","payload":{"resource":"airport"}}`, http.StatusAccepted},
+		{"unknown kind", admin, `{"kind":"reboot"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs := &fakeJob{}
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/jobs", strings.NewReader(tt.body))
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			auth.Required(http.HandlerFunc(NewHandler(&service.Service{Job: jobs}).CreateJob)).ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+			if tt.want != http.StatusAccepted {
+				if len(jobs.queued) != 0 {
+					t.Errorf("queued %v after a refusal", jobs.queued)
+				}
+				return
+			}
+			var j structs.Job
+			if err := json.NewDecoder(w.Body).Decode(&j); err != nil || len(jobs.queued) != 1 {
+				t.Errorf("queued %v, body %v", jobs.queued, err)
+			}
+			if !strings.HasPrefix(w.Header().Get("Location"), "/api/v1/jobs/") {
+				t.Errorf("Location = %q", w.Header().Get("Location"))
+			}
+		})
+	}
+}
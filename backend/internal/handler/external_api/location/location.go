@@ -3,10 +3,17 @@ package location
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"time"
 
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/conditional"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/history"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/imports"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/patch"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/render"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/trash"
 	internal_api "github.com/FACorreiaa/aviatoon-tracker/internal/handler/internalApi"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
@@ -14,10 +21,6 @@ import (
 	"github.com/google/uuid"
 )
 
-func NewCustomTime(t time.Time) structs.CustomTime {
-	return structs.CustomTime{Time: t}
-}
-
 type Handler struct {
 	service *service.Service
 	ctx     context.Context
@@ -31,27 +34,32 @@ func NewHandler(s *service.Service) *Handler {
 Countries
 **/
 
-func (h *Handler) InsertCountry(w http.ResponseWriter, r *http.Request) error {
-	apiResponse, err, _ := internal_api.FetchAviationStackData("countries")
+// ImportCountries fetches the countries of the upstream API and, unless dryRun, stores
+// them. Either way the import is recorded as an ingestion run.
+func (h *Handler) ImportCountries(ctx context.Context, dryRun bool) (structs.IngestionRun, error) {
+	run, err := internal_api.Ingest(ctx, h.service.Ingestion, "country", "countries", dryRun, decodeCountries,
+		h.service.Country.DiffCountries, h.service.Country.CreateCountries)
+	if err != nil || dryRun {
+		return run, err
+	}
 
-	if err != nil {
-		log.Printf("error getting .data: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if _, err := h.service.Integrity.LinkReferences(ctx); err != nil {
+		log.Printf("error linking references: %v", err)
 	}
 
-	var response structs.CountryApiData
-	err = json.Unmarshal(apiResponse, &response)
-	if err != nil {
-		log.Printf("error unmarshaling API response: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	return run, nil
+}
 
+func decodeCountries(apiResponse []byte) ([]structs.Country, error) {
+	var response structs.CountryApiData
+	if err := json.Unmarshal(apiResponse, &response); err != nil {
+		return nil, fmt.Errorf("error unmarshalling API response: %w", err)
 	}
 
+	createdAt := structs.NewInstant(time.Now())
+	countries := make([]structs.Country, 0, len(response.Data))
 	for _, c := range response.Data {
-		createdTime := time.Now()
-		createdAt := NewCustomTime(createdTime)
-
-		err := h.service.Country.CreateCountry(h.ctx, &structs.Country{
+		countries = append(countries, structs.Country{
 			ID:                uuid.New(),
 			CountryName:       c.CountryName,
 			CountryIso2:       c.CountryIso2,
@@ -67,13 +75,9 @@ func (h *Handler) InsertCountry(w http.ResponseWriter, r *http.Request) error {
 			CreatedAt:         createdAt,
 			UpdatedAt:         nil,
 		})
-		if err != nil {
-			log.Printf("error creating aircrafts in database: %v", err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return err
-		}
 	}
-	return nil
+
+	return countries, nil
 }
 
 func (h *Handler) CreateCountry(w http.ResponseWriter, r *http.Request) {
@@ -95,49 +99,30 @@ func (h *Handler) CreateCountry(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) GetCountries(w http.ResponseWriter, r *http.Request) {
-	countries, err := h.service.Country.GetCountries(h.ctx)
-	if len(countries) == 0 {
-		err := h.InsertCountry(w, r)
-		if err != nil {
-			log.Printf("Error inserting aircraft: %v", err)
-
-			// Write an error response to the client
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte("Internal server error"))
-			return
-		}
-		countries, err := h.service.Country.GetCountries(h.ctx)
-
-		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("Invalid country"))
-			return
-		}
-		// Write the list of countries to the response
-		err = json.NewEncoder(w).Encode(countries)
-		if err != nil {
-			log.Printf("error encoding tax as JSON: %v", err)
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("Error encoding json"))
-			return
-		}
-
+	format, ok := render.Negotiate(w, r)
+	if !ok {
+		return
 	}
+	w.Header().Set("Access-Control-Allow-Origin", "*")
 
+	version, err := h.service.Version.GetVersion(h.ctx, "country")
 	if err != nil {
 		log.Printf("Error fetching countries .data: %v", err)
-
-		// Write an error response to the client
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if version.Rows == 0 {
+		imports.Queue[structs.Country](h.ctx, w, h.service, "country", format)
+		return
+	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(countries)
+	if conditional.Check(w, r, version.ETag(trash.Variant(r, string(format))), version.LastModified) {
+		return
+	}
+	render.Stream(w, format, func(fn func(structs.Country) error) error {
+		return h.service.Country.StreamCountries(trash.Context(h.ctx, r), fn)
+	})
 }
 
 func (h *Handler) GetCountry(w http.ResponseWriter, r *http.Request) {
@@ -149,7 +134,13 @@ func (h *Handler) GetCountry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	country, err := h.service.Country.GetCountry(h.ctx, id)
+	ctx, err := history.Context(trash.Context(h.ctx, r), r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	country, err := h.service.Country.GetCountry(ctx, id)
 	if err != nil {
 		log.Printf("Error fetching country .data: %v", err)
 
@@ -158,11 +149,8 @@ func (h *Handler) GetCountry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(country)
+	conditional.WriteJSON(w, r, country, conditional.LastModified(country))
 }
 
 func (h *Handler) GetCountryCount(w http.ResponseWriter, r *http.Request) {
@@ -193,12 +181,7 @@ func (h *Handler) DeleteCountry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.Airport.DeleteAirport(h.ctx, id)
-	if err != nil {
-		log.Printf("Error fetching countries .data: %v", err)
-
-		// Write an error response to the client
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if !patch.Delete(h.ctx, w, r, h.service, "country", id) {
 		return
 	}
 
@@ -209,59 +192,70 @@ func (h *Handler) DeleteCountry(w http.ResponseWriter, r *http.Request) {
 	//json.NewEncoder(w).Encode(taxs)
 }
 
+// UpdateCountry replaces a country with the body, in the shape GET returns.
+// Fields the body leaves out are cleared.
 func (h *Handler) UpdateCountry(w http.ResponseWriter, r *http.Request) {
 	id, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
 		// Handle the error for invalid UUID format
 		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid aircraft ID"))
+		w.Write([]byte("Invalid country ID"))
 		return
 	}
 
-	var updates map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !patch.Replace(h.ctx, w, r, h.service, "country", id) {
 		return
 	}
-	updates["UpdatedAt"] = time.Now()
+	patch.Written(h.ctx, w, r, h.service.Country.GetCountry, id)
+}
 
-	if err := h.service.Country.UpdateCountry(h.ctx, id, updates); err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+// PatchCountry applies a JSON Merge Patch or a JSON Patch to a country as GET
+// returns it.
+func (h *Handler) PatchCountry(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		// Handle the error for invalid UUID format
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid country ID"))
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-
-	w.WriteHeader(http.StatusOK)
+	if !patch.Patch(h.ctx, w, r, h.service, "country", id) {
+		return
+	}
+	patch.Written(h.ctx, w, r, h.service.Country.GetCountry, id)
 }
 
 /**
 Cities
 */
 
-func (h *Handler) InsertCity(w http.ResponseWriter, r *http.Request) error {
-	apiResponse, err, _ := internal_api.FetchAviationStackData("cities")
+// ImportCities fetches the cities of the upstream API and, unless dryRun, stores
+// them. Either way the import is recorded as an ingestion run.
+func (h *Handler) ImportCities(ctx context.Context, dryRun bool) (structs.IngestionRun, error) {
+	run, err := internal_api.Ingest(ctx, h.service.Ingestion, "city", "cities", dryRun, decodeCities,
+		h.service.City.DiffCities, h.service.City.CreateCities)
+	if err != nil || dryRun {
+		return run, err
+	}
 
-	if err != nil {
-		log.Printf("error getting .data: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if _, err := h.service.Integrity.LinkReferences(ctx); err != nil {
+		log.Printf("error linking references: %v", err)
 	}
 
-	var response structs.CityApiData
-	err = json.Unmarshal(apiResponse, &response)
-	if err != nil {
-		log.Printf("error unmarshaling API response: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	return run, nil
+}
 
+func decodeCities(apiResponse []byte) ([]structs.City, error) {
+	var response structs.CityApiData
+	if err := json.Unmarshal(apiResponse, &response); err != nil {
+		return nil, fmt.Errorf("error unmarshalling API response: %w", err)
 	}
 
+	createdAt := structs.NewInstant(time.Now())
+	cities := make([]structs.City, 0, len(response.Data))
 	for _, c := range response.Data {
-		createdTime := time.Now()
-		createdAt := NewCustomTime(createdTime)
-
-		err := h.service.City.CreateCity(h.ctx, &structs.City{
+		cities = append(cities, structs.City{
 			ID:          uuid.New(),
 			GMT:         c.GMT,
 			CityId:      c.CityId,
@@ -275,14 +269,9 @@ func (h *Handler) InsertCity(w http.ResponseWriter, r *http.Request) error {
 			CreatedAt:   createdAt,
 			UpdatedAt:   nil,
 		})
-
-		if err != nil {
-			log.Printf("error creating aircrafts in database: %v", err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return err
-		}
 	}
-	return nil
+
+	return cities, nil
 }
 
 func (h *Handler) CreateCity(w http.ResponseWriter, r *http.Request) {
@@ -304,50 +293,30 @@ func (h *Handler) CreateCity(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) GetCities(w http.ResponseWriter, r *http.Request) {
-	cities, err := h.service.City.GetCities(h.ctx)
-
-	if len(cities) == 0 {
-		err := h.InsertCity(w, r)
-		if err != nil {
-			log.Printf("Error inserting city: %v", err)
-
-			// Write an error response to the client
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte("Internal server error"))
-			return
-		}
-		aircrafts, err := h.service.Aircraft.GetAircrafts(h.ctx)
-
-		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("Invalid city"))
-			return
-		}
-		// Write the list of cities to the response
-		err = json.NewEncoder(w).Encode(aircrafts)
-		if err != nil {
-			log.Printf("error encoding tax as JSON: %v", err)
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte("Error encoding json"))
-			return
-		}
-
+	format, ok := render.Negotiate(w, r)
+	if !ok {
+		return
 	}
+	w.Header().Set("Access-Control-Allow-Origin", "*")
 
+	version, err := h.service.Version.GetVersion(h.ctx, "city")
 	if err != nil {
 		log.Printf("Error fetching city .data: %v", err)
-
-		// Write an error response to the client
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	if version.Rows == 0 {
+		imports.Queue[structs.City](h.ctx, w, h.service, "city", format)
+		return
+	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(cities)
+	if conditional.Check(w, r, version.ETag(trash.Variant(r, string(format))), version.LastModified) {
+		return
+	}
+	render.Stream(w, format, func(fn func(structs.City) error) error {
+		return h.service.City.StreamCities(trash.Context(h.ctx, r), fn)
+	})
 }
 
 func (h *Handler) GetCity(w http.ResponseWriter, r *http.Request) {
@@ -359,7 +328,13 @@ func (h *Handler) GetCity(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	city, err := h.service.City.GetCity(h.ctx, id)
+	ctx, err := history.Context(trash.Context(h.ctx, r), r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	city, err := h.service.City.GetCity(ctx, id)
 	if err != nil {
 		log.Printf("Error fetching country .data: %v", err)
 
@@ -368,12 +343,8 @@ func (h *Handler) GetCity(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(city)
+	conditional.WriteJSON(w, r, city, conditional.LastModified(city))
 }
 
 func (h *Handler) GetCityCount(w http.ResponseWriter, r *http.Request) {
@@ -403,12 +374,7 @@ func (h *Handler) DeleteCity(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.City.DeleteCity(h.ctx, id)
-	if err != nil {
-		log.Printf("Error fetching countries .data: %v", err)
-
-		// Write an error response to the client
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if !patch.Delete(h.ctx, w, r, h.service, "city", id) {
 		return
 	}
 
@@ -419,49 +385,60 @@ func (h *Handler) DeleteCity(w http.ResponseWriter, r *http.Request) {
 	//json.NewEncoder(w).Encode(taxs)
 }
 
+// UpdateCity replaces a city with the body, in the shape GET returns.
+// Fields the body leaves out are cleared.
 func (h *Handler) UpdateCity(w http.ResponseWriter, r *http.Request) {
 	id, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
 		// Handle the error for invalid UUID format
 		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte("Invalid aircraft ID"))
+		w.Write([]byte("Invalid city ID"))
 		return
 	}
 
-	var updates map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !patch.Replace(h.ctx, w, r, h.service, "city", id) {
 		return
 	}
-	updates["UpdatedAt"] = time.Now()
+	patch.Written(h.ctx, w, r, h.service.City.GetCity, id)
+}
 
-	if err := h.service.City.UpdateCity(h.ctx, id, updates); err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+// PatchCity applies a JSON Merge Patch or a JSON Patch to a city as GET
+// returns it.
+func (h *Handler) PatchCity(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		// Handle the error for invalid UUID format
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("Invalid city ID"))
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.WriteHeader(http.StatusOK)
+	if !patch.Patch(h.ctx, w, r, h.service, "city", id) {
+		return
+	}
+	patch.Written(h.ctx, w, r, h.service.City.GetCity, id)
 }
 
 func (h *Handler) GetCitiesFromCountry(w http.ResponseWriter, r *http.Request) {
-	city, err := h.service.City.GetCitiesFromCountry(h.ctx)
+	format, ok := render.Negotiate(w, r)
+	if !ok {
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	version, err := h.service.Version.GetVersion(h.ctx, "city", "country")
 	if err != nil {
 		log.Printf("Error fetching country .data: %v", err)
-
-		// Write an error response to the client
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(city)
+	if conditional.Check(w, r, version.ETag(string(format)), version.LastModified) {
+		return
+	}
+	render.Stream(w, format, func(fn func(structs.CityInfo) error) error {
+		return h.service.City.StreamCitiesFromCountry(h.ctx, fn)
+	})
 }
 
 func (h *Handler) GetCityFromCountry(w http.ResponseWriter, r *http.Request) {
@@ -482,10 +459,6 @@ func (h *Handler) GetCityFromCountry(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Serialize the response as JSON and write to the response writer
-	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(city)
+	conditional.WriteJSON(w, r, city, time.Time{})
 }
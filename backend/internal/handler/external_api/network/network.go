@@ -0,0 +1,103 @@
+package network
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/conditional"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultHubLimit = 20
+	maxHubLimit     = 500
+)
+
+var hubRankings = map[string]bool{
+	"degree":      true,
+	"betweenness": true,
+	"flights":     true,
+}
+
+type Handler struct {
+	service *service.Service
+	ctx     context.Context
+}
+
+func NewHandler(s *service.Service) *Handler {
+	return &Handler{service: s, ctx: context.Background()}
+}
+
+// GetDestinations lists the airports served non-stop from {iata}, busiest
+// first, with the airlines seen operating each route.
+func (h *Handler) GetDestinations(w http.ResponseWriter, r *http.Request) {
+	destinations, err := h.service.Network.GetDestinations(h.ctx, chi.URLParam(r, "iata"))
+	if err != nil {
+		log.Printf("Error fetching destinations: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	conditional.WriteJSON(w, r, destinations, time.Time{})
+}
+
+// GetAirlineRoutes returns the route map of the airline with IATA code {iata}.
+func (h *Handler) GetAirlineRoutes(w http.ResponseWriter, r *http.Request) {
+	routes, err := h.service.Network.GetAirlineRoutes(h.ctx, chi.URLParam(r, "iata"))
+	if err != nil {
+		log.Printf("Error fetching airline routes: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	conditional.WriteJSON(w, r, routes, time.Time{})
+}
+
+// GetHubs ranks airports by ?by= (degree, betweenness or flights), at most
+// ?limit= of them.
+func (h *Handler) GetHubs(w http.ResponseWriter, r *http.Request) {
+	by := r.URL.Query().Get("by")
+	if by == "" {
+		by = "degree"
+	}
+	if !hubRankings[by] {
+		http.Error(w, "Invalid ranking", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultHubLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxHubLimit {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	hubs, err := h.service.Network.GetHubs(h.ctx, by, limit)
+	if err != nil {
+		log.Printf("Error ranking hubs: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	conditional.WriteJSON(w, r, hubs, time.Time{})
+}
+
+// GetDirectRoute reports whether a non-stop route from {from} to {to} has
+// been observed, and by which airlines.
+func (h *Handler) GetDirectRoute(w http.ResponseWriter, r *http.Request) {
+	direct, err := h.service.Network.GetDirectRoute(h.ctx, chi.URLParam(r, "from"), chi.URLParam(r, "to"))
+	if err != nil {
+		log.Printf("Error checking direct route: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	conditional.WriteJSON(w, r, direct, time.Time{})
+}
@@ -0,0 +1,129 @@
+package patch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/conditional"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/patch"
+	"github.com/google/uuid"
+)
+
+// Media types PATCH accepts.
+const (
+	MergePatch = "application/merge-patch+json"
+	JSONPatch  = "application/json-patch+json"
+)
+
+// Replace handles the write of a PUT on the row id of resource: the body is
+// the whole row, as GET returns it. The If-Match of r is checked against the
+// row inside the transaction of the write. It writes the error response and
+// returns false when the row was not replaced.
+func Replace(ctx context.Context, w http.ResponseWriter, r *http.Request, s *service.Service, resource string, id uuid.UUID) bool {
+	check, ok := conditional.Match(w, r)
+	if !ok {
+		return false
+	}
+
+	var doc map[string]interface{}
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return false
+	}
+	return written(ctx, w, s, resource, id, s.Patch.Replace(ctx, resource, id, check, doc))
+}
+
+// Patch handles the write of a PATCH on the row id of resource, with a JSON
+// Merge Patch or a JSON Patch body as its Content-Type says. The If-Match
+// of r is checked as Replace does. It writes the error response and returns
+// false when the row was not patched.
+func Patch(ctx context.Context, w http.ResponseWriter, r *http.Request, s *service.Service, resource string, id uuid.UUID) bool {
+	check, ok := conditional.Match(w, r)
+	if !ok {
+		return false
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+
+	switch mediaType {
+	case MergePatch:
+		var body interface{}
+		if err := decoder.Decode(&body); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return false
+		}
+		return written(ctx, w, s, resource, id, s.Patch.MergePatch(ctx, resource, id, check, body))
+	case JSONPatch:
+		var ops []patch.Operation
+		if err := decoder.Decode(&ops); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return false
+		}
+		return written(ctx, w, s, resource, id, s.Patch.JSONPatch(ctx, resource, id, check, ops))
+	}
+	w.Header().Set("Accept-Patch", MergePatch+", "+JSONPatch)
+	http.Error(w, "Unsupported patch format", http.StatusUnsupportedMediaType)
+	return false
+}
+
+// Delete handles a DELETE of the row id of resource. The If-Match of r is
+// checked as Replace does. It writes the error response and returns false
+// when the row was not deleted.
+func Delete(ctx context.Context, w http.ResponseWriter, r *http.Request, s *service.Service, resource string, id uuid.UUID) bool {
+	check, ok := conditional.Match(w, r)
+	if !ok {
+		return false
+	}
+	return written(ctx, w, s, resource, id, s.Patch.Delete(ctx, resource, id, check))
+}
+
+// Written answers a successful write with the row as it is now, so its
+// ETag can go into the If-Match of the next one.
+func Written[T conditional.Versioned](ctx context.Context, w http.ResponseWriter, r *http.Request, load func(ctx context.Context, id uuid.UUID) (T, error), id uuid.UUID) {
+	row, err := load(ctx, id)
+	if err != nil {
+		log.Printf("Error fetching written row: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	conditional.WriteJSON(w, r, row, conditional.LastModified(row))
+}
+
+// written relinks the references of the written row id and of the rows
+// that share its codes, which may have changed, or writes the error
+// response of a failed write.
+func written(ctx context.Context, w http.ResponseWriter, s *service.Service, resource string, id uuid.UUID, err error) bool {
+	var invalid patch.Invalid
+	switch {
+	case err == nil:
+		if _, err := s.Integrity.LinkRows(ctx, resource, []uuid.UUID{id}); err != nil {
+			log.Printf("error linking references: %v", err)
+		}
+		return true
+	case errors.As(err, &invalid):
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(invalid)
+	case errors.Is(err, conditional.ErrModified):
+		http.Error(w, "Resource was modified", http.StatusPreconditionFailed)
+	case errors.Is(err, patch.ErrNotFound):
+		http.Error(w, "Not found", http.StatusNotFound)
+	case errors.Is(err, patch.ErrBadPatch):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, patch.ErrTestFailed), errors.Is(err, patch.ErrRejected):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("Error updating %s: %v", resource, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+	return false
+}
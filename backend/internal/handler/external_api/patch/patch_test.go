@@ -0,0 +1,103 @@
+package patch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/conditional"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/patch"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+)
+
+// fakePatch deletes rows the way the repositories do: the check sees the
+// current row before anything is written.
+type fakePatch struct {
+	service.Patch
+	rows    map[uuid.UUID]structs.Tax
+	deleted []uuid.UUID
+}
+
+func (f *fakePatch) Delete(ctx context.Context, resource string, id uuid.UUID, check patch.Check) error {
+	row, ok := f.rows[id]
+	if !ok {
+		return patch.ErrNotFound
+	}
+	if err := check(row); err != nil {
+		return err
+	}
+	delete(f.rows, id)
+	f.deleted = append(f.deleted, id)
+	return nil
+}
+
+type fakeIntegrity struct {
+	service.Integrity
+	linked []uuid.UUID
+}
+
+func (f *fakeIntegrity) LinkRows(ctx context.Context, table string, ids []uuid.UUID) (structs.LinkResult, error) {
+	f.linked = append(f.linked, ids...)
+	return structs.LinkResult{}, nil
+}
+
+func TestDelete(t *testing.T) {
+	id := uuid.New()
+	row := structs.Tax{ID: id, TaxId: 1, TaxName: "Airport Tax"}
+	current, _, err := conditional.ETag(row)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		id      uuid.UUID
+		ifMatch string
+		want    int
+		deleted bool
+	}{
+		{"no If-Match", id, "", http.StatusPreconditionRequired, false},
+		{"stale", id, `"stale"`, http.StatusPreconditionFailed, false},
+		{"missing", uuid.New(), current, http.StatusNotFound, false},
+		{"current", id, current, http.StatusOK, true},
+		{"any", id, "*", http.StatusOK, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := &fakePatch{rows: map[uuid.UUID]structs.Tax{id: row}}
+			links := &fakeIntegrity{}
+			s := &service.Service{Patch: rows, Integrity: links}
+
+			r := httptest.NewRequest(http.MethodDelete, "/api/v1/taxes/"+tt.id.String(), nil)
+			if tt.ifMatch != "" {
+				r.Header.Set("If-Match", tt.ifMatch)
+			}
+			w := httptest.NewRecorder()
+
+			if ok := Delete(context.Background(), w, r, s, "tax", tt.id); ok != tt.deleted {
+				t.Errorf("Delete = %v, want %v", ok, tt.deleted)
+			}
+			if !tt.deleted && w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			if tt.want == http.StatusPreconditionFailed && w.Header().Get("ETag") != current {
+				t.Errorf("ETag = %q, want the current %q", w.Header().Get("ETag"), current)
+			}
+
+			var want []uuid.UUID
+			if tt.deleted {
+				want = []uuid.UUID{id}
+			}
+			if !reflect.DeepEqual(rows.deleted, want) {
+				t.Errorf("deleted %v, want %v", rows.deleted, want)
+			}
+			if !reflect.DeepEqual(links.linked, want) {
+				t.Errorf("relinked %v, want %v", links.linked, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package render
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// column is one exported field of a row struct, named after its JSON key.
+// Columns keep the struct declaration order, which is both the CSV header
+// order and the protobuf field numbering (column n is field n+1).
+type column struct {
+	name  string
+	index []int
+}
+
+var columnCache sync.Map
+
+func columnsOf(t reflect.Type) []column {
+	if cached, ok := columnCache.Load(t); ok {
+		return cached.([]column)
+	}
+
+	var columns []column
+	if t.Kind() == reflect.Struct {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			columns = append(columns, column{name: name, index: field.Index})
+		}
+	}
+
+	columnCache.Store(t, columns)
+	return columns
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	dateType    = reflect.TypeOf(structs.Date{})
+	instantType = reflect.TypeOf(structs.Instant{})
+	uuidType    = reflect.TypeOf(uuid.UUID{})
+)
+
+// deref follows pointers and interfaces, reporting false for nil.
+func deref(v reflect.Value) (reflect.Value, bool) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return v, false
+		}
+		v = v.Elem()
+	}
+	return v, true
+}
+
+func timeOf(v reflect.Value) (time.Time, bool) {
+	switch v.Type() {
+	case timeType:
+		return v.Interface().(time.Time), true
+	case dateType:
+		return v.Interface().(structs.Date).Time, true
+	case instantType:
+		return v.Interface().(structs.Instant).Time, true
+	}
+	return time.Time{}, false
+}
+
+// cell renders a field for CSV: NULLs and zero dates are empty cells.
+func cell(v reflect.Value) string {
+	v, ok := deref(v)
+	if !ok {
+		return ""
+	}
+	if t, ok := timeOf(v); ok {
+		if t.IsZero() {
+			return ""
+		}
+		if v.Type() == dateType {
+			return t.Format("2006-01-02")
+		}
+		return t.Format(time.RFC3339)
+	}
+	if v.Type() == uuidType {
+		return v.Interface().(uuid.UUID).String()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// appendField encodes a field with proto3 semantics: NULLs and zero values
+// are left out. Times become google.protobuf.Timestamp, UUIDs and untyped
+// values their string form.
+func appendField(b []byte, num protowire.Number, v reflect.Value) []byte {
+	v, ok := deref(v)
+	if !ok || v.IsZero() {
+		return b
+	}
+	if t, ok := timeOf(v); ok {
+		if t.IsZero() {
+			return b
+		}
+		var ts []byte
+		ts = protowire.AppendTag(ts, 1, protowire.VarintType)
+		ts = protowire.AppendVarint(ts, uint64(t.Unix()))
+		if nanos := t.Nanosecond(); nanos != 0 {
+			ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(nanos))
+		}
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, ts)
+	}
+
+	switch v.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		b = protowire.AppendTag(b, num, protowire.VarintType)
+		if v.Kind() == reflect.Bool {
+			return protowire.AppendVarint(b, 1)
+		}
+		return protowire.AppendVarint(b, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		b = protowire.AppendTag(b, num, protowire.VarintType)
+		return protowire.AppendVarint(b, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		b = protowire.AppendTag(b, num, protowire.Fixed64Type)
+		return protowire.AppendFixed64(b, math.Float64bits(v.Float()))
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, cell(v))
+}
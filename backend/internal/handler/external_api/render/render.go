@@ -0,0 +1,275 @@
+package render
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type Format string
+
+const (
+	JSON     Format = "json"
+	NDJSON   Format = "ndjson"
+	CSV      Format = "csv"
+	Protobuf Format = "protobuf"
+)
+
+// flushEvery is how many rows are written between flushes, so clients start
+// receiving data before the cursor is drained.
+const flushEvery = 500
+
+var contentTypes = map[Format]string{
+	JSON:     "application/json",
+	NDJSON:   "application/x-ndjson",
+	CSV:      "text/csv; charset=utf-8",
+	Protobuf: "application/x-protobuf",
+}
+
+var mediaTypes = map[string]Format{
+	"application/json":                JSON,
+	"application/*":                   JSON,
+	"*/*":                             JSON,
+	"application/x-ndjson":            NDJSON,
+	"application/ndjson":              NDJSON,
+	"application/jsonl":               NDJSON,
+	"text/csv":                        CSV,
+	"text/*":                          CSV,
+	"application/x-protobuf":          Protobuf,
+	"application/protobuf":            Protobuf,
+	"application/vnd.google.protobuf": Protobuf,
+}
+
+// Negotiate picks the response format from ?format= or, failing that, the
+// Accept header by descending quality. No preference means JSON. It answers
+// 406 itself and returns false when no supported format is acceptable.
+func Negotiate(w http.ResponseWriter, r *http.Request) (Format, bool) {
+	w.Header().Add("Vary", "Accept")
+
+	format, ok := negotiate(r)
+	if !ok {
+		http.Error(w, "None of the accepted media types can be produced", http.StatusNotAcceptable)
+	}
+	return format, ok
+}
+
+// ParseFormat returns the format named by s, as in ?format=.
+func ParseFormat(s string) (Format, bool) {
+	switch f := Format(strings.ToLower(s)); f {
+	case JSON, NDJSON, CSV, Protobuf:
+		return f, true
+	}
+	return "", false
+}
+
+// ContentType is the media type of format.
+func ContentType(format Format) string {
+	return contentTypes[format]
+}
+
+func negotiate(r *http.Request) (Format, bool) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return ParseFormat(format)
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return JSON, true
+	}
+
+	type candidate struct {
+		format  Format
+		quality float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		format, ok := mediaTypes[mediaType]
+		if !ok {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality > 0 {
+			candidates = append(candidates, candidate{format, quality})
+		}
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+	return candidates[0].format, true
+}
+
+// Stream writes every row produced by each in format without holding the
+// result set in memory: JSON goes out as an array written element by
+// element. Once the first byte is out the
+// status can no longer change, so a failing cursor aborts the response
+// instead of truncating it silently.
+func Stream[T any](w http.ResponseWriter, format Format, each func(func(T) error) error) {
+	w.Header().Set("Content-Type", contentTypes[format])
+
+	buffered := bufio.NewWriter(w)
+	encoder := newEncoder[T](buffered, format)
+
+	written := 0
+	started := false
+	err := each(func(row T) error {
+		if !started {
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		if err := encoder.row(row); err != nil {
+			return err
+		}
+		written++
+		if written%flushEvery == 0 {
+			return flush(w, buffered)
+		}
+		return nil
+	})
+	if err == nil {
+		err = encoder.close()
+	}
+
+	if err != nil {
+		log.Printf("error streaming %s response: %v", format, err)
+		if !started {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		panic(http.ErrAbortHandler)
+	}
+
+	if err := flush(w, buffered); err != nil {
+		log.Printf("error streaming %s response: %v", format, err)
+	}
+}
+
+// Encode writes every row produced by each to out in format, e.g. to build
+// an export file.
+func Encode[T any](out io.Writer, format Format, each func(func(T) error) error) error {
+	buffered := bufio.NewWriter(out)
+	encoder := newEncoder[T](buffered, format)
+	if err := each(encoder.row); err != nil {
+		return err
+	}
+	if err := encoder.close(); err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
+
+func flush(w http.ResponseWriter, buffered *bufio.Writer) error {
+	if err := buffered.Flush(); err != nil {
+		return err
+	}
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+type encoder[T any] struct {
+	format  Format
+	out     *bufio.Writer
+	json    *json.Encoder
+	csv     *csv.Writer
+	columns []column
+	record  []string
+	proto   []byte
+	rows    int
+}
+
+func newEncoder[T any](out *bufio.Writer, format Format) *encoder[T] {
+	e := &encoder[T]{
+		format:  format,
+		out:     out,
+		columns: columnsOf(reflect.TypeOf((*T)(nil)).Elem()),
+	}
+
+	switch format {
+	case JSON:
+		out.WriteByte('[')
+	case NDJSON:
+		e.json = json.NewEncoder(out)
+	case CSV:
+		e.csv = csv.NewWriter(out)
+		e.record = make([]string, len(e.columns))
+		for i, c := range e.columns {
+			e.record[i] = c.name
+		}
+		e.csv.Write(e.record)
+	}
+	return e
+}
+
+func (e *encoder[T]) row(row T) error {
+	v := reflect.ValueOf(row)
+
+	switch e.format {
+	case CSV:
+		for i, c := range e.columns {
+			e.record[i] = cell(v.FieldByIndex(c.index))
+		}
+		return e.csv.Write(e.record)
+	case Protobuf:
+		// Length-delimited framing, the same as writeDelimitedTo/parseDelimitedFrom.
+		e.proto = e.proto[:0]
+		for i, c := range e.columns {
+			e.proto = appendField(e.proto, protowire.Number(i+1), v.FieldByIndex(c.index))
+		}
+		var size []byte
+		size = protowire.AppendVarint(size, uint64(len(e.proto)))
+		if _, err := e.out.Write(size); err != nil {
+			return err
+		}
+		_, err := e.out.Write(e.proto)
+		return err
+	case JSON:
+		body, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		if e.rows > 0 {
+			e.out.WriteByte(',')
+		}
+		e.rows++
+		_, err = e.out.Write(body)
+		return err
+	default:
+		return e.json.Encode(row)
+	}
+}
+
+func (e *encoder[T]) close() error {
+	switch e.format {
+	case JSON:
+		_, err := e.out.WriteString("]\n")
+		return err
+	case CSV:
+		e.csv.Flush()
+		return e.csv.Error()
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+package render
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// rowTypes are the row structs described by rows.proto, by message name.
+var rowTypes = map[string]reflect.Type{
+	"Airline":  reflect.TypeOf(structs.Airline{}),
+	"Aircraft": reflect.TypeOf(structs.Aircraft{}),
+	"Airplane": reflect.TypeOf(structs.Airplane{}),
+	"Airport":  reflect.TypeOf(structs.Airport{}),
+	"City":     reflect.TypeOf(structs.City{}),
+	"Country":  reflect.TypeOf(structs.Country{}),
+	"Tax":      reflect.TypeOf(structs.Tax{}),
+}
+
+var (
+	messageLine = regexp.MustCompile(`^message (\w+) \{$`)
+	fieldLine   = regexp.MustCompile(`^([\w.]+) (\w+) = (\d+);$`)
+)
+
+var scalarTypes = map[string]descriptorpb.FieldDescriptorProto_Type{
+	"string": descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	"double": descriptorpb.FieldDescriptorProto_TYPE_DOUBLE,
+	"int64":  descriptorpb.FieldDescriptorProto_TYPE_INT64,
+}
+
+// rowsProto builds the descriptor of rows.proto from the file itself, so the
+// tests decode with the schema clients are given. It reads only the subset
+// of the language the file uses: messages of scalar and Timestamp fields.
+func rowsProto(t *testing.T) protoreflect.FileDescriptor {
+	t.Helper()
+	f, err := os.Open("rows.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("render/rows.proto"),
+		Package:    proto.String("render"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/timestamp.proto"},
+	}
+	var message *descriptorpb.DescriptorProto
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := string(bytes.TrimSpace(scanner.Bytes()))
+		if m := messageLine.FindStringSubmatch(line); m != nil {
+			message = &descriptorpb.DescriptorProto{Name: proto.String(m[1])}
+			file.MessageType = append(file.MessageType, message)
+			continue
+		}
+		m := fieldLine.FindStringSubmatch(line)
+		if m == nil || message == nil {
+			continue
+		}
+		number, _ := strconv.Atoi(m[3])
+		field := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(m[2]),
+			Number: proto.Int32(int32(number)),
+			Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+		if m[1] == "google.protobuf.Timestamp" {
+			field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+			field.TypeName = proto.String(".google.protobuf.Timestamp")
+		} else if typ, ok := scalarTypes[m[1]]; ok {
+			field.Type = typ.Enum()
+		} else {
+			t.Fatalf("rows.proto: unsupported type %s of %s.%s", m[1], message.GetName(), m[2])
+		}
+		message.Field = append(message.Field, field)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	fd, err := protodesc.NewFile(file, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("rows.proto: %v", err)
+	}
+	return fd
+}
+
+func TestRowsProtoInSync(t *testing.T) {
+	messages := rowsProto(t).Messages()
+	if messages.Len() != len(rowTypes) {
+		t.Errorf("rows.proto has %d messages, want %d", messages.Len(), len(rowTypes))
+	}
+	for i := 0; i < messages.Len(); i++ {
+		md := messages.Get(i)
+		typ, ok := rowTypes[string(md.Name())]
+		if !ok {
+			t.Errorf("message %s has no row struct", md.Name())
+			continue
+		}
+		columns := columnsOf(typ)
+		if md.Fields().Len() != len(columns) {
+			t.Errorf("%s has %d fields, want the %d columns of %s", md.Name(), md.Fields().Len(), len(columns), typ)
+		}
+		for n, c := range columns {
+			fd := md.Fields().ByNumber(protowire.Number(n + 1))
+			if fd == nil || string(fd.Name()) != c.name {
+				t.Errorf("%s field %d is %v, want %s", md.Name(), n+1, fd, c.name)
+			}
+		}
+	}
+}
+
+var (
+	created = time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	updated = time.Date(2024, 5, 2, 8, 0, 0, 250_000_000, time.UTC)
+	cityRef = uuid.MustParse("6f1c2a9e-6d3b-4c1e-9b1a-2f7e8d9c0a1b")
+)
+
+func testAirports() []structs.Airport {
+	return []structs.Airport{{
+		ID:          uuid.MustParse("0b7d3c1e-8a2f-4e5d-9c6b-1a2b3c4d5e6f"),
+		GMT:         -4.5,
+		IataCode:    "CCS",
+		IcaoCode:    "SVMI",
+		GeonameId:   3646738,
+		Latitude:    10.601194,
+		Longitude:   -66.991222,
+		AirportName: "Simón Bolívar, \"Maiquetía\"",
+		PhoneNumber: "+58 212 355 1111",
+		Timezone:    "America/Caracas",
+		CityRef:     &cityRef,
+		CreatedAt:   structs.NewInstant(created),
+		UpdatedAt:   &updated,
+	}, {
+		// Zero values and NULLs: empty cells, absent protobuf fields.
+		ID:        uuid.MustParse("1c8e4d2f-9b3a-4f6e-8d7c-2b3c4d5e6f70"),
+		IataCode:  "LIS",
+		CreatedAt: structs.NewInstant(created),
+	}}
+}
+
+func encode[T any](t *testing.T, format Format, rows []T) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	err := Encode(&out, format, func(fn func(T) error) error {
+		for _, row := range rows {
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestCSV(t *testing.T) {
+	records, err := csv.NewReader(bytes.NewReader(encode(t, CSV, testAirports()))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{{
+		"id", "gmt", "iata_code", "city_iata_code", "icao_code", "country_iso2", "geoname_id", "latitude", "longitude",
+		"airport_name", "country_name", "phone_number", "timezone", "city_ref", "country_ref", "created_at", "updated_at", "deleted_at",
+	}, {
+		"0b7d3c1e-8a2f-4e5d-9c6b-1a2b3c4d5e6f", "-4.5", "CCS", "", "SVMI", "", "3646738", "10.601194", "-66.991222",
+		"Simón Bolívar, \"Maiquetía\"", "", "+58 212 355 1111", "America/Caracas", cityRef.String(), "", "2024-05-01T12:30:00Z", "2024-05-02T08:00:00Z", "",
+	}, {
+		"1c8e4d2f-9b3a-4f6e-8d7c-2b3c4d5e6f70", "0", "LIS", "", "", "", "0", "0", "0",
+		"", "", "", "", "", "", "2024-05-01T12:30:00Z", "", "",
+	}}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("got\n%q\nwant\n%q", records, want)
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	rows := testAirports()
+	lines := bytes.Split(bytes.TrimSuffix(encode(t, NDJSON, rows), []byte("\n")), []byte("\n"))
+	if len(lines) != len(rows) {
+		t.Fatalf("got %d lines, want one per row: %q", len(lines), lines)
+	}
+	for i, line := range lines {
+		var got structs.Airport
+		if err := json.Unmarshal(line, &got); err != nil {
+			t.Fatalf("line %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, rows[i]) {
+			t.Errorf("line %d decodes to\n%+v\nwant\n%+v", i, got, rows[i])
+		}
+	}
+}
+
+func TestProtobuf(t *testing.T) {
+	md := rowsProto(t).Messages().ByName("Airport")
+	body := encode(t, Protobuf, testAirports())
+
+	var messages []*dynamicpb.Message
+	for len(body) > 0 {
+		size, n := protowire.ConsumeVarint(body)
+		if n < 0 || uint64(len(body)-n) < size {
+			t.Fatalf("bad length prefix after %d messages", len(messages))
+		}
+		m := dynamicpb.NewMessage(md)
+		if err := proto.Unmarshal(body[n:n+int(size)], m); err != nil {
+			t.Fatal(err)
+		}
+		if unknown := m.GetUnknown(); len(unknown) > 0 {
+			t.Errorf("message %d has fields rows.proto does not know: %x", len(messages), unknown)
+		}
+		messages = append(messages, m)
+		body = body[n+int(size):]
+	}
+	if len(messages) != 2 {
+		t.Fatalf("got %d messages, want 2", len(messages))
+	}
+
+	timestamp := func(t time.Time) map[string]interface{} {
+		ts := map[string]interface{}{"seconds": t.Unix()}
+		if t.Nanosecond() != 0 {
+			ts["nanos"] = int32(t.Nanosecond())
+		}
+		return ts
+	}
+	want := []map[string]interface{}{{
+		"id":           "0b7d3c1e-8a2f-4e5d-9c6b-1a2b3c4d5e6f",
+		"gmt":          -4.5,
+		"iata_code":    "CCS",
+		"icao_code":    "SVMI",
+		"geoname_id":   int64(3646738),
+		"latitude":     10.601194,
+		"longitude":    -66.991222,
+		"airport_name": "Simón Bolívar, \"Maiquetía\"",
+		"phone_number": "+58 212 355 1111",
+		"timezone":     "America/Caracas",
+		"city_ref":     cityRef.String(),
+		"created_at":   timestamp(created),
+		"updated_at":   timestamp(updated),
+	}, {
+		"id":         "1c8e4d2f-9b3a-4f6e-8d7c-2b3c4d5e6f70",
+		"iata_code":  "LIS",
+		"created_at": timestamp(created),
+	}}
+	for i, m := range messages {
+		if got := fields(m); !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("message %d decodes to\n%v\nwant\n%v", i, got, want[i])
+		}
+	}
+}
+
+// fields lists the fields set in m by name, with messages as nested maps.
+func fields(m protoreflect.Message) map[string]interface{} {
+	set := map[string]interface{}{}
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Kind() == protoreflect.MessageKind {
+			set[string(fd.Name())] = fields(v.Message())
+		} else {
+			set[string(fd.Name())] = v.Interface()
+		}
+		return true
+	})
+	return set
+}
@@ -5,7 +5,21 @@ import (
 
 	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/airlines"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/airports"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/auth"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/batch"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/calendar"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/emissions"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/flights"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/history"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/ingestions"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/integrity"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/itinerary"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/jobs"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/location"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/network"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/stats"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/trash"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/watchlists"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/swagger"
 
 	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
@@ -30,6 +44,19 @@ func InitRouter(s *service.Service) *chi.Mux {
 	aircraftHandler := airlines.NewHandler(s)
 	airlineHandler := airlines.NewHandler(s)
 	airplaneHandler := airlines.NewHandler(s)
+	integrityHandler := integrity.NewHandler(s)
+	networkHandler := network.NewHandler(s)
+	itineraryHandler := itinerary.NewHandler(s)
+	emissionsHandler := emissions.NewHandler(s)
+	statsHandler := stats.NewHandler(s)
+	flightHandler := flights.NewHandler(s)
+	watchlistHandler := watchlists.NewHandler(s)
+	calendarHandler := calendar.NewHandler(s)
+	jobHandler := jobs.NewHandler(s)
+	ingestionHandler := ingestions.NewHandler(s)
+	trashHandler := trash.NewHandler(s)
+	historyHandler := history.NewHandler(s)
+	batchHandler := batch.NewHandler(s)
 
 	//protected routes
 	//jwtProtected := jwtmiddleware.New(configs.JWTConfig())
@@ -51,16 +78,21 @@ func InitRouter(s *service.Service) *chi.Mux {
 	router.Get("/api/v1/tax", taxHandler.GetTaxs)
 	//router.Get("/api/v1/tax/tax-name={tax_name}", taxHandler.GetTaxName)
 	router.Get("/api/v1/tax/count", taxHandler.GetTaxesCount)
+	router.With(auth.Required, auth.Admin).Post("/api/v1/tax/bulk", batchHandler.Apply("tax"))
 
 	router.Route("/api/v1/tax/{id}", func(r chi.Router) {
 		r.Get("/", taxHandler.GetTax)
 		r.Delete("/", taxHandler.DeleteTax)
 		r.Put("/", taxHandler.UpdateTax)
+		r.Patch("/", taxHandler.PatchTax)
+		r.With(auth.Required, auth.Admin).Post("/restore", trashHandler.Restore("tax"))
+		r.Get("/history", historyHandler.GetHistory("tax"))
 	})
 
 	//Airport
 	router.Get("/api/v1/airport", airportHandler.GetAirports)
 	router.Get("/api/v1/airport/count", airportHandler.GetAirportCount)
+	router.With(auth.Required, auth.Admin).Post("/api/v1/airport/bulk", batchHandler.Apply("airport"))
 	router.Get("/api/v1/airport/city", airportHandler.GetCitiesAirport)
 	router.Get("/api/v1/airport/city={city_name}", airportHandler.GetCityNameAirport)
 	router.Get("/api/v1/airport/country={country_name}", airportHandler.GetCountryNameAirport)
@@ -71,65 +103,162 @@ func InitRouter(s *service.Service) *chi.Mux {
 		r.Get("/", airportHandler.GetAirport)
 		r.Delete("/", airportHandler.DeleteAirport)
 		r.Put("/", airportHandler.UpdateAirport)
+		r.Patch("/", airportHandler.PatchAirport)
+		r.With(auth.Required, auth.Admin).Post("/restore", trashHandler.Restore("airport"))
+		r.Get("/history", historyHandler.GetHistory("airport"))
+		r.Get("/departures", airportHandler.GetDepartures)
+		r.Get("/arrivals", airportHandler.GetArrivals)
 	})
 
 	//Country
 	router.Get("/api/v1/countries", locationHandler.GetCountries)
 	router.Get("/api/v1/countries/count", locationHandler.GetCountryCount)
+	router.With(auth.Required, auth.Admin).Post("/api/v1/countries/bulk", batchHandler.Apply("country"))
 	router.Route("/api/v1/countries/{id}", func(r chi.Router) {
 		r.Get("/", locationHandler.GetCountry)
 		r.Delete("/", locationHandler.DeleteCountry)
 		r.Put("/", locationHandler.UpdateCountry)
+		r.Patch("/", locationHandler.PatchCountry)
+		r.With(auth.Required, auth.Admin).Post("/restore", trashHandler.Restore("country"))
+		r.Get("/history", historyHandler.GetHistory("country"))
 		r.Get("/city", locationHandler.GetCitiesFromCountry)
 	})
 
 	//Cities
 	router.Get("/api/v1/cities", locationHandler.GetCities)
 	router.Get("/api/v1/cities/count", locationHandler.GetCityCount)
+	router.With(auth.Required, auth.Admin).Post("/api/v1/cities/bulk", batchHandler.Apply("city"))
 
 	router.Route("/api/v1/cities/{id}", func(r chi.Router) {
 		r.Get("/", locationHandler.GetCity)
 		r.Delete("/", locationHandler.DeleteCity)
 		r.Put("/", locationHandler.UpdateCity)
+		r.Patch("/", locationHandler.PatchCity)
+		r.With(auth.Required, auth.Admin).Post("/restore", trashHandler.Restore("city"))
+		r.Get("/history", historyHandler.GetHistory("city"))
 	})
 
 	//Aircraft
 	router.Get("/api/v1/aircrafts", aircraftHandler.GetAircrafts)
 	router.Get("/api/v1/aircrafts/count", aircraftHandler.GetAircraftCount)
+	router.With(auth.Required, auth.Admin).Post("/api/v1/aircrafts/bulk", batchHandler.Apply("aircraft"))
 
 	router.Route("/api/v1/aircrafts/{id}", func(r chi.Router) {
 		r.Get("/", aircraftHandler.GetAircraft)
 		r.Delete("/", aircraftHandler.DeleteAircraft)
 		r.Put("/", aircraftHandler.UpdateAircraft)
+		r.Patch("/", aircraftHandler.PatchAircraft)
+		r.With(auth.Required, auth.Admin).Post("/restore", trashHandler.Restore("aircraft"))
+		r.Get("/history", historyHandler.GetHistory("aircraft"))
 	})
 
 	//Airline
 	router.Get("/api/v1/airline", airlineHandler.GetAirlines)
 	router.Get("/api/v1/airline/count", airlineHandler.GetAirlineCount)
+	router.With(auth.Required, auth.Admin).Post("/api/v1/airline/bulk", batchHandler.Apply("airline"))
 	//router.Get("/api/v1/airline/city/country", airlineHandler.GetAirlineCountry)
 	router.Get("/api/v1/airline/country={country_name}", airlineHandler.GetAirlineCountryName)
 	router.Get("/api/v1/airline/city={city_name}", airlineHandler.GetAirlineCityName)
 	router.Get("/api/v1/airline/country={country_name}/city={city_name}", airlineHandler.GetAirlineCountryCityName)
+	router.Get("/api/v1/airline/fleet", airlineHandler.GetFleetLeaderboard)
 
 	router.Route("/api/v1/airline/{id}", func(r chi.Router) {
 		r.Get("/", airlineHandler.GetAirline)
 		r.Get("/city/country", airlineHandler.GetAirlineCountry)
+		r.Get("/fleet", airlineHandler.GetAirlineFleet)
 
 		r.Delete("/", airlineHandler.DeleteAirline)
 		r.Put("/", airlineHandler.UpdateAirline)
+		r.Patch("/", airlineHandler.PatchAirline)
+		r.With(auth.Required, auth.Admin).Post("/restore", trashHandler.Restore("airline"))
+		r.Get("/history", historyHandler.GetHistory("airline"))
 	})
 
 	//Airplanes
 	router.Get("/api/v1/airplanes", airplaneHandler.GetAirplanes)
 	router.Get("/api/v1/airplanes/count", airplaneHandler.GetAirplaneCount)
+	router.With(auth.Required, auth.Admin).Post("/api/v1/airplanes/bulk", batchHandler.Apply("airplane"))
 	router.Route("/api/v1/airplanes/{id}", func(r chi.Router) {
 		r.Get("/", airplaneHandler.GetAirplane)
 		r.Delete("/", airplaneHandler.DeleteAirplane)
 		r.Put("/", airplaneHandler.UpdateAirplane)
+		r.Patch("/", airplaneHandler.PatchAirplane)
+		r.With(auth.Required, auth.Admin).Post("/restore", trashHandler.Restore("airplane"))
+		r.Get("/history", historyHandler.GetHistory("airplane"))
 	})
 	router.Get("/api/v1/airplanes/airline", airplaneHandler.GetAirplaneAirline)
 	router.Get("/api/v1/airplanes/airline/airline={airline_name}", airplaneHandler.GetAirplanesFromAirlineName)
 	router.Get("/api/v1/airplanes/airline/country={country_name}", airplaneHandler.GetAirplanesFromAirlineCountry)
 
+	//Integrity
+	router.Get("/api/v1/integrity", integrityHandler.GetIntegrityReport)
+	router.With(auth.Required, auth.Admin).Post("/api/v1/integrity/link", integrityHandler.LinkReferences)
+
+	//Route network
+	router.Get("/api/v1/network/hubs", networkHandler.GetHubs)
+	router.Get("/api/v1/network/airports/{iata}/destinations", networkHandler.GetDestinations)
+	router.Get("/api/v1/network/airlines/{iata}/routes", networkHandler.GetAirlineRoutes)
+	router.Get("/api/v1/network/routes/{from}/{to}", networkHandler.GetDirectRoute)
+
+	//Itineraries
+	router.Get("/api/v1/itineraries", itineraryHandler.FindItineraries)
+
+	//Emissions
+	router.Get("/api/v1/emissions", emissionsHandler.GetEmissions)
+
+	//Statistics
+	router.Get("/api/v1/stats/otp", statsHandler.GetOTP)
+	router.Post("/api/v1/stats/otp/rebuild", statsHandler.RebuildOTP)
+
+	//Flights
+	router.Get("/api/v1/flights", flightHandler.GetFlights)
+	router.Get("/api/v1/flights/{flight_iata}/codeshares", flightHandler.GetCodeshares)
+
+	//Watchlists and webhooks
+	router.Post("/api/v1/users", watchlistHandler.CreateUser)
+	router.Post("/api/v1/users/token", watchlistHandler.RefreshToken)
+	router.Group(func(r chi.Router) {
+		r.Use(auth.Required)
+		r.Get("/api/v1/users/me", watchlistHandler.GetCurrentUser)
+		r.Get("/api/v1/watchlists", watchlistHandler.GetWatchlists)
+		r.Post("/api/v1/watchlists", watchlistHandler.CreateWatchlist)
+		r.Get("/api/v1/watchlists/{id}", watchlistHandler.GetWatchlist)
+		r.Delete("/api/v1/watchlists/{id}", watchlistHandler.DeleteWatchlist)
+		r.Post("/api/v1/watchlists/{id}/watches", watchlistHandler.AddWatch)
+		r.Delete("/api/v1/watchlists/{id}/watches/{watch_id}", watchlistHandler.DeleteWatch)
+		r.Get("/api/v1/webhooks", watchlistHandler.GetWebhooks)
+		r.Post("/api/v1/webhooks", watchlistHandler.CreateWebhook)
+		r.Patch("/api/v1/webhooks/{id}", watchlistHandler.UpdateWebhook)
+		r.Delete("/api/v1/webhooks/{id}", watchlistHandler.DeleteWebhook)
+		r.Get("/api/v1/webhooks/{id}/deliveries", watchlistHandler.GetDeliveries)
+		r.Get("/api/v1/webhooks/{id}/deliveries/{delivery_id}", watchlistHandler.GetDelivery)
+		r.Post("/api/v1/webhooks/{id}/deliveries/{delivery_id}/retry", watchlistHandler.RetryDelivery)
+	})
+
+	//Calendar feeds
+	router.Get("/api/v1/flights/{flight_iata}/calendar.ics", calendarHandler.GetFlightCalendar)
+	router.With(auth.Required).Get("/api/v1/users/me/calendar", calendarHandler.GetCalendarURL)
+	router.Get("/api/v1/calendars/{user_id}.ics", calendarHandler.GetUserCalendar)
+
+	//Jobs
+	router.Group(func(r chi.Router) {
+		r.Use(auth.Required)
+		r.Get("/api/v1/jobs", jobHandler.GetJobs)
+		r.Post("/api/v1/jobs", jobHandler.CreateJob)
+		r.Get("/api/v1/jobs/{id}", jobHandler.GetJob)
+		r.With(auth.Admin).Delete("/api/v1/jobs/{id}", jobHandler.CancelJob)
+		r.Get("/api/v1/jobs/{id}/output", jobHandler.GetJobOutput)
+		r.With(auth.Admin).Post("/api/v1/jobs/{id}/retry", jobHandler.RetryJob)
+	})
+
+	//Ingestion runs
+	router.With(auth.Required, auth.Admin).Get("/api/v1/admin/ingestions", ingestionHandler.GetIngestions)
+	router.With(auth.Required, auth.Admin).Post("/api/v1/admin/ingestions", ingestionHandler.CreateIngestion)
+	router.With(auth.Required, auth.Admin).Get("/api/v1/admin/ingestions/{id}", ingestionHandler.GetIngestion)
+	router.With(auth.Required, auth.Admin).Get("/api/v1/admin/ingestions/{id}/diff", ingestionHandler.GetIngestionDiff)
+	router.With(auth.Required, auth.Admin).Get("/api/v1/admin/ingestions/{id}/drift", ingestionHandler.GetIngestionDrift)
+	router.With(auth.Required, auth.Admin).Get("/api/v1/admin/schemas/{endpoint}", ingestionHandler.GetSchema)
+	router.With(auth.Required, auth.Admin).Delete("/api/v1/admin/schemas/{endpoint}", ingestionHandler.DeleteSchema)
+
 	return router
 }
@@ -0,0 +1,149 @@
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/conditional"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/stats"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+const (
+	defaultWindowDays = 30
+	maxWindowDays     = 366
+	defaultLimit      = 50
+	maxLimit          = 1000
+)
+
+type Handler struct {
+	service *service.Service
+	ctx     context.Context
+}
+
+func NewHandler(s *service.Service) *Handler {
+	return &Handler{service: s, ctx: context.Background()}
+}
+
+// GetOTP serves on-time performance per ?dimension= (airline, airport, route
+// or hour), optionally of one ?key=. The range is ?from= to ?to=, or the
+// rolling ?window= of days (7d, 30d, ...) ending at ?to= or today.
+func (h *Handler) GetOTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := structs.OTPFilter{
+		Dimension: query.Get("dimension"),
+		Key:       strings.ToUpper(query.Get("key")),
+		Limit:     defaultLimit,
+	}
+	if filter.Dimension == "" {
+		filter.Dimension = "airline"
+	}
+	if !validDimension(filter.Dimension) {
+		http.Error(w, "Invalid dimension", http.StatusBadRequest)
+		return
+	}
+
+	from, to, ok := dateRange(w, r)
+	if !ok {
+		return
+	}
+	filter.From, filter.To = from, to
+
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxLimit {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = n
+	}
+
+	otp, err := h.service.Stats.GetOTP(h.ctx, filter)
+	if err != nil {
+		log.Printf("Error fetching on-time performance: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	conditional.WriteJSON(w, r, otp, time.Time{})
+}
+
+// RebuildOTP recomputes the daily on-time performance of ?from= to ?to=,
+// e.g. after flights were backfilled.
+func (h *Handler) RebuildOTP(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := dateRange(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := h.service.Stats.RefreshOTP(h.ctx, from, to)
+	if err != nil {
+		log.Printf("Error rebuilding on-time performance: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"from": from.Format("2006-01-02"),
+		"to":   to.Format("2006-01-02"),
+		"rows": rows,
+	})
+}
+
+func validDimension(dimension string) bool {
+	for _, d := range stats.Dimensions {
+		if d == dimension {
+			return true
+		}
+	}
+	return false
+}
+
+// dateRange reads ?from=, ?to= and ?window=, answering 400 itself when they
+// are invalid.
+func dateRange(w http.ResponseWriter, r *http.Request) (time.Time, time.Time, bool) {
+	query := r.URL.Query()
+
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	if v := query.Get("to"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid to, expected YYYY-MM-DD", http.StatusBadRequest)
+			return time.Time{}, time.Time{}, false
+		}
+		to = d
+	}
+
+	days := defaultWindowDays
+	if v := query.Get("window"); v != "" {
+		n, err := strconv.Atoi(strings.TrimSuffix(v, "d"))
+		if err != nil || n <= 0 || n > maxWindowDays {
+			http.Error(w, "Invalid window", http.StatusBadRequest)
+			return time.Time{}, time.Time{}, false
+		}
+		days = n
+	}
+	from := to.AddDate(0, 0, 1-days)
+
+	if v := query.Get("from"); v != "" {
+		d, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid from, expected YYYY-MM-DD", http.StatusBadRequest)
+			return time.Time{}, time.Time{}, false
+		}
+		from = d
+	}
+	if from.After(to) || to.Sub(from) > maxWindowDays*24*time.Hour {
+		http.Error(w, "Invalid date range", http.StatusBadRequest)
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
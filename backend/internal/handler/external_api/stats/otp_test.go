@@ -0,0 +1,41 @@
+package stats
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDateRange(t *testing.T) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	day := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	tests := []struct {
+		query    string
+		from, to time.Time
+		status   int
+	}{
+		{"", today.AddDate(0, 0, -29), today, http.StatusOK},
+		{"?window=7d&to=2024-05-08", day("2024-05-02"), day("2024-05-08"), http.StatusOK},
+		{"?window=1&to=2024-05-08", day("2024-05-08"), day("2024-05-08"), http.StatusOK},
+		{"?from=2024-04-01&to=2024-05-08&window=7d", day("2024-04-01"), day("2024-05-08"), http.StatusOK},
+		{"?window=0d", time.Time{}, time.Time{}, http.StatusBadRequest},
+		{"?window=367d", time.Time{}, time.Time{}, http.StatusBadRequest},
+		{"?from=2024-05-09&to=2024-05-08", time.Time{}, time.Time{}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			from, to, ok := dateRange(w, httptest.NewRequest(http.MethodGet, "/api/v1/stats/otp"+tt.query, nil))
+			if ok != (tt.status == http.StatusOK) || w.Code != tt.status {
+				t.Fatalf("dateRange = %v with status %d, want %d", ok, w.Code, tt.status)
+			}
+			if !from.Equal(tt.from) || !to.Equal(tt.to) {
+				t.Errorf("range = %s to %s, want %s to %s", from, to, tt.from, tt.to)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/auth"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/trash"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	service *service.Service
+	ctx     context.Context
+}
+
+func NewHandler(s *service.Service) *Handler {
+	return &Handler{service: s, ctx: context.Background()}
+}
+
+// Context returns ctx set to read soft deleted rows too when an
+// administrator asks for them with ?include_deleted=true. Other users get
+// the live rows.
+func Context(ctx context.Context, r *http.Request) context.Context {
+	if included(r) {
+		return structs.WithDeleted(ctx)
+	}
+	return ctx
+}
+
+// Variant tells the ETag of a list with its soft deleted rows apart from
+// the one without.
+func Variant(r *http.Request, variant string) string {
+	if included(r) {
+		return variant + "+deleted"
+	}
+	return variant
+}
+
+func included(r *http.Request) bool {
+	include, _ := strconv.ParseBool(r.URL.Query().Get("include_deleted"))
+	return include && auth.IsAdmin(r)
+}
+
+// Restore answers POST /{resource}/{id}/restore, bringing back a soft
+// deleted row of the given table. The route is for administrators.
+func (h *Handler) Restore(resource string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, "Invalid ID", http.StatusBadRequest)
+			return
+		}
+
+		err = h.service.Trash.Restore(h.ctx, resource, id)
+		switch {
+		case err == nil:
+			w.WriteHeader(http.StatusNoContent)
+		case errors.Is(err, trash.ErrNotFound):
+			http.Error(w, "Not found", http.StatusNotFound)
+		default:
+			log.Printf("Error restoring %s: %v", resource, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package watchlists
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/auth"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/conditional"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/user"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/utils"
+)
+
+// tokens is the answer of the endpoints that issue tokens.
+type tokens struct {
+	User         structs.User `json:"user"`
+	AccessToken  string       `json:"access_token"`
+	RefreshToken string       `json:"refresh_token"`
+}
+
+// CreateUser registers {"email": "..."} and answers with an access token for
+// the watchlist and webhook endpoints, and the refresh token that renews it.
+func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	if !auth.Configured() {
+		http.Error(w, "Authentication is not configured", http.StatusServiceUnavailable)
+		return
+	}
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	refreshToken, err := utils.GenerateNewJWTRefreshToken()
+	if err != nil {
+		log.Printf("Error generating refresh token: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	u, err := h.service.User.CreateUser(h.ctx, body.Email, refreshToken)
+	switch {
+	case errors.Is(err, user.ErrInvalidEmail):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, user.ErrEmailTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		log.Printf("Error creating user: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	token, err := utils.GenerateNewJWTAccessToken(auth.Credentials(u.Admin), u.ID)
+	if err != nil {
+		log.Printf("Error generating access token: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, tokens{u, token, refreshToken})
+}
+
+// RefreshToken takes {"refresh_token": "..."} and answers with a new access
+// token and a new refresh token. The refresh token sent is spent.
+func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	if !auth.Configured() {
+		http.Error(w, "Authentication is not configured", http.StatusServiceUnavailable)
+		return
+	}
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	next, err := utils.GenerateNewJWTRefreshToken()
+	if err != nil {
+		log.Printf("Error generating refresh token: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	u, err := h.service.User.RefreshToken(h.ctx, body.RefreshToken, next)
+	switch {
+	case errors.Is(err, user.ErrInvalidRefreshToken):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	case err != nil:
+		log.Printf("Error refreshing token: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	token, err := utils.GenerateNewJWTAccessToken(auth.Credentials(u.Admin), u.ID)
+	if err != nil {
+		log.Printf("Error generating access token: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, tokens{u, token, next})
+}
+
+func (h *Handler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
+	u, err := h.service.User.GetUser(h.ctx, auth.UserID(r))
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	conditional.WriteJSON(w, r, u, time.Time{})
+}
@@ -0,0 +1,153 @@
+package watchlists
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/auth"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/conditional"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/watch"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type Handler struct {
+	service *service.Service
+	ctx     context.Context
+}
+
+func NewHandler(s *service.Service) *Handler {
+	return &Handler{service: s, ctx: context.Background()}
+}
+
+func (h *Handler) GetWatchlists(w http.ResponseWriter, r *http.Request) {
+	lists, err := h.service.Watch.GetWatchlists(h.ctx, auth.UserID(r))
+	if err != nil {
+		log.Printf("Error fetching watchlists: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	conditional.WriteJSON(w, r, lists, time.Time{})
+}
+
+// CreateWatchlist takes {"name": "...", "watches": [{"kind": "flight",
+// "value": "TP1350"}, ...]}.
+func (h *Handler) CreateWatchlist(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name    string          `json:"name"`
+		Watches []structs.Watch `json:"watches"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	list, err := h.service.Watch.CreateWatchlist(h.ctx, auth.UserID(r), body.Name, body.Watches)
+	if !h.ok(w, err, "creating watchlist") {
+		return
+	}
+	writeJSON(w, http.StatusCreated, list)
+}
+
+func (h *Handler) GetWatchlist(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	list, err := h.service.Watch.GetWatchlist(h.ctx, auth.UserID(r), id)
+	if !h.ok(w, err, "fetching watchlist") {
+		return
+	}
+	conditional.WriteJSON(w, r, list, time.Time{})
+}
+
+func (h *Handler) DeleteWatchlist(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	err := h.service.Watch.DeleteWatchlist(h.ctx, auth.UserID(r), id)
+	if !h.ok(w, err, "deleting watchlist") {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddWatch takes {"kind": "route", "value": "LIS-LHR"}.
+func (h *Handler) AddWatch(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r, "id")
+	if !ok {
+		return
+	}
+	var body structs.Watch
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	added, err := h.service.Watch.AddWatch(h.ctx, auth.UserID(r), id, body)
+	if !h.ok(w, err, "adding watch") {
+		return
+	}
+	writeJSON(w, http.StatusCreated, added)
+}
+
+func (h *Handler) DeleteWatch(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r, "id")
+	if !ok {
+		return
+	}
+	watchID, ok := idParam(w, r, "watch_id")
+	if !ok {
+		return
+	}
+
+	err := h.service.Watch.DeleteWatch(h.ctx, auth.UserID(r), id, watchID)
+	if !h.ok(w, err, "deleting watch") {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ok answers the request itself when err is not nil: 404 for rows of
+// other users or that do not exist, 400 for invalid input.
+func (h *Handler) ok(w http.ResponseWriter, err error, action string) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, watch.ErrNotFound):
+		http.Error(w, "Not found", http.StatusNotFound)
+	case errors.Is(err, watch.ErrInvalidWatch), errors.Is(err, watch.ErrInvalidName),
+		errors.Is(err, watch.ErrInvalidURL), errors.Is(err, watch.ErrPrivateURL),
+		errors.Is(err, watch.ErrInvalidStatus):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("Error %s: %v", action, err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+	return false
+}
+
+func idParam(w http.ResponseWriter, r *http.Request, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, name))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,138 @@
+package watchlists
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/auth"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/conditional"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 500
+)
+
+func (h *Handler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	hooks, err := h.service.Watch.GetWebhooks(h.ctx, auth.UserID(r))
+	if !h.ok(w, err, "fetching webhooks") {
+		return
+	}
+	conditional.WriteJSON(w, r, hooks, time.Time{})
+}
+
+// CreateWebhook takes {"url": "https://..."} and answers with the webhook
+// and its signing secret, which is not shown again.
+func (h *Handler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	hook, err := h.service.Watch.CreateWebhook(h.ctx, auth.UserID(r), body.URL)
+	if !h.ok(w, err, "creating webhook") {
+		return
+	}
+	writeJSON(w, http.StatusCreated, hook)
+}
+
+// UpdateWebhook takes {"active": false} to pause a webhook or true to resume
+// it.
+func (h *Handler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r, "id")
+	if !ok {
+		return
+	}
+	var body struct {
+		Active *bool `json:"active"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Active == nil {
+		http.Error(w, "Invalid request body, expected {\"active\": true|false}", http.StatusBadRequest)
+		return
+	}
+
+	err := h.service.Watch.SetWebhookActive(h.ctx, auth.UserID(r), id, *body.Active)
+	if !h.ok(w, err, "updating webhook") {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	err := h.service.Watch.DeleteWebhook(h.ctx, auth.UserID(r), id)
+	if !h.ok(w, err, "deleting webhook") {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetDeliveries is the delivery log of a webhook, newest first, optionally
+// only ?status=pending, delivered or dead.
+func (h *Handler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r, "id")
+	if !ok {
+		return
+	}
+	filter := structs.DeliveryFilter{Status: r.URL.Query().Get("status"), Limit: defaultDeliveryLimit}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxDeliveryLimit {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	deliveries, err := h.service.Watch.GetDeliveries(h.ctx, auth.UserID(r), id, filter)
+	if !h.ok(w, err, "fetching deliveries") {
+		return
+	}
+	conditional.WriteJSON(w, r, deliveries, time.Time{})
+}
+
+// GetDelivery returns a delivery with every attempt made to send it.
+func (h *Handler) GetDelivery(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := idParam(w, r, "delivery_id")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.service.Watch.GetDelivery(h.ctx, auth.UserID(r), id, deliveryID)
+	if !h.ok(w, err, "fetching delivery") {
+		return
+	}
+	conditional.WriteJSON(w, r, delivery, time.Time{})
+}
+
+// RetryDelivery queues a dead delivery again.
+func (h *Handler) RetryDelivery(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := idParam(w, r, "delivery_id")
+	if !ok {
+		return
+	}
+
+	err := h.service.Watch.RetryDelivery(h.ctx, auth.UserID(r), id, deliveryID)
+	if !h.ok(w, err, "retrying delivery") {
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -2,9 +2,13 @@ package handler
 
 import (
 	"context"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/dispatcher"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/poller"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/pprof"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/prometheus"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/relay"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/worker"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
 	"github.com/FACorreiaa/aviatoon-tracker/pkg/logs"
 	"os"
@@ -21,17 +25,29 @@ type Config struct {
 	externalApiConfig external_api.Config
 	pprofConfig       pprof.Config
 	prometheusConfig  prometheus.Config
+	pollerConfig      poller.Config
+	dispatcherConfig  dispatcher.Config
+	workerConfig      worker.Config
+	relayConfig       relay.Config
 }
 
 func NewConfig(
 	apiConfig external_api.Config,
 	pprofConfig pprof.Config,
 	prometheusConfig prometheus.Config,
+	pollerConfig poller.Config,
+	dispatcherConfig dispatcher.Config,
+	workerConfig worker.Config,
+	relayConfig relay.Config,
 ) Config {
 	return Config{
 		externalApiConfig: apiConfig,
 		pprofConfig:       pprofConfig,
 		prometheusConfig:  prometheusConfig,
+		pollerConfig:      pollerConfig,
+		dispatcherConfig:  dispatcherConfig,
+		workerConfig:      workerConfig,
+		relayConfig:       relayConfig,
 	}
 }
 
@@ -43,6 +59,10 @@ type Handler struct {
 	externalApi handler
 	pprof       handler
 	prometheus  handler
+	poller      handler
+	dispatcher  handler
+	worker      handler
+	relay       handler
 }
 
 func NewHandler(
@@ -59,6 +79,10 @@ func (h *Handler) Handle(exitSignal *os.Signal) {
 	h.externalApi = external_api.New(h.config.externalApiConfig, h.service)
 	h.pprof = pprof.New(h.config.pprofConfig)
 	h.prometheus = prometheus.New(h.config.prometheusConfig)
+	h.poller = poller.New(h.config.pollerConfig, h.service)
+	h.dispatcher = dispatcher.New(h.config.dispatcherConfig, h.service)
+	h.worker = worker.New(h.config.workerConfig, h.service)
+	h.relay = relay.New(h.config.relayConfig, h.service)
 	go func() {
 		if err := h.pprof.Run(); err != nil && exitSignal == nil {
 			logs.DefaultLogger.WithError(err).Fatal("Pprof server was closed unexpectedly")
@@ -77,26 +101,74 @@ func (h *Handler) Handle(exitSignal *os.Signal) {
 			syscall.Kill(syscall.Getpid(), syscall.SIGQUIT)
 		}
 	}()
+	go func() {
+		if err := h.poller.Run(); err != nil && exitSignal == nil {
+			logs.DefaultLogger.WithError(err).Fatal("Flight poller was stopped unexpectedly")
+			syscall.Kill(syscall.Getpid(), syscall.SIGQUIT)
+		}
+	}()
+	go func() {
+		if err := h.dispatcher.Run(); err != nil && exitSignal == nil {
+			logs.DefaultLogger.WithError(err).Fatal("Webhook dispatcher was stopped unexpectedly")
+			syscall.Kill(syscall.Getpid(), syscall.SIGQUIT)
+		}
+	}()
+	go func() {
+		if err := h.worker.Run(); err != nil && exitSignal == nil {
+			logs.DefaultLogger.WithError(err).Fatal("Job workers were stopped unexpectedly")
+			syscall.Kill(syscall.Getpid(), syscall.SIGQUIT)
+		}
+	}()
+	go func() {
+		if err := h.relay.Run(); err != nil && exitSignal == nil {
+			logs.DefaultLogger.WithError(err).Fatal("Outbox relay was stopped unexpectedly")
+			syscall.Kill(syscall.Getpid(), syscall.SIGQUIT)
+		}
+	}()
 }
 
 func (h *Handler) Shutdown(ctx context.Context) {
 	var wg sync.WaitGroup
-	wg.Add(2)
+	wg.Add(7)
 	go func() {
 		if err := h.externalApi.Shutdown(ctx); err != nil {
-			logs.DefaultLogger.WithError(err).Fatal("Error on restApi shutdown")
+			logs.DefaultLogger.WithError(err).Error("Error on restApi shutdown")
 		}
 		wg.Done()
 	}()
 	go func() {
 		if err := h.pprof.Shutdown(ctx); err != nil {
-			logs.DefaultLogger.WithError(err).Fatal("Error on pprof shutdown")
+			logs.DefaultLogger.WithError(err).Error("Error on pprof shutdown")
 		}
 		wg.Done()
 	}()
 	go func() {
 		if err := h.prometheus.Shutdown(ctx); err != nil {
-			logs.DefaultLogger.WithError(err).Fatal("Error on pprof shutdown")
+			logs.DefaultLogger.WithError(err).Error("Error on pprof shutdown")
+		}
+		wg.Done()
+	}()
+	go func() {
+		if err := h.poller.Shutdown(ctx); err != nil {
+			logs.DefaultLogger.WithError(err).Error("Error on flight poller shutdown")
+		}
+		wg.Done()
+	}()
+	go func() {
+		if err := h.dispatcher.Shutdown(ctx); err != nil {
+			logs.DefaultLogger.WithError(err).Error("Error on webhook dispatcher shutdown")
+		}
+		wg.Done()
+	}()
+	go func() {
+		if err := h.worker.Shutdown(ctx); err != nil {
+			logs.DefaultLogger.WithError(err).Error("Error on job workers shutdown")
+		}
+		wg.Done()
+	}()
+	go func() {
+		if err := h.relay.Shutdown(ctx); err != nil {
+			logs.DefaultLogger.WithError(err).Error("Error on outbox relay shutdown")
 		}
 		wg.Done()
 	}()
@@ -1,15 +1,29 @@
 package internal_api
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"strings"
+	"time"
 )
 
+// upstreamTimeout bounds every request to the upstream API, however the
+// caller's context is set.
+const upstreamTimeout = 30 * time.Second
+
+var upstreamClient = &http.Client{Timeout: upstreamTimeout}
+
 func FetchAviationStackData(endpoint string, queryParams ...string) ([]byte, error, bool) {
+	return FetchAviationStackDataContext(context.Background(), endpoint, queryParams...)
+}
+
+// FetchAviationStackDataContext is FetchAviationStackData giving up when
+// ctx is done.
+func FetchAviationStackDataContext(ctx context.Context, endpoint string, queryParams ...string) ([]byte, error, bool) {
 	accessKey := os.Getenv("AVIATION_STACK_API_KEY")
 	if accessKey == "" {
 		return nil, fmt.Errorf("missing API access key"), false
@@ -46,17 +60,21 @@ func FetchAviationStackData(endpoint string, queryParams ...string) ([]byte, err
 
 	finalURL := parsedURL.String()
 
-	response, err := http.Get(finalURL)
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, finalURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to make GET request: %v", err), false
 	}
+	response, err := upstreamClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make GET request: %v", err), false
+	}
+
+	defer response.Body.Close()
 
 	if response.StatusCode >= http.StatusBadRequest {
 		return nil, fmt.Errorf("something is not ok"), false
 	}
 
-	defer response.Body.Close()
-
 	body, err := io.ReadAll(response.Body)
 
 	if err != nil {
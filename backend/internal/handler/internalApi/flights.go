@@ -0,0 +1,29 @@
+package internal_api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+// FetchFlights queries the upstream flights endpoint and flattens the
+// result, e.g. FetchFlights(ctx, "dep_iata=LIS", "limit=100").
+func FetchFlights(ctx context.Context, queryParams ...string) ([]structs.Flight, error) {
+	body, err, _ := FetchAviationStackDataContext(ctx, "flights", queryParams...)
+	if err != nil {
+		return nil, err
+	}
+
+	var response structs.FlightApiData
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to decode flights: %w", err)
+	}
+
+	flights := make([]structs.Flight, len(response.Data))
+	for i, f := range response.Data {
+		flights[i] = f.ToFlight()
+	}
+	return flights, nil
+}
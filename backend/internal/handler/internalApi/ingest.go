@@ -0,0 +1,96 @@
+package internal_api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/ingestion"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+// finishTimeout bounds recording the outcome of a run whose context was
+// cancelled half way, so that it does not stay running forever.
+const finishTimeout = 10 * time.Second
+
+// Ingest records an import of dataset as a run: it fetches endpoint, checks
+// the payload against the expected schema, decodes its rows, diffs them with
+// the table and, unless dryRun, writes them. A failed diff or schema check
+// only fails dry runs; real imports note it in the run and go on. Schema
+// drift fails the run in strict mode.
+func Ingest[T any](ctx context.Context, runs service.Ingestion, dataset string, endpoint string, dryRun bool,
+	decode func([]byte) ([]T, error),
+	diff func(context.Context, []T) (structs.IngestionDiff, error),
+	write func(context.Context, []T) (structs.BatchResult, error),
+) (structs.IngestionRun, error) {
+	run, err := runs.StartRun(ctx, dataset, dryRun)
+	if err != nil {
+		return run, fmt.Errorf("error starting %s ingestion run: %w", dataset, err)
+	}
+
+	var changes *structs.IngestionDiff
+	err = func() error {
+		body, err, _ := FetchAviationStackData(endpoint)
+		if err != nil {
+			return fmt.Errorf("error getting %s data: %w", dataset, err)
+		}
+		run.Pages = 1
+
+		drift, err := runs.CheckSchema(ctx, run, endpoint, body)
+		run.DriftEvents = len(drift)
+		switch {
+		case err == nil:
+		case dryRun, errors.Is(err, ingestion.ErrSchemaDrift):
+			return fmt.Errorf("error checking %s schema: %w", endpoint, err)
+		default:
+			run.Errors = append(run.Errors, fmt.Sprintf("error checking %s schema: %v", endpoint, err))
+		}
+
+		rows, err := decode(body)
+		if err != nil {
+			return err
+		}
+		run.RowsFetched = int64(len(rows))
+
+		d, err := diff(ctx, rows)
+		switch {
+		case err == nil:
+			changes = &d
+		case dryRun:
+			return fmt.Errorf("error diffing %s: %w", dataset, err)
+		default:
+			run.Errors = append(run.Errors, fmt.Sprintf("error diffing %s: %v", dataset, err))
+		}
+		if dryRun {
+			return nil
+		}
+
+		result, err := write(ctx, rows)
+		run.RowsInserted = result.Inserted
+		run.RowsUpdated = result.Updated
+		run.RowsFailed = result.Failed
+		if err != nil {
+			return fmt.Errorf("error writing %s: %w", dataset, err)
+		}
+		return nil
+	}()
+
+	run.Status = structs.IngestionSucceeded
+	if err != nil {
+		run.Status = structs.IngestionFailed
+		run.Errors = append(run.Errors, err.Error())
+	}
+
+	finishCtx, cancel := context.WithTimeout(context.Background(), finishTimeout)
+	defer cancel()
+	if ferr := runs.FinishRun(finishCtx, run, changes); ferr != nil {
+		log.Printf("error recording %s ingestion run %s: %v", dataset, run.ID, ferr)
+	}
+	now := time.Now()
+	run.FinishedAt = &now
+
+	return run, err
+}
@@ -0,0 +1,48 @@
+package poller
+
+import (
+	"context"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+)
+
+type Config struct {
+	interval time.Duration
+	pages    int
+	limit    int
+}
+
+// NewConfig configures the flight poller. An interval of zero or less
+// disables polling.
+func NewConfig(
+	interval time.Duration,
+	pages int,
+	limit int,
+) Config {
+	if pages < 1 {
+		pages = 1
+	}
+	if limit < 1 {
+		limit = 100
+	}
+	return Config{
+		interval: interval,
+		pages:    pages,
+		limit:    limit,
+	}
+}
+
+type Poller interface {
+	Run() error
+	Shutdown(ctx context.Context) error
+}
+
+func New(config Config, s *service.Service) Poller {
+	return &worker{
+		config:  config,
+		service: s,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
@@ -0,0 +1,112 @@
+package poller
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	internal_api "github.com/FACorreiaa/aviatoon-tracker/internal/handler/internalApi"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/FACorreiaa/aviatoon-tracker/pkg/logs"
+)
+
+// otpDays is how many days back on-time performance is recomputed after a
+// poll; flights keep landing and being updated after their date.
+const otpDays = 2
+
+// worker pulls the upstream flights endpoint on an interval, records what it
+// sees and then refreshes the route network from the routes it touched.
+type worker struct {
+	config  Config
+	service *service.Service
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func (p *worker) Run() error {
+	defer close(p.done)
+	if p.config.interval <= 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-p.stop
+		cancel()
+	}()
+
+	ticker := time.NewTicker(p.config.interval)
+	defer ticker.Stop()
+	for {
+		p.cycle(ctx)
+		select {
+		case <-ticker.C:
+		case <-p.stop:
+			return nil
+		}
+	}
+}
+
+func (p *worker) Shutdown(ctx context.Context) error {
+	p.stopOnce.Do(func() { close(p.stop) })
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *worker) cycle(ctx context.Context) {
+	var total structs.FlightIngestion
+	for page := 0; page < p.config.pages; page++ {
+		flights, err := p.fetch(ctx, page)
+		if err != nil {
+			logs.DefaultLogger.WithError(err).Error("Error fetching flights")
+			break
+		}
+
+		result, err := p.service.Flight.RecordFlights(ctx, flights)
+		if err != nil {
+			logs.DefaultLogger.WithError(err).Error("Error recording flights")
+			break
+		}
+		total.Add(result)
+
+		if len(flights) < p.config.limit {
+			break
+		}
+	}
+
+	if total.Routes > 0 {
+		if err := p.service.Network.Refresh(ctx); err != nil {
+			logs.DefaultLogger.WithError(err).Error("Error refreshing route network")
+		}
+	}
+	if total.Inserted+total.Updated > 0 {
+		// Flights are dated in local time, so a poll touches the days either
+		// side of today in UTC.
+		today := time.Now().UTC().Truncate(24 * time.Hour)
+		if _, err := p.service.Stats.RefreshOTP(ctx, today.AddDate(0, 0, -otpDays), today.AddDate(0, 0, 1)); err != nil {
+			logs.DefaultLogger.WithError(err).Error("Error refreshing on-time performance")
+		}
+	}
+	logs.DefaultLogger.WithFields(map[string]any{
+		"observed": total.Observed,
+		"inserted": total.Inserted,
+		"updated":  total.Updated,
+		"skipped":  total.Skipped,
+		"routes":   total.Routes,
+	}).Info("Flights were polled")
+}
+
+func (p *worker) fetch(ctx context.Context, page int) ([]structs.Flight, error) {
+	return internal_api.FetchFlights(ctx,
+		"limit="+strconv.Itoa(p.config.limit),
+		"offset="+strconv.Itoa(page*p.config.limit))
+}
@@ -0,0 +1,222 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/airlines"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/airports"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/location"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api/render"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/trash"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+// Kinds of job.
+const (
+	KindImport    = structs.ImportKind
+	KindExport    = "export"
+	KindIntegrity = "integrity.link"
+	KindOTP       = "otp.rebuild"
+	KindPurge     = "trash.purge"
+)
+
+// importers refresh a reference table from the upstream API, or only diff
+// it on a dry run.
+var importers = map[string]func(ctx context.Context, s *service.Service, dryRun bool) (structs.IngestionRun, error){
+	"aircraft": func(ctx context.Context, s *service.Service, dryRun bool) (structs.IngestionRun, error) {
+		return airlines.NewHandler(s).ImportAircrafts(ctx, dryRun)
+	},
+	"airline": func(ctx context.Context, s *service.Service, dryRun bool) (structs.IngestionRun, error) {
+		return airlines.NewHandler(s).ImportAirlines(ctx, dryRun)
+	},
+	"airplane": func(ctx context.Context, s *service.Service, dryRun bool) (structs.IngestionRun, error) {
+		return airlines.NewHandler(s).ImportAirplanes(ctx, dryRun)
+	},
+	"airport": func(ctx context.Context, s *service.Service, dryRun bool) (structs.IngestionRun, error) {
+		return airports.NewHandler(s).ImportAirports(ctx, dryRun)
+	},
+	"city": func(ctx context.Context, s *service.Service, dryRun bool) (structs.IngestionRun, error) {
+		return location.NewHandler(s).ImportCities(ctx, dryRun)
+	},
+	"country": func(ctx context.Context, s *service.Service, dryRun bool) (structs.IngestionRun, error) {
+		return location.NewHandler(s).ImportCountries(ctx, dryRun)
+	},
+	"tax": func(ctx context.Context, s *service.Service, dryRun bool) (structs.IngestionRun, error) {
+		return airlines.NewHandler(s).ImportTaxes(ctx, dryRun)
+	},
+}
+
+// exporters write a reference table in a format.
+var exporters = map[string]func(ctx context.Context, s *service.Service, out io.Writer, format render.Format) error{
+	"aircraft": func(ctx context.Context, s *service.Service, out io.Writer, format render.Format) error {
+		return render.Encode(out, format, func(fn func(structs.Aircraft) error) error {
+			return s.Aircraft.StreamAircrafts(ctx, fn)
+		})
+	},
+	"airline": func(ctx context.Context, s *service.Service, out io.Writer, format render.Format) error {
+		return render.Encode(out, format, func(fn func(structs.Airline) error) error {
+			return s.Airline.StreamAirlines(ctx, fn)
+		})
+	},
+	"airplane": func(ctx context.Context, s *service.Service, out io.Writer, format render.Format) error {
+		return render.Encode(out, format, func(fn func(structs.Airplane) error) error {
+			return s.Airplane.StreamAirplanes(ctx, fn)
+		})
+	},
+	"airport": func(ctx context.Context, s *service.Service, out io.Writer, format render.Format) error {
+		return render.Encode(out, format, func(fn func(structs.Airport) error) error {
+			return s.Airport.StreamAirports(ctx, fn)
+		})
+	},
+	"city": func(ctx context.Context, s *service.Service, out io.Writer, format render.Format) error {
+		return render.Encode(out, format, func(fn func(structs.City) error) error {
+			return s.City.StreamCities(ctx, fn)
+		})
+	},
+	"country": func(ctx context.Context, s *service.Service, out io.Writer, format render.Format) error {
+		return render.Encode(out, format, func(fn func(structs.Country) error) error {
+			return s.Country.StreamCountries(ctx, fn)
+		})
+	},
+	"tax": func(ctx context.Context, s *service.Service, out io.Writer, format render.Format) error {
+		return render.Encode(out, format, func(fn func(structs.Tax) error) error {
+			return s.Tax.StreamTaxes(ctx, fn)
+		})
+	},
+}
+
+func init() {
+	register(KindImport, checkImport, runImport)
+	register(KindExport, checkExport, runExport)
+	register(KindIntegrity, func(struct{}) (string, error) { return KindIntegrity, nil }, runIntegrity)
+	register(KindOTP, checkOTP, runOTP)
+	register(KindPurge, checkPurge, runPurge)
+}
+
+func checkImport(p structs.ImportJob) (string, error) {
+	if _, ok := importers[p.Resource]; !ok {
+		return "", fmt.Errorf("invalid resource %q, expected one of %v", p.Resource, resources(importers))
+	}
+	return p.Key(), nil
+}
+
+func runImport(ctx context.Context, s *service.Service, _ output, p structs.ImportJob) (outcome, error) {
+	run, err := importers[p.Resource](ctx, s, p.DryRun)
+	return outcome{result: run}, err
+}
+
+func checkExport(p structs.ExportJob) (string, error) {
+	if _, ok := exporters[p.Resource]; !ok {
+		return "", fmt.Errorf("invalid resource %q, expected one of %v", p.Resource, resources(exporters))
+	}
+	if _, ok := exportFormat(p); !ok {
+		return "", errors.New("invalid format, expected json, ndjson, csv or protobuf")
+	}
+	return "", nil
+}
+
+func runExport(ctx context.Context, s *service.Service, out output, p structs.ExportJob) (outcome, error) {
+	format, _ := exportFormat(p)
+	file := out()
+	written := &counter{w: file}
+	if err := exporters[p.Resource](ctx, s, written, format); err != nil {
+		return outcome{}, err
+	}
+	if err := file.Close(); err != nil {
+		return outcome{}, err
+	}
+	return outcome{
+		result:     map[string]interface{}{"resource": p.Resource, "format": format, "bytes": written.n},
+		outputType: render.ContentType(format),
+	}, nil
+}
+
+// counter counts the bytes written through it.
+type counter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *counter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// exportFormat is the format of an export, JSON when none is given.
+func exportFormat(p structs.ExportJob) (render.Format, bool) {
+	if p.Format == "" {
+		return render.JSON, true
+	}
+	return render.ParseFormat(p.Format)
+}
+
+func runIntegrity(ctx context.Context, s *service.Service, _ output, _ struct{}) (outcome, error) {
+	result, err := s.Integrity.LinkReferences(ctx)
+	return outcome{result: result}, err
+}
+
+func checkOTP(p structs.OTPJob) (string, error) {
+	from, to, err := otpRange(p)
+	if err != nil {
+		return "", err
+	}
+	return KindOTP + ":" + from.Format("2006-01-02") + ":" + to.Format("2006-01-02"), nil
+}
+
+func runOTP(ctx context.Context, s *service.Service, _ output, p structs.OTPJob) (outcome, error) {
+	from, to, _ := otpRange(p)
+	rows, err := s.Stats.RefreshOTP(ctx, from, to)
+	return outcome{result: map[string]interface{}{"from": p.From, "to": p.To, "rows": rows}}, err
+}
+
+func otpRange(p structs.OTPJob) (time.Time, time.Time, error) {
+	from, err := time.Parse("2006-01-02", p.From)
+	if err != nil {
+		return from, from, errors.New("invalid from, expected YYYY-MM-DD")
+	}
+	to, err := time.Parse("2006-01-02", p.To)
+	if err != nil {
+		return from, to, errors.New("invalid to, expected YYYY-MM-DD")
+	}
+	if to.Before(from) {
+		return from, to, errors.New("to is before from")
+	}
+	return from, to, nil
+}
+
+func checkPurge(p structs.PurgeJob) (string, error) {
+	if p.OlderThanDays < 0 {
+		return "", errors.New("invalid older_than_days, expected zero or more")
+	}
+	if p.Resource == "" {
+		return KindPurge + ":all", nil
+	}
+	for _, r := range trash.Resources {
+		if r == p.Resource {
+			return KindPurge + ":" + p.Resource, nil
+		}
+	}
+	return "", fmt.Errorf("invalid resource %q, expected one of %v", p.Resource, trash.Resources)
+}
+
+func runPurge(ctx context.Context, s *service.Service, _ output, p structs.PurgeJob) (outcome, error) {
+	before := time.Now().AddDate(0, 0, -p.OlderThanDays)
+	purged, err := s.Trash.Purge(ctx, p.Resource, before)
+	return outcome{result: map[string]interface{}{"resource": p.Resource, "before": before, "purged": purged}}, err
+}
+
+func resources[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,136 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+)
+
+type fakeTax struct {
+	service.Tax
+	rows []structs.Tax
+	err  error
+}
+
+func (f *fakeTax) StreamTaxes(_ context.Context, fn func(structs.Tax) error) error {
+	for _, row := range f.rows {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return f.err
+}
+
+// file is the output of a job under test.
+type file struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (f *file) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		kind    string
+		payload string
+		key     string
+		valid   bool
+	}{
+		{KindImport, `{"resource":"airport"}`, "import:airport", true},
+		{KindImport, `{"resource":"airport","dry_run":true}`, "import:airport:dry-run", true},
+		{KindImport, `{"resource":"flight"}`, "", false},
+		{KindImport, `{"resource":`, "", false},
+		{KindExport, `{"resource":"tax","format":"csv"}`, "", true},
+		{KindExport, `{"resource":"tax"}`, "", true},
+		{KindExport, `{"resource":"tax","format":"xml"}`, "", false},
+		{KindExport, `{"resource":"flight"}`, "", false},
+		{"unknown", `{}`, "", false},
+	}
+	for _, tt := range tests {
+		key, err := Check(tt.kind, json.RawMessage(tt.payload))
+		if (err == nil) != tt.valid || key != tt.key {
+			t.Errorf("Check(%s, %s) = %q, %v; want %q, valid %t", tt.kind, tt.payload, key, err, tt.key, tt.valid)
+		}
+	}
+}
+
+func TestRunExport(t *testing.T) {
+	s := &service.Service{Tax: &fakeTax{rows: []structs.Tax{
+		{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001"), TaxId: 1, TaxName: "Airport Tax", IataCode: "AT"},
+		{ID: uuid.MustParse("00000000-0000-0000-0000-000000000002"), TaxId: 2, TaxName: "Security Fee", IataCode: "SF"},
+	}}}
+	out := &file{}
+	opened := 0
+	open := func() io.WriteCloser {
+		opened++
+		return out
+	}
+
+	got, err := kinds[KindExport].run(context.Background(), s, open, json.RawMessage(`{"resource":"tax","format":"ndjson"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened != 1 || !out.closed {
+		t.Errorf("output opened %d times, closed %t; want once and closed", opened, out.closed)
+	}
+	if got.outputType != "application/x-ndjson" {
+		t.Errorf("output type = %q", got.outputType)
+	}
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("export has %d lines, want 2: %s", len(lines), out.Bytes())
+	}
+	var row structs.Tax
+	if err := json.Unmarshal(lines[1], &row); err != nil || row.TaxName != "Security Fee" {
+		t.Errorf("second line = %s, %v", lines[1], err)
+	}
+	if result := got.result.(map[string]interface{}); result["bytes"] != int64(out.Len()) {
+		t.Errorf("result bytes = %v, want %d", result["bytes"], out.Len())
+	}
+}
+
+func TestRunExportFailure(t *testing.T) {
+	cursor := errors.New("cursor closed")
+	s := &service.Service{Tax: &fakeTax{err: cursor}}
+	out := &file{}
+	_, err := kinds[KindExport].run(context.Background(), s, func() io.WriteCloser { return out }, json.RawMessage(`{"resource":"tax"}`))
+	if !errors.Is(err, cursor) {
+		t.Errorf("err = %v, want %v", err, cursor)
+	}
+	if out.closed {
+		t.Error("a failed export closed its output as complete")
+	}
+}
+
+func TestRunImport(t *testing.T) {
+	var dryRun *bool
+	importers["test"] = func(_ context.Context, _ *service.Service, d bool) (structs.IngestionRun, error) {
+		dryRun = &d
+		return structs.IngestionRun{Dataset: "test", DryRun: d}, nil
+	}
+	t.Cleanup(func() { delete(importers, "test") })
+
+	got, err := kinds[KindImport].run(context.Background(), nil, nil, json.RawMessage(`{"resource":"test","dry_run":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dryRun == nil || !*dryRun {
+		t.Errorf("importer ran with dry run %v, want true", dryRun)
+	}
+	if run, ok := got.result.(structs.IngestionRun); !ok || run.Dataset != "test" {
+		t.Errorf("result = %#v, want the ingestion run", got.result)
+	}
+	if got.outputType != "" {
+		t.Errorf("an import has output type %q", got.outputType)
+	}
+}
@@ -0,0 +1,88 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/job"
+)
+
+var ErrUnknownKind = errors.New("unknown job kind")
+
+// outcome is what a job reports on success: a JSON result and, for jobs
+// that wrote a file to their output, its content type.
+type outcome struct {
+	result     interface{}
+	outputType string
+}
+
+// output opens the file of the running job, see job.Service.Output.
+type output func() io.WriteCloser
+
+// kind runs the jobs of one kind. Payloads are checked before they are
+// queued; key names the work a payload asks for, so the same import is not
+// queued twice.
+type kind struct {
+	check func(payload json.RawMessage) (key string, err error)
+	run   func(ctx context.Context, s *service.Service, out output, payload json.RawMessage) (outcome, error)
+}
+
+var kinds = map[string]kind{}
+
+// register adds a kind of job whose payload decodes into P. check returns
+// the key of a valid payload; an empty key lets equal jobs queue side by
+// side.
+func register[P any](
+	name string,
+	check func(payload P) (string, error),
+	run func(ctx context.Context, s *service.Service, out output, payload P) (outcome, error),
+) {
+	decode := func(raw json.RawMessage) (P, string, error) {
+		var payload P
+		if len(raw) > 0 {
+			if err := json.Unmarshal(raw, &payload); err != nil {
+				return payload, "", fmt.Errorf("invalid %s payload: %w", name, err)
+			}
+		}
+		key, err := check(payload)
+		return payload, key, err
+	}
+
+	kinds[name] = kind{
+		check: func(raw json.RawMessage) (string, error) {
+			_, key, err := decode(raw)
+			return key, err
+		},
+		run: func(ctx context.Context, s *service.Service, out output, raw json.RawMessage) (outcome, error) {
+			payload, _, err := decode(raw)
+			if err != nil {
+				return outcome{}, job.Permanent(err)
+			}
+			return run(ctx, s, out, payload)
+		},
+	}
+}
+
+// Check validates a job before it is queued and returns its key.
+func Check(name string, payload json.RawMessage) (string, error) {
+	k, ok := kinds[name]
+	if !ok {
+		return "", fmt.Errorf("%w %q, expected one of %v", ErrUnknownKind, name, Kinds())
+	}
+	return k.check(payload)
+}
+
+// Kinds lists the kinds of job the workers run.
+func Kinds() []string {
+	names := make([]string, 0, len(kinds))
+	for name := range kinds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,182 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/job"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/FACorreiaa/aviatoon-tracker/pkg/logs"
+)
+
+// recordTimeout bounds the writes that record how a job ended, which run
+// after the job's own context may have been cancelled.
+const recordTimeout = 10 * time.Second
+
+// pool runs queued jobs, one per worker goroutine.
+type pool struct {
+	config  Config
+	service *service.Service
+	id      string
+
+	// ctx is the parent of every job; it is cancelled when a shutdown runs
+	// out of time.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func (p *pool) Run() error {
+	defer close(p.done)
+	if p.config.workers <= 0 {
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(p.config.workers)
+	for i := 0; i < p.config.workers; i++ {
+		go func() {
+			defer wg.Done()
+			p.loop()
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// Shutdown stops claiming jobs and waits for the running ones to finish.
+// If ctx ends first they are cancelled and given back to the queue.
+func (p *pool) Shutdown(ctx context.Context) error {
+	p.stopOnce.Do(func() { close(p.stop) })
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		// Cancelled jobs still release themselves, within recordTimeout.
+		select {
+		case <-p.done:
+		case <-time.After(recordTimeout):
+		}
+		return ctx.Err()
+	}
+}
+
+// loop claims and runs one job at a time until the pool stops.
+func (p *pool) loop() {
+	for {
+		select {
+		case <-p.stop:
+			return
+		default:
+		}
+
+		jobs, err := p.service.Job.Claim(p.ctx, p.id, 1, p.config.lease)
+		if err != nil {
+			logs.DefaultLogger.WithError(err).Error("Error claiming jobs")
+		}
+		if len(jobs) == 0 {
+			select {
+			case <-time.After(p.config.interval):
+			case <-p.stop:
+				return
+			}
+			continue
+		}
+		p.run(jobs[0])
+	}
+}
+
+// run executes a claimed job while renewing its lease, then records the
+// outcome. A job interrupted by shutdown is released rather than failed.
+func (p *pool) run(j structs.Job) {
+	ctx, cancel := context.WithCancel(p.ctx)
+	var lost atomic.Bool
+	beating := make(chan struct{})
+	go func() {
+		defer close(beating)
+		p.heartbeat(ctx, cancel, j, &lost)
+	}()
+
+	start := time.Now()
+	out, err := p.execute(ctx, j)
+	cancel()
+	<-beating
+
+	record, cancelRecord := context.WithTimeout(context.Background(), recordTimeout)
+	defer cancelRecord()
+
+	fields := map[string]interface{}{
+		"job":      j.ID.String(),
+		"kind":     j.Kind,
+		"attempt":  j.Attempts,
+		"duration": time.Since(start).String(),
+	}
+	switch {
+	case lost.Load():
+		logs.DefaultLogger.WithFields(fields).Info("Job lease was lost, leaving it to its new worker")
+		return
+	case err == nil:
+		err = p.service.Job.Finish(record, j.ID, p.id, out.result, out.outputType)
+		if err == nil {
+			logs.DefaultLogger.WithFields(fields).Info("Job succeeded")
+		}
+	case p.ctx.Err() != nil:
+		err = p.service.Job.Release(record, j.ID, p.id)
+	default:
+		logs.DefaultLogger.WithError(err).Error(fmt.Sprintf("Job %s (%s) failed", j.ID, j.Kind))
+		err = p.service.Job.Fail(record, j, p.id, err)
+	}
+	if err != nil {
+		logs.DefaultLogger.WithError(err).Error(fmt.Sprintf("Error recording job %s", j.ID))
+	}
+}
+
+// heartbeat renews the lease on j until ctx ends, cancelling the job when
+// the lease turns out to be lost.
+func (p *pool) heartbeat(ctx context.Context, cancel context.CancelFunc, j structs.Job, lost *atomic.Bool) {
+	ticker := time.NewTicker(p.config.lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := p.service.Job.Renew(ctx, j.ID, p.id, p.config.lease)
+		if errors.Is(err, job.ErrLeaseLost) {
+			lost.Store(true)
+			cancel()
+			return
+		}
+		if err != nil && ctx.Err() == nil {
+			logs.DefaultLogger.WithError(err).Error(fmt.Sprintf("Error renewing lease of job %s", j.ID))
+		}
+	}
+}
+
+// execute runs the handler of the job's kind, turning a panic into a
+// failed attempt.
+func (p *pool) execute(ctx context.Context, j structs.Job) (out outcome, err error) {
+	k, ok := kinds[j.Kind]
+	if !ok {
+		return out, job.Permanent(fmt.Errorf("unknown job kind %q", j.Kind))
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	open := func() io.WriteCloser { return p.service.Job.Output(ctx, j.ID, p.id) }
+	return k.run(ctx, p.service, open, j.Payload)
+}
@@ -0,0 +1,105 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+)
+
+// fakeJobs hands out one job and keeps how it ended.
+type fakeJobs struct {
+	service.Job
+	mu       sync.Mutex
+	job      *structs.Job
+	released []uuid.UUID
+	finished []uuid.UUID
+}
+
+func (f *fakeJobs) Claim(context.Context, string, int, time.Duration) ([]structs.Job, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.job == nil {
+		return nil, nil
+	}
+	j := *f.job
+	f.job = nil
+	return []structs.Job{j}, nil
+}
+
+func (f *fakeJobs) Renew(context.Context, uuid.UUID, string, time.Duration) error { return nil }
+
+func (f *fakeJobs) Release(_ context.Context, id uuid.UUID, _ string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.released = append(f.released, id)
+	return nil
+}
+
+func (f *fakeJobs) Finish(_ context.Context, id uuid.UUID, _ string, _ interface{}, _ string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.finished = append(f.finished, id)
+	return nil
+}
+
+func testPool(t *testing.T, run func(ctx context.Context) error) (*fakeJobs, *pool, uuid.UUID) {
+	t.Helper()
+	const name = "test.run"
+	kinds[name] = kind{run: func(ctx context.Context, _ *service.Service, _ output, _ json.RawMessage) (outcome, error) {
+		return outcome{}, run(ctx)
+	}}
+	t.Cleanup(func() { delete(kinds, name) })
+
+	j := structs.Job{ID: uuid.New(), Kind: name}
+	jobs := &fakeJobs{job: &j}
+	p := New(NewConfig(1, 10*time.Millisecond, time.Minute), &service.Service{Job: jobs}).(*pool)
+	return jobs, p, j.ID
+}
+
+func TestShutdownWaitsForRunningJobs(t *testing.T) {
+	started := make(chan struct{})
+	jobs, p, id := testPool(t, func(context.Context) error {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	})
+	go p.Run()
+	<-started
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown = %v", err)
+	}
+	if len(jobs.finished) != 1 || jobs.finished[0] != id || len(jobs.released) != 0 {
+		t.Errorf("finished %v, released %v, want the job finished", jobs.finished, jobs.released)
+	}
+}
+
+func TestShutdownReleasesCancelledJobs(t *testing.T) {
+	started := make(chan struct{})
+	jobs, p, id := testPool(t, func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	go p.Run()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown = %v, want %v", err, context.DeadlineExceeded)
+	}
+	// Shutdown returns once the cancelled job was given back.
+	jobs.mu.Lock()
+	defer jobs.mu.Unlock()
+	if len(jobs.released) != 1 || jobs.released[0] != id || len(jobs.finished) != 0 {
+		t.Errorf("released %v, finished %v, want the job released", jobs.released, jobs.finished)
+	}
+}
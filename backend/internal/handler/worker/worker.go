@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/google/uuid"
+)
+
+type Config struct {
+	workers  int
+	interval time.Duration
+	lease    time.Duration
+}
+
+// NewConfig configures the job workers: how many jobs run at once, how
+// often an idle worker looks at the queue and how long a claimed job stays
+// reserved without a heartbeat. Zero workers disables them.
+func NewConfig(
+	workers int,
+	interval time.Duration,
+	lease time.Duration,
+) Config {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	if lease <= 0 {
+		lease = time.Minute
+	}
+	return Config{
+		workers:  workers,
+		interval: interval,
+		lease:    lease,
+	}
+}
+
+type Worker interface {
+	Run() error
+	Shutdown(ctx context.Context) error
+}
+
+func New(config Config, s *service.Service) Worker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &pool{
+		config:  config,
+		service: s,
+		id:      workerID(),
+		ctx:     ctx,
+		cancel:  cancel,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// workerID names this process in the leases it takes.
+func workerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.NewString()[:8])
+}
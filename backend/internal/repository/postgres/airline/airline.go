@@ -5,19 +5,22 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/bulk"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/history"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/trash"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"strings"
 )
 
 type AirlineRepository struct {
-	db *pgxpool.Pool
+	db   *pgxpool.Pool
+	bulk *bulk.Writer
 }
 
-func NewRepositoryAirline(db *pgxpool.Pool) *AirlineRepository {
-	return &AirlineRepository{db: db}
+func NewRepositoryAirline(db *pgxpool.Pool, bulkConfig bulk.Config) *AirlineRepository {
+	return &AirlineRepository{db: db, bulk: bulk.NewWriter(db, bulkConfig)}
 }
 
 // Tax
@@ -53,19 +56,17 @@ func (r *AirlineRepository) CreateTax(ctx context.Context, t *structs.Tax) error
 	return nil
 }
 
-func (q *AirlineRepository) GetTaxs(ctx context.Context) ([]structs.Tax, error) {
-	var tax []structs.Tax
-
+func (q *AirlineRepository) StreamTaxes(ctx context.Context, fn func(structs.Tax) error) error {
 	tx, err := q.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer tx.Rollback(ctx)
 
 	// Send query to database.
-	rows, err := tx.Query(ctx, `SELECT id, tax_id, tax_name, iata_code, created_at, updated_at FROM tax ORDER BY id`)
+	rows, err := tx.Query(ctx, `SELECT id, tax_id, tax_name, iata_code, created_at, updated_at, deleted_at FROM tax WHERE `+trash.Live(ctx, "deleted_at")+` ORDER BY id`)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
@@ -77,23 +78,39 @@ func (q *AirlineRepository) GetTaxs(ctx context.Context) ([]structs.Tax, error)
 			&t.TaxName,
 			&t.IataCode,
 			&t.CreatedAt,
-			&t.UpdatedAt)
+			&t.UpdatedAt,
+			&t.DeletedAt)
 
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if err := fn(t); err != nil {
+			return err
 		}
-		tax = append(tax, t)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (q *AirlineRepository) GetTaxs(ctx context.Context) ([]structs.Tax, error) {
+	var taxes []structs.Tax
+	err := q.StreamTaxes(ctx, func(tax structs.Tax) error {
+		taxes = append(taxes, tax)
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	return tax, nil
+	return taxes, nil
 }
 
 func (q *AirlineRepository) GetTax(ctx context.Context, id uuid.UUID) (structs.Tax, error) {
@@ -105,20 +122,22 @@ func (q *AirlineRepository) GetTax(ctx context.Context, id uuid.UUID) (structs.T
 	}
 	defer tx.Rollback(ctx)
 
-	_ = tx.QueryRow(ctx, `SELECT
+	err = tx.QueryRow(ctx, `SELECT
 			id,
 			tax_id,
 			tax_name,
 			iata_code,
 			created_at,
-			updated_at
-		FROM tax
-		WHERE id = $1 LIMIT 1`, id).Scan(&tax.ID,
+			updated_at,
+			deleted_at
+		FROM `+history.Source(ctx, "tax")+`
+		WHERE id = $1 AND `+trash.Live(ctx, "deleted_at")+` LIMIT 1`, id).Scan(&tax.ID,
 		&tax.TaxId,
 		&tax.TaxName,
 		&tax.IataCode,
 		&tax.CreatedAt,
-		&tax.UpdatedAt)
+		&tax.UpdatedAt,
+		&tax.DeletedAt)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -134,54 +153,6 @@ func (q *AirlineRepository) GetTax(ctx context.Context, id uuid.UUID) (structs.T
 	return tax, nil
 }
 
-func (q *AirlineRepository) DeleteTax(ctx context.Context, id uuid.UUID) error {
-	tx, err := q.db.BeginTx(ctx, pgx.TxOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback(ctx)
-
-	_, err = tx.Exec(ctx, "DELETE FROM tax WHERE id = $1", id)
-	if err != nil {
-		return fmt.Errorf("failed to delete airline: %w", err)
-	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	return nil
-}
-
-func (q *AirlineRepository) UpdateTax(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error {
-	tx, err := q.db.BeginTx(ctx, pgx.TxOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback(ctx)
-
-	var setColumns []string
-	var args []interface{}
-
-	for key, value := range updates {
-		setColumns = append(setColumns, fmt.Sprintf("%s = $%d", key, len(args)+1))
-		args = append(args, value)
-	}
-	args = append(args, id)
-
-	stmt := fmt.Sprintf("UPDATE tax SET %s WHERE id = $%d", strings.Join(setColumns, ", "), len(args))
-	_, err = tx.Exec(ctx, stmt, args...)
-	if err != nil {
-		return fmt.Errorf("failed to update airline: %w", err)
-	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	return nil
-}
-
 func (q *AirlineRepository) GetTaxesCount(ctx context.Context) (int, error) {
 	tx, err := q.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
 	if err != nil {
@@ -190,7 +161,7 @@ func (q *AirlineRepository) GetTaxesCount(ctx context.Context) (int, error) {
 	defer tx.Rollback(ctx)
 
 	var count int
-	err = tx.QueryRow(ctx, "SELECT COUNT(*) FROM tax").Scan(&count)
+	err = tx.QueryRow(ctx, "SELECT COUNT(*) FROM tax WHERE deleted_at IS NULL").Scan(&count)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return 0, fmt.Errorf("no airline found")
@@ -243,19 +214,17 @@ func (r *AirlineRepository) CreateAircraft(ctx context.Context, a *structs.Aircr
 	return nil
 }
 
-func (r *AirlineRepository) GetAircrafts(ctx context.Context) ([]structs.Aircraft, error) {
-	var aircraftTypes []structs.Aircraft
-
+func (r *AirlineRepository) StreamAircrafts(ctx context.Context, fn func(structs.Aircraft) error) error {
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer tx.Rollback(ctx)
 
 	// Send query to database.
-	rows, err := tx.Query(ctx, `SELECT id, iata_code, aircraft_name, plane_type_id, created_at, updated_at FROM aircraft ORDER BY iata_code`)
+	rows, err := tx.Query(ctx, `SELECT id, iata_code, aircraft_name, plane_type_id, created_at, updated_at, deleted_at FROM aircraft WHERE `+trash.Live(ctx, "deleted_at")+` ORDER BY iata_code`)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
@@ -267,23 +236,39 @@ func (r *AirlineRepository) GetAircrafts(ctx context.Context) ([]structs.Aircraf
 			&aircraft.AircraftName,
 			&aircraft.PlaneTypeId,
 			&aircraft.CreatedAt,
-			&aircraft.UpdatedAt)
+			&aircraft.UpdatedAt,
+			&aircraft.DeletedAt)
 
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if err := fn(aircraft); err != nil {
+			return err
 		}
-		aircraftTypes = append(aircraftTypes, aircraft)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *AirlineRepository) GetAircrafts(ctx context.Context) ([]structs.Aircraft, error) {
+	var aircrafts []structs.Aircraft
+	err := r.StreamAircrafts(ctx, func(aircraft structs.Aircraft) error {
+		aircrafts = append(aircrafts, aircraft)
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	return aircraftTypes, nil
+	return aircrafts, nil
 }
 
 func (r *AirlineRepository) GetAircraft(ctx context.Context, id uuid.UUID) (structs.Aircraft, error) {
@@ -301,9 +286,10 @@ func (r *AirlineRepository) GetAircraft(ctx context.Context, id uuid.UUID) (stru
 			aircraft_name,
 			plane_type_id,
 			created_at,
-			updated_at
-		FROM aircraft
-		WHERE id = $1 LIMIT 1 `, id)
+			updated_at,
+			deleted_at
+		FROM `+history.Source(ctx, "aircraft")+`
+		WHERE id = $1 AND `+trash.Live(ctx, "deleted_at")+` LIMIT 1`, id)
 	err = row.Scan(
 		&aircraft.ID,
 		&aircraft.IataCode,
@@ -312,6 +298,7 @@ func (r *AirlineRepository) GetAircraft(ctx context.Context, id uuid.UUID) (stru
 		&aircraft.PlaneTypeId,
 		&aircraft.CreatedAt,
 		&aircraft.UpdatedAt,
+		&aircraft.DeletedAt,
 	)
 
 	if err != nil {
@@ -328,54 +315,6 @@ func (r *AirlineRepository) GetAircraft(ctx context.Context, id uuid.UUID) (stru
 	return aircraft, nil
 }
 
-func (r *AirlineRepository) DeleteAircraft(ctx context.Context, id uuid.UUID) error {
-	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback(ctx)
-
-	_, err = tx.Exec(ctx, "DELETE FROM aircraft WHERE id = $1", id)
-	if err != nil {
-		return fmt.Errorf("failed to delete aircraft: %w", err)
-	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	return nil
-}
-
-func (r *AirlineRepository) UpdateAircraft(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error {
-	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback(ctx)
-
-	var setColumns []string
-	var args []interface{}
-
-	for key, value := range updates {
-		setColumns = append(setColumns, fmt.Sprintf("%s = $%d", key, len(args)+1))
-		args = append(args, value)
-	}
-	args = append(args, id)
-
-	stmt := fmt.Sprintf("UPDATE aircraft SET %s WHERE id = $%d", strings.Join(setColumns, ", "), len(args))
-	_, err = tx.Exec(ctx, stmt, args...)
-	if err != nil {
-		return fmt.Errorf("failed to update aircraft: %w", err)
-	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	return nil
-}
-
 func (r *AirlineRepository) GetAircraftCount(ctx context.Context) (int, error) {
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
 	if err != nil {
@@ -384,7 +323,7 @@ func (r *AirlineRepository) GetAircraftCount(ctx context.Context) (int, error) {
 	defer tx.Rollback(ctx)
 
 	var count int
-	err = tx.QueryRow(ctx, "SELECT COUNT(*) FROM aircraft").Scan(&count)
+	err = tx.QueryRow(ctx, "SELECT COUNT(*) FROM aircraft WHERE deleted_at IS NULL").Scan(&count)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return 0, fmt.Errorf("no aircraft found")
@@ -444,19 +383,21 @@ func (r *AirlineRepository) CreateAirline(ctx context.Context, a *structs.Airlin
 	return nil
 }
 
-func (r *AirlineRepository) GetAirlines(ctx context.Context) ([]structs.Airline, error) {
-	var airlines []structs.Airline
-
+func (r *AirlineRepository) StreamAirlines(ctx context.Context, fn func(structs.Airline) error) error {
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer tx.Rollback(ctx)
 
 	// Send query to database.
-	rows, err := tx.Query(ctx, `SELECT * FROM airline ORDER BY airline_id`)
+	rows, err := tx.Query(ctx, `SELECT
+			id, fleet_average_age, airline_id, call_sign, hub_code, iata_code, icao_code,
+			country_iso_2, data_founded, iata_prefix_accounting, airline_name, country_name,
+			fleet_size, status, type, country_ref, created_at, updated_at, deleted_at
+		FROM airline WHERE `+trash.Live(ctx, "deleted_at")+` ORDER BY airline_id`)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
@@ -478,20 +419,37 @@ func (r *AirlineRepository) GetAirlines(ctx context.Context) ([]structs.Airline,
 			&airline.FleetSize,
 			&airline.Status,
 			&airline.Type,
+			&airline.CountryRef,
 			&airline.CreatedAt,
-			&airline.UpdatedAt)
+			&airline.UpdatedAt,
+			&airline.DeletedAt)
 
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if err := fn(airline); err != nil {
+			return err
 		}
-		airlines = append(airlines, airline)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *AirlineRepository) GetAirlines(ctx context.Context) ([]structs.Airline, error) {
+	var airlines []structs.Airline
+	err := r.StreamAirlines(ctx, func(airline structs.Airline) error {
+		airlines = append(airlines, airline)
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -523,10 +481,12 @@ func (r *AirlineRepository) GetAirline(ctx context.Context, id uuid.UUID) (struc
 			fleet_size,
 			status,
 			type,
+			country_ref,
 			created_at,
-			updated_at
-		FROM airline
-		WHERE id = $1 LIMIT 1`, id)
+			updated_at,
+			deleted_at
+		FROM `+history.Source(ctx, "airline")+`
+		WHERE id = $1 AND `+trash.Live(ctx, "deleted_at")+` LIMIT 1`, id)
 	err = row.Scan(
 		&airline.ID,
 		&airline.FleetAverageAge,
@@ -543,8 +503,10 @@ func (r *AirlineRepository) GetAirline(ctx context.Context, id uuid.UUID) (struc
 		&airline.FleetSize,
 		&airline.Status,
 		&airline.Type,
+		&airline.CountryRef,
 		&airline.CreatedAt,
-		&airline.UpdatedAt)
+		&airline.UpdatedAt,
+		&airline.DeletedAt)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -560,54 +522,6 @@ func (r *AirlineRepository) GetAirline(ctx context.Context, id uuid.UUID) (struc
 	return airline, nil
 }
 
-func (r *AirlineRepository) DeleteAirline(ctx context.Context, id uuid.UUID) error {
-	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback(ctx)
-
-	_, err = tx.Exec(ctx, "DELETE FROM airline WHERE id = $1", id)
-	if err != nil {
-		return fmt.Errorf("failed to delete aircraft: %w", err)
-	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	return nil
-}
-
-func (r *AirlineRepository) UpdateAirline(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error {
-	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback(ctx)
-
-	var setColumns []string
-	var args []interface{}
-
-	for key, value := range updates {
-		setColumns = append(setColumns, fmt.Sprintf("%s = $%d", key, len(args)+1))
-		args = append(args, value)
-	}
-	args = append(args, id)
-
-	stmt := fmt.Sprintf("UPDATE airline SET %s WHERE id = $%d", strings.Join(setColumns, ", "), len(args))
-	_, err = tx.Exec(ctx, stmt, args...)
-	if err != nil {
-		return fmt.Errorf("failed to update aircraft: %w", err)
-	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	return nil
-}
-
 func (r *AirlineRepository) GetAirlineCount(ctx context.Context) (int, error) {
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
@@ -616,7 +530,7 @@ func (r *AirlineRepository) GetAirlineCount(ctx context.Context) (int, error) {
 	defer tx.Rollback(ctx)
 
 	var count int
-	err = tx.QueryRow(ctx, "SELECT COUNT(*) FROM airline").Scan(&count)
+	err = tx.QueryRow(ctx, "SELECT COUNT(*) FROM airline WHERE deleted_at IS NULL").Scan(&count)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return 0, fmt.Errorf("no airline found")
@@ -631,11 +545,10 @@ func (r *AirlineRepository) GetAirlineCount(ctx context.Context) (int, error) {
 	return count, nil
 }
 
-func (r *AirlineRepository) GetAirlinesCountry(ctx context.Context) ([]structs.AirlineInfo, error) {
-	var airlines []structs.AirlineInfo
+func (r *AirlineRepository) StreamAirlinesCountry(ctx context.Context, fn func(structs.AirlineInfo) error) error {
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
 	if err != nil {
-		return airlines, fmt.Errorf("failed to begin transaction: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
@@ -647,11 +560,12 @@ func (r *AirlineRepository) GetAirlinesCountry(ctx context.Context) ([]structs.A
                c.id, c.population, c.country_name, c.capital, c.currency_name, c.currency_code, c.continent,
                c.phone_prefix, a.created_at, a.updated_at
         FROM airline a
-        INNER JOIN city ct ON ct.country_iso2 = a.country_iso_2
-        INNER JOIN country c   ON a.country_iso_2 = c.country_iso_2
+        INNER JOIN city ct ON ct.country_ref = a.country_ref
+        INNER JOIN country c   ON a.country_ref = c.id
+        WHERE a.deleted_at IS NULL
         ORDER BY a.airline_id`)
 	if err != nil {
-		return airlines, fmt.Errorf("failed to execute query: %w", err)
+		return fmt.Errorf("failed to execute query: %w", err)
 	}
 	defer rows.Close()
 
@@ -667,16 +581,31 @@ func (r *AirlineRepository) GetAirlinesCountry(ctx context.Context) ([]structs.A
 			&airlineInfo.CreatedAt, &airlineInfo.UpdatedAt)
 
 		if err != nil {
-			return airlines, fmt.Errorf("failed to scan airline info: %w", err)
+			return fmt.Errorf("failed to scan airline info: %w", err)
+		}
+		if err := fn(airlineInfo); err != nil {
+			return err
 		}
-		airlines = append(airlines, airlineInfo)
 	}
 	if err := rows.Err(); err != nil {
-		return airlines, fmt.Errorf("failed to iterate over results: %w", err)
+		return fmt.Errorf("failed to iterate over results: %w", err)
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return airlines, fmt.Errorf("failed to commit transaction: %w", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AirlineRepository) GetAirlinesCountry(ctx context.Context) ([]structs.AirlineInfo, error) {
+	var airlines []structs.AirlineInfo
+	err := r.StreamAirlinesCountry(ctx, func(airline structs.AirlineInfo) error {
+		airlines = append(airlines, airline)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return airlines, nil
@@ -698,9 +627,9 @@ func (r *AirlineRepository) GetAirlineCountry(ctx context.Context, id int) ([]st
                c.id as country_id, c.population, c.country_name, c.capital, c.currency_name, c.currency_code, c.continent,
                c.phone_prefix, a.created_at, a.updated_at
         FROM airline a
-        INNER JOIN city ct ON ct.country_iso2 = a.country_iso_2
-        INNER JOIN country c   ON a.country_iso_2 = c.country_iso_2
-        WHERE a.airline_id = $1
+        INNER JOIN city ct ON ct.country_ref = a.country_ref
+        INNER JOIN country c   ON a.country_ref = c.id
+        WHERE a.airline_id = $1 AND a.deleted_at IS NULL
         ORDER BY a.airline_id`, id)
 	if err != nil {
 		return airlines, fmt.Errorf("failed to execute query: %w", err)
@@ -749,9 +678,9 @@ func (r *AirlineRepository) GetAirlineCountryName(ctx context.Context, countryNa
                c.id, c.population, c.country_name, c.capital, c.currency_name, c.currency_code, c.continent,
                c.phone_prefix, a.created_at, a.updated_at
         FROM airline a
-        INNER JOIN city ct ON ct.country_iso2 = a.country_iso_2
-        INNER JOIN country c   ON a.country_iso_2 = c.country_iso_2
-        WHERE c.country_name = $1
+        INNER JOIN city ct ON ct.country_ref = a.country_ref
+        INNER JOIN country c   ON a.country_ref = c.id
+        WHERE c.country_name = $1 AND a.deleted_at IS NULL
         ORDER BY a.airline_id`, countryName)
 	if err != nil {
 		return airlines, fmt.Errorf("failed to execute query: %w", err)
@@ -801,9 +730,9 @@ func (r *AirlineRepository) GetAirlineCityName(ctx context.Context, cityName str
                c.id, c.population, c.country_name, c.capital, c.currency_name, c.currency_code, c.continent,
                c.phone_prefix, a.created_at, a.updated_at
         FROM airline a
-        INNER JOIN city ct ON ct.country_iso2 = a.country_iso_2
-        INNER JOIN country c   ON a.country_iso_2 = c.country_iso_2
-        WHERE ct.city_name = $1
+        INNER JOIN city ct ON ct.country_ref = a.country_ref
+        INNER JOIN country c   ON a.country_ref = c.id
+        WHERE ct.city_name = $1 AND a.deleted_at IS NULL
         ORDER BY a.airline_id`, cityName)
 	if err != nil {
 		return airlines, fmt.Errorf("failed to execute query: %w", err)
@@ -853,9 +782,9 @@ func (r *AirlineRepository) GetAirlineCountryCityName(ctx context.Context, count
                c.id, c.population, c.country_name, c.capital, c.currency_name, c.currency_code, c.continent,
                c.phone_prefix, a.created_at, a.updated_at
         FROM airline a
-        INNER JOIN city ct ON ct.country_iso2 = a.country_iso_2
-        INNER JOIN country c   ON a.country_iso_2 = c.country_iso_2
-        WHERE c.country_name = $1 AND ct.city_name = $2
+        INNER JOIN city ct ON ct.country_ref = a.country_ref
+        INNER JOIN country c   ON a.country_ref = c.id
+        WHERE c.country_name = $1 AND ct.city_name = $2 AND a.deleted_at IS NULL
         ORDER BY a.airline_id`, countryName, cityName)
 	if err != nil {
 		return airlines, fmt.Errorf("failed to execute query: %w", err)
@@ -947,19 +876,24 @@ func (r *AirlineRepository) CreateAirplane(ctx context.Context, a *structs.Airpl
 	return nil
 }
 
-func (r *AirlineRepository) GetAirplanes(ctx context.Context) ([]structs.Airplane, error) {
-	var airplanes []structs.Airplane
-
+func (r *AirlineRepository) StreamAirplanes(ctx context.Context, fn func(structs.Airplane) error) error {
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer tx.Rollback(ctx)
 
 	// Send query to database.
-	rows, err := tx.Query(ctx, `SELECT * FROM airplane ORDER BY airplane_id`)
+	rows, err := tx.Query(ctx, `SELECT
+			id, iata_type, airplane_id, airline_iata_code, iata_code_long,
+			iata_code_short, airline_icao_code, construction_number, delivery_date, engines_count,
+			engines_type, first_flight_date, icao_code_hex, line_number, model_code,
+			registration_number, test_registration_number, plane_age, plane_class,
+			model_name, plane_owner, plane_series, plane_status, production_line,
+			registration_date, rollout_date, airline_ref, created_at, updated_at, deleted_at
+		FROM airplane WHERE `+trash.Live(ctx, "deleted_at")+` ORDER BY airplane_id`)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
@@ -992,20 +926,37 @@ func (r *AirlineRepository) GetAirplanes(ctx context.Context) ([]structs.Airplan
 			&airplane.ProductionLine,
 			&airplane.RegistrationDate,
 			&airplane.RolloutDate,
+			&airplane.AirlineRef,
 			&airplane.CreatedAt,
-			&airplane.UpdatedAt)
+			&airplane.UpdatedAt,
+			&airplane.DeletedAt)
 
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if err := fn(airplane); err != nil {
+			return err
 		}
-		airplanes = append(airplanes, airplane)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *AirlineRepository) GetAirplanes(ctx context.Context) ([]structs.Airplane, error) {
+	var airplanes []structs.Airplane
+	err := r.StreamAirplanes(ctx, func(airplane structs.Airplane) error {
+		airplanes = append(airplanes, airplane)
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -1027,9 +978,9 @@ func (r *AirlineRepository) GetAirplane(ctx context.Context, id uuid.UUID) (stru
 			engines_type, first_flight_date, icao_code_hex, line_number, model_code,
 			registration_number, test_registration_number, plane_age, plane_class,
 			model_name, plane_owner, plane_series, plane_status, production_line,
-			registration_date, rollout_date, created_at, updated_at
-		FROM airplane
-		WHERE id = $1 LIMIT 1`, id)
+			registration_date, rollout_date, airline_ref, created_at, updated_at, deleted_at
+		FROM `+history.Source(ctx, "airplane")+`
+		WHERE id = $1 AND `+trash.Live(ctx, "deleted_at")+` LIMIT 1`, id)
 	err = row.Scan(
 		&airplane.ID, &airplane.IataType, &airplane.AirplaneId, &airplane.AirlineIataCode,
 		&airplane.IataCodeLong, &airplane.IataCodeShort, &airplane.AirlineIcaoCode,
@@ -1037,8 +988,8 @@ func (r *AirlineRepository) GetAirplane(ctx context.Context, id uuid.UUID) (stru
 		&airplane.FirstFlightDate, &airplane.IcaoCodeHex, &airplane.LineNumber, &airplane.ModelCode,
 		&airplane.RegistrationNumber, &airplane.TestRegistrationNumber, &airplane.PlaneAge, &airplane.PlaneClass,
 		&airplane.ModelName, &airplane.PlaneOwner, &airplane.PlaneSeries, &airplane.PlaneStatus,
-		&airplane.ProductionLine, &airplane.RegistrationDate, &airplane.RolloutDate, &airplane.CreatedAt,
-		&airplane.UpdatedAt)
+		&airplane.ProductionLine, &airplane.RegistrationDate, &airplane.RolloutDate, &airplane.AirlineRef,
+		&airplane.CreatedAt, &airplane.UpdatedAt, &airplane.DeletedAt)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -1054,54 +1005,6 @@ func (r *AirlineRepository) GetAirplane(ctx context.Context, id uuid.UUID) (stru
 	return airplane, nil
 }
 
-func (r *AirlineRepository) DeleteAirplane(ctx context.Context, id uuid.UUID) error {
-	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback(ctx)
-
-	_, err = tx.Exec(ctx, "DELETE FROM airplane WHERE id = $1", id)
-	if err != nil {
-		return fmt.Errorf("failed to delete airplane: %w", err)
-	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	return nil
-}
-
-func (r *AirlineRepository) UpdateAirplane(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error {
-	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback(ctx)
-
-	var setColumns []string
-	var args []interface{}
-
-	for key, value := range updates {
-		setColumns = append(setColumns, fmt.Sprintf("%s = $%d", key, len(args)+1))
-		args = append(args, value)
-	}
-	args = append(args, id)
-
-	stmt := fmt.Sprintf("UPDATE airplane SET %s WHERE id = $%d", strings.Join(setColumns, ", "), len(args))
-	_, err = tx.Exec(ctx, stmt, args...)
-	if err != nil {
-		return fmt.Errorf("failed to update airplane: %w", err)
-	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	return nil
-}
-
 func (r *AirlineRepository) GetAirplaneCount(ctx context.Context) (int, error) {
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
@@ -1110,7 +1013,7 @@ func (r *AirlineRepository) GetAirplaneCount(ctx context.Context) (int, error) {
 	defer tx.Rollback(ctx)
 
 	var count int
-	err = tx.QueryRow(ctx, "SELECT COUNT(*) FROM airplane").Scan(&count)
+	err = tx.QueryRow(ctx, "SELECT COUNT(*) FROM airplane WHERE deleted_at IS NULL").Scan(&count)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return 0, fmt.Errorf("no airline found")
@@ -1125,24 +1028,30 @@ func (r *AirlineRepository) GetAirplaneCount(ctx context.Context) (int, error) {
 	return count, nil
 }
 
-func (r *AirlineRepository) GetAirplaneAirline(ctx context.Context) ([]structs.AirplaneInfo, error) {
-	var airplanesInfo []structs.AirplaneInfo
+func (r *AirlineRepository) StreamAirplaneAirline(ctx context.Context, fn func(structs.AirplaneInfo) error) error {
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
 	if err != nil {
-		return airplanesInfo, fmt.Errorf("failed to begin transaction: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
 	rows, err := tx.Query(ctx, `
-        SELECT ap.*,
-					al.airline_name, al.country_name,
-              	 	al.country_iso_2, al.fleet_size, al.status,
-               		al.type, al.hub_code, al.call_sign
+        SELECT ap.id, ap.iata_type, ap.airplane_id, ap.airline_iata_code, ap.iata_code_long,
+               ap.iata_code_short, ap.airline_icao_code, ap.construction_number, ap.delivery_date,
+               ap.engines_count, ap.engines_type, ap.first_flight_date, ap.icao_code_hex,
+               ap.line_number, ap.model_code, ap.registration_number, ap.test_registration_number,
+               ap.plane_age, ap.plane_class, ap.model_name, ap.plane_owner, ap.plane_series,
+               ap.plane_status, ap.production_line, ap.registration_date, ap.rollout_date,
+               ap.created_at, ap.updated_at,
+               al.airline_name, al.country_name,
+               al.country_iso_2, al.fleet_size, al.status,
+               al.type, al.hub_code, al.call_sign
         FROM airplane ap
-        INNER JOIN airline al ON ap.airline_iata_code = al.iata_code
+        INNER JOIN airline al ON ap.airline_ref = al.id
+        WHERE ap.deleted_at IS NULL
         ORDER BY airplane_id`)
 	if err != nil {
-		return airplanesInfo, fmt.Errorf("failed to execute query: %w", err)
+		return fmt.Errorf("failed to execute query: %w", err)
 	}
 	defer rows.Close()
 
@@ -1161,16 +1070,31 @@ func (r *AirlineRepository) GetAirplaneAirline(ctx context.Context) ([]structs.A
 			&airplaneInfo.UpdatedAt, &airplaneInfo.AirlineName, &airplaneInfo.CountryName, &airplaneInfo.CountryIso2,
 			&airplaneInfo.FleetSize, &airplaneInfo.Status, &airplaneInfo.Type, &airplaneInfo.HubCode, &airplaneInfo.CallSign)
 		if err != nil {
-			return airplanesInfo, fmt.Errorf("failed to scan airplanes info: %w", err)
+			return fmt.Errorf("failed to scan airplanes info: %w", err)
+		}
+		if err := fn(airplaneInfo); err != nil {
+			return err
 		}
-		airplanesInfo = append(airplanesInfo, airplaneInfo)
 	}
 	if err := rows.Err(); err != nil {
-		return airplanesInfo, fmt.Errorf("failed to iterate over results: %w", err)
+		return fmt.Errorf("failed to iterate over results: %w", err)
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return airplanesInfo, fmt.Errorf("failed to commit transaction: %w", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AirlineRepository) GetAirplaneAirline(ctx context.Context) ([]structs.AirplaneInfo, error) {
+	var airplanesInfo []structs.AirplaneInfo
+	err := r.StreamAirplaneAirline(ctx, func(airplaneInfo structs.AirplaneInfo) error {
+		airplanesInfo = append(airplanesInfo, airplaneInfo)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return airplanesInfo, nil
@@ -1185,12 +1109,19 @@ func (r *AirlineRepository) GetAirplanesFromAirlineName(ctx context.Context, air
 	defer tx.Rollback(ctx)
 
 	rows, err := tx.Query(ctx, `
-        SELECT ap.*, al.airline_name, al.country_name,
+        SELECT ap.id, ap.iata_type, ap.airplane_id, ap.airline_iata_code, ap.iata_code_long,
+               ap.iata_code_short, ap.airline_icao_code, ap.construction_number, ap.delivery_date,
+               ap.engines_count, ap.engines_type, ap.first_flight_date, ap.icao_code_hex,
+               ap.line_number, ap.model_code, ap.registration_number, ap.test_registration_number,
+               ap.plane_age, ap.plane_class, ap.model_name, ap.plane_owner, ap.plane_series,
+               ap.plane_status, ap.production_line, ap.registration_date, ap.rollout_date,
+               ap.created_at, ap.updated_at,
+               al.airline_name, al.country_name,
                al.country_iso_2, al.fleet_size, al.status,
                al.type, al.hub_code, al.call_sign
         FROM airplane ap
-        INNER JOIN airline al ON ap.airline_iata_code = al.iata_code
-        WHERE al.airline_name = $1
+        INNER JOIN airline al ON ap.airline_ref = al.id
+        WHERE al.airline_name = $1 AND ap.deleted_at IS NULL
         ORDER BY ap.airplane_id`, airlineName)
 	if err != nil {
 		return airplanesInfo, fmt.Errorf("failed to execute query: %w", err)
@@ -1236,13 +1167,19 @@ func (r *AirlineRepository) GetAirplanesFromAirlineCountry(ctx context.Context,
 	defer tx.Rollback(ctx)
 
 	rows, err := tx.Query(ctx, `
-        SELECT ap.*,
-					al.airline_name, al.country_name,
-              	 	al.country_iso_2, al.fleet_size, al.status,
-               		al.type, al.hub_code, al.call_sign
+        SELECT ap.id, ap.iata_type, ap.airplane_id, ap.airline_iata_code, ap.iata_code_long,
+               ap.iata_code_short, ap.airline_icao_code, ap.construction_number, ap.delivery_date,
+               ap.engines_count, ap.engines_type, ap.first_flight_date, ap.icao_code_hex,
+               ap.line_number, ap.model_code, ap.registration_number, ap.test_registration_number,
+               ap.plane_age, ap.plane_class, ap.model_name, ap.plane_owner, ap.plane_series,
+               ap.plane_status, ap.production_line, ap.registration_date, ap.rollout_date,
+               ap.created_at, ap.updated_at,
+               al.airline_name, al.country_name,
+               al.country_iso_2, al.fleet_size, al.status,
+               al.type, al.hub_code, al.call_sign
         FROM airplane ap
-        INNER JOIN airline al ON ap.airline_iata_code = al.iata_code
-        WHERE al.country_name = $1
+        INNER JOIN airline al ON ap.airline_ref = al.id
+        WHERE al.country_name = $1 AND ap.deleted_at IS NULL
         ORDER BY ap.airplane_id`, countryName)
 	if err != nil {
 		return airplanesInfo, fmt.Errorf("failed to execute query: %w", err)
@@ -1278,3 +1215,85 @@ func (r *AirlineRepository) GetAirplanesFromAirlineCountry(ctx context.Context,
 
 	return airplanesInfo, nil
 }
+
+// GetAirplanesByRegistration returns the type codes of the airplanes with
+// the given registrations, one airplane per registration.
+func (r *AirlineRepository) GetAirplanesByRegistration(ctx context.Context, registrations []string) ([]structs.Airplane, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT DISTINCT ON (UPPER(registration_number)) id, UPPER(registration_number),
+		       COALESCE(iata_type, ''), COALESCE(iata_code_short, ''),
+		       COALESCE(iata_code_long, ''), COALESCE(model_code, '')
+		FROM airplane
+		WHERE UPPER(registration_number) = ANY($1) AND deleted_at IS NULL
+		ORDER BY UPPER(registration_number), created_at DESC`, registrations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	var airplanes []structs.Airplane
+	for rows.Next() {
+		var a structs.Airplane
+		err := rows.Scan(&a.ID, &a.RegistrationNumber, &a.IataType,
+			&a.IataCodeShort, &a.IataCodeLong, &a.ModelCode)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan airplane: %w", err)
+		}
+		airplanes = append(airplanes, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over results: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return airplanes, nil
+}
+
+// GetAirlinesByCode returns the airlines whose IATA or ICAO code is one of
+// codes. Codes are reused by defunct carriers, so active airlines come first.
+func (r *AirlineRepository) GetAirlinesByCode(ctx context.Context, codes []string) ([]structs.Airline, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, UPPER(COALESCE(iata_code, '')), UPPER(COALESCE(icao_code, '')),
+		       COALESCE(airline_name, ''), COALESCE(country_name, ''), COALESCE(status, '')
+		FROM airline
+		WHERE (UPPER(iata_code) = ANY($1) OR UPPER(icao_code) = ANY($1)) AND deleted_at IS NULL
+		ORDER BY COALESCE(status, '') = 'active' DESC, created_at DESC`, codes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	var airlines []structs.Airline
+	for rows.Next() {
+		var a structs.Airline
+		err := rows.Scan(&a.ID, &a.IataCode, &a.IcaoCode, &a.AirlineName, &a.CountryName, &a.Status)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan airline: %w", err)
+		}
+		airlines = append(airlines, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over results: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return airlines, nil
+}
@@ -0,0 +1,109 @@
+package airline
+
+import (
+	"context"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/bulk"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+var (
+	taxTable = bulk.Table{
+		Name:    "tax",
+		Key:     "tax_id",
+		Columns: []string{"id", "tax_id", "tax_name", "iata_code", "created_at"},
+	}
+	aircraftTable = bulk.Table{
+		Name:    "aircraft",
+		Key:     "plane_type_id",
+		Columns: []string{"id", "iata_code", "aircraft_name", "plane_type_id", "created_at"},
+	}
+	airlineTable = bulk.Table{
+		Name: "airline",
+		Key:  "airline_id",
+		Columns: []string{"id", "fleet_average_age", "airline_id", "call_sign", "hub_code", "iata_code",
+			"icao_code", "country_iso_2", "data_founded", "iata_prefix_accounting", "airline_name",
+			"country_name", "fleet_size", "status", "type", "created_at"},
+	}
+	airplaneTable = bulk.Table{
+		Name: "airplane",
+		Key:  "airplane_id",
+		Columns: []string{"id", "iata_type", "airplane_id", "airline_iata_code", "iata_code_long",
+			"iata_code_short", "airline_icao_code", "construction_number", "delivery_date", "engines_count",
+			"engines_type", "first_flight_date", "icao_code_hex", "line_number", "model_code",
+			"registration_number", "test_registration_number", "plane_age", "plane_class", "model_name",
+			"plane_owner", "plane_series", "plane_status", "production_line", "registration_date",
+			"rollout_date", "created_at"},
+	}
+)
+
+func (r *AirlineRepository) CreateTaxes(ctx context.Context, taxes []structs.Tax) (structs.BatchResult, error) {
+	return r.bulk.Write(ctx, taxTable, taxRows(taxes))
+}
+
+func (r *AirlineRepository) DiffTaxes(ctx context.Context, taxes []structs.Tax) (structs.IngestionDiff, error) {
+	return r.bulk.Diff(ctx, taxTable, taxRows(taxes))
+}
+
+func taxRows(taxes []structs.Tax) [][]any {
+	rows := make([][]any, len(taxes))
+	for i, t := range taxes {
+		rows[i] = []any{t.ID, t.TaxId, t.TaxName, t.IataCode, t.CreatedAt.Time}
+	}
+	return rows
+}
+
+func (r *AirlineRepository) CreateAircrafts(ctx context.Context, aircrafts []structs.Aircraft) (structs.BatchResult, error) {
+	return r.bulk.Write(ctx, aircraftTable, aircraftRows(aircrafts))
+}
+
+func (r *AirlineRepository) DiffAircrafts(ctx context.Context, aircrafts []structs.Aircraft) (structs.IngestionDiff, error) {
+	return r.bulk.Diff(ctx, aircraftTable, aircraftRows(aircrafts))
+}
+
+func aircraftRows(aircrafts []structs.Aircraft) [][]any {
+	rows := make([][]any, len(aircrafts))
+	for i, a := range aircrafts {
+		rows[i] = []any{a.ID, a.IataCode, a.AircraftName, a.PlaneTypeId, a.CreatedAt.Time}
+	}
+	return rows
+}
+
+func (r *AirlineRepository) CreateAirlines(ctx context.Context, airlines []structs.Airline) (structs.BatchResult, error) {
+	return r.bulk.Write(ctx, airlineTable, airlineRows(airlines))
+}
+
+func (r *AirlineRepository) DiffAirlines(ctx context.Context, airlines []structs.Airline) (structs.IngestionDiff, error) {
+	return r.bulk.Diff(ctx, airlineTable, airlineRows(airlines))
+}
+
+func airlineRows(airlines []structs.Airline) [][]any {
+	rows := make([][]any, len(airlines))
+	for i, a := range airlines {
+		rows[i] = []any{a.ID, a.FleetAverageAge, a.AirlineId, a.Callsign, a.HubCode, a.IataCode,
+			a.IcaoCode, a.CountryIso2, a.DateFounded, a.IataPrefixAccounting, a.AirlineName,
+			a.CountryName, a.FleetSize, a.Status, a.Type, a.CreatedAt.Time}
+	}
+	return rows
+}
+
+func (r *AirlineRepository) CreateAirplanes(ctx context.Context, airplanes []structs.Airplane) (structs.BatchResult, error) {
+	return r.bulk.Write(ctx, airplaneTable, airplaneRows(airplanes))
+}
+
+func (r *AirlineRepository) DiffAirplanes(ctx context.Context, airplanes []structs.Airplane) (structs.IngestionDiff, error) {
+	return r.bulk.Diff(ctx, airplaneTable, airplaneRows(airplanes))
+}
+
+func airplaneRows(airplanes []structs.Airplane) [][]any {
+	rows := make([][]any, len(airplanes))
+	for i, a := range airplanes {
+		rows[i] = []any{a.ID, a.IataType, a.AirplaneId, a.AirlineIataCode, a.IataCodeLong,
+			a.IataCodeShort, bulk.Text(a.AirlineIcaoCode), a.ConstructionNumber, bulk.Date(a.DeliveryDate), a.EnginesCount,
+			a.EnginesType, bulk.Date(a.FirstFlightDate), a.IcaoCodeHex, bulk.Text(a.LineNumber), a.ModelCode,
+			a.RegistrationNumber, bulk.Text(a.TestRegistrationNumber), a.PlaneAge, bulk.Text(a.PlaneClass), a.ModelName,
+			bulk.Text(a.PlaneOwner), a.PlaneSeries, a.PlaneStatus, a.ProductionLine, bulk.Date(a.RegistrationDate),
+			bulk.Date(a.RolloutDate), a.CreatedAt.Time}
+	}
+	return rows
+}
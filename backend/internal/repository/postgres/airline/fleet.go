@@ -0,0 +1,197 @@
+package airline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// fleetMembers pairs airlines with airplanes through either code. IATA codes
+// are reused by defunct carriers, so a plane can belong to several fleets;
+// the UNION only removes the double match of one airline on both codes.
+// Soft deleted airlines and airplanes are left out.
+const fleetMembers = `
+	SELECT a.id AS airline, ap.id AS airplane
+	FROM airline a JOIN airplane ap ON ap.airline_iata_code = a.iata_code
+	WHERE COALESCE(a.iata_code, '') <> '' AND a.deleted_at IS NULL AND ap.deleted_at IS NULL
+	UNION
+	SELECT a.id, ap.id
+	FROM airline a JOIN airplane ap ON ap.airline_icao_code = a.icao_code
+	WHERE COALESCE(a.icao_code, '') <> '' AND a.deleted_at IS NULL AND ap.deleted_at IS NULL`
+
+// airplaneAge prefers the upstream plane_age and falls back to the first
+// flight; imports store unknown dates as year 1.
+const airplaneAge = `
+	CASE
+		WHEN ap.plane_age > 0 THEN ap.plane_age::FLOAT
+		WHEN ap.first_flight_date > '1900-01-01' THEN ((current_date - ap.first_flight_date::DATE) / 365.25)::FLOAT
+	END`
+
+// fleetAgeBuckets are the lower bounds, in years, of the age distribution.
+var fleetAgeBuckets = []int{0, 5, 10, 15, 20, 25}
+
+var fleetLeaderboardOrder = map[string]string{
+	"observed_fleet": "observed_fleet_size DESC",
+	"reported_fleet": "a.fleet_size DESC",
+	"average_age":    "observed_average_age DESC NULLS LAST",
+	"youngest":       "observed_average_age ASC NULLS LAST",
+}
+
+func (r *AirlineRepository) GetAirlineFleet(ctx context.Context, id uuid.UUID) (structs.AirlineFleet, error) {
+	fleet := structs.AirlineFleet{AirlineID: id}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fleet, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		SELECT COALESCE(airline_name, ''), COALESCE(iata_code, ''), COALESCE(icao_code, ''),
+		       COALESCE(fleet_size, 0), COALESCE(fleet_average_age, 0)
+		FROM airline WHERE id = $1 AND deleted_at IS NULL`, id).Scan(
+		&fleet.AirlineName, &fleet.IataCode, &fleet.IcaoCode,
+		&fleet.ReportedFleetSize, &fleet.ReportedAverageAge)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fleet, fmt.Errorf("airline with ID %s not found: %w", id, err)
+		}
+		return fleet, fmt.Errorf("failed to scan airline: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `
+		WITH fleet AS (`+fleetMembers+`)
+		SELECT COALESCE(ap.model_name, ''), COALESCE(ap.engines_type, ''),
+		       COALESCE(ap.plane_status, ''), `+airplaneAge+`
+		FROM fleet JOIN airplane ap ON ap.id = fleet.airplane
+		WHERE fleet.airline = $1`, id)
+	if err != nil {
+		return fleet, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	models := make(map[string]int)
+	engines := make(map[string]int)
+	statuses := make(map[string]int)
+	ages := make([]int, len(fleetAgeBuckets))
+	var ageSum float64
+	for rows.Next() {
+		var model, engine, status string
+		var age *float64
+		if err := rows.Scan(&model, &engine, &status, &age); err != nil {
+			return fleet, fmt.Errorf("failed to scan airplane: %w", err)
+		}
+
+		fleet.ObservedFleetSize++
+		models[model]++
+		engines[engine]++
+		statuses[status]++
+		if age == nil {
+			fleet.UnknownAge++
+			continue
+		}
+		ageSum += *age
+		bucket := len(fleetAgeBuckets) - 1
+		for bucket > 0 && *age < float64(fleetAgeBuckets[bucket]) {
+			bucket--
+		}
+		ages[bucket]++
+	}
+	if err := rows.Err(); err != nil {
+		return fleet, fmt.Errorf("failed to iterate over results: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fleet, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if known := fleet.ObservedFleetSize - fleet.UnknownAge; known > 0 {
+		average := ageSum / float64(known)
+		fleet.ObservedAverageAge = &average
+	}
+	for i, from := range fleetAgeBuckets {
+		bucket := structs.FleetAgeBucket{From: from, Count: ages[i]}
+		if i+1 < len(fleetAgeBuckets) {
+			to := fleetAgeBuckets[i+1]
+			bucket.To = &to
+		}
+		fleet.AgeDistribution = append(fleet.AgeDistribution, bucket)
+	}
+	fleet.Models = fleetShares(models)
+	fleet.EngineTypes = fleetShares(engines)
+	fleet.Statuses = fleetShares(statuses)
+
+	return fleet, nil
+}
+
+// GetFleetLeaderboard ranks airlines by order, one of fleetLeaderboardOrder,
+// skipping airlines without any matched airplane.
+func (r *AirlineRepository) GetFleetLeaderboard(ctx context.Context, order string, limit int) ([]structs.FleetLeaderboardEntry, error) {
+	orderBy, ok := fleetLeaderboardOrder[order]
+	if !ok {
+		return nil, fmt.Errorf("unknown leaderboard order %q", order)
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		WITH fleet AS (`+fleetMembers+`)
+		SELECT a.id, COALESCE(a.airline_name, ''), COALESCE(a.iata_code, ''),
+		       COALESCE(a.fleet_size, 0), COALESCE(a.fleet_average_age, 0),
+		       COUNT(*) AS observed_fleet_size, AVG(`+airplaneAge+`) AS observed_average_age
+		FROM fleet
+		JOIN airline a ON a.id = fleet.airline
+		JOIN airplane ap ON ap.id = fleet.airplane
+		GROUP BY a.id, a.airline_name, a.iata_code, a.fleet_size, a.fleet_average_age
+		ORDER BY `+orderBy+`, a.airline_name
+		LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	leaderboard := []structs.FleetLeaderboardEntry{}
+	for rows.Next() {
+		var entry structs.FleetLeaderboardEntry
+		err := rows.Scan(&entry.AirlineID, &entry.AirlineName, &entry.IataCode,
+			&entry.ReportedFleetSize, &entry.ReportedAverageAge,
+			&entry.ObservedFleetSize, &entry.ObservedAverageAge)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan leaderboard entry: %w", err)
+		}
+		leaderboard = append(leaderboard, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over results: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return leaderboard, nil
+}
+
+// fleetShares sorts counts by size, most common first.
+func fleetShares(counts map[string]int) []structs.FleetShare {
+	shares := make([]structs.FleetShare, 0, len(counts))
+	for value, count := range counts {
+		shares = append(shares, structs.FleetShare{Value: value, Count: count})
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Count != shares[j].Count {
+			return shares[i].Count > shares[j].Count
+		}
+		return shares[i].Value < shares[j].Value
+	})
+	return shares
+}
@@ -0,0 +1,71 @@
+package airline
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/bulk"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/pgtest"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+)
+
+func TestFleet(t *testing.T) {
+	db := pgtest.New(t)
+	tap, iberia := uuid.New(), uuid.New()
+	pgtest.Exec(t, db, `
+		INSERT INTO airline (id, airline_name, iata_code, icao_code, fleet_size, fleet_average_age, deleted_at) VALUES
+			($1, 'TAP Air Portugal', 'TP', 'TAP', 5, 10.5, NULL),
+			($2, 'Iberia', 'IB', 'IBE', 1, 8, NULL),
+			(gen_random_uuid(), 'TAP (deleted)', 'TP', 'TAP', 90, 20, NOW())`, tap, iberia)
+	pgtest.Exec(t, db, `
+		INSERT INTO airplane (airline_iata_code, airline_icao_code, model_name, plane_status, plane_age, first_flight_date, deleted_at) VALUES
+			-- Matched on both codes, counted once.
+			('TP', 'TAP', 'A320', 'active', 3, NULL, NULL),
+			('TP', NULL, 'A320', 'stored', 0, NULL, NULL),
+			(NULL, 'TAP', 'A330', 'active', 12, NULL, NULL),
+			-- Imports store an unknown first flight as year 1.
+			('TP', NULL, 'A320', 'active', 0, '0001-01-01', NULL),
+			('TP', 'TAP', 'A340', 'active', 30, NULL, NOW()),
+			('IB', 'IBE', 'A350', 'active', 2, NULL, NULL)`)
+	r := NewRepositoryAirline(db, bulk.NewConfig(0))
+	ctx := context.Background()
+
+	fleet, err := r.GetAirlineFleet(ctx, tap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fleet.ReportedFleetSize != 5 || fleet.ObservedFleetSize != 4 || fleet.UnknownAge != 2 {
+		t.Errorf("reported %d, observed %d airplanes with %d unknown ages, want 5, 4 and 2",
+			fleet.ReportedFleetSize, fleet.ObservedFleetSize, fleet.UnknownAge)
+	}
+	if fleet.ReportedAverageAge != 10.5 || fleet.ObservedAverageAge == nil || *fleet.ObservedAverageAge != 7.5 {
+		t.Errorf("reported average age %v, observed %v, want 10.5 and 7.5", fleet.ReportedAverageAge, fleet.ObservedAverageAge)
+	}
+	var ages []int
+	for _, b := range fleet.AgeDistribution {
+		ages = append(ages, b.Count)
+	}
+	if want := []int{1, 0, 1, 0, 0, 0}; !reflect.DeepEqual(ages, want) {
+		t.Errorf("age distribution %v, want %v", ages, want)
+	}
+	if want := []structs.FleetShare{{Value: "A320", Count: 3}, {Value: "A330", Count: 1}}; !reflect.DeepEqual(fleet.Models, want) {
+		t.Errorf("models %+v, want %+v", fleet.Models, want)
+	}
+	if want := []structs.FleetShare{{Value: "active", Count: 3}, {Value: "stored", Count: 1}}; !reflect.DeepEqual(fleet.Statuses, want) {
+		t.Errorf("statuses %+v, want %+v", fleet.Statuses, want)
+	}
+
+	leaderboard, err := r.GetFleetLeaderboard(ctx, "observed_fleet", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leaderboard) != 2 || leaderboard[0].AirlineID != tap || leaderboard[1].AirlineID != iberia {
+		t.Fatalf("leaderboard %+v, want TAP then Iberia", leaderboard)
+	}
+	if got := leaderboard[0]; got.ReportedFleetSize != 5 || got.ObservedFleetSize != 4 ||
+		got.ObservedAverageAge == nil || *got.ObservedAverageAge != 7.5 {
+		t.Errorf("TAP entry %+v, want 5 reported, 4 observed, average age 7.5", got)
+	}
+}
@@ -0,0 +1,148 @@
+package airline
+
+import (
+	"context"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/batch"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var taxEntity = batch.Entity[structs.Tax]{
+	Table:   "tax",
+	Columns: `id, tax_id, tax_name, iata_code, created_at, updated_at, deleted_at`,
+	Scan: func(row pgx.Row) (structs.Tax, error) {
+		var t structs.Tax
+		err := row.Scan(&t.ID, &t.TaxId, &t.TaxName, &t.IataCode, &t.CreatedAt, &t.UpdatedAt, &t.DeletedAt)
+		return t, err
+	},
+	Values: func(t structs.Tax) map[string]interface{} {
+		return map[string]interface{}{
+			"tax_id": t.TaxId, "tax_name": t.TaxName, "iata_code": t.IataCode,
+		}
+	},
+}
+
+var aircraftEntity = batch.Entity[structs.Aircraft]{
+	Table:   "aircraft",
+	Columns: `id, iata_code, aircraft_name, plane_type_id, created_at, updated_at, deleted_at`,
+	Scan: func(row pgx.Row) (structs.Aircraft, error) {
+		var a structs.Aircraft
+		err := row.Scan(&a.ID, &a.IataCode, &a.AircraftName, &a.PlaneTypeId, &a.CreatedAt, &a.UpdatedAt, &a.DeletedAt)
+		return a, err
+	},
+	Values: func(a structs.Aircraft) map[string]interface{} {
+		return map[string]interface{}{
+			"iata_code": a.IataCode, "aircraft_name": a.AircraftName, "plane_type_id": a.PlaneTypeId,
+		}
+	},
+}
+
+var airlineEntity = batch.Entity[structs.Airline]{
+	Table: "airline",
+	Columns: `id, fleet_average_age, airline_id, call_sign, hub_code, iata_code, icao_code,
+		country_iso_2, data_founded, iata_prefix_accounting, airline_name, country_name,
+		fleet_size, status, type, country_ref, created_at, updated_at, deleted_at`,
+	Scan: func(row pgx.Row) (structs.Airline, error) {
+		var a structs.Airline
+		err := row.Scan(
+			&a.ID, &a.FleetAverageAge, &a.AirlineId, &a.Callsign, &a.HubCode, &a.IataCode, &a.IcaoCode,
+			&a.CountryIso2, &a.DateFounded, &a.IataPrefixAccounting, &a.AirlineName, &a.CountryName,
+			&a.FleetSize, &a.Status, &a.Type, &a.CountryRef, &a.CreatedAt, &a.UpdatedAt, &a.DeletedAt)
+		return a, err
+	},
+	Values: func(a structs.Airline) map[string]interface{} {
+		return map[string]interface{}{
+			"fleet_average_age": a.FleetAverageAge, "airline_id": a.AirlineId, "call_sign": a.Callsign,
+			"hub_code": a.HubCode, "iata_code": a.IataCode, "icao_code": a.IcaoCode,
+			"country_iso_2": a.CountryIso2, "data_founded": a.DateFounded,
+			"iata_prefix_accounting": a.IataPrefixAccounting, "airline_name": a.AirlineName,
+			"country_name": a.CountryName, "fleet_size": a.FleetSize, "status": a.Status,
+			"type": a.Type, "country_ref": a.CountryRef,
+		}
+	},
+}
+
+var airplaneEntity = batch.Entity[structs.Airplane]{
+	Table: "airplane",
+	Columns: `id, iata_type, airplane_id, airline_iata_code, iata_code_long, iata_code_short,
+		airline_icao_code, construction_number, delivery_date, engines_count, engines_type,
+		first_flight_date, icao_code_hex, line_number, model_code, registration_number,
+		test_registration_number, plane_age, plane_class, model_name, plane_owner, plane_series,
+		plane_status, production_line, registration_date, rollout_date, airline_ref,
+		created_at, updated_at, deleted_at`,
+	Scan: func(row pgx.Row) (structs.Airplane, error) {
+		var a structs.Airplane
+		err := row.Scan(
+			&a.ID, &a.IataType, &a.AirplaneId, &a.AirlineIataCode, &a.IataCodeLong, &a.IataCodeShort,
+			&a.AirlineIcaoCode, &a.ConstructionNumber, &a.DeliveryDate, &a.EnginesCount, &a.EnginesType,
+			&a.FirstFlightDate, &a.IcaoCodeHex, &a.LineNumber, &a.ModelCode, &a.RegistrationNumber,
+			&a.TestRegistrationNumber, &a.PlaneAge, &a.PlaneClass, &a.ModelName, &a.PlaneOwner, &a.PlaneSeries,
+			&a.PlaneStatus, &a.ProductionLine, &a.RegistrationDate, &a.RolloutDate, &a.AirlineRef,
+			&a.CreatedAt, &a.UpdatedAt, &a.DeletedAt)
+		return a, err
+	},
+	Values: func(a structs.Airplane) map[string]interface{} {
+		return map[string]interface{}{
+			"iata_type": a.IataType, "airplane_id": a.AirplaneId, "airline_iata_code": a.AirlineIataCode,
+			"iata_code_long": a.IataCodeLong, "iata_code_short": a.IataCodeShort,
+			"airline_icao_code": a.AirlineIcaoCode, "construction_number": a.ConstructionNumber,
+			"delivery_date": a.DeliveryDate, "engines_count": a.EnginesCount, "engines_type": a.EnginesType,
+			"first_flight_date": a.FirstFlightDate, "icao_code_hex": a.IcaoCodeHex,
+			"line_number": a.LineNumber, "model_code": a.ModelCode,
+			"registration_number": a.RegistrationNumber, "test_registration_number": a.TestRegistrationNumber,
+			"plane_age": a.PlaneAge, "plane_class": a.PlaneClass, "model_name": a.ModelName,
+			"plane_owner": a.PlaneOwner, "plane_series": a.PlaneSeries, "plane_status": a.PlaneStatus,
+			"production_line": a.ProductionLine, "registration_date": a.RegistrationDate,
+			"rollout_date": a.RolloutDate, "airline_ref": a.AirlineRef,
+		}
+	},
+}
+
+// UpdateTax locks the live tax id and writes back the tax fn makes of it.
+func (r *AirlineRepository) UpdateTax(ctx context.Context, id uuid.UUID, fn func(structs.Tax) (structs.Tax, error)) error {
+	return batch.Update(ctx, r.db, taxEntity, id, fn)
+}
+
+// DeleteTax locks the live tax id and soft deletes it once
+// check accepts it.
+func (r *AirlineRepository) DeleteTax(ctx context.Context, id uuid.UUID, check func(structs.Tax) error) error {
+	return batch.Delete(ctx, r.db, taxEntity, id, check)
+}
+
+// UpdateAircraft locks the live aircraft id and writes back the aircraft fn
+// makes of it.
+func (r *AirlineRepository) UpdateAircraft(ctx context.Context, id uuid.UUID, fn func(structs.Aircraft) (structs.Aircraft, error)) error {
+	return batch.Update(ctx, r.db, aircraftEntity, id, fn)
+}
+
+// DeleteAircraft locks the live aircraft id and soft deletes it once
+// check accepts it.
+func (r *AirlineRepository) DeleteAircraft(ctx context.Context, id uuid.UUID, check func(structs.Aircraft) error) error {
+	return batch.Delete(ctx, r.db, aircraftEntity, id, check)
+}
+
+// UpdateAirline locks the live airline id and writes back the airline fn
+// makes of it.
+func (r *AirlineRepository) UpdateAirline(ctx context.Context, id uuid.UUID, fn func(structs.Airline) (structs.Airline, error)) error {
+	return batch.Update(ctx, r.db, airlineEntity, id, fn)
+}
+
+// DeleteAirline locks the live airline id and soft deletes it once
+// check accepts it.
+func (r *AirlineRepository) DeleteAirline(ctx context.Context, id uuid.UUID, check func(structs.Airline) error) error {
+	return batch.Delete(ctx, r.db, airlineEntity, id, check)
+}
+
+// UpdateAirplane locks the live airplane id and writes back the airplane fn
+// makes of it.
+func (r *AirlineRepository) UpdateAirplane(ctx context.Context, id uuid.UUID, fn func(structs.Airplane) (structs.Airplane, error)) error {
+	return batch.Update(ctx, r.db, airplaneEntity, id, fn)
+}
+
+// DeleteAirplane locks the live airplane id and soft deletes it once
+// check accepts it.
+func (r *AirlineRepository) DeleteAirplane(ctx context.Context, id uuid.UUID, check func(structs.Airplane) error) error {
+	return batch.Delete(ctx, r.db, airplaneEntity, id, check)
+}
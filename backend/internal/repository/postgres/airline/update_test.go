@@ -0,0 +1,42 @@
+package airline
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/batch"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+// writes returns the sorted columns values writes and the writable columns
+// of table.
+func writes(values map[string]interface{}, table string) (string, string) {
+	var got, want []string
+	for c := range values {
+		got = append(got, c)
+	}
+	for c := range batch.Tables[table] {
+		want = append(want, c)
+	}
+	sort.Strings(got)
+	sort.Strings(want)
+	return strings.Join(got, ","), strings.Join(want, ",")
+}
+
+func TestEntityValues(t *testing.T) {
+	tests := []struct {
+		table  string
+		values map[string]interface{}
+	}{
+		{taxEntity.Table, taxEntity.Values(structs.Tax{})},
+		{aircraftEntity.Table, aircraftEntity.Values(structs.Aircraft{})},
+		{airlineEntity.Table, airlineEntity.Values(structs.Airline{})},
+		{airplaneEntity.Table, airplaneEntity.Values(structs.Airplane{})},
+	}
+	for _, tt := range tests {
+		if got, want := writes(tt.values, tt.table); got != want {
+			t.Errorf("%s writes %s, want %s", tt.table, got, want)
+		}
+	}
+}
@@ -4,20 +4,23 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/bulk"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/history"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/trash"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/pkg/errors"
-	"strings"
 )
 
 type AirportRepository struct {
-	db *pgxpool.Pool
+	db   *pgxpool.Pool
+	bulk *bulk.Writer
 }
 
-func NewRepositoryAirport(db *pgxpool.Pool) *AirportRepository {
-	return &AirportRepository{db: db}
+func NewRepositoryAirport(db *pgxpool.Pool, bulkConfig bulk.Config) *AirportRepository {
+	return &AirportRepository{db: db, bulk: bulk.NewWriter(db, bulkConfig)}
 }
 
 func (r *AirportRepository) CreateAirport(ctx context.Context, a *structs.Airport) error {
@@ -61,12 +64,10 @@ func (r *AirportRepository) CreateAirport(ctx context.Context, a *structs.Airpor
 	return nil
 }
 
-func (r *AirportRepository) GetAirports(ctx context.Context) ([]structs.Airport, error) {
-	var airport []structs.Airport
-
+func (r *AirportRepository) StreamAirports(ctx context.Context, fn func(structs.Airport) error) error {
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer tx.Rollback(ctx)
 
@@ -75,10 +76,10 @@ func (r *AirportRepository) GetAirports(ctx context.Context) ([]structs.Airport,
        										city_iata_code, icao_code, country_iso2,
        										geoname_id, latitude, longitude, airport_name,
        										country_name, phone_number, timezone,
-       										created_at, updated_at
-       								FROM airport ORDER BY id`)
+       										city_ref, country_ref, created_at, updated_at, deleted_at
+       								FROM airport WHERE `+trash.Live(ctx, "deleted_at")+` ORDER BY id`)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
@@ -89,24 +90,40 @@ func (r *AirportRepository) GetAirports(ctx context.Context) ([]structs.Airport,
 			&a.CityIataCode, &a.IcaoCode, &a.CountryIso2,
 			&a.GeonameId, &a.Latitude, &a.Longitude,
 			&a.AirportName, &a.CountryName, &a.PhoneNumber,
-			&a.Timezone, &a.CreatedAt, &a.UpdatedAt,
+			&a.Timezone, &a.CityRef, &a.CountryRef,
+			&a.CreatedAt, &a.UpdatedAt, &a.DeletedAt,
 		)
 
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if err := fn(a); err != nil {
+			return err
 		}
-		airport = append(airport, a)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *AirportRepository) GetAirports(ctx context.Context) ([]structs.Airport, error) {
+	var airports []structs.Airport
+	err := r.StreamAirports(ctx, func(airport structs.Airport) error {
+		airports = append(airports, airport)
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	return airport, nil
+	return airports, nil
 }
 
 func (r *AirportRepository) GetAirport(ctx context.Context, id uuid.UUID) (structs.Airport, error) {
@@ -118,30 +135,33 @@ func (r *AirportRepository) GetAirport(ctx context.Context, id uuid.UUID) (struc
 	}
 	defer tx.Rollback(ctx)
 
-	_ = tx.QueryRow(ctx, `
+	err = tx.QueryRow(ctx, `
 		SELECT id, gmt, airport_id, iata_code,
        			city_iata_code, icao_code, country_iso2,
        			geoname_id, latitude, longitude, airport_name,
        			country_name, phone_number, timezone,
-       			created_at, updated_at
-		FROM airport
-		WHERE id = $1 LIMIT 1`, id).Scan(
-		airport.ID,
-		airport.GMT,
-		airport.AirportId,
-		airport.IataCode,
-		airport.CityIataCode,
-		airport.IcaoCode,
-		airport.CountryIso2,
-		airport.GeonameId,
-		airport.Latitude,
-		airport.Longitude,
-		airport.AirportName,
-		airport.CountryName,
-		airport.PhoneNumber,
-		airport.Timezone,
-		airport.CreatedAt,
-		airport.UpdatedAt)
+       			city_ref, country_ref, created_at, updated_at, deleted_at
+		FROM `+history.Source(ctx, "airport")+`
+		WHERE id = $1 AND `+trash.Live(ctx, "deleted_at")+` LIMIT 1`, id).Scan(
+		&airport.ID,
+		&airport.GMT,
+		&airport.AirportId,
+		&airport.IataCode,
+		&airport.CityIataCode,
+		&airport.IcaoCode,
+		&airport.CountryIso2,
+		&airport.GeonameId,
+		&airport.Latitude,
+		&airport.Longitude,
+		&airport.AirportName,
+		&airport.CountryName,
+		&airport.PhoneNumber,
+		&airport.Timezone,
+		&airport.CityRef,
+		&airport.CountryRef,
+		&airport.CreatedAt,
+		&airport.UpdatedAt,
+		&airport.DeletedAt)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -157,54 +177,6 @@ func (r *AirportRepository) GetAirport(ctx context.Context, id uuid.UUID) (struc
 	return airport, nil
 }
 
-func (r *AirportRepository) DeleteAirport(ctx context.Context, id uuid.UUID) error {
-	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback(ctx)
-
-	_, err = tx.Exec(ctx, "DELETE FROM airport WHERE id = $1", id)
-	if err != nil {
-		return fmt.Errorf("failed to delete airplane: %w", err)
-	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	return nil
-}
-
-func (r *AirportRepository) UpdateAirport(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error {
-	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback(ctx)
-
-	var setColumns []string
-	var args []interface{}
-
-	for key, value := range updates {
-		setColumns = append(setColumns, fmt.Sprintf("%s = $%d", key, len(args)+1))
-		args = append(args, value)
-	}
-	args = append(args, id)
-
-	stmt := fmt.Sprintf("UPDATE airport SET %s WHERE id = $%d", strings.Join(setColumns, ", "), len(args))
-	_, err = tx.Exec(ctx, stmt, args...)
-	if err != nil {
-		return fmt.Errorf("failed to update airplane: %w", err)
-	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	return nil
-}
-
 func (r *AirportRepository) GetAirportCount(ctx context.Context) (int, error) {
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
 	if err != nil {
@@ -213,7 +185,7 @@ func (r *AirportRepository) GetAirportCount(ctx context.Context) (int, error) {
 	defer tx.Rollback(ctx)
 
 	var count int
-	err = tx.QueryRow(ctx, "SELECT COUNT(*) FROM airport").Scan(&count)
+	err = tx.QueryRow(ctx, "SELECT COUNT(*) FROM airport WHERE deleted_at IS NULL").Scan(&count)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return 0, fmt.Errorf("no airport found")
@@ -228,20 +200,25 @@ func (r *AirportRepository) GetAirportCount(ctx context.Context) (int, error) {
 	return count, nil
 }
 
-func (r *AirportRepository) GetCitiesAirports(ctx context.Context) ([]structs.AirportInfo, error) {
-	var airportsInfo []structs.AirportInfo
+func (r *AirportRepository) StreamCitiesAirports(ctx context.Context, fn func(structs.AirportInfo) error) error {
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
 	if err != nil {
-		return airportsInfo, fmt.Errorf("failed to begin transaction: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
 	rows, err := tx.Query(ctx, `
-        SELECT ap.*, ct.city_name  FROM airport ap
-		INNER JOIN city ct ON ap.city_iata_code = ct.iata_code
+        SELECT ap.id, ap.gmt, ap.airport_id, ap.iata_code,
+               ap.city_iata_code, ap.icao_code, ap.country_iso2,
+               ap.geoname_id, ap.latitude, ap.longitude, ap.airport_name,
+               ap.country_name, ap.phone_number, ap.timezone,
+               ap.created_at, ap.updated_at, ct.city_name
+        FROM airport ap
+		INNER JOIN city ct ON ap.city_ref = ct.id
+        WHERE ap.deleted_at IS NULL
         ORDER BY ap.airport_id`)
 	if err != nil {
-		return airportsInfo, fmt.Errorf("failed to execute query: %w", err)
+		return fmt.Errorf("failed to execute query: %w", err)
 	}
 	defer rows.Close()
 
@@ -266,16 +243,31 @@ func (r *AirportRepository) GetCitiesAirports(ctx context.Context) ([]structs.Ai
 			&airportInfo.UpdatedAt,
 			&airportInfo.CityName)
 		if err != nil {
-			return airportsInfo, fmt.Errorf("failed to scan airplanes info: %w", err)
+			return fmt.Errorf("failed to scan airplanes info: %w", err)
+		}
+		if err := fn(airportInfo); err != nil {
+			return err
 		}
-		airportsInfo = append(airportsInfo, airportInfo)
 	}
 	if err := rows.Err(); err != nil {
-		return airportsInfo, fmt.Errorf("failed to iterate over results: %w", err)
+		return fmt.Errorf("failed to iterate over results: %w", err)
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return airportsInfo, fmt.Errorf("failed to commit transaction: %w", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AirportRepository) GetCitiesAirports(ctx context.Context) ([]structs.AirportInfo, error) {
+	var airportsInfo []structs.AirportInfo
+	err := r.StreamCitiesAirports(ctx, func(airportInfo structs.AirportInfo) error {
+		airportsInfo = append(airportsInfo, airportInfo)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return airportsInfo, nil
@@ -290,9 +282,14 @@ func (r *AirportRepository) GetCityNameAirport(ctx context.Context, cityName str
 	defer tx.Rollback(ctx)
 
 	rows, err := tx.Query(context.Background(), `
-        SELECT ap.*, ct.city_name  FROM airport ap
-		INNER JOIN city ct ON ap.city_iata_code = ct.iata_code
-        WHERE ct.city_name = $1
+        SELECT ap.id, ap.gmt, ap.airport_id, ap.iata_code,
+               ap.city_iata_code, ap.icao_code, ap.country_iso2,
+               ap.geoname_id, ap.latitude, ap.longitude, ap.airport_name,
+               ap.country_name, ap.phone_number, ap.timezone,
+               ap.created_at, ap.updated_at, ct.city_name
+        FROM airport ap
+		INNER JOIN city ct ON ap.city_ref = ct.id
+        WHERE ct.city_name = $1 AND ap.deleted_at IS NULL
         ORDER BY ap.airport_id`, cityName)
 	if err != nil {
 		return airportsInfo, fmt.Errorf("failed to execute query: %w", err)
@@ -344,7 +341,7 @@ func (r *AirportRepository) GetCityNameAirportAlternative(ctx context.Context, c
 	if err != nil {
 		return airportsInfo, fmt.Errorf("failed to query city names: %w", err)
 	}
-	cityRows, err := tx.Query(ctx, `SELECT city_name, iata_code FROM city`)
+	cityRows, err := tx.Query(ctx, `SELECT iata_code, city_name FROM city WHERE deleted_at IS NULL`)
 	if err != nil {
 		return airportsInfo, fmt.Errorf("failed to query city names: %w", err)
 	}
@@ -361,9 +358,12 @@ func (r *AirportRepository) GetCityNameAirportAlternative(ctx context.Context, c
 	}
 
 	rows, err := tx.Query(ctx, `
-       	SELECT ap.*, ct.city_name  FROM airport ap
-		INNER JOIN city ct ON ap.city_iata_code = ct.iata_code
-        WHERE ct.city_name = $1
+        SELECT ap.airport_id, ap.iata_code, ap.city_iata_code, ap.country_iso2,
+               ap.geoname_id, ap.latitude, ap.longitude, ap.airport_name,
+               ap.country_name, ap.timezone, ap.created_at, ap.updated_at
+        FROM airport ap
+		INNER JOIN city ct ON ap.city_ref = ct.id
+        WHERE ct.city_name = $1 AND ap.deleted_at IS NULL
         ORDER BY ap.airport_id`, cityName)
 	if err != nil {
 		return airportsInfo, fmt.Errorf("failed to execute query: %w", err)
@@ -413,9 +413,14 @@ func (r *AirportRepository) GetCountryNameAirport(ctx context.Context, countryNa
 	rows, err := tx.Query(
 		context.Background(),
 		`
-        SELECT ap.*, ct.city_name  FROM airport ap
-		INNER JOIN city ct ON ap.city_iata_code = ct.iata_code
-        WHERE ap.country_name = $1
+        SELECT ap.id, ap.gmt, ap.airport_id, ap.iata_code,
+               ap.city_iata_code, ap.icao_code, ap.country_iso2,
+               ap.geoname_id, ap.latitude, ap.longitude, ap.airport_name,
+               ap.country_name, ap.phone_number, ap.timezone,
+               ap.created_at, ap.updated_at, ct.city_name
+        FROM airport ap
+		INNER JOIN city ct ON ap.city_ref = ct.id
+        WHERE ap.country_name = $1 AND ap.deleted_at IS NULL
         ORDER BY ap.airport_id`,
 		countryName,
 	)
@@ -469,9 +474,14 @@ func (r *AirportRepository) GetCityIataCodeAirport(ctx context.Context, iataCode
 	defer tx.Rollback(ctx)
 
 	rows, err := tx.Query(ctx, `
-        SELECT ap.*, ct.city_name  FROM airport ap
-		INNER JOIN city ct ON ap.city_iata_code = ct.iata_code
-        WHERE ap.city_iata_code = $1
+        SELECT ap.id, ap.gmt, ap.airport_id, ap.iata_code,
+               ap.city_iata_code, ap.icao_code, ap.country_iso2,
+               ap.geoname_id, ap.latitude, ap.longitude, ap.airport_name,
+               ap.country_name, ap.phone_number, ap.timezone,
+               ap.created_at, ap.updated_at, ct.city_name
+        FROM airport ap
+		INNER JOIN city ct ON ap.city_ref = ct.id
+        WHERE ap.city_iata_code = $1 AND ap.deleted_at IS NULL
         ORDER BY ap.airport_id`, iataCode)
 	if err != nil {
 		return airportsInfo, fmt.Errorf("failed to execute query: %w", err)
@@ -513,3 +523,42 @@ func (r *AirportRepository) GetCityIataCodeAirport(ctx context.Context, iataCode
 
 	return airportsInfo, nil
 }
+
+// GetAirportPositions returns coordinates and timezone of the airports with
+// the given IATA codes. Codes without an airport row are left out.
+func (r *AirportRepository) GetAirportPositions(ctx context.Context, iataCodes []string) ([]structs.AirportPosition, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT DISTINCT ON (iata_code) iata_code,
+		       COALESCE(latitude, 0), COALESCE(longitude, 0), COALESCE(timezone, '')
+		FROM airport
+		WHERE iata_code = ANY($1) AND deleted_at IS NULL
+		ORDER BY iata_code, created_at`, iataCodes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	var positions []structs.AirportPosition
+	for rows.Next() {
+		var p structs.AirportPosition
+		if err := rows.Scan(&p.Iata, &p.Latitude, &p.Longitude, &p.Timezone); err != nil {
+			return nil, fmt.Errorf("failed to scan airport position: %w", err)
+		}
+		positions = append(positions, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over results: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return positions, nil
+}
@@ -0,0 +1,34 @@
+package airport
+
+import (
+	"context"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/bulk"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+var airportTable = bulk.Table{
+	Name: "airport",
+	Key:  "airport_id",
+	Columns: []string{"id", "gmt", "airport_id", "iata_code", "city_iata_code", "icao_code",
+		"country_iso2", "geoname_id", "latitude", "longitude", "airport_name", "country_name",
+		"phone_number", "timezone", "created_at"},
+}
+
+func (r *AirportRepository) CreateAirports(ctx context.Context, airports []structs.Airport) (structs.BatchResult, error) {
+	return r.bulk.Write(ctx, airportTable, airportRows(airports))
+}
+
+func (r *AirportRepository) DiffAirports(ctx context.Context, airports []structs.Airport) (structs.IngestionDiff, error) {
+	return r.bulk.Diff(ctx, airportTable, airportRows(airports))
+}
+
+func airportRows(airports []structs.Airport) [][]any {
+	rows := make([][]any, len(airports))
+	for i, a := range airports {
+		rows[i] = []any{a.ID, a.GMT, a.AirportId, a.IataCode, a.CityIataCode, a.IcaoCode,
+			a.CountryIso2, a.GeonameId, a.Latitude, a.Longitude, a.AirportName, a.CountryName,
+			bulk.Text(a.PhoneNumber), a.Timezone, a.CreatedAt.Time}
+	}
+	return rows
+}
@@ -0,0 +1,46 @@
+package airport
+
+import (
+	"context"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/batch"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var airportEntity = batch.Entity[structs.Airport]{
+	Table: "airport",
+	Columns: `id, gmt, airport_id, iata_code, city_iata_code, icao_code, country_iso2,
+		geoname_id, latitude, longitude, airport_name, country_name, phone_number, timezone,
+		city_ref, country_ref, created_at, updated_at, deleted_at`,
+	Scan: func(row pgx.Row) (structs.Airport, error) {
+		var a structs.Airport
+		err := row.Scan(
+			&a.ID, &a.GMT, &a.AirportId, &a.IataCode, &a.CityIataCode, &a.IcaoCode, &a.CountryIso2,
+			&a.GeonameId, &a.Latitude, &a.Longitude, &a.AirportName, &a.CountryName, &a.PhoneNumber, &a.Timezone,
+			&a.CityRef, &a.CountryRef, &a.CreatedAt, &a.UpdatedAt, &a.DeletedAt)
+		return a, err
+	},
+	Values: func(a structs.Airport) map[string]interface{} {
+		return map[string]interface{}{
+			"gmt": a.GMT, "airport_id": a.AirportId, "iata_code": a.IataCode,
+			"city_iata_code": a.CityIataCode, "icao_code": a.IcaoCode, "country_iso2": a.CountryIso2,
+			"geoname_id": a.GeonameId, "latitude": a.Latitude, "longitude": a.Longitude,
+			"airport_name": a.AirportName, "country_name": a.CountryName, "phone_number": a.PhoneNumber,
+			"timezone": a.Timezone, "city_ref": a.CityRef, "country_ref": a.CountryRef,
+		}
+	},
+}
+
+// UpdateAirport locks the live airport id and writes back the airport fn
+// makes of it.
+func (r *AirportRepository) UpdateAirport(ctx context.Context, id uuid.UUID, fn func(structs.Airport) (structs.Airport, error)) error {
+	return batch.Update(ctx, r.db, airportEntity, id, fn)
+}
+
+// DeleteAirport locks the live airport id and soft deletes it once
+// check accepts it.
+func (r *AirportRepository) DeleteAirport(ctx context.Context, id uuid.UUID, check func(structs.Airport) error) error {
+	return batch.Delete(ctx, r.db, airportEntity, id, check)
+}
@@ -0,0 +1,343 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Kinds of the columns a bulk write may set.
+const (
+	Text      = "text"
+	Int       = "int"
+	Float     = "float"
+	Timestamp = "timestamp"
+	UUID      = "uuid"
+)
+
+// TextLength is the length of every text column.
+const TextLength = 255
+
+// Tables maps every reference table to the kinds of its writable columns.
+// id, created_at, updated_at and deleted_at are managed by the repository.
+var Tables = map[string]map[string]string{
+	"tax": {
+		"tax_id": Int, "tax_name": Text, "iata_code": Text,
+	},
+	"aircraft": {
+		"iata_code": Text, "aircraft_name": Text, "plane_type_id": Int,
+	},
+	"airline": {
+		"fleet_average_age": Float, "airline_id": Int, "call_sign": Text, "hub_code": Text,
+		"iata_code": Text, "icao_code": Text, "country_iso_2": Text, "data_founded": Int,
+		"iata_prefix_accounting": Int, "airline_name": Text, "country_name": Text,
+		"fleet_size": Int, "status": Text, "type": Text, "country_ref": UUID,
+	},
+	"airplane": {
+		"iata_type": Text, "airplane_id": Int, "airline_iata_code": Text, "iata_code_long": Text,
+		"iata_code_short": Text, "airline_icao_code": Text, "construction_number": Text,
+		"delivery_date": Timestamp, "engines_count": Int, "engines_type": Text,
+		"first_flight_date": Timestamp, "icao_code_hex": Text, "line_number": Text,
+		"model_code": Text, "registration_number": Text, "test_registration_number": Text,
+		"plane_age": Int, "plane_class": Text, "model_name": Text, "plane_owner": Text,
+		"plane_series": Text, "plane_status": Text, "production_line": Text,
+		"registration_date": Timestamp, "rollout_date": Timestamp, "airline_ref": UUID,
+	},
+	"airport": {
+		"gmt": Float, "airport_id": Int, "iata_code": Text, "city_iata_code": Text,
+		"icao_code": Text, "country_iso2": Text, "geoname_id": Int, "latitude": Float,
+		"longitude": Float, "airport_name": Text, "country_name": Text, "phone_number": Text,
+		"timezone": Text, "city_ref": UUID, "country_ref": UUID,
+	},
+	"city": {
+		"gmt": Float, "city_id": Int, "iata_code": Text, "country_iso2": Text, "geoname_id": Int,
+		"latitude": Float, "longitude": Float, "city_name": Text, "timezone": Text,
+		"country_ref": UUID,
+	},
+	"country": {
+		"country_name": Text, "country_iso_2": Text, "country_iso_3": Text,
+		"country_iso_numeric": Int, "population": Int, "capital": Text, "continent": Text,
+		"currency_name": Text, "currency_code": Text, "fips_code": Text, "phone_prefix": Text,
+	},
+}
+
+// ErrRejected is returned by Update when the database rejects the new
+// values of the row: a missing reference or a bad value.
+var ErrRejected = errors.New("rejected by the database")
+
+// Required are the columns of each table a full row must set, not null.
+var Required = map[string][]string{
+	"tax":      {"tax_id", "tax_name"},
+	"aircraft": {"plane_type_id", "aircraft_name"},
+	"airline":  {"airline_id", "airline_name"},
+	"airplane": {"airplane_id", "registration_number"},
+	"airport":  {"airport_id", "airport_name", "iata_code"},
+	"city":     {"city_id", "city_name"},
+	"country":  {"country_name", "country_iso_2"},
+}
+
+// errFailed marks an operation that failed on its own: its row is missing,
+// or the database rejected its values.
+type errFailed struct {
+	status int
+	err    error
+}
+
+func (e errFailed) Error() string { return e.err.Error() }
+func (e errFailed) Unwrap() error { return e.err }
+
+type BatchRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepositoryBatch(db *pgxpool.Pool) *BatchRepository {
+	return &BatchRepository{db: db}
+}
+
+// Apply runs the validated operations on table in one transaction and
+// returns the outcome of each, in order. An atomic batch stops at the first
+// failed operation and writes nothing; a partial one runs each operation in
+// its own savepoint and commits the ones that succeeded. Errors other than
+// a failed operation abort the whole batch.
+func (r *BatchRepository) Apply(ctx context.Context, table string, ops []structs.BulkOperation, partial bool) ([]structs.BulkItemResult, error) {
+	if _, ok := Tables[table]; !ok {
+		return nil, fmt.Errorf("unknown table %s", table)
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	results := make([]structs.BulkItemResult, len(ops))
+	for i, op := range ops {
+		results[i] = structs.BulkItemResult{Index: i, Op: op.Op, ID: op.ID}
+
+		sp, err := tx.Begin(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create savepoint: %w", err)
+		}
+		id, status, err := apply(ctx, sp, table, op)
+		var failed errFailed
+		if errors.As(err, &failed) {
+			sp.Rollback(ctx)
+			results[i].Status = failed.status
+			results[i].Errors = []string{failed.Error()}
+			if !partial {
+				return rolledBack(results, ops, i), nil
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := sp.Commit(ctx); err != nil {
+			return nil, fmt.Errorf("failed to release savepoint: %w", err)
+		}
+		results[i].ID = &id
+		results[i].Status = status
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return results, nil
+}
+
+// Entity reads and writes the live rows of one table as T.
+type Entity[T any] struct {
+	Table string
+	// Columns are selected for Scan, in the order it reads them.
+	Columns string
+	Scan    func(row pgx.Row) (T, error)
+	// Values maps the writable columns of the table to the values of a T.
+	Values func(T) map[string]interface{}
+}
+
+// Update locks the live row id of the table of e, passes it to fn and
+// writes back the writable columns of the entity fn returns, all in one
+// transaction. An error of fn is returned as is and writes nothing.
+func Update[T any](ctx context.Context, db *pgxpool.Pool, e Entity[T], id uuid.UUID, fn func(current T) (T, error)) error {
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	current, err := e.Scan(tx.QueryRow(ctx, `SELECT `+e.Columns+` FROM `+e.Table+`
+		WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%s with ID %s not found: %w", e.Table, id, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query %s: %w", e.Table, err)
+	}
+
+	next, err := fn(current)
+	if err != nil {
+		return err
+	}
+
+	columns, args := assignments(e.Values(next))
+	set := make([]string, len(columns))
+	for i, c := range columns {
+		set[i] = fmt.Sprintf("%s = $%d", c, i+2)
+	}
+	query := fmt.Sprintf(`UPDATE %s SET %s, updated_at = NOW() WHERE id = $1`, e.Table, strings.Join(set, ", "))
+	_, err = tx.Exec(ctx, query, append([]interface{}{id}, args...)...)
+	var failed errFailed
+	if errors.As(rejected(err, "failed to update "+e.Table), &failed) {
+		return fmt.Errorf("%w: %v", ErrRejected, failed)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", e.Table, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Delete locks the live row id of the table of e, passes it to check and
+// soft deletes it, all in one transaction. An error of check is returned as
+// is and deletes nothing.
+func Delete[T any](ctx context.Context, db *pgxpool.Pool, e Entity[T], id uuid.UUID, check func(current T) error) error {
+	tx, err := db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	current, err := e.Scan(tx.QueryRow(ctx, `SELECT `+e.Columns+` FROM `+e.Table+`
+		WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%s with ID %s not found: %w", e.Table, id, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query %s: %w", e.Table, err)
+	}
+
+	if err := check(current); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE `+e.Table+` SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", e.Table, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// rolledBack marks every operation of a failed atomic batch but the failed
+// one as not applied.
+func rolledBack(results []structs.BulkItemResult, ops []structs.BulkOperation, failed int) []structs.BulkItemResult {
+	for i := range results {
+		if i == failed {
+			continue
+		}
+		results[i].ID = ops[i].ID
+		results[i].Status = http.StatusFailedDependency
+		results[i].Errors = []string{fmt.Sprintf("not applied, operation %d failed", failed)}
+	}
+	return results
+}
+
+func apply(ctx context.Context, tx pgx.Tx, table string, op structs.BulkOperation) (uuid.UUID, int, error) {
+	switch op.Op {
+	case structs.BulkCreate:
+		id := uuid.New()
+		if op.ID != nil {
+			id = *op.ID
+		}
+		columns, args := assignments(op.Data)
+		placeholders := make([]string, len(columns))
+		for i := range columns {
+			placeholders[i] = fmt.Sprintf("$%d", i+2)
+		}
+		query := fmt.Sprintf(`INSERT INTO %s (id, %s) VALUES ($1, %s)`,
+			table, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+		if len(columns) == 0 {
+			query = fmt.Sprintf(`INSERT INTO %s (id) VALUES ($1)`, table)
+		}
+		_, err := tx.Exec(ctx, query, append([]interface{}{id}, args...)...)
+		return id, http.StatusCreated, rejected(err, "failed to insert "+table)
+
+	case structs.BulkUpdate:
+		columns, args := assignments(op.Data)
+		set := make([]string, len(columns))
+		for i, c := range columns {
+			set[i] = fmt.Sprintf("%s = $%d", c, i+2)
+		}
+		query := fmt.Sprintf(`UPDATE %s SET %s, updated_at = NOW() WHERE id = $1 AND deleted_at IS NULL`,
+			table, strings.Join(set, ", "))
+		tag, err := tx.Exec(ctx, query, append([]interface{}{*op.ID}, args...)...)
+		if err != nil {
+			return *op.ID, 0, rejected(err, "failed to update "+table)
+		}
+		if tag.RowsAffected() == 0 {
+			return *op.ID, 0, errFailed{http.StatusNotFound, fmt.Errorf("%s with ID %s not found", table, op.ID)}
+		}
+		return *op.ID, http.StatusOK, nil
+
+	case structs.BulkDelete:
+		tag, err := tx.Exec(ctx, `UPDATE `+table+` SET deleted_at = NOW(), updated_at = NOW()
+			WHERE id = $1 AND deleted_at IS NULL`, *op.ID)
+		if err != nil {
+			return *op.ID, 0, fmt.Errorf("failed to delete %s: %w", table, err)
+		}
+		if tag.RowsAffected() == 0 {
+			return *op.ID, 0, errFailed{http.StatusNotFound, fmt.Errorf("%s with ID %s not found", table, op.ID)}
+		}
+		return *op.ID, http.StatusNoContent, nil
+	}
+	return uuid.Nil, 0, fmt.Errorf("unknown operation %s", op.Op)
+}
+
+// assignments returns the columns of data in a stable order, with their
+// values.
+func assignments(data map[string]interface{}) ([]string, []interface{}) {
+	columns := make([]string, 0, len(data))
+	for c := range data {
+		columns = append(columns, c)
+	}
+	sort.Strings(columns)
+	args := make([]interface{}, len(columns))
+	for i, c := range columns {
+		args[i] = data[c]
+	}
+	return columns, args
+}
+
+// rejected turns an error of the database about the values of one row, a
+// duplicate id, a missing reference or a bad value, into a failed
+// operation.
+func rejected(err error, what string) error {
+	if err == nil {
+		return nil
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case strings.HasPrefix(pgErr.Code, "23"):
+			return errFailed{http.StatusConflict, errors.New(pgErr.Message)}
+		case strings.HasPrefix(pgErr.Code, "22"):
+			return errFailed{http.StatusUnprocessableEntity, errors.New(pgErr.Message)}
+		}
+	}
+	return fmt.Errorf("%s: %w", what, err)
+}
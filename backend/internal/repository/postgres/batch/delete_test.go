@@ -0,0 +1,73 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/pgtest"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var taxEntity = Entity[structs.Tax]{
+	Table:   "tax",
+	Columns: "id, tax_id, tax_name",
+	Scan: func(row pgx.Row) (structs.Tax, error) {
+		var t structs.Tax
+		err := row.Scan(&t.ID, &t.TaxId, &t.TaxName)
+		return t, err
+	},
+}
+
+func TestDelete(t *testing.T) {
+	db := pgtest.New(t)
+	ctx := context.Background()
+	id := uuid.New()
+	pgtest.Exec(t, db, `INSERT INTO tax (id, tax_id, tax_name) VALUES ($1, 1, 'Airport Tax')`, id)
+
+	live := func() bool {
+		t.Helper()
+		var n int
+		if err := db.QueryRow(ctx, `SELECT COUNT(*) FROM tax WHERE id = $1 AND deleted_at IS NULL`, id).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n == 1
+	}
+
+	// The check runs under the row lock: nobody else can lock the row
+	// until the delete is over.
+	modified := errors.New("modified")
+	err := Delete(ctx, db, taxEntity, id, func(current structs.Tax) error {
+		if current.ID != id || current.TaxName != "Airport Tax" {
+			t.Errorf("checked %+v, want the current row", current)
+		}
+		_, err := db.Exec(ctx, `SELECT 1 FROM tax WHERE id = $1 FOR UPDATE NOWAIT`, id)
+		if err == nil {
+			t.Error("the row was not locked during the check")
+		}
+		return modified
+	})
+	if !errors.Is(err, modified) {
+		t.Errorf("Delete with a failed check = %v, want %v", err, modified)
+	}
+	if !live() {
+		t.Error("a failed check deleted the row")
+	}
+
+	if err := Delete(ctx, db, taxEntity, id, func(structs.Tax) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if live() {
+		t.Error("the row is still live")
+	}
+
+	err = Delete(ctx, db, taxEntity, id, func(structs.Tax) error {
+		t.Error("checked a deleted row")
+		return nil
+	})
+	if !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("Delete of a deleted row = %v, want %v", err, pgx.ErrNoRows)
+	}
+}
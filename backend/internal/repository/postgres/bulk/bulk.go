@@ -0,0 +1,198 @@
+package bulk
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/FACorreiaa/aviatoon-tracker/pkg/logs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const defaultBatchSize = 1000
+
+type Config struct {
+	batchSize int
+}
+
+func NewConfig(batchSize int) Config {
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	return Config{batchSize: batchSize}
+}
+
+// Table describes how upstream rows are merged into a table. Rows are matched
+// on Key, the upstream id; id and created_at are only written on insert.
+type Table struct {
+	Name    string
+	Key     string
+	Columns []string
+}
+
+type Writer struct {
+	db        *pgxpool.Pool
+	batchSize int
+}
+
+func NewWriter(db *pgxpool.Pool, config Config) *Writer {
+	return &Writer{db: db, batchSize: config.batchSize}
+}
+
+// Write splits rows into pages of the configured batch size. Each page is
+// copied into a staging table and merged into table in its own transaction,
+// so a failure only loses the page being written.
+func (w *Writer) Write(ctx context.Context, table Table, rows [][]any) (structs.BatchResult, error) {
+	var result structs.BatchResult
+
+	for offset := 0; offset < len(rows); offset += w.batchSize {
+		end := offset + w.batchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		page, err := w.writePage(ctx, table, rows[offset:end])
+		if err != nil {
+			result.Failed = int64(len(rows) - offset)
+			return result, fmt.Errorf("failed to write %s rows %d-%d: %w", table.Name, offset, end, err)
+		}
+		result.Add(page)
+		logThroughput("Batch page written", table.Name, page)
+	}
+
+	if result.Pages > 1 {
+		logThroughput("Batch written", table.Name, result)
+	}
+
+	return result, nil
+}
+
+func (w *Writer) writePage(ctx context.Context, table Table, rows [][]any) (structs.BatchResult, error) {
+	result := structs.BatchResult{Pages: 1}
+	start := time.Now()
+
+	tx, err := w.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return result, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	staging := "staging_" + table.Name
+	if _, err := tx.Exec(ctx, fmt.Sprintf(
+		`CREATE TEMP TABLE %s (LIKE %s INCLUDING DEFAULTS) ON COMMIT DROP`, staging, table.Name)); err != nil {
+		return result, fmt.Errorf("failed to create staging table: %w", err)
+	}
+
+	result.Rows, err = tx.CopyFrom(ctx, pgx.Identifier{staging}, table.Columns, pgx.CopyFromRows(rows))
+	if err != nil {
+		return result, fmt.Errorf("failed to copy rows: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, table.updateSQL(staging))
+	if err != nil {
+		return result, fmt.Errorf("failed to merge updated rows: %w", err)
+	}
+	result.Updated = tag.RowsAffected()
+
+	tag, err = tx.Exec(ctx, table.insertSQL(staging))
+	if err != nil {
+		return result, fmt.Errorf("failed to merge new rows: %w", err)
+	}
+	result.Inserted = tag.RowsAffected()
+
+	if err := tx.Commit(ctx); err != nil {
+		return result, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// mutable are the columns an upstream refresh may overwrite.
+func (t Table) mutable() []string {
+	var columns []string
+	for _, c := range t.Columns {
+		if c != t.Key && c != "id" && c != "created_at" && c != "updated_at" {
+			columns = append(columns, c)
+		}
+	}
+	return columns
+}
+
+// updateSQL only touches rows whose values actually changed so Updated
+// reflects real changes and updated_at stays meaningful. Duplicate keys
+// within a page are collapsed, the upstream sometimes repeats records.
+// Soft deleted rows are left as they were deleted.
+func (t Table) updateSQL(staging string) string {
+	mutable := t.mutable()
+	set := make([]string, len(mutable))
+	target := make([]string, len(mutable))
+	source := make([]string, len(mutable))
+	for i, c := range mutable {
+		set[i] = fmt.Sprintf("%s = s.%s", c, c)
+		target[i] = "t." + c
+		source[i] = "s." + c
+	}
+
+	return fmt.Sprintf(`
+		UPDATE %[1]s t SET %[3]s, updated_at = now()
+		FROM (SELECT DISTINCT ON (%[4]s) * FROM %[2]s ORDER BY %[4]s) s
+		WHERE t.%[4]s = s.%[4]s AND t.deleted_at IS NULL AND (%[5]s) IS DISTINCT FROM (%[6]s)`,
+		t.Name, staging, strings.Join(set, ", "), t.Key,
+		strings.Join(target, ", "), strings.Join(source, ", "))
+}
+
+// insertSQL skips keys that still exist, soft deleted or not, and the
+// tombstones of purged rows, so manual deletions survive a re-import.
+func (t Table) insertSQL(staging string) string {
+	columns := strings.Join(t.Columns, ", ")
+
+	return fmt.Sprintf(`
+		INSERT INTO %[1]s (%[3]s)
+		SELECT DISTINCT ON (s.%[4]s) %[5]s FROM %[2]s s
+		WHERE NOT EXISTS (SELECT 1 FROM %[1]s t WHERE t.%[4]s = s.%[4]s)
+		AND NOT EXISTS (SELECT 1 FROM tombstones d WHERE d.resource = '%[1]s' AND d.upstream_key = s.%[4]s::TEXT)
+		ORDER BY s.%[4]s`,
+		t.Name, staging, columns, t.Key, "s."+strings.Join(t.Columns, ", s."))
+}
+
+func logThroughput(msg string, table string, result structs.BatchResult) {
+	rate := 0.0
+	if result.Duration > 0 {
+		rate = float64(result.Rows) / result.Duration.Seconds()
+	}
+	logs.DefaultLogger.WithFields(map[string]any{
+		"table":        table,
+		"pages":        result.Pages,
+		"rows":         result.Rows,
+		"inserted":     result.Inserted,
+		"updated":      result.Updated,
+		"duration_ms":  result.Duration.Milliseconds(),
+		"rows_per_sec": int64(rate),
+	}).Info(msg)
+}
+
+// Date converts the upstream dates for COPY, which needs a binary encodable
+// value: "0000-00-00" becomes NULL rather than year 1.
+func Date(d structs.Date) any {
+	if d.IsZero() {
+		return nil
+	}
+	return d.Time
+}
+
+// Text converts the loosely typed upstream fields (null, string or number)
+// into a varchar value.
+func Text(v any) any {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
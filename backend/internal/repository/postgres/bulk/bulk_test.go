@@ -0,0 +1,86 @@
+package bulk
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+var taxTable = Table{
+	Name:    "tax",
+	Key:     "tax_id",
+	Columns: []string{"id", "tax_id", "tax_name", "iata_code", "created_at"},
+}
+
+// squash collapses the whitespace of a statement.
+func squash(sql string) string {
+	return strings.Join(strings.Fields(sql), " ")
+}
+
+func TestMutable(t *testing.T) {
+	table := Table{Name: "t", Key: "k", Columns: []string{"id", "k", "a", "created_at", "b", "updated_at"}}
+	if got, want := table.mutable(), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("mutable = %v, want %v", got, want)
+	}
+}
+
+func TestUpdateSQL(t *testing.T) {
+	want := `UPDATE tax t SET tax_name = s.tax_name, iata_code = s.iata_code, updated_at = now() ` +
+		`FROM (SELECT DISTINCT ON (tax_id) * FROM staging_tax ORDER BY tax_id) s ` +
+		`WHERE t.tax_id = s.tax_id AND t.deleted_at IS NULL ` +
+		`AND (t.tax_name, t.iata_code) IS DISTINCT FROM (s.tax_name, s.iata_code)`
+	if got := squash(taxTable.updateSQL("staging_tax")); got != want {
+		t.Errorf("updateSQL =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestInsertSQL(t *testing.T) {
+	want := `INSERT INTO tax (id, tax_id, tax_name, iata_code, created_at) ` +
+		`SELECT DISTINCT ON (s.tax_id) s.id, s.tax_id, s.tax_name, s.iata_code, s.created_at FROM staging_tax s ` +
+		`WHERE NOT EXISTS (SELECT 1 FROM tax t WHERE t.tax_id = s.tax_id) ` +
+		`AND NOT EXISTS (SELECT 1 FROM tombstones d WHERE d.resource = 'tax' AND d.upstream_key = s.tax_id::TEXT) ` +
+		`ORDER BY s.tax_id`
+	if got := squash(taxTable.insertSQL("staging_tax")); got != want {
+		t.Errorf("insertSQL =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestNewConfig(t *testing.T) {
+	for batchSize, want := range map[int]int{-1: defaultBatchSize, 0: defaultBatchSize, 1: 1, 250: 250} {
+		if got := NewConfig(batchSize).batchSize; got != want {
+			t.Errorf("NewConfig(%d).batchSize = %d, want %d", batchSize, got, want)
+		}
+	}
+}
+
+func TestDate(t *testing.T) {
+	if got := Date(structs.Date{}); got != nil {
+		t.Errorf("Date of the zero date = %v, want nil", got)
+	}
+	day := time.Date(2023, 4, 19, 0, 0, 0, 0, time.UTC)
+	if got := Date(structs.NewDate(day)); got != day {
+		t.Errorf("Date = %v, want %v", got, day)
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		in   any
+		want any
+	}{
+		{nil, nil},
+		{"", ""},
+		{"LIS", "LIS"},
+		{float64(351), "351"},
+		{2.5, "2.5"},
+		{true, "true"},
+	}
+	for _, tt := range tests {
+		if got := Text(tt.in); got != tt.want {
+			t.Errorf("Text(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package bulk
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/jackc/pgx/v5"
+)
+
+// Caps on the listed parts of a diff.
+const (
+	maxDiffChanges = 1000
+	maxDiffKeys    = 100
+)
+
+// Diff compares rows with table the way Write would merge them, without
+// writing: the rows are staged in a transaction that is rolled back.
+func (w *Writer) Diff(ctx context.Context, table Table, rows [][]any) (structs.IngestionDiff, error) {
+	diff := structs.IngestionDiff{
+		Fields:      map[string]int64{},
+		Changes:     []structs.FieldDiff{},
+		AddedKeys:   []string{},
+		MissingKeys: []string{},
+	}
+
+	tx, err := w.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return diff, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	staging := "staging_" + table.Name
+	incoming := "incoming_" + table.Name
+	if _, err := tx.Exec(ctx, fmt.Sprintf(
+		`CREATE TEMP TABLE %s (LIKE %s INCLUDING DEFAULTS) ON COMMIT DROP`, staging, table.Name)); err != nil {
+		return diff, fmt.Errorf("failed to create staging table: %w", err)
+	}
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{staging}, table.Columns, pgx.CopyFromRows(rows)); err != nil {
+		return diff, fmt.Errorf("failed to copy rows: %w", err)
+	}
+	// Duplicate keys collapse as they do in the merge.
+	if _, err := tx.Exec(ctx, fmt.Sprintf(
+		`CREATE TEMP TABLE %[1]s ON COMMIT DROP AS SELECT DISTINCT ON (%[3]s) * FROM %[2]s ORDER BY %[3]s`,
+		incoming, staging, table.Key)); err != nil {
+		return diff, fmt.Errorf("failed to create incoming table: %w", err)
+	}
+	// Deleted rows are neither updated nor re-inserted by the merge.
+	tag, err := tx.Exec(ctx, fmt.Sprintf(`
+		DELETE FROM %[2]s s
+		WHERE EXISTS (SELECT 1 FROM %[1]s t WHERE t.%[3]s = s.%[3]s AND t.deleted_at IS NOT NULL)
+		OR EXISTS (SELECT 1 FROM tombstones d WHERE d.resource = '%[1]s' AND d.upstream_key = s.%[3]s::TEXT)`,
+		table.Name, incoming, table.Key))
+	if err != nil {
+		return diff, fmt.Errorf("failed to skip deleted rows: %w", err)
+	}
+	diff.Tombstoned = tag.RowsAffected()
+
+	mutable := table.mutable()
+	target := "t." + strings.Join(mutable, ", t.")
+	source := "s." + strings.Join(mutable, ", s.")
+	err = tx.QueryRow(ctx, fmt.Sprintf(`
+		SELECT
+			(SELECT count(*) FROM %[2]s s WHERE NOT EXISTS (SELECT 1 FROM %[1]s t WHERE t.%[3]s = s.%[3]s)),
+			(SELECT count(*) FROM %[2]s s JOIN %[1]s t ON t.%[3]s = s.%[3]s WHERE (%[4]s) IS DISTINCT FROM (%[5]s)),
+			(SELECT count(*) FROM %[2]s s JOIN %[1]s t ON t.%[3]s = s.%[3]s WHERE (%[4]s) IS NOT DISTINCT FROM (%[5]s)),
+			(SELECT count(*) FROM %[1]s t WHERE t.deleted_at IS NULL AND NOT EXISTS (SELECT 1 FROM %[2]s s WHERE s.%[3]s = t.%[3]s))`,
+		table.Name, incoming, table.Key, target, source)).Scan(&diff.Added, &diff.Changed, &diff.Unchanged, &diff.Missing)
+	if err != nil {
+		return diff, fmt.Errorf("failed to count differences: %w", err)
+	}
+
+	// One row per changed column, compared as text.
+	values := make([]string, len(mutable))
+	for i, c := range mutable {
+		values[i] = fmt.Sprintf("('%[1]s', t.%[1]s::TEXT, s.%[1]s::TEXT)", c)
+	}
+	changes := fmt.Sprintf(`
+		FROM %[2]s s JOIN %[1]s t ON t.%[3]s = s.%[3]s
+		CROSS JOIN LATERAL (VALUES %[4]s) AS f (field, old, new)
+		WHERE f.old IS DISTINCT FROM f.new`,
+		table.Name, incoming, table.Key, strings.Join(values, ", "))
+
+	changed, err := tx.Query(ctx, `SELECT f.field, count(*) `+changes+` GROUP BY f.field`)
+	if err != nil {
+		return diff, fmt.Errorf("failed to count changed fields: %w", err)
+	}
+	var total int64
+	for changed.Next() {
+		var field string
+		var n int64
+		if err := changed.Scan(&field, &n); err != nil {
+			changed.Close()
+			return diff, fmt.Errorf("failed to scan changed field: %w", err)
+		}
+		diff.Fields[field] = n
+		total += n
+	}
+	changed.Close()
+	if err := changed.Err(); err != nil {
+		return diff, fmt.Errorf("failed to iterate over results: %w", err)
+	}
+
+	changed, err = tx.Query(ctx, fmt.Sprintf(`SELECT s.%s::TEXT, f.field, f.old, f.new `+changes+`
+		ORDER BY 1, 2 LIMIT %d`, table.Key, maxDiffChanges))
+	if err != nil {
+		return diff, fmt.Errorf("failed to list changes: %w", err)
+	}
+	for changed.Next() {
+		var c structs.FieldDiff
+		if err := changed.Scan(&c.Key, &c.Field, &c.Old, &c.New); err != nil {
+			changed.Close()
+			return diff, fmt.Errorf("failed to scan change: %w", err)
+		}
+		diff.Changes = append(diff.Changes, c)
+	}
+	changed.Close()
+	if err := changed.Err(); err != nil {
+		return diff, fmt.Errorf("failed to iterate over results: %w", err)
+	}
+
+	diff.AddedKeys, err = keys(ctx, tx, fmt.Sprintf(`
+		SELECT s.%[3]s::TEXT FROM %[2]s s
+		WHERE NOT EXISTS (SELECT 1 FROM %[1]s t WHERE t.%[3]s = s.%[3]s)
+		ORDER BY 1 LIMIT %[4]d`, table.Name, incoming, table.Key, maxDiffKeys))
+	if err != nil {
+		return diff, err
+	}
+	diff.MissingKeys, err = keys(ctx, tx, fmt.Sprintf(`
+		SELECT t.%[3]s::TEXT FROM %[1]s t
+		WHERE t.deleted_at IS NULL AND NOT EXISTS (SELECT 1 FROM %[2]s s WHERE s.%[3]s = t.%[3]s)
+		ORDER BY 1 LIMIT %[4]d`, table.Name, incoming, table.Key, maxDiffKeys))
+	if err != nil {
+		return diff, err
+	}
+
+	diff.Truncated = total > int64(len(diff.Changes)) ||
+		diff.Added > int64(len(diff.AddedKeys)) || diff.Missing > int64(len(diff.MissingKeys))
+	return diff, nil
+}
+
+func keys(ctx context.Context, tx pgx.Tx, sql string) ([]string, error) {
+	rows, err := tx.Query(ctx, sql)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over results: %w", err)
+	}
+	return keys, nil
+}
@@ -0,0 +1,97 @@
+package bulk
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/pgtest"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+)
+
+func TestDiff(t *testing.T) {
+	db := pgtest.New(t)
+	pgtest.Exec(t, db, `
+		INSERT INTO tax (tax_id, tax_name, iata_code) VALUES
+			(1, 'Airport Tax', 'AT'),
+			(2, 'Security Fee', 'SF'),
+			(3, 'Fuel Surcharge', NULL),
+			(4, 'Noise Charge', 'NC'),
+			(5, 'Departure Tax', 'DT')`)
+
+	now := time.Now()
+	tax := func(id int, name string, iata any) []any {
+		return []any{uuid.New(), id, name, iata, now}
+	}
+	rows := [][]any{
+		tax(1, "Airport Tax", "AT"),       // unchanged
+		tax(2, "Security Charge", "SF"),   // changed field
+		tax(3, "Fuel Surcharge", "FS"),    // field added, NULL before
+		tax(4, "Noise Charge", nil),       // field removed, NULL now
+		tax(6, "Passenger Service", "PS"), // new row
+		tax(6, "Passenger Service", "PS"), // repeated upstream
+	}
+
+	diff, err := NewWriter(db, NewConfig(0)).Diff(context.Background(), taxTable, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := func(s string) *string { return &s }
+	want := structs.IngestionDiff{
+		Added:     1,
+		Changed:   3,
+		Unchanged: 1,
+		Missing:   1,
+		Fields:    map[string]int64{"tax_name": 1, "iata_code": 2},
+		Changes: []structs.FieldDiff{
+			{Key: "2", Field: "tax_name", Old: text("Security Fee"), New: text("Security Charge")},
+			{Key: "3", Field: "iata_code", Old: nil, New: text("FS")},
+			{Key: "4", Field: "iata_code", Old: text("NC"), New: nil},
+		},
+		AddedKeys:   []string{"6"},
+		MissingKeys: []string{"5"},
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("Diff =\n%+v\nwant\n%+v", diff, want)
+	}
+
+	// A diff writes nothing.
+	var names []string
+	r, err := db.Query(context.Background(), `SELECT tax_name FROM tax ORDER BY tax_id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for r.Next() {
+		var name string
+		if err := r.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	r.Close()
+	if len(names) != 5 || names[1] != "Security Fee" {
+		t.Errorf("tax rows after a diff = %v", names)
+	}
+}
+
+func TestDiffSkipsDeleted(t *testing.T) {
+	db := pgtest.New(t)
+	pgtest.Exec(t, db, `INSERT INTO tax (tax_id, tax_name, deleted_at) VALUES (1, 'Airport Tax', NOW())`)
+	pgtest.Exec(t, db, `INSERT INTO tombstones (resource, upstream_key, deleted_at) VALUES ('tax', '2', NOW())`)
+
+	now := time.Now()
+	rows := [][]any{
+		{uuid.New(), 1, "Airport Tax (renamed)", nil, now},
+		{uuid.New(), 2, "Purged Tax", nil, now},
+	}
+	diff, err := NewWriter(db, NewConfig(0)).Diff(context.Background(), taxTable, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.Tombstoned != 2 || diff.Added != 0 || diff.Changed != 0 || diff.Missing != 0 {
+		t.Errorf("Diff = %+v, want both rows tombstoned", diff)
+	}
+}
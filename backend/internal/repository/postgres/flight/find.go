@@ -0,0 +1,132 @@
+package flight
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/jackc/pgx/v5"
+)
+
+const flightColumns = `
+	id, flight_date, COALESCE(flight_number, ''), flight_iata, COALESCE(flight_icao, ''),
+	COALESCE(status, ''), COALESCE(airline_name, ''), COALESCE(airline_iata, ''), COALESCE(airline_icao, ''),
+	dep_iata, COALESCE(dep_icao, ''), COALESCE(dep_airport, ''), COALESCE(dep_timezone, ''),
+	COALESCE(dep_terminal, ''), COALESCE(dep_gate, ''), dep_delay, dep_scheduled, dep_estimated, dep_actual,
+	arr_iata, COALESCE(arr_icao, ''), COALESCE(arr_airport, ''), COALESCE(arr_timezone, ''),
+	COALESCE(arr_terminal, ''), COALESCE(arr_gate, ''), COALESCE(arr_baggage, ''),
+	arr_delay, arr_scheduled, arr_estimated, arr_actual,
+	COALESCE(codeshare_airline_iata, ''), COALESCE(codeshare_airline_icao, ''),
+	COALESCE(codeshare_flight_iata, ''), COALESCE(codeshare_flight_icao, ''),
+	COALESCE(aircraft_iata, ''), COALESCE(aircraft_icao, ''), COALESCE(aircraft_registration, ''),
+	sequence, first_seen, last_seen`
+
+func scanFlight(row pgx.Row) (structs.Flight, error) {
+	var f structs.Flight
+	var status string
+	var c structs.FlightCodeshare
+	var a structs.FlightAircraft
+	err := row.Scan(
+		&f.ID, &f.FlightDate, &f.FlightNumber, &f.FlightIata, &f.FlightIcao,
+		&status, &f.AirlineName, &f.AirlineIata, &f.AirlineIcao,
+		&f.Departure.Iata, &f.Departure.Icao, &f.Departure.Airport, &f.Departure.Timezone,
+		&f.Departure.Terminal, &f.Departure.Gate, &f.Departure.Delay,
+		&f.Departure.Scheduled, &f.Departure.Estimated, &f.Departure.Actual,
+		&f.Arrival.Iata, &f.Arrival.Icao, &f.Arrival.Airport, &f.Arrival.Timezone,
+		&f.Arrival.Terminal, &f.Arrival.Gate, &f.Arrival.Baggage,
+		&f.Arrival.Delay, &f.Arrival.Scheduled, &f.Arrival.Estimated, &f.Arrival.Actual,
+		&c.AirlineIata, &c.AirlineIcao, &c.FlightIata, &c.FlightIcao,
+		&a.Iata, &a.Icao, &a.Registration,
+		&f.Sequence, &f.FirstSeen, &f.LastSeen,
+	)
+	if err != nil {
+		return f, err
+	}
+	f.Status = structs.FlightStatus(status)
+	if c.FlightIata != "" {
+		f.Codeshare = &c
+	}
+	if a != (structs.FlightAircraft{}) {
+		f.Aircraft = &a
+	}
+	return f, nil
+}
+
+// FindFlights returns the stored flights matching filter, ordered by
+// scheduled departure.
+func (r *FlightRepository) FindFlights(ctx context.Context, filter structs.FlightFilter) ([]structs.Flight, error) {
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, strings.Replace(condition, "?", "$"+strconv.Itoa(len(args)), 1))
+	}
+	switch {
+	case !filter.Until.IsZero():
+		if !filter.Date.IsZero() {
+			where("flight_date >= ?", filter.Date)
+		}
+		where("flight_date <= ?", filter.Until)
+	case !filter.Date.IsZero():
+		where("flight_date = ?", filter.Date)
+	}
+	if filter.FlightIata != "" {
+		where("flight_iata = ?", strings.ToUpper(filter.FlightIata))
+	}
+	if filter.AirlineIata != "" {
+		where("airline_iata = ?", strings.ToUpper(filter.AirlineIata))
+	}
+	if filter.DepIata != "" {
+		where("dep_iata = ?", strings.ToUpper(filter.DepIata))
+	}
+	if filter.ArrIata != "" {
+		where("arr_iata = ?", strings.ToUpper(filter.ArrIata))
+	}
+	if filter.OperatingIata != "" {
+		where("codeshare_flight_iata = ?", strings.ToUpper(filter.OperatingIata))
+	}
+	if filter.OperatedOnly {
+		conditions = append(conditions, "codeshare_flight_iata IS NULL")
+	}
+
+	query := "SELECT " + flightColumns + " FROM flight"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY dep_scheduled NULLS LAST, flight_iata"
+	if filter.Limit > 0 {
+		query += " LIMIT " + strconv.Itoa(filter.Limit)
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	flights := []structs.Flight{}
+	for rows.Next() {
+		f, err := scanFlight(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan flight: %w", err)
+		}
+		flights = append(flights, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over results: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return flights, nil
+}
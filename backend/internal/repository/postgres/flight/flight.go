@@ -0,0 +1,355 @@
+package flight
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/watch"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const insertFlight = `
+	INSERT INTO flight (
+		flight_date, flight_number, flight_iata, flight_icao, status,
+		airline_name, airline_iata, airline_icao,
+		dep_iata, dep_icao, dep_airport, dep_timezone, dep_terminal, dep_gate,
+		dep_delay, dep_scheduled, dep_estimated, dep_actual,
+		arr_iata, arr_icao, arr_airport, arr_timezone, arr_terminal, arr_gate, arr_baggage,
+		arr_delay, arr_scheduled, arr_estimated, arr_actual,
+		codeshare_airline_iata, codeshare_airline_icao, codeshare_flight_iata, codeshare_flight_icao,
+		aircraft_iata, aircraft_icao, aircraft_registration
+	) VALUES (
+		$1, NULLIF($2, ''), $3, NULLIF($4, ''), NULLIF($5, ''),
+		NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''),
+		$9, NULLIF($10, ''), NULLIF($11, ''), NULLIF($12, ''), NULLIF($13, ''), NULLIF($14, ''),
+		$15, $16, $17, $18,
+		$19, NULLIF($20, ''), NULLIF($21, ''), NULLIF($22, ''), NULLIF($23, ''), NULLIF($24, ''), NULLIF($25, ''),
+		$26, $27, $28, $29,
+		NULLIF($30, ''), NULLIF($31, ''), NULLIF($32, ''), NULLIF($33, ''),
+		NULLIF($34, ''), NULLIF($35, ''), NULLIF($36, '')
+	)
+	ON CONFLICT (flight_date, flight_iata, dep_iata) DO NOTHING
+	RETURNING id`
+
+// updateFlight refreshes the fields that change while a flight is tracked
+// and bumps sequence when its times moved. The right-hand sides read the row
+// as it was before the update.
+const updateFlight = `
+	UPDATE flight SET
+		status = NULLIF($4, ''),
+		dep_terminal = NULLIF($5, ''), dep_gate = NULLIF($6, ''), dep_delay = $7,
+		dep_scheduled = $8, dep_estimated = $9, dep_actual = $10,
+		arr_terminal = NULLIF($11, ''), arr_gate = NULLIF($12, ''), arr_baggage = NULLIF($13, ''),
+		arr_delay = $14, arr_scheduled = $15, arr_estimated = $16, arr_actual = $17,
+		aircraft_iata = COALESCE(NULLIF($18, ''), aircraft_iata),
+		aircraft_icao = COALESCE(NULLIF($19, ''), aircraft_icao),
+		aircraft_registration = COALESCE(NULLIF($20, ''), aircraft_registration),
+		sequence = sequence + CASE WHEN
+			dep_scheduled IS DISTINCT FROM $8 OR dep_estimated IS DISTINCT FROM $9 OR dep_actual IS DISTINCT FROM $10 OR
+			arr_scheduled IS DISTINCT FROM $15 OR arr_estimated IS DISTINCT FROM $16 OR arr_actual IS DISTINCT FROM $17
+			THEN 1 ELSE 0 END,
+		last_seen = NOW()
+	WHERE flight_date = $1 AND flight_iata = $2 AND dep_iata = $3`
+
+// selectLiveFields reads the fields change notifications are raised for,
+// locking the row until the update that follows.
+const selectLiveFields = `
+	SELECT COALESCE(status, ''), COALESCE(dep_gate, ''), COALESCE(arr_gate, ''), dep_delay, arr_delay
+	FROM flight
+	WHERE flight_date = $1 AND flight_iata = $2 AND dep_iata = $3
+	FOR UPDATE`
+
+const upsertRoute = `
+	INSERT INTO route (dep_iata, arr_iata, airline_iata, flights)
+	VALUES ($1, $2, $3, 1)
+	ON CONFLICT (dep_iata, arr_iata, airline_iata)
+	DO UPDATE SET flights = route.flights + 1, last_seen = NOW()`
+
+type FlightRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepositoryFlight(db *pgxpool.Pool) *FlightRepository {
+	return &FlightRepository{db: db}
+}
+
+// RecordFlights stores a page of observed flights. New operated legs add one
+// to the flights count of their route, so the route network is maintained
+// incrementally; re-observing a leg only refreshes its live fields, and the
+// ones that changed are reported in the result. The webhook deliveries notify
+// makes of the changes are queued in the same transaction.
+func (r *FlightRepository) RecordFlights(ctx context.Context, flights []structs.Flight, notify structs.Notifier) (structs.FlightIngestion, error) {
+	result := structs.FlightIngestion{Observed: len(flights)}
+
+	valid := make([]structs.Flight, 0, len(flights))
+	for _, f := range flights {
+		f.FlightIata = strings.ToUpper(f.FlightIata)
+		f.Departure.Iata = strings.ToUpper(f.Departure.Iata)
+		f.Arrival.Iata = strings.ToUpper(f.Arrival.Iata)
+		f.AirlineIata = strings.ToUpper(f.AirlineIata)
+		if f.FlightDate.IsZero() || f.FlightIata == "" || f.Departure.Iata == "" || f.Arrival.Iata == "" {
+			result.Skipped++
+			continue
+		}
+		valid = append(valid, f)
+	}
+	if len(valid) == 0 {
+		return result, nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return result, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	inserts := &pgx.Batch{}
+	for _, f := range valid {
+		inserts.Queue(insertFlight,
+			f.FlightDate, f.FlightNumber, f.FlightIata, f.FlightIcao, string(f.Status),
+			f.AirlineName, f.AirlineIata, f.AirlineIcao,
+			f.Departure.Iata, f.Departure.Icao, f.Departure.Airport, f.Departure.Timezone,
+			f.Departure.Terminal, f.Departure.Gate,
+			f.Departure.Delay, f.Departure.Scheduled, f.Departure.Estimated, f.Departure.Actual,
+			f.Arrival.Iata, f.Arrival.Icao, f.Arrival.Airport, f.Arrival.Timezone,
+			f.Arrival.Terminal, f.Arrival.Gate, f.Arrival.Baggage,
+			f.Arrival.Delay, f.Arrival.Scheduled, f.Arrival.Estimated, f.Arrival.Actual,
+			codeshare(f).AirlineIata, codeshare(f).AirlineIcao, codeshare(f).FlightIata, codeshare(f).FlightIcao,
+			aircraft(f).Iata, aircraft(f).Icao, aircraft(f).Registration)
+	}
+
+	inserted := make([]bool, len(valid))
+	results := tx.SendBatch(ctx, inserts)
+	for i := range valid {
+		var id uuid.UUID
+		err := results.QueryRow().Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			results.Close()
+			return result, fmt.Errorf("failed to insert flight %s: %w", valid[i].FlightIata, err)
+		}
+		inserted[i] = true
+	}
+	if err := results.Close(); err != nil {
+		return result, fmt.Errorf("failed to insert flights: %w", err)
+	}
+
+	previous := &pgx.Batch{}
+	for i, f := range valid {
+		if !inserted[i] {
+			previous.Queue(selectLiveFields, f.FlightDate, f.FlightIata, f.Departure.Iata)
+		}
+	}
+	if previous.Len() > 0 {
+		results := tx.SendBatch(ctx, previous)
+		for i, f := range valid {
+			if inserted[i] {
+				continue
+			}
+			var before liveFields
+			err := results.QueryRow().Scan(&before.status, &before.depGate, &before.arrGate, &before.depDelay, &before.arrDelay)
+			if err != nil {
+				results.Close()
+				return result, fmt.Errorf("failed to read flight %s: %w", f.FlightIata, err)
+			}
+			if changes := before.diff(f); len(changes) > 0 {
+				result.Changes = append(result.Changes, structs.FlightChange{Flight: f, Changes: changes})
+			}
+		}
+		if err := results.Close(); err != nil {
+			return result, fmt.Errorf("failed to read flights: %w", err)
+		}
+	}
+
+	updates := &pgx.Batch{}
+	for i, f := range valid {
+		if !inserted[i] {
+			updates.Queue(updateFlight,
+				f.FlightDate, f.FlightIata, f.Departure.Iata, string(f.Status),
+				f.Departure.Terminal, f.Departure.Gate, f.Departure.Delay,
+				f.Departure.Scheduled, f.Departure.Estimated, f.Departure.Actual,
+				f.Arrival.Terminal, f.Arrival.Gate, f.Arrival.Baggage, f.Arrival.Delay,
+				f.Arrival.Scheduled, f.Arrival.Estimated, f.Arrival.Actual,
+				aircraft(f).Iata, aircraft(f).Icao, aircraft(f).Registration)
+			result.Updated++
+			continue
+		}
+
+		result.Inserted++
+		if f.Codeshare == nil {
+			updates.Queue(upsertRoute, f.Departure.Iata, f.Arrival.Iata, f.AirlineIata)
+			result.Routes++
+		}
+	}
+	if updates.Len() > 0 {
+		if err := tx.SendBatch(ctx, updates).Close(); err != nil {
+			return result, fmt.Errorf("failed to update flights and routes: %w", err)
+		}
+	}
+
+	if len(result.Changes) > 0 && notify != nil {
+		deliveries, err := notify(result.Changes, func(kinds []string, values []string) ([]structs.WebhookMatch, error) {
+			return watch.MatchWebhooks(ctx, tx, kinds, values)
+		})
+		if err != nil {
+			return result, fmt.Errorf("failed to make deliveries: %w", err)
+		}
+		if err := watch.EnqueueDeliveries(ctx, tx, deliveries); err != nil {
+			return result, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return result, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetRoutesSince returns the routes observed at or after since; a zero since
+// returns the whole network.
+func (r *FlightRepository) GetRoutesSince(ctx context.Context, since time.Time) ([]structs.Route, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT dep_iata, arr_iata, airline_iata, flights, first_seen, last_seen
+		FROM route
+		WHERE last_seen >= $1
+		ORDER BY last_seen`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	var routes []structs.Route
+	for rows.Next() {
+		var route structs.Route
+		err := rows.Scan(&route.DepIata, &route.ArrIata, &route.AirlineIata,
+			&route.Flights, &route.FirstSeen, &route.LastSeen)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan route: %w", err)
+		}
+		routes = append(routes, route)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over results: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return routes, nil
+}
+
+// GetScheduledLegs returns the operated legs dated between from and until,
+// inclusive, that leave one of the given airports and have both scheduled
+// times. Codeshare duplicates are left out.
+func (r *FlightRepository) GetScheduledLegs(ctx context.Context, from time.Time, until time.Time, departures []string) ([]structs.Flight, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, flight_date, flight_iata, COALESCE(airline_iata, ''),
+		       dep_iata, COALESCE(dep_timezone, ''), dep_scheduled,
+		       arr_iata, COALESCE(arr_timezone, ''), arr_scheduled
+		FROM flight
+		WHERE flight_date BETWEEN $1 AND $2
+		  AND dep_iata = ANY($3)
+		  AND dep_scheduled IS NOT NULL AND arr_scheduled IS NOT NULL
+		  AND codeshare_flight_iata IS NULL
+		ORDER BY dep_scheduled`, from, until, departures)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	var flights []structs.Flight
+	for rows.Next() {
+		var f structs.Flight
+		err := rows.Scan(&f.ID, &f.FlightDate, &f.FlightIata, &f.AirlineIata,
+			&f.Departure.Iata, &f.Departure.Timezone, &f.Departure.Scheduled,
+			&f.Arrival.Iata, &f.Arrival.Timezone, &f.Arrival.Scheduled)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan flight: %w", err)
+		}
+		flights = append(flights, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over results: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return flights, nil
+}
+
+// liveFields is the stored state of the fields watched for changes.
+type liveFields struct {
+	status   string
+	depGate  string
+	arrGate  string
+	depDelay *int
+	arrDelay *int
+}
+
+// diff lists the watched fields f reports a new value for. A value the feed
+// dropped is not a change worth notifying.
+func (l liveFields) diff(f structs.Flight) []structs.FieldChange {
+	var changes []structs.FieldChange
+	text := func(field string, old string, new string) {
+		if new != "" && new != old {
+			change := structs.FieldChange{Field: field, New: new}
+			if old != "" {
+				change.Old = old
+			}
+			changes = append(changes, change)
+		}
+	}
+	number := func(field string, old *int, new *int) {
+		if new != nil && (old == nil || *old != *new) {
+			change := structs.FieldChange{Field: field, New: *new}
+			if old != nil {
+				change.Old = *old
+			}
+			changes = append(changes, change)
+		}
+	}
+	text("status", l.status, string(f.Status))
+	text("departure_gate", l.depGate, f.Departure.Gate)
+	text("arrival_gate", l.arrGate, f.Arrival.Gate)
+	number("departure_delay", l.depDelay, f.Departure.Delay)
+	number("arrival_delay", l.arrDelay, f.Arrival.Delay)
+	return changes
+}
+
+func codeshare(f structs.Flight) structs.FlightCodeshare {
+	if f.Codeshare == nil {
+		return structs.FlightCodeshare{}
+	}
+	return *f.Codeshare
+}
+
+func aircraft(f structs.Flight) structs.FlightAircraft {
+	if f.Aircraft == nil {
+		return structs.FlightAircraft{}
+	}
+	return *f.Aircraft
+}
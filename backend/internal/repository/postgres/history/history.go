@@ -0,0 +1,90 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Source is what a single row read of table selects from: the table itself,
+// or its version at structs.AsOf(ctx) rebuilt from reference_history. The
+// read must pass the row id as $1; a row that did not exist yet, or was
+// purged, comes back with a NULL id and so is not found. So does a row
+// soft deleted since, unless ctx asks for deleted rows.
+func Source(ctx context.Context, table string) string {
+	asOf, ok := structs.AsOf(ctx)
+	if !ok {
+		return table
+	}
+	return fmt.Sprintf(`(
+		SELECT r.* FROM (
+			SELECT after FROM reference_history
+			WHERE resource = '%[1]s' AND row_id = $1 AND changed_at <= '%[2]s' AND %[3]s
+			ORDER BY changed_at DESC, id DESC LIMIT 1
+		) h, jsonb_populate_record(NULL::%[1]s, COALESCE(h.after, '{}')) r
+	) AS %[1]s`, table, asOf.UTC().Format(time.RFC3339Nano), current(ctx, table, "$1"))
+}
+
+// current is the condition that hides the history of a row that is soft
+// deleted or purged now, unless ctx asks for deleted rows with
+// structs.WithDeleted. id is the placeholder of the row id.
+func current(ctx context.Context, table string, id string) string {
+	if structs.IncludeDeleted(ctx) {
+		return "TRUE"
+	}
+	return fmt.Sprintf("EXISTS (SELECT 1 FROM %s WHERE id = %s AND deleted_at IS NULL)", table, id)
+}
+
+type HistoryRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepositoryHistory(db *pgxpool.Pool) *HistoryRepository {
+	return &HistoryRepository{db: db}
+}
+
+// GetHistory returns the latest versions of the row id of table, newest
+// first. A row that is soft deleted or purged has none, unless ctx asks for
+// deleted rows.
+func (r *HistoryRepository) GetHistory(ctx context.Context, table string, id uuid.UUID, limit int) ([]structs.HistoryEntry, error) {
+	entries := []structs.HistoryEntry{}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return entries, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, resource, row_id, operation,
+		       COALESCE(before, 'null'), COALESCE(after, 'null'), changed_at
+		FROM reference_history
+		WHERE resource = $1 AND row_id = $2 AND `+current(ctx, table, "$2")+`
+		ORDER BY changed_at DESC, id DESC
+		LIMIT $3`, table, id, limit)
+	if err != nil {
+		return entries, fmt.Errorf("failed to query history: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e structs.HistoryEntry
+		if err := rows.Scan(&e.ID, &e.Resource, &e.RowID, &e.Operation, &e.Before, &e.After, &e.ChangedAt); err != nil {
+			return entries, fmt.Errorf("failed to scan history: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return entries, fmt.Errorf("failed to iterate over results: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return entries, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return entries, nil
+}
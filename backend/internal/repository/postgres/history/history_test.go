@@ -0,0 +1,116 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/pgtest"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestSource(t *testing.T) {
+	asOf := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	live := "EXISTS (SELECT 1 FROM airport WHERE id = $1 AND deleted_at IS NULL)"
+
+	if got := Source(context.Background(), "airport"); got != "airport" {
+		t.Errorf("Source without as_of = %q, want the table", got)
+	}
+
+	got := Source(structs.WithAsOf(context.Background(), asOf), "airport")
+	for _, want := range []string{"resource = 'airport'", "changed_at <= '2024-05-01T12:00:00Z'", live, "NULL::airport"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Source as of %s lacks %q:\n%s", asOf, want, got)
+		}
+	}
+
+	got = Source(structs.WithDeleted(structs.WithAsOf(context.Background(), asOf)), "airport")
+	if strings.Contains(got, live) {
+		t.Errorf("Source with deleted rows hides deleted rows:\n%s", got)
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	if got := current(context.Background(), "city", "$2"); got != "EXISTS (SELECT 1 FROM city WHERE id = $2 AND deleted_at IS NULL)" {
+		t.Errorf("current = %q", got)
+	}
+	if got := current(structs.WithDeleted(context.Background()), "city", "$2"); got != "TRUE" {
+		t.Errorf("current with deleted rows = %q, want TRUE", got)
+	}
+}
+
+func TestSourceAsOf(t *testing.T) {
+	db := pgtest.New(t)
+	id := uuid.New()
+	day := func(month time.Month) time.Time { return time.Date(2024, month, 1, 0, 0, 0, 0, time.UTC) }
+
+	// write runs sql on the tax row and dates the version it records.
+	write := func(sql string, at time.Time) {
+		t.Helper()
+		pgtest.Exec(t, db, sql, id)
+		pgtest.Exec(t, db, `
+			UPDATE reference_history SET changed_at = $2
+			WHERE id = (SELECT MAX(id) FROM reference_history WHERE row_id = $1)`, id, at)
+	}
+	write(`INSERT INTO tax (id, tax_id, tax_name, iata_code) VALUES ($1, 1, 'Airport Tax', 'LIS')`, day(1))
+	write(`UPDATE tax SET tax_name = 'Airport Fee' WHERE id = $1`, day(3))
+
+	tests := []struct {
+		name string
+		asOf time.Time
+		want string
+	}{
+		{"before it was created", day(1).Add(-time.Second), ""},
+		{"when it was created", day(1), "Airport Tax"},
+		{"between versions", day(2), "Airport Tax"},
+		{"after the update", day(4), "Airport Fee"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := taxName(t, db, structs.WithAsOf(context.Background(), tt.asOf), id); got != tt.want {
+				t.Errorf("tax as of %s = %q, want %q", tt.asOf, got, tt.want)
+			}
+		})
+	}
+
+	write(`UPDATE tax SET deleted_at = NOW() WHERE id = $1`, day(5))
+	asOf := structs.WithAsOf(context.Background(), day(4))
+	if got := taxName(t, db, asOf, id); got != "" {
+		t.Errorf("the past of a deleted row = %q, want it hidden", got)
+	}
+	if got := taxName(t, db, structs.WithDeleted(asOf), id); got != "Airport Fee" {
+		t.Errorf("the past of a deleted row with deleted rows = %q, want Airport Fee", got)
+	}
+
+	write(`DELETE FROM tax WHERE id = $1`, day(6))
+	if got := taxName(t, db, structs.WithDeleted(structs.WithAsOf(context.Background(), day(7))), id); got != "" {
+		t.Errorf("a purged row = %q, want it not found", got)
+	}
+}
+
+// taxName reads the tax row id the way the repositories do, returning ""
+// when it is not found.
+func taxName(t *testing.T, db *pgxpool.Pool, ctx context.Context, id uuid.UUID) string {
+	t.Helper()
+	var found *uuid.UUID
+	var name, iata *string
+	err := db.QueryRow(ctx, `SELECT id, tax_name, iata_code FROM `+Source(ctx, "tax")+` WHERE id = $1`, id).Scan(&found, &name, &iata)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ""
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found == nil {
+		return ""
+	}
+	if iata == nil || *iata != "LIS" {
+		t.Errorf("iata_code = %v, want the LIS of every version", iata)
+	}
+	return *name
+}
@@ -0,0 +1,186 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const runColumns = `
+	id, dataset, provider, dry_run, status, started_at, finished_at, pages, rows_fetched,
+	rows_inserted, rows_updated, rows_deleted, rows_failed, drift_events, errors`
+
+func scanRun(row pgx.Row) (structs.IngestionRun, error) {
+	var r structs.IngestionRun
+	err := row.Scan(&r.ID, &r.Dataset, &r.Provider, &r.DryRun, &r.Status, &r.StartedAt, &r.FinishedAt, &r.Pages,
+		&r.RowsFetched, &r.RowsInserted, &r.RowsUpdated, &r.RowsDeleted, &r.RowsFailed, &r.DriftEvents, &r.Errors)
+	return r, err
+}
+
+type IngestionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepositoryIngestion(db *pgxpool.Pool) *IngestionRepository {
+	return &IngestionRepository{db: db}
+}
+
+// CreateRun records the start of an import.
+func (r *IngestionRepository) CreateRun(ctx context.Context, dataset string, provider string, dryRun bool) (structs.IngestionRun, error) {
+	var run structs.IngestionRun
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return run, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	run, err = scanRun(tx.QueryRow(ctx, `
+		INSERT INTO ingestion_runs (dataset, provider, dry_run) VALUES ($1, $2, $3)
+		RETURNING`+runColumns, dataset, provider, dryRun))
+	if err != nil {
+		return run, fmt.Errorf("failed to insert ingestion run: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return run, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return run, nil
+}
+
+// FinishRun stores the outcome of an import and the diff it computed.
+func (r *IngestionRepository) FinishRun(ctx context.Context, run structs.IngestionRun, diff *structs.IngestionDiff) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	errs := run.Errors
+	if errs == nil {
+		errs = []string{}
+	}
+	tag, err := tx.Exec(ctx, `
+		UPDATE ingestion_runs SET
+			status = $2, finished_at = NOW(), pages = $3, rows_fetched = $4, rows_inserted = $5,
+			rows_updated = $6, rows_deleted = $7, rows_failed = $8, drift_events = $9, errors = $10,
+			diff = $11
+		WHERE id = $1`,
+		run.ID, run.Status, run.Pages, run.RowsFetched, run.RowsInserted,
+		run.RowsUpdated, run.RowsDeleted, run.RowsFailed, run.DriftEvents, errs, diff)
+	if err != nil {
+		return fmt.Errorf("failed to update ingestion run: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("ingestion run with ID %s not found: %w", run.ID, pgx.ErrNoRows)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetRuns returns the latest runs, newest first.
+func (r *IngestionRepository) GetRuns(ctx context.Context, filter structs.IngestionFilter) ([]structs.IngestionRun, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `SELECT` + runColumns + ` FROM ingestion_runs WHERE TRUE`
+	var args []interface{}
+	if filter.Dataset != "" {
+		args = append(args, filter.Dataset)
+		query += ` AND dataset = $` + strconv.Itoa(len(args))
+	}
+	if filter.DryRun != nil {
+		args = append(args, *filter.DryRun)
+		query += ` AND dry_run = $` + strconv.Itoa(len(args))
+	}
+	query += ` ORDER BY started_at DESC, id`
+	if filter.Limit > 0 {
+		query += ` LIMIT ` + strconv.Itoa(filter.Limit)
+	}
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []structs.IngestionRun{}
+	for rows.Next() {
+		run, err := scanRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan ingestion run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over results: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return runs, nil
+}
+
+func (r *IngestionRepository) GetRun(ctx context.Context, id uuid.UUID) (structs.IngestionRun, error) {
+	var run structs.IngestionRun
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return run, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	run, err = scanRun(tx.QueryRow(ctx, `SELECT`+runColumns+` FROM ingestion_runs WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return run, fmt.Errorf("ingestion run with ID %s not found: %w", id, err)
+		}
+		return run, fmt.Errorf("failed to scan ingestion run: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return run, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return run, nil
+}
+
+// GetRunDiff returns the diff of a run that got far enough to compute one.
+func (r *IngestionRepository) GetRunDiff(ctx context.Context, id uuid.UUID) (structs.IngestionDiff, error) {
+	var diff structs.IngestionDiff
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return diff, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `SELECT diff FROM ingestion_runs WHERE id = $1 AND diff IS NOT NULL`, id).Scan(&diff)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return diff, fmt.Errorf("diff of ingestion run with ID %s not found: %w", id, err)
+		}
+		return diff, fmt.Errorf("failed to read ingestion diff: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return diff, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return diff, nil
+}
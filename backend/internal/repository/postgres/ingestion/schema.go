@@ -0,0 +1,157 @@
+package ingestion
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// GetSchema returns the expected schema of endpoint, without fields when none
+// was learnt yet.
+func (r *IngestionRepository) GetSchema(ctx context.Context, endpoint string) (structs.UpstreamSchema, error) {
+	schema := structs.UpstreamSchema{Endpoint: endpoint, Fields: []structs.FieldSchema{}}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return schema, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT field, type, nullable, updated_at FROM upstream_schemas
+		WHERE endpoint = $1
+		ORDER BY field`, endpoint)
+	if err != nil {
+		return schema, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var f structs.FieldSchema
+		if err := rows.Scan(&f.Field, &f.Type, &f.Nullable, &schema.UpdatedAt); err != nil {
+			return schema, fmt.Errorf("failed to scan field schema: %w", err)
+		}
+		schema.Fields = append(schema.Fields, f)
+	}
+	if err := rows.Err(); err != nil {
+		return schema, fmt.Errorf("failed to iterate over results: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return schema, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return schema, nil
+}
+
+// SaveSchema replaces the expected schema of endpoint.
+func (r *IngestionRepository) SaveSchema(ctx context.Context, endpoint string, fields []structs.FieldSchema) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM upstream_schemas WHERE endpoint = $1`, endpoint); err != nil {
+		return fmt.Errorf("failed to delete schema of %s: %w", endpoint, err)
+	}
+	for _, f := range fields {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO upstream_schemas (endpoint, field, type, nullable) VALUES ($1, $2, $3, $4)`,
+			endpoint, f.Field, f.Type, f.Nullable)
+		if err != nil {
+			return fmt.Errorf("failed to insert schema of %s.%s: %w", endpoint, f.Field, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteSchema forgets the expected schema of endpoint.
+func (r *IngestionRepository) DeleteSchema(ctx context.Context, endpoint string) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `DELETE FROM upstream_schemas WHERE endpoint = $1`, endpoint)
+	if err != nil {
+		return fmt.Errorf("failed to delete schema of %s: %w", endpoint, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("schema of %s not found: %w", endpoint, pgx.ErrNoRows)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *IngestionRepository) CreateDrift(ctx context.Context, drift []structs.SchemaDrift) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, d := range drift {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO schema_drift (run_id, endpoint, field, kind, expected, observed, rows)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			d.RunID, d.Endpoint, d.Field, d.Kind, d.Expected, d.Observed, d.Rows)
+		if err != nil {
+			return fmt.Errorf("failed to insert schema drift: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *IngestionRepository) GetRunDrift(ctx context.Context, runID uuid.UUID) ([]structs.SchemaDrift, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT run_id, endpoint, field, kind, expected, observed, rows FROM schema_drift
+		WHERE run_id = $1
+		ORDER BY field, kind, observed`, runID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	drift := []structs.SchemaDrift{}
+	for rows.Next() {
+		var d structs.SchemaDrift
+		if err := rows.Scan(&d.RunID, &d.Endpoint, &d.Field, &d.Kind, &d.Expected, &d.Observed, &d.Rows); err != nil {
+			return nil, fmt.Errorf("failed to scan schema drift: %w", err)
+		}
+		drift = append(drift, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over results: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return drift, nil
+}
@@ -0,0 +1,281 @@
+package integrity
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// relationship describes one upstream code column and the *_ref column it
+// resolves to. link points the rows of scope at the row their code resolves
+// to now: it clears references whose code no longer matches, then links the
+// rows whose reference differs from their code's, so it is safe to run after
+// every write. Codes are not unique upstream; a code shared by several rows
+// resolves to the live one first, then to the lowest id.
+type relationship struct {
+	name   string
+	table  string
+	alias  string
+	code   string
+	ref    string
+	parent string
+	// keys pairs the code columns of table with the parent columns they
+	// are matched on.
+	keys [][2]string
+	// link holds the statements, with the scope condition as %[1]s.
+	link []string
+}
+
+var relationships = []relationship{
+	{
+		name:   "city.country",
+		table:  "city",
+		alias:  "ct",
+		code:   "country_iso2",
+		ref:    "country_ref",
+		parent: "country",
+		keys:   [][2]string{{"country_iso2", "country_iso_2"}},
+		link: []string{`
+			UPDATE city ct SET country_ref = NULL
+			WHERE ct.country_ref IS NOT NULL AND NOT EXISTS (
+				SELECT 1 FROM country c WHERE c.id = ct.country_ref AND c.country_iso_2 = ct.country_iso2)
+				AND %[1]s`, `
+			UPDATE city ct SET country_ref = c.id
+			FROM (
+				SELECT DISTINCT ON (country_iso_2) id, country_iso_2
+				FROM country
+				ORDER BY country_iso_2, deleted_at IS NOT NULL, id
+			) c
+			WHERE ct.country_iso2 = c.country_iso_2 AND ct.country_ref IS DISTINCT FROM c.id AND %[1]s`},
+	},
+	{
+		name:   "airline.country",
+		table:  "airline",
+		alias:  "a",
+		code:   "country_iso_2",
+		ref:    "country_ref",
+		parent: "country",
+		keys:   [][2]string{{"country_iso_2", "country_iso_2"}},
+		link: []string{`
+			UPDATE airline a SET country_ref = NULL
+			WHERE a.country_ref IS NOT NULL AND NOT EXISTS (
+				SELECT 1 FROM country c WHERE c.id = a.country_ref AND c.country_iso_2 = a.country_iso_2)
+				AND %[1]s`, `
+			UPDATE airline a SET country_ref = c.id
+			FROM (
+				SELECT DISTINCT ON (country_iso_2) id, country_iso_2
+				FROM country
+				ORDER BY country_iso_2, deleted_at IS NOT NULL, id
+			) c
+			WHERE a.country_iso_2 = c.country_iso_2 AND a.country_ref IS DISTINCT FROM c.id AND %[1]s`},
+	},
+	{
+		name:   "airport.city",
+		table:  "airport",
+		alias:  "ap",
+		code:   "city_iata_code",
+		ref:    "city_ref",
+		parent: "city",
+		keys:   [][2]string{{"city_iata_code", "iata_code"}},
+		link: []string{`
+			UPDATE airport ap SET city_ref = NULL
+			WHERE ap.city_ref IS NOT NULL AND NOT EXISTS (
+				SELECT 1 FROM city ct WHERE ct.id = ap.city_ref AND ct.iata_code = ap.city_iata_code)
+				AND %[1]s`, `
+			UPDATE airport ap SET city_ref = ct.id
+			FROM (
+				SELECT DISTINCT ON (iata_code) id, iata_code
+				FROM city
+				ORDER BY iata_code, deleted_at IS NOT NULL, id
+			) ct
+			WHERE ap.city_iata_code = ct.iata_code AND ap.city_ref IS DISTINCT FROM ct.id AND %[1]s`},
+	},
+	{
+		name:   "airport.country",
+		table:  "airport",
+		alias:  "ap",
+		code:   "country_iso2",
+		ref:    "country_ref",
+		parent: "country",
+		keys:   [][2]string{{"country_iso2", "country_iso_2"}},
+		link: []string{`
+			UPDATE airport ap SET country_ref = NULL
+			WHERE ap.country_ref IS NOT NULL AND NOT EXISTS (
+				SELECT 1 FROM country c WHERE c.id = ap.country_ref AND c.country_iso_2 = ap.country_iso2)
+				AND %[1]s`, `
+			UPDATE airport ap SET country_ref = c.id
+			FROM (
+				SELECT DISTINCT ON (country_iso_2) id, country_iso_2
+				FROM country
+				ORDER BY country_iso_2, deleted_at IS NOT NULL, id
+			) c
+			WHERE ap.country_iso2 = c.country_iso_2 AND ap.country_ref IS DISTINCT FROM c.id AND %[1]s`},
+	},
+	{
+		name:   "airplane.airline",
+		table:  "airplane",
+		alias:  "ap",
+		code:   "airline_iata_code",
+		ref:    "airline_ref",
+		parent: "airline",
+		keys:   [][2]string{{"airline_iata_code", "iata_code"}, {"airline_icao_code", "icao_code"}},
+		// Airline codes are reused by defunct carriers, prefer the active one
+		// and fall back to the ICAO code when the IATA code is unknown.
+		link: []string{`
+			UPDATE airplane ap SET airline_ref = NULL
+			WHERE ap.airline_ref IS NOT NULL AND NOT EXISTS (
+				SELECT 1 FROM airline al
+				WHERE al.id = ap.airline_ref
+					AND ((al.iata_code <> '' AND al.iata_code = ap.airline_iata_code)
+						OR (al.icao_code <> '' AND al.icao_code = ap.airline_icao_code)))
+				AND %[1]s`, `
+			UPDATE airplane ap SET airline_ref = al.id
+			FROM (
+				SELECT DISTINCT ON (iata_code) id, iata_code
+				FROM airline
+				WHERE iata_code <> ''
+				ORDER BY iata_code, (status = 'active') DESC, deleted_at IS NOT NULL, airline_id, id
+			) al
+			WHERE ap.airline_iata_code = al.iata_code AND ap.airline_ref IS DISTINCT FROM al.id AND %[1]s`, `
+			UPDATE airplane ap SET airline_ref = al.id
+			FROM (
+				SELECT DISTINCT ON (icao_code) id, icao_code
+				FROM airline
+				WHERE icao_code <> ''
+				ORDER BY icao_code, (status = 'active') DESC, deleted_at IS NOT NULL, airline_id, id
+			) al
+			WHERE ap.airline_icao_code = al.icao_code AND ap.airline_ref IS DISTINCT FROM al.id
+				AND NOT EXISTS (SELECT 1 FROM airline WHERE iata_code <> '' AND iata_code = ap.airline_iata_code)
+				AND %[1]s`},
+	},
+}
+
+// scope is the condition on the rows of rel whose reference a write of the
+// rows $1 of table may change: those rows themselves when table holds the
+// codes, else the rows that point at them or share a code with them.
+func (rel relationship) scope(table string) string {
+	if table == rel.table {
+		return rel.alias + ".id = ANY($1)"
+	}
+	conds := []string{rel.alias + "." + rel.ref + " = ANY($1)"}
+	for _, k := range rel.keys {
+		conds = append(conds, fmt.Sprintf("%s.%s IN (SELECT %s FROM %s WHERE id = ANY($1))", rel.alias, k[0], k[1], rel.parent))
+	}
+	return "(" + strings.Join(conds, " OR ") + ")"
+}
+
+type IntegrityRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepositoryIntegrity(db *pgxpool.Pool) *IntegrityRepository {
+	return &IntegrityRepository{db: db}
+}
+
+func (r *IntegrityRepository) LinkReferences(ctx context.Context) (structs.LinkResult, error) {
+	return r.link(ctx, relationships, func(relationship) string { return "TRUE" })
+}
+
+// LinkRows links the references the write of the rows ids of table may have
+// changed, instead of every row of every relationship.
+func (r *IntegrityRepository) LinkRows(ctx context.Context, table string, ids []uuid.UUID) (structs.LinkResult, error) {
+	var rels []relationship
+	for _, rel := range relationships {
+		if rel.table == table || rel.parent == table {
+			rels = append(rels, rel)
+		}
+	}
+	if len(rels) == 0 || len(ids) == 0 {
+		return structs.LinkResult{}, nil
+	}
+	return r.link(ctx, rels, func(rel relationship) string { return rel.scope(table) }, ids)
+}
+
+func (r *IntegrityRepository) link(ctx context.Context, rels []relationship, scope func(relationship) string, args ...interface{}) (structs.LinkResult, error) {
+	result := make(structs.LinkResult, len(rels))
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return result, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, rel := range rels {
+		for _, stmt := range rel.link {
+			tag, err := tx.Exec(ctx, fmt.Sprintf(stmt, scope(rel)), args...)
+			if err != nil {
+				return result, fmt.Errorf("failed to link %s: %w", rel.name, err)
+			}
+			result[rel.name] += tag.RowsAffected()
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return result, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetIntegrityReport counts linked and orphaned rows per relationship and
+// returns up to limit orphans of each. Rows without a code are not orphans,
+// the provider simply has no value for them.
+func (r *IntegrityRepository) GetIntegrityReport(ctx context.Context, limit int) (structs.IntegrityReport, error) {
+	var report structs.IntegrityReport
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return report, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, rel := range relationships {
+		entry := structs.RelationshipIntegrity{
+			Relationship: rel.name,
+			Column:       rel.table + "." + rel.code,
+			Samples:      []structs.OrphanRow{},
+		}
+
+		err := tx.QueryRow(ctx, fmt.Sprintf(`
+			SELECT COUNT(*) FILTER (WHERE %[2]s IS NOT NULL),
+			       COUNT(*) FILTER (WHERE %[2]s IS NULL AND COALESCE(%[3]s, '') <> '')
+			FROM %[1]s`, rel.table, rel.ref, rel.code)).Scan(&entry.Linked, &entry.Orphaned)
+		if err != nil {
+			return report, fmt.Errorf("failed to count %s: %w", rel.name, err)
+		}
+
+		rows, err := tx.Query(ctx, fmt.Sprintf(`
+			SELECT id, %[3]s FROM %[1]s
+			WHERE %[2]s IS NULL AND COALESCE(%[3]s, '') <> ''
+			ORDER BY %[3]s, id
+			LIMIT $1`, rel.table, rel.ref, rel.code), limit)
+		if err != nil {
+			return report, fmt.Errorf("failed to query %s orphans: %w", rel.name, err)
+		}
+		for rows.Next() {
+			var orphan structs.OrphanRow
+			if err := rows.Scan(&orphan.ID, &orphan.Code); err != nil {
+				rows.Close()
+				return report, fmt.Errorf("failed to scan %s orphan: %w", rel.name, err)
+			}
+			entry.Samples = append(entry.Samples, orphan)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return report, fmt.Errorf("failed to iterate over %s orphans: %w", rel.name, err)
+		}
+
+		report = append(report, entry)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return report, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,123 @@
+package integrity
+
+import (
+	"context"
+	"testing"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/pgtest"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	deletedPT = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	firstPT   = uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	secondPT  = uuid.MustParse("00000000-0000-0000-0000-000000000003")
+	lisbon    = uuid.MustParse("00000000-0000-0000-0000-00000000000a")
+	porto     = uuid.MustParse("00000000-0000-0000-0000-00000000000b")
+	madrid    = uuid.MustParse("00000000-0000-0000-0000-00000000000c")
+)
+
+func seed(t *testing.T) *pgxpool.Pool {
+	db := pgtest.New(t)
+	pgtest.Exec(t, db, `
+		INSERT INTO country (id, country_name, country_iso_2, deleted_at) VALUES
+			($1, 'Portugal (old)', 'PT', NOW()),
+			($2, 'Portugal', 'PT', NULL),
+			($3, 'Portuguese Republic', 'PT', NULL)`, deletedPT, firstPT, secondPT)
+	pgtest.Exec(t, db, `
+		INSERT INTO city (id, city_name, iata_code, country_iso2) VALUES
+			($1, 'Lisbon', 'LIS', 'PT'),
+			($2, 'Porto', 'OPO', 'PT'),
+			($3, 'Madrid', 'MAD', 'ES')`, lisbon, porto, madrid)
+	return db
+}
+
+func countryOf(t *testing.T, db *pgxpool.Pool, city uuid.UUID) *uuid.UUID {
+	t.Helper()
+	var ref *uuid.UUID
+	if err := db.QueryRow(context.Background(), `SELECT country_ref FROM city WHERE id = $1`, city).Scan(&ref); err != nil {
+		t.Fatal(err)
+	}
+	return ref
+}
+
+func TestLinkReferencesSharedCode(t *testing.T) {
+	db := seed(t)
+	r := NewRepositoryIntegrity(db)
+
+	result, err := r.LinkReferences(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result["city.country"] != 2 {
+		t.Errorf("linked %d cities, want 2", result["city.country"])
+	}
+	for _, city := range []uuid.UUID{lisbon, porto} {
+		if ref := countryOf(t, db, city); ref == nil || *ref != firstPT {
+			t.Errorf("city %s links %v, want the live country with the lowest id %s", city, ref, firstPT)
+		}
+	}
+	if ref := countryOf(t, db, madrid); ref != nil {
+		t.Errorf("Madrid links %v, want no country", ref)
+	}
+
+	result, err = r.LinkReferences(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result["city.country"] != 0 {
+		t.Errorf("relinked %d cities, want the links to be stable", result["city.country"])
+	}
+}
+
+func TestLinkRows(t *testing.T) {
+	db := seed(t)
+	r := NewRepositoryIntegrity(db)
+	ctx := context.Background()
+
+	if _, err := r.LinkRows(ctx, "city", []uuid.UUID{lisbon}); err != nil {
+		t.Fatal(err)
+	}
+	if ref := countryOf(t, db, lisbon); ref == nil || *ref != firstPT {
+		t.Errorf("Lisbon links %v, want %s", ref, firstPT)
+	}
+	if ref := countryOf(t, db, porto); ref != nil {
+		t.Errorf("Porto links %v, want it untouched by a write of Lisbon", ref)
+	}
+
+	// Recoding the country Lisbon points at moves Lisbon to the next PT
+	// country and links the cities of the new code; Porto, whose code the
+	// write did not touch, stays unlinked.
+	pgtest.Exec(t, db, `UPDATE country SET country_iso_2 = 'ES' WHERE id = $1`, firstPT)
+	if _, err := r.LinkRows(ctx, "country", []uuid.UUID{firstPT}); err != nil {
+		t.Fatal(err)
+	}
+	if ref := countryOf(t, db, lisbon); ref == nil || *ref != secondPT {
+		t.Errorf("Lisbon links %v, want %s", ref, secondPT)
+	}
+	if ref := countryOf(t, db, madrid); ref == nil || *ref != firstPT {
+		t.Errorf("Madrid links %v, want %s", ref, firstPT)
+	}
+	if ref := countryOf(t, db, porto); ref != nil {
+		t.Errorf("Porto links %v, want it untouched", ref)
+	}
+
+	result, err := r.LinkRows(ctx, "tax", []uuid.UUID{uuid.New()})
+	if err != nil || len(result) != 0 {
+		t.Errorf("LinkRows(tax) = %v, %v, want nothing to link", result, err)
+	}
+}
+
+func TestScope(t *testing.T) {
+	rel := relationships[4]
+	if got, want := rel.scope("airplane"), "ap.id = ANY($1)"; got != want {
+		t.Errorf("scope(airplane) = %q, want %q", got, want)
+	}
+	want := "(ap.airline_ref = ANY($1)" +
+		" OR ap.airline_iata_code IN (SELECT iata_code FROM airline WHERE id = ANY($1))" +
+		" OR ap.airline_icao_code IN (SELECT icao_code FROM airline WHERE id = ANY($1)))"
+	if got := rel.scope("airline"); got != want {
+		t.Errorf("scope(airline) = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,377 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// uniqueViolation is the SQLSTATE of a duplicate key.
+const uniqueViolation = "23505"
+
+var ErrActiveKey = errors.New("a job with the same key is already queued or running")
+
+const jobColumns = `
+	id, kind, key, payload, status, attempts, max_attempts, run_at, last_error,
+	result, output_type, created_at, started_at, finished_at, updated_at`
+
+func scanJob(row pgx.Row) (structs.Job, error) {
+	var j structs.Job
+	err := row.Scan(&j.ID, &j.Kind, &j.Key, &j.Payload, &j.Status, &j.Attempts, &j.MaxAttempts, &j.RunAt,
+		&j.LastError, &j.Result, &j.OutputType, &j.CreatedAt, &j.StartedAt, &j.FinishedAt, &j.UpdatedAt)
+	return j, err
+}
+
+type JobRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepositoryJob(db *pgxpool.Pool) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+// CreateJob queues j. When j has a key and a job with that key is already
+// queued or running, that job is returned instead and created is false.
+func (r *JobRepository) CreateJob(ctx context.Context, j structs.Job) (structs.Job, bool, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return j, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	created := true
+	job, err := scanJob(tx.QueryRow(ctx, `
+		INSERT INTO jobs (kind, key, payload, max_attempts, run_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (key) WHERE status IN ('queued', 'running') DO NOTHING
+		RETURNING`+jobColumns, j.Kind, j.Key, j.Payload, j.MaxAttempts, j.RunAt))
+	if errors.Is(err, pgx.ErrNoRows) {
+		created = false
+		job, err = scanJob(tx.QueryRow(ctx, `
+			SELECT`+jobColumns+` FROM jobs
+			WHERE key = $1 AND status IN ('queued', 'running')`, j.Key))
+	}
+	if err != nil {
+		return j, false, fmt.Errorf("failed to insert job: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return j, false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return job, created, nil
+}
+
+// GetJobs returns the latest jobs, newest first.
+func (r *JobRepository) GetJobs(ctx context.Context, filter structs.JobFilter) ([]structs.Job, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `SELECT` + jobColumns + ` FROM jobs WHERE TRUE`
+	var args []interface{}
+	if filter.Kind != "" {
+		args = append(args, filter.Kind)
+		query += ` AND kind = $` + strconv.Itoa(len(args))
+	}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += ` AND status = $` + strconv.Itoa(len(args))
+	}
+	query += ` ORDER BY created_at DESC, id`
+	if filter.Limit > 0 {
+		query += ` LIMIT ` + strconv.Itoa(filter.Limit)
+	}
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []structs.Job{}
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, j)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over results: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return jobs, nil
+}
+
+func (r *JobRepository) GetJob(ctx context.Context, id uuid.UUID) (structs.Job, error) {
+	var j structs.Job
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return j, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	j, err = scanJob(tx.QueryRow(ctx, `SELECT`+jobColumns+` FROM jobs WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return j, fmt.Errorf("job with ID %s not found: %w", id, err)
+		}
+		return j, fmt.Errorf("failed to scan job: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return j, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return j, nil
+}
+
+// GetJobOutput returns the type and size of the file stored by a succeeded
+// job.
+func (r *JobRepository) GetJobOutput(ctx context.Context, id uuid.UUID) (structs.JobOutput, error) {
+	var out structs.JobOutput
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return out, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		SELECT j.output_type, COALESCE(SUM(octet_length(c.data)), 0)
+		FROM jobs j LEFT JOIN job_output_chunks c ON c.job_id = j.id
+		WHERE j.id = $1 AND j.status = 'succeeded' AND j.output_type IS NOT NULL
+		GROUP BY j.id`, id).Scan(&out.ContentType, &out.Size)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return out, fmt.Errorf("output of job with ID %s not found: %w", id, err)
+		}
+		return out, fmt.Errorf("failed to read job output: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return out, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return out, nil
+}
+
+// StreamJobOutput passes the chunks of the file stored by a job to fn, in
+// order.
+func (r *JobRepository) StreamJobOutput(ctx context.Context, id uuid.UUID, fn func([]byte) error) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `SELECT data FROM job_output_chunks WHERE job_id = $1 ORDER BY seq`, id)
+	if err != nil {
+		return fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return fmt.Errorf("failed to scan job output: %w", err)
+		}
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to iterate over results: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// ClearJobOutput drops the file an earlier attempt of a job held by worker
+// left behind.
+func (r *JobRepository) ClearJobOutput(ctx context.Context, id uuid.UUID, worker string) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM job_output_chunks c USING jobs j
+		WHERE c.job_id = $1 AND j.id = c.job_id AND j.locked_by = $2 AND j.status = 'running'`, id, worker)
+	if err != nil {
+		return fmt.Errorf("failed to delete job output: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// AppendJobOutput stores chunk seq of the file of a job held by worker.
+func (r *JobRepository) AppendJobOutput(ctx context.Context, id uuid.UUID, worker string, seq int, data []byte) error {
+	return r.exec(ctx, "running job", id, `
+		INSERT INTO job_output_chunks (job_id, seq, data)
+		SELECT id, $3, $4 FROM jobs
+		WHERE id = $1 AND locked_by = $2 AND status = 'running'`, id, worker, seq, data)
+}
+
+// ClaimJobs hands up to limit due jobs to worker until leaseUntil. Jobs
+// whose lease ran out are claimed again, unless that was their last attempt,
+// in which case they fail.
+func (r *JobRepository) ClaimJobs(ctx context.Context, worker string, limit int, leaseUntil time.Time) ([]structs.Job, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE jobs SET status = 'failed', last_error = 'lease expired', locked_by = NULL,
+			locked_until = NULL, finished_at = NOW(), updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM jobs
+			WHERE status = 'running' AND locked_until < NOW() AND attempts >= max_attempts
+			FOR UPDATE SKIP LOCKED)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire jobs: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `
+		UPDATE jobs SET status = 'running', attempts = attempts + 1, locked_by = $2, locked_until = $3,
+			started_at = COALESCE(started_at, NOW()), updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM jobs
+			WHERE (status = 'queued' AND run_at <= NOW())
+			   OR (status = 'running' AND locked_until < NOW())
+			ORDER BY run_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED)
+		RETURNING`+jobColumns, limit, worker, leaseUntil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim jobs: %w", err)
+	}
+	var jobs []structs.Job
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, j)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over results: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// RenewLease extends the lease of worker on a running job. It reports
+// pgx.ErrNoRows when the job is no longer held by worker.
+func (r *JobRepository) RenewLease(ctx context.Context, id uuid.UUID, worker string, leaseUntil time.Time) error {
+	return r.exec(ctx, "running job", id, `
+		UPDATE jobs SET locked_until = $3
+		WHERE id = $1 AND locked_by = $2 AND status = 'running'`, id, worker, leaseUntil)
+}
+
+// FinishJob records the success of a job held by worker.
+// A job that wrote a file gives its outputType.
+func (r *JobRepository) FinishJob(ctx context.Context, id uuid.UUID, worker string, result json.RawMessage, outputType *string) error {
+	return r.exec(ctx, "running job", id, `
+		UPDATE jobs SET status = 'succeeded', result = $3, output_type = $4, last_error = NULL,
+			locked_by = NULL, locked_until = NULL, finished_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND locked_by = $2 AND status = 'running'`, id, worker, result, outputType)
+}
+
+// FailJob records a failed attempt of a job held by worker. It is queued
+// again at retryAt, or failed for good when retryAt is nil.
+func (r *JobRepository) FailJob(ctx context.Context, id uuid.UUID, worker string, lastError string, retryAt *time.Time) error {
+	return r.exec(ctx, "running job", id, `
+		UPDATE jobs SET
+			status = CASE WHEN $4::TIMESTAMPTZ IS NULL THEN 'failed' ELSE 'queued' END,
+			run_at = COALESCE($4, run_at), last_error = $3, locked_by = NULL, locked_until = NULL,
+			finished_at = CASE WHEN $4::TIMESTAMPTZ IS NULL THEN NOW() END, updated_at = NOW()
+		WHERE id = $1 AND locked_by = $2 AND status = 'running'`, id, worker, lastError, retryAt)
+}
+
+// ReleaseJob gives a job back to the queue without counting the attempt,
+// for workers that stop before finishing it.
+func (r *JobRepository) ReleaseJob(ctx context.Context, id uuid.UUID, worker string) error {
+	return r.exec(ctx, "running job", id, `
+		UPDATE jobs SET status = 'queued', attempts = attempts - 1, run_at = NOW(),
+			locked_by = NULL, locked_until = NULL, updated_at = NOW()
+		WHERE id = $1 AND locked_by = $2 AND status = 'running'`, id, worker)
+}
+
+// CancelJob cancels a job that has not started yet.
+func (r *JobRepository) CancelJob(ctx context.Context, id uuid.UUID) error {
+	return r.exec(ctx, "queued job", id, `
+		UPDATE jobs SET status = 'cancelled', finished_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'queued'`, id)
+}
+
+// RetryJob queues a failed or cancelled job again with a fresh set of
+// attempts.
+func (r *JobRepository) RetryJob(ctx context.Context, id uuid.UUID) error {
+	err := r.exec(ctx, "failed job", id, `
+		UPDATE jobs SET status = 'queued', attempts = 0, run_at = NOW(), last_error = NULL,
+			started_at = NULL, finished_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND status IN ('failed', 'cancelled')`, id)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return ErrActiveKey
+	}
+	return err
+}
+
+// exec runs a statement on one job and reports a missing row as
+// pgx.ErrNoRows.
+func (r *JobRepository) exec(ctx context.Context, what string, id uuid.UUID, sql string, args ...interface{}) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", what, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s with ID %s not found: %w", what, id, pgx.ErrNoRows)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package location
+
+import (
+	"context"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/bulk"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+var (
+	cityTable = bulk.Table{
+		Name: "city",
+		Key:  "city_id",
+		Columns: []string{"id", "gmt", "city_id", "iata_code", "country_iso2", "geoname_id",
+			"latitude", "longitude", "city_name", "timezone", "created_at"},
+	}
+	// Countries have no numeric upstream id, the ISO code is stable.
+	countryTable = bulk.Table{
+		Name: "country",
+		Key:  "country_iso_2",
+		Columns: []string{"id", "country_name", "country_iso_2", "country_iso_3", "country_iso_numeric",
+			"population", "capital", "continent", "currency_name", "currency_code", "fips_code",
+			"phone_prefix", "created_at"},
+	}
+)
+
+func (r *LocationRepository) CreateCities(ctx context.Context, cities []structs.City) (structs.BatchResult, error) {
+	return r.bulk.Write(ctx, cityTable, cityRows(cities))
+}
+
+func (r *LocationRepository) DiffCities(ctx context.Context, cities []structs.City) (structs.IngestionDiff, error) {
+	return r.bulk.Diff(ctx, cityTable, cityRows(cities))
+}
+
+func cityRows(cities []structs.City) [][]any {
+	rows := make([][]any, len(cities))
+	for i, c := range cities {
+		rows[i] = []any{c.ID, c.GMT, c.CityId, c.IataCode, c.CountryIso2, c.GeonameId,
+			c.Latitude, c.Longitude, c.CityName, c.Timezone, c.CreatedAt.Time}
+	}
+	return rows
+}
+
+func (r *LocationRepository) CreateCountries(ctx context.Context, countries []structs.Country) (structs.BatchResult, error) {
+	return r.bulk.Write(ctx, countryTable, countryRows(countries))
+}
+
+func (r *LocationRepository) DiffCountries(ctx context.Context, countries []structs.Country) (structs.IngestionDiff, error) {
+	return r.bulk.Diff(ctx, countryTable, countryRows(countries))
+}
+
+func countryRows(countries []structs.Country) [][]any {
+	rows := make([][]any, len(countries))
+	for i, c := range countries {
+		rows[i] = []any{c.ID, c.CountryName, c.CountryIso2, c.CountryIso3, c.CountryIsoNumeric,
+			c.Population, c.Capital, c.Continent, c.CurrencyName, c.CurrencyCode, c.FipsCode,
+			c.PhonePrefix, c.CreatedAt.Time}
+	}
+	return rows
+}
@@ -5,19 +5,22 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/bulk"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/history"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/trash"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"strings"
 )
 
 type LocationRepository struct {
-	db *pgxpool.Pool
+	db   *pgxpool.Pool
+	bulk *bulk.Writer
 }
 
-func NewRepositoryLocation(db *pgxpool.Pool) *LocationRepository {
-	return &LocationRepository{db: db}
+func NewRepositoryLocation(db *pgxpool.Pool, bulkConfig bulk.Config) *LocationRepository {
+	return &LocationRepository{db: db, bulk: bulk.NewWriter(db, bulkConfig)}
 }
 
 /** City **/
@@ -62,19 +65,20 @@ func (r *LocationRepository) CreateCity(ctx context.Context, c *structs.City) er
 	return nil
 }
 
-func (r *LocationRepository) GetCities(ctx context.Context) ([]structs.City, error) {
-	var cities []structs.City
-
+func (r *LocationRepository) StreamCities(ctx context.Context, fn func(structs.City) error) error {
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer tx.Rollback(ctx)
 
 	// Send query to database.
-	rows, err := tx.Query(ctx, `SELECT * FROM city ORDER BY city_id`)
+	rows, err := tx.Query(ctx, `SELECT
+			id, gmt, city_id, iata_code, country_iso2, geoname_id, latitude,
+			longitude, city_name, timezone, country_ref, created_at, updated_at, deleted_at
+		FROM city WHERE `+trash.Live(ctx, "deleted_at")+` ORDER BY city_id`)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
@@ -91,20 +95,37 @@ func (r *LocationRepository) GetCities(ctx context.Context) ([]structs.City, err
 			&city.Longitude,
 			&city.CityName,
 			&city.Timezone,
+			&city.CountryRef,
 			&city.CreatedAt,
-			&city.UpdatedAt)
+			&city.UpdatedAt,
+			&city.DeletedAt)
 
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if err := fn(city); err != nil {
+			return err
 		}
-		cities = append(cities, city)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *LocationRepository) GetCities(ctx context.Context) ([]structs.City, error) {
+	var cities []structs.City
+	err := r.StreamCities(ctx, func(city structs.City) error {
+		cities = append(cities, city)
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -131,10 +152,12 @@ func (r *LocationRepository) GetCity(ctx context.Context, id uuid.UUID) (structs
 			longitude,
 			city_name,
 			timezone,
+			country_ref,
 			created_at,
-			updated_at
-		FROM city
-		WHERE id = $1 LIMIT 1`, id)
+			updated_at,
+			deleted_at
+		FROM `+history.Source(ctx, "city")+`
+		WHERE id = $1 AND `+trash.Live(ctx, "deleted_at")+` LIMIT 1`, id)
 	err = row.Scan(
 		&city.ID,
 		&city.GMT,
@@ -146,8 +169,10 @@ func (r *LocationRepository) GetCity(ctx context.Context, id uuid.UUID) (structs
 		&city.Longitude,
 		&city.CityName,
 		&city.Timezone,
+		&city.CountryRef,
 		&city.CreatedAt,
 		&city.UpdatedAt,
+		&city.DeletedAt,
 	)
 
 	if err != nil {
@@ -164,54 +189,6 @@ func (r *LocationRepository) GetCity(ctx context.Context, id uuid.UUID) (structs
 	return city, nil
 }
 
-func (q *LocationRepository) DeleteCity(ctx context.Context, id uuid.UUID) error {
-	tx, err := q.db.BeginTx(context.Background(), pgx.TxOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback(ctx)
-
-	_, err = tx.Exec(ctx, "DELETE FROM city WHERE id = $1", id)
-	if err != nil {
-		return fmt.Errorf("failed to delete city: %w", err)
-	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	return nil
-}
-
-func (r *LocationRepository) UpdateCity(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error {
-	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback(ctx)
-
-	var setColumns []string
-	var args []interface{}
-
-	for key, value := range updates {
-		setColumns = append(setColumns, fmt.Sprintf("%s = $%d", key, len(args)+1))
-		args = append(args, value)
-	}
-	args = append(args, id)
-
-	stmt := fmt.Sprintf("UPDATE city SET %s WHERE id = $%d", strings.Join(setColumns, ", "), len(args))
-	_, err = tx.Exec(ctx, stmt, args...)
-	if err != nil {
-		return fmt.Errorf("failed to update country: %w", err)
-	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	return nil
-}
-
 func (r *LocationRepository) GetCityCount(ctx context.Context) (int, error) {
 	tx, err := r.db.BeginTx(context.TODO(), pgx.TxOptions{})
 	if err != nil {
@@ -220,7 +197,7 @@ func (r *LocationRepository) GetCityCount(ctx context.Context) (int, error) {
 	defer tx.Rollback(ctx)
 
 	var count int
-	err = tx.QueryRow(ctx, "SELECT COUNT(*) FROM city").Scan(&count)
+	err = tx.QueryRow(ctx, "SELECT COUNT(*) FROM city WHERE deleted_at IS NULL").Scan(&count)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return 0, fmt.Errorf("no cities found")
@@ -280,19 +257,21 @@ func (r *LocationRepository) CreateCountry(ctx context.Context, c *structs.Count
 	return nil
 }
 
-func (r *LocationRepository) GetCountries(ctx context.Context) ([]structs.Country, error) {
-	var countries []structs.Country
-
+func (r *LocationRepository) StreamCountries(ctx context.Context, fn func(structs.Country) error) error {
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer tx.Rollback(ctx)
 
 	// Send query to database.
-	rows, err := tx.Query(ctx, `SELECT * FROM country ORDER BY country_iso_2`)
+	rows, err := tx.Query(ctx, `SELECT
+			id, country_name, country_iso_2, country_iso_3, country_iso_numeric,
+			population, capital, continent, currency_name, currency_code, fips_code,
+			phone_prefix, created_at, updated_at, deleted_at
+		FROM country WHERE `+trash.Live(ctx, "deleted_at")+` ORDER BY country_iso_2`)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
@@ -306,19 +285,34 @@ func (r *LocationRepository) GetCountries(ctx context.Context) ([]structs.Countr
 			&country.Continent, &country.CurrencyName,
 			&country.CurrencyCode, &country.FipsCode,
 			&country.PhonePrefix, &country.CreatedAt,
-			&country.UpdatedAt)
+			&country.UpdatedAt, &country.DeletedAt)
 
 		if err != nil {
-			return nil, err
+			return err
+		}
+		if err := fn(country); err != nil {
+			return err
 		}
-		countries = append(countries, country)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return err
 	}
 
 	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *LocationRepository) GetCountries(ctx context.Context) ([]structs.Country, error) {
+	var countries []structs.Country
+	err := r.StreamCountries(ctx, func(country structs.Country) error {
+		countries = append(countries, country)
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -348,9 +342,10 @@ func (r *LocationRepository) GetCountry(ctx context.Context, id uuid.UUID) (stru
 			fips_code,
 			phone_prefix,
 			created_at,
-			updated_at
-		FROM country
-		WHERE id = $1 LIMIT 1`, id)
+			updated_at,
+			deleted_at
+		FROM `+history.Source(ctx, "country")+`
+		WHERE id = $1 AND `+trash.Live(ctx, "deleted_at")+` LIMIT 1`, id)
 	err = row.Scan(
 		&country.ID,
 		&country.CountryName,
@@ -366,6 +361,7 @@ func (r *LocationRepository) GetCountry(ctx context.Context, id uuid.UUID) (stru
 		&country.PhonePrefix,
 		&country.CreatedAt,
 		&country.UpdatedAt,
+		&country.DeletedAt,
 	)
 
 	if err != nil {
@@ -382,54 +378,6 @@ func (r *LocationRepository) GetCountry(ctx context.Context, id uuid.UUID) (stru
 	return country, nil
 }
 
-func (r *LocationRepository) DeleteCountry(ctx context.Context, id uuid.UUID) error {
-	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback(ctx)
-
-	_, err = tx.Exec(ctx, "DELETE FROM country WHERE id = $1", id)
-	if err != nil {
-		return fmt.Errorf("failed to delete country: %w", err)
-	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	return nil
-}
-
-func (r *LocationRepository) UpdateCountry(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error {
-	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback(ctx)
-
-	var setColumns []string
-	var args []interface{}
-
-	for key, value := range updates {
-		setColumns = append(setColumns, fmt.Sprintf("%s = $%d", key, len(args)+1))
-		args = append(args, value)
-	}
-	args = append(args, id)
-
-	stmt := fmt.Sprintf("UPDATE country SET %s WHERE id = $%d", strings.Join(setColumns, ", "), len(args))
-	_, err = tx.Exec(ctx, stmt, args...)
-	if err != nil {
-		return fmt.Errorf("failed to update country: %w", err)
-	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	return nil
-}
-
 func (r *LocationRepository) GetCountryCount(ctx context.Context) (int, error) {
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
@@ -438,7 +386,7 @@ func (r *LocationRepository) GetCountryCount(ctx context.Context) (int, error) {
 	defer tx.Rollback(ctx)
 
 	var count int
-	err = tx.QueryRow(context.TODO(), "SELECT COUNT(*) FROM country").Scan(&count)
+	err = tx.QueryRow(context.TODO(), "SELECT COUNT(*) FROM country WHERE deleted_at IS NULL").Scan(&count)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return 0, fmt.Errorf("no countries found")
@@ -453,11 +401,10 @@ func (r *LocationRepository) GetCountryCount(ctx context.Context) (int, error) {
 	return count, nil
 }
 
-func (r *LocationRepository) GetCitiesFromCountry(ctx context.Context) ([]structs.CityInfo, error) {
-	var cities []structs.CityInfo
+func (r *LocationRepository) StreamCitiesFromCountry(ctx context.Context, fn func(structs.CityInfo) error) error {
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
 	if err != nil {
-		return cities, fmt.Errorf("failed to begin transaction: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
@@ -466,10 +413,11 @@ func (r *LocationRepository) GetCitiesFromCountry(ctx context.Context) ([]struct
                country.currency_name, country.currency_code, country.continent,
                country.phone_prefix
         FROM country
-        INNER JOIN city ON city.country_iso2 = country.country_iso_2
+        INNER JOIN city ON city.country_ref = country.id
+        WHERE city.deleted_at IS NULL AND country.deleted_at IS NULL
         ORDER BY city.country_iso2`)
 	if err != nil {
-		return cities, fmt.Errorf("failed to execute query: %w", err)
+		return fmt.Errorf("failed to execute query: %w", err)
 	}
 	defer rows.Close()
 
@@ -479,16 +427,31 @@ func (r *LocationRepository) GetCitiesFromCountry(ctx context.Context) ([]struct
 			&cityInfo.CountryName, &cityInfo.CurrencyName,
 			&cityInfo.CurrencyCode, &cityInfo.Continent, &cityInfo.PhonePrefix)
 		if err != nil {
-			return cities, fmt.Errorf("failed to scan city info: %w", err)
+			return fmt.Errorf("failed to scan city info: %w", err)
+		}
+		if err := fn(cityInfo); err != nil {
+			return err
 		}
-		cities = append(cities, cityInfo)
 	}
 	if err := rows.Err(); err != nil {
-		return cities, fmt.Errorf("failed to iterate over results: %w", err)
+		return fmt.Errorf("failed to iterate over results: %w", err)
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return cities, fmt.Errorf("failed to commit transaction: %w", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func (r *LocationRepository) GetCitiesFromCountry(ctx context.Context) ([]structs.CityInfo, error) {
+	var cities []structs.CityInfo
+	err := r.StreamCitiesFromCountry(ctx, func(city structs.CityInfo) error {
+		cities = append(cities, city)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return cities, nil
@@ -507,8 +470,8 @@ func (r *LocationRepository) GetCityFromCountry(ctx context.Context, id uuid.UUI
                country.currency_name, country.currency_code, country.continent,
                country.phone_prefix
         FROM country
-        INNER JOIN city ON city.country_iso2 = country.country_iso_2
-        WHERE country.id = $1
+        INNER JOIN city ON city.country_ref = country.id
+        WHERE country.id = $1 AND city.deleted_at IS NULL AND country.deleted_at IS NULL
         ORDER BY city.country_iso2
         `, id)
 	if err != nil {
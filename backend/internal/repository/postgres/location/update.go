@@ -0,0 +1,76 @@
+package location
+
+import (
+	"context"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/batch"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var cityEntity = batch.Entity[structs.City]{
+	Table: "city",
+	Columns: `id, gmt, city_id, iata_code, country_iso2, geoname_id, latitude, longitude,
+		city_name, timezone, country_ref, created_at, updated_at, deleted_at`,
+	Scan: func(row pgx.Row) (structs.City, error) {
+		var c structs.City
+		err := row.Scan(
+			&c.ID, &c.GMT, &c.CityId, &c.IataCode, &c.CountryIso2, &c.GeonameId, &c.Latitude, &c.Longitude,
+			&c.CityName, &c.Timezone, &c.CountryRef, &c.CreatedAt, &c.UpdatedAt, &c.DeletedAt)
+		return c, err
+	},
+	Values: func(c structs.City) map[string]interface{} {
+		return map[string]interface{}{
+			"gmt": c.GMT, "city_id": c.CityId, "iata_code": c.IataCode, "country_iso2": c.CountryIso2,
+			"geoname_id": c.GeonameId, "latitude": c.Latitude, "longitude": c.Longitude,
+			"city_name": c.CityName, "timezone": c.Timezone, "country_ref": c.CountryRef,
+		}
+	},
+}
+
+var countryEntity = batch.Entity[structs.Country]{
+	Table: "country",
+	Columns: `id, country_name, country_iso_2, country_iso_3, country_iso_numeric, population,
+		capital, continent, currency_name, currency_code, fips_code, phone_prefix,
+		created_at, updated_at, deleted_at`,
+	Scan: func(row pgx.Row) (structs.Country, error) {
+		var c structs.Country
+		err := row.Scan(
+			&c.ID, &c.CountryName, &c.CountryIso2, &c.CountryIso3, &c.CountryIsoNumeric, &c.Population,
+			&c.Capital, &c.Continent, &c.CurrencyName, &c.CurrencyCode, &c.FipsCode, &c.PhonePrefix,
+			&c.CreatedAt, &c.UpdatedAt, &c.DeletedAt)
+		return c, err
+	},
+	Values: func(c structs.Country) map[string]interface{} {
+		return map[string]interface{}{
+			"country_name": c.CountryName, "country_iso_2": c.CountryIso2, "country_iso_3": c.CountryIso3,
+			"country_iso_numeric": c.CountryIsoNumeric, "population": c.Population, "capital": c.Capital,
+			"continent": c.Continent, "currency_name": c.CurrencyName, "currency_code": c.CurrencyCode,
+			"fips_code": c.FipsCode, "phone_prefix": c.PhonePrefix,
+		}
+	},
+}
+
+// UpdateCity locks the live city id and writes back the city fn makes of it.
+func (r *LocationRepository) UpdateCity(ctx context.Context, id uuid.UUID, fn func(structs.City) (structs.City, error)) error {
+	return batch.Update(ctx, r.db, cityEntity, id, fn)
+}
+
+// DeleteCity locks the live city id and soft deletes it once
+// check accepts it.
+func (r *LocationRepository) DeleteCity(ctx context.Context, id uuid.UUID, check func(structs.City) error) error {
+	return batch.Delete(ctx, r.db, cityEntity, id, check)
+}
+
+// UpdateCountry locks the live country id and writes back the country fn
+// makes of it.
+func (r *LocationRepository) UpdateCountry(ctx context.Context, id uuid.UUID, fn func(structs.Country) (structs.Country, error)) error {
+	return batch.Update(ctx, r.db, countryEntity, id, fn)
+}
+
+// DeleteCountry locks the live country id and soft deletes it once
+// check accepts it.
+func (r *LocationRepository) DeleteCountry(ctx context.Context, id uuid.UUID, check func(structs.Country) error) error {
+	return batch.Delete(ctx, r.db, countryEntity, id, check)
+}
@@ -0,0 +1,368 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/FACorreiaa/aviatoon-tracker/pkg/logs"
+	"github.com/FACorreiaa/aviatoon-tracker/schema"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// advisoryLockID is the pg_advisory_lock key every instance takes before
+// touching schema_migrations, so concurrent deploys apply each step once.
+const advisoryLockID int64 = 0x61766961746f6f6e
+
+var (
+	ErrDirty       = errors.New("schema is dirty, fix it manually and run `migrate force <version>`")
+	ErrSchemaAhead = errors.New("database schema is newer than this binary")
+	ErrNoChange    = errors.New("no migration to apply")
+
+	fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+)
+
+type Config struct {
+	autoMigrate bool
+}
+
+func NewConfig(autoMigrate bool) Config {
+	return Config{autoMigrate: autoMigrate}
+}
+
+type Migration struct {
+	Version uint
+	Name    string
+	up      string
+	down    string
+}
+
+type Status struct {
+	Version uint
+	Dirty   bool
+	Latest  uint
+	Applied []Migration
+	Pending []Migration
+}
+
+type Migrator struct {
+	db         *pgxpool.Pool
+	migrations []Migration
+}
+
+func NewMigrator(db *pgxpool.Pool, source fs.FS) (*Migrator, error) {
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[uint]*Migration)
+	for _, e := range entries {
+		match := fileName.FindStringSubmatch(e.Name())
+		if e.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %q: %w", e.Name(), err)
+		}
+		body, err := fs.ReadFile(source, e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", e.Name(), err)
+		}
+
+		m, ok := byVersion[uint(version)]
+		if !ok {
+			m = &Migration{Version: uint(version), Name: match[2]}
+			byVersion[uint(version)] = m
+		}
+		if match[3] == "up" {
+			m.up = string(body)
+		} else {
+			m.down = string(body)
+		}
+	}
+
+	migrator := &Migrator{db: db}
+	for _, m := range byVersion {
+		if m.up == "" {
+			return nil, fmt.Errorf("migration %06d_%s has no up file", m.Version, m.Name)
+		}
+		migrator.migrations = append(migrator.migrations, *m)
+	}
+	sort.Slice(migrator.migrations, func(i, j int) bool {
+		return migrator.migrations[i].Version < migrator.migrations[j].Version
+	})
+
+	return migrator, nil
+}
+
+// Init runs on every start: it applies pending migrations when autoMigrate
+// is set and refuses to continue if the database is dirty or ahead of the
+// migrations compiled into this binary.
+func Init(ctx context.Context, db *pgxpool.Pool, config Config) error {
+	m, err := NewMigrator(db, schema.Migrations)
+	if err != nil {
+		return err
+	}
+
+	if config.autoMigrate {
+		if err := m.Up(ctx, 0); err != nil && !errors.Is(err, ErrNoChange) {
+			return err
+		}
+	}
+
+	return m.Check(ctx)
+}
+
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func (m *Migrator) Check(ctx context.Context) error {
+	var version uint
+	var dirty bool
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		var err error
+		version, dirty, err = currentVersion(ctx, conn)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		return fmt.Errorf("version %d: %w", version, ErrDirty)
+	}
+	if version > m.Latest() {
+		return fmt.Errorf("database is at version %d, binary knows up to %d: %w", version, m.Latest(), ErrSchemaAhead)
+	}
+	if version < m.Latest() {
+		logs.DefaultLogger.WithFields(map[string]any{
+			"version": version,
+			"latest":  m.Latest(),
+		}).Warn("Database schema has pending migrations")
+	}
+
+	return nil
+}
+
+// Up applies at most steps pending migrations, or all of them when steps <= 0.
+func (m *Migrator) Up(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		version, dirty, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("version %d: %w", version, ErrDirty)
+		}
+		if version > m.Latest() {
+			return fmt.Errorf("database is at version %d, binary knows up to %d: %w", version, m.Latest(), ErrSchemaAhead)
+		}
+
+		applied := 0
+		for _, migration := range m.migrations {
+			if migration.Version <= version {
+				continue
+			}
+			if steps > 0 && applied == steps {
+				break
+			}
+			if err := apply(ctx, conn, migration.Version, migration.up, migration.Version); err != nil {
+				return fmt.Errorf("migration %06d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			logs.DefaultLogger.WithField("version", migration.Version).Info("Migration " + migration.Name + " applied")
+			applied++
+		}
+
+		if applied == 0 {
+			return ErrNoChange
+		}
+		return nil
+	})
+}
+
+// Down reverts steps applied migrations, or a single one when steps <= 0.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if steps <= 0 {
+		steps = 1
+	}
+
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		version, dirty, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("version %d: %w", version, ErrDirty)
+		}
+
+		for ; steps > 0; steps-- {
+			if version == 0 {
+				return nil
+			}
+			idx := m.index(version)
+			if idx < 0 {
+				return fmt.Errorf("database is at version %d, binary knows up to %d: %w", version, m.Latest(), ErrSchemaAhead)
+			}
+			migration := m.migrations[idx]
+			if migration.down == "" {
+				return fmt.Errorf("migration %06d_%s has no down file", migration.Version, migration.Name)
+			}
+
+			var previous uint
+			if idx > 0 {
+				previous = m.migrations[idx-1].Version
+			}
+			if err := apply(ctx, conn, migration.Version, migration.down, previous); err != nil {
+				return fmt.Errorf("migration %06d_%s rollback failed: %w", migration.Version, migration.Name, err)
+			}
+			logs.DefaultLogger.WithField("version", previous).Info("Migration " + migration.Name + " reverted")
+			version = previous
+		}
+		return nil
+	})
+}
+
+// Force overwrites the recorded version and clears the dirty flag without
+// running any SQL. Version 0 removes the record entirely.
+func (m *Migrator) Force(ctx context.Context, version uint) error {
+	if version != 0 && m.index(version) < 0 {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	return m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback(ctx)
+
+		if err := setVersion(ctx, tx, version, false); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return nil
+	})
+}
+
+func (m *Migrator) Status(ctx context.Context) (Status, error) {
+	status := Status{Latest: m.Latest()}
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		var err error
+		status.Version, status.Dirty, err = currentVersion(ctx, conn)
+		return err
+	})
+	if err != nil {
+		return status, err
+	}
+
+	for _, migration := range m.migrations {
+		if migration.Version <= status.Version {
+			status.Applied = append(status.Applied, migration)
+		} else {
+			status.Pending = append(status.Pending, migration)
+		}
+	}
+
+	return status, nil
+}
+
+func (m *Migrator) index(version uint) int {
+	for i, migration := range m.migrations {
+		if migration.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", advisoryLockID); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockID)
+
+	if _, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT NOT NULL PRIMARY KEY,
+			dirty BOOLEAN NOT NULL
+		)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+// apply marks target as dirty, then runs body and records target as clean in
+// a single transaction, the same bookkeeping golang-migrate uses.
+func apply(ctx context.Context, conn *pgxpool.Conn, version uint, body string, target uint) error {
+	if _, err := conn.Exec(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return fmt.Errorf("failed to reset version: %w", err)
+	}
+	if _, err := conn.Exec(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, true)`, version); err != nil {
+		return fmt.Errorf("failed to mark version dirty: %w", err)
+	}
+
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, body); err != nil {
+		return err
+	}
+	if err := setVersion(ctx, tx, target, false); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func currentVersion(ctx context.Context, conn *pgxpool.Conn) (uint, bool, error) {
+	var version int64
+	var dirty bool
+	err := conn.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return uint(version), dirty, nil
+}
+
+func setVersion(ctx context.Context, tx pgx.Tx, version uint, dirty bool) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return fmt.Errorf("failed to reset version: %w", err)
+	}
+	if version == 0 {
+		return nil
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)`, version, dirty); err != nil {
+		return fmt.Errorf("failed to record version: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package migrations
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/FACorreiaa/aviatoon-tracker/schema"
+)
+
+func TestNewMigrator(t *testing.T) {
+	source := fstest.MapFS{
+		"000002_add_b.up.sql":   {Data: []byte("CREATE TABLE b ();")},
+		"000002_add_b.down.sql": {Data: []byte("DROP TABLE b;")},
+		"000010_add_c.up.sql":   {Data: []byte("CREATE TABLE c ();")},
+		"000001_add_a.up.sql":   {Data: []byte("CREATE TABLE a ();")},
+		"000001_add_a.down.sql": {Data: []byte("DROP TABLE a;")},
+		"schema.go":             {Data: []byte("package schema")},
+		"README.md":             {Data: []byte("not a migration")},
+	}
+	m, err := NewMigrator(nil, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Migration{
+		{Version: 1, Name: "add_a", up: "CREATE TABLE a ();", down: "DROP TABLE a;"},
+		{Version: 2, Name: "add_b", up: "CREATE TABLE b ();", down: "DROP TABLE b;"},
+		{Version: 10, Name: "add_c", up: "CREATE TABLE c ();"},
+	}
+	if len(m.migrations) != len(want) {
+		t.Fatalf("migrations = %+v, want %+v", m.migrations, want)
+	}
+	for i := range want {
+		if m.migrations[i] != want[i] {
+			t.Errorf("migration %d = %+v, want %+v", i, m.migrations[i], want[i])
+		}
+	}
+	if m.Latest() != 10 {
+		t.Errorf("Latest = %d, want 10", m.Latest())
+	}
+	for version, want := range map[uint]int{1: 0, 2: 1, 10: 2, 3: -1} {
+		if got := m.index(version); got != want {
+			t.Errorf("index(%d) = %d, want %d", version, got, want)
+		}
+	}
+}
+
+func TestNewMigratorWithoutUp(t *testing.T) {
+	source := fstest.MapFS{
+		"000001_add_a.down.sql": {Data: []byte("DROP TABLE a;")},
+	}
+	if _, err := NewMigrator(nil, source); err == nil {
+		t.Error("NewMigrator accepted a migration without an up file")
+	}
+}
+
+func TestNewMigratorEmpty(t *testing.T) {
+	m, err := NewMigrator(nil, fstest.MapFS{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Latest() != 0 {
+		t.Errorf("Latest = %d, want 0", m.Latest())
+	}
+}
+
+// TestSchema checks the migrations compiled into the binary: numbered from 1
+// without gaps, each with a down file.
+func TestSchema(t *testing.T) {
+	m, err := NewMigrator(nil, schema.Migrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, migration := range m.migrations {
+		if migration.Version != uint(i+1) {
+			t.Errorf("migration %06d_%s, want version %d", migration.Version, migration.Name, i+1)
+		}
+		if migration.down == "" {
+			t.Errorf("migration %06d_%s has no down file", migration.Version, migration.Name)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type OutboxRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepositoryOutbox(db *pgxpool.Pool) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// ClaimEvents takes up to limit due change events, oldest first, and pushes
+// them back until leaseUntil so another relay does not publish them too.
+// Only the oldest pending event of an entity is taken: a later one waits
+// until it is published, which keeps the events of an entity in order even
+// across relays and retries.
+func (r *OutboxRepository) ClaimEvents(ctx context.Context, limit int, leaseUntil time.Time) ([]structs.ChangeEvent, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		UPDATE outbox AS o SET next_attempt_at = $2
+		WHERE o.id IN (
+			SELECT q.id FROM outbox q
+			WHERE q.next_attempt_at <= NOW()
+			AND NOT EXISTS (
+				SELECT 1 FROM outbox p
+				WHERE p.entity = q.entity AND p.entity_id = q.entity_id AND p.id < q.id)
+			ORDER BY q.id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED)
+		RETURNING o.id, o.entity, o.entity_id, o.operation, o.fields, o.created_at, o.attempts`,
+		limit, leaseUntil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []structs.ChangeEvent
+	for rows.Next() {
+		var e structs.ChangeEvent
+		if err := rows.Scan(&e.ID, &e.Entity, &e.EntityID, &e.Operation, &e.Fields, &e.OccurredAt, &e.Attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over results: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events, nil
+}
+
+// DeleteEvent drops a published event, which lets the next event of its
+// entity be claimed.
+func (r *OutboxRepository) DeleteEvent(ctx context.Context, id int64) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM outbox WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete event: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// FailEvent records a failed publish of an event and makes it due again at
+// next.
+func (r *OutboxRepository) FailEvent(ctx context.Context, id int64, lastError string, next time.Time) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE outbox SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3
+		WHERE id = $1`, id, lastError, next)
+	if err != nil {
+		return fmt.Errorf("failed to update event: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+package stats
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const insertOTPDaily = `
+	INSERT INTO otp_daily (
+		dimension, key, flight_date, flights, landed, on_time, cancelled, diverted,
+		delay_count, delay_sum, delay_histogram
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+
+type StatsRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepositoryStats(db *pgxpool.Pool) *StatsRepository {
+	return &StatsRepository{db: db}
+}
+
+// ReplaceOTPDaily swaps the daily rows between from and to, inclusive, for
+// rows in one transaction, so readers never see a half recomputed day.
+func (r *StatsRepository) ReplaceOTPDaily(ctx context.Context, from time.Time, to time.Time, rows []structs.OTPDaily) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `DELETE FROM otp_daily WHERE flight_date BETWEEN $1 AND $2`, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to delete daily otp: %w", err)
+	}
+
+	batch := &pgx.Batch{}
+	for _, d := range rows {
+		batch.Queue(insertOTPDaily, d.Dimension, d.Key, d.FlightDate, d.Flights, d.Landed,
+			d.OnTime, d.Cancelled, d.Diverted, d.DelayCount, d.DelaySum, d.DelayHistogram)
+	}
+	if batch.Len() > 0 {
+		if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+			return fmt.Errorf("failed to insert daily otp: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetOTPDaily returns the daily rows of filter.Dimension between filter.From
+// and filter.To, of filter.Key only when it is set.
+func (r *StatsRepository) GetOTPDaily(ctx context.Context, filter structs.OTPFilter) ([]structs.OTPDaily, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		SELECT dimension, key, flight_date, flights, landed, on_time, cancelled, diverted,
+		       delay_count, delay_sum, delay_histogram
+		FROM otp_daily
+		WHERE dimension = $1 AND flight_date BETWEEN $2 AND $3`
+	args := []interface{}{filter.Dimension, filter.From, filter.To}
+	if filter.Key != "" {
+		args = append(args, filter.Key)
+		query += ` AND key = $` + strconv.Itoa(len(args))
+	}
+	query += ` ORDER BY key, flight_date`
+
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	var days []structs.OTPDaily
+	for rows.Next() {
+		var d structs.OTPDaily
+		err := rows.Scan(&d.Dimension, &d.Key, &d.FlightDate, &d.Flights, &d.Landed,
+			&d.OnTime, &d.Cancelled, &d.Diverted, &d.DelayCount, &d.DelaySum, &d.DelayHistogram)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan daily otp: %w", err)
+		}
+		days = append(days, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over results: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return days, nil
+}
@@ -0,0 +1,103 @@
+package trash
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Tables maps every soft deletable table to the upstream column that is
+// written to the tombstones when its rows are purged.
+var Tables = map[string]string{
+	"tax":      "tax_id",
+	"aircraft": "plane_type_id",
+	"airline":  "airline_id",
+	"airplane": "airplane_id",
+	"airport":  "airport_id",
+	"city":     "city_id",
+	"country":  "country_iso_2",
+}
+
+// Live is the condition a read uses to skip soft deleted rows, unless ctx
+// asks for them with structs.WithDeleted.
+func Live(ctx context.Context, column string) string {
+	if structs.IncludeDeleted(ctx) {
+		return "TRUE"
+	}
+	return column + " IS NULL"
+}
+
+type TrashRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepositoryTrash(db *pgxpool.Pool) *TrashRepository {
+	return &TrashRepository{db: db}
+}
+
+func (r *TrashRepository) Restore(ctx context.Context, table string, id uuid.UUID) error {
+	if _, ok := Tables[table]; !ok {
+		return fmt.Errorf("unknown table %s", table)
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `UPDATE ` + table + ` SET deleted_at = NULL, updated_at = NOW()
+			  WHERE id = $1 AND deleted_at IS NOT NULL`
+	tag, err := tx.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to restore %s: %w", table, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("deleted %s with ID %s not found: %w", table, id, pgx.ErrNoRows)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Purge removes the rows of table that were soft deleted before the given
+// time, leaving a tombstone with their upstream key behind.
+func (r *TrashRepository) Purge(ctx context.Context, table string, before time.Time) (int64, error) {
+	key, ok := Tables[table]
+	if !ok {
+		return 0, fmt.Errorf("unknown table %s", table)
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `INSERT INTO tombstones (resource, upstream_key, deleted_at)
+			  SELECT DISTINCT ON (` + key + `) $1, ` + key + `::TEXT, deleted_at
+			  FROM ` + table + `
+			  WHERE deleted_at < $2 AND ` + key + ` IS NOT NULL
+			  ORDER BY ` + key + `, deleted_at DESC
+			  ON CONFLICT (resource, upstream_key) DO NOTHING`
+	if _, err := tx.Exec(ctx, query, table, before); err != nil {
+		return 0, fmt.Errorf("failed to write %s tombstones: %w", table, err)
+	}
+
+	tag, err := tx.Exec(ctx, `DELETE FROM `+table+` WHERE deleted_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge %s: %w", table, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,113 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// uniqueViolation is the SQLSTATE of a duplicate key.
+const uniqueViolation = "23505"
+
+var ErrDuplicateEmail = errors.New("email is already registered")
+
+// ErrUnknownRefreshToken is returned by RotateRefreshToken when no user holds
+// the refresh token.
+var ErrUnknownRefreshToken = errors.New("unknown refresh token")
+
+type UserRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepositoryUser(db *pgxpool.Pool) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// CreateUser registers email with the hash of its first refresh token.
+func (r *UserRepository) CreateUser(ctx context.Context, email string, refreshTokenHash string) (structs.User, error) {
+	user := structs.User{Email: email}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return user, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO users (email, user_status, refresh_token_hash) VALUES ($1, 1, $2)
+		RETURNING id, admin, created_at`, email, refreshTokenHash).Scan(&user.ID, &user.Admin, &user.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return user, ErrDuplicateEmail
+		}
+		return user, fmt.Errorf("failed to insert user: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return user, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return user, nil
+}
+
+func (r *UserRepository) GetUser(ctx context.Context, id uuid.UUID) (structs.User, error) {
+	var user structs.User
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return user, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `SELECT id, email, admin, created_at FROM users WHERE id = $1`, id).
+		Scan(&user.ID, &user.Email, &user.Admin, &user.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return user, fmt.Errorf("user with ID %s not found: %w", id, err)
+		}
+		return user, fmt.Errorf("failed to scan user: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return user, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return user, nil
+}
+
+// RotateRefreshToken replaces the refresh token hashed as current with the
+// one hashed as next and returns its user. A token is only ever rotated
+// once: replaying it finds no user.
+func (r *UserRepository) RotateRefreshToken(ctx context.Context, current string, next string) (structs.User, error) {
+	var user structs.User
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return user, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		UPDATE users SET refresh_token_hash = $2, updated_at = NOW()
+		WHERE refresh_token_hash = $1
+		RETURNING id, email, admin, created_at`, current, next).Scan(&user.ID, &user.Email, &user.Admin, &user.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return user, ErrUnknownRefreshToken
+		}
+		return user, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return user, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return user, nil
+}
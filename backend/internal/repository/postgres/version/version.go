@@ -0,0 +1,71 @@
+package version
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// tables are the reference tables a version can be taken of. Table names
+// are interpolated into the query, so anything else is rejected.
+var tables = map[string]bool{
+	"tax":      true,
+	"aircraft": true,
+	"airline":  true,
+	"airplane": true,
+	"airport":  true,
+	"city":     true,
+	"country":  true,
+}
+
+type VersionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepositoryVersion(db *pgxpool.Pool) *VersionRepository {
+	return &VersionRepository{db: db}
+}
+
+// GetVersion sums the row counts and takes the latest created_at/updated_at
+// over every table in names, the tables behind one (possibly joined) list.
+func (r *VersionRepository) GetVersion(ctx context.Context, names ...string) (structs.TableVersion, error) {
+	var version structs.TableVersion
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return version, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, name := range names {
+		if !tables[name] {
+			return version, fmt.Errorf("unknown table %q", name)
+		}
+
+		var rows int64
+		var created, updated *time.Time
+		err := tx.QueryRow(ctx, fmt.Sprintf(`
+			SELECT COUNT(*), MAX(created_at)::TIMESTAMPTZ, MAX(updated_at)::TIMESTAMPTZ
+			FROM %s`, name)).Scan(&rows, &created, &updated)
+		if err != nil {
+			return version, fmt.Errorf("failed to read version of %s: %w", name, err)
+		}
+
+		version.Rows += rows
+		for _, t := range []*time.Time{created, updated} {
+			if t != nil && t.After(version.LastModified) {
+				version.LastModified = *t
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return version, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return version, nil
+}
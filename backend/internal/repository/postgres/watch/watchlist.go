@@ -0,0 +1,249 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const insertWatch = `
+	INSERT INTO watch (watchlist_id, kind, value) VALUES ($1, $2, $3)
+	ON CONFLICT (watchlist_id, kind, value) DO UPDATE SET value = EXCLUDED.value
+	RETURNING id, created_at`
+
+type WatchRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepositoryWatch(db *pgxpool.Pool) *WatchRepository {
+	return &WatchRepository{db: db}
+}
+
+// CreateWatchlist stores a watchlist of userID with its watches.
+func (r *WatchRepository) CreateWatchlist(ctx context.Context, userID uuid.UUID, name string, watches []structs.Watch) (structs.Watchlist, error) {
+	list := structs.Watchlist{UserID: userID, Name: name, Watches: []structs.Watch{}}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return list, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO watchlist (user_id, name) VALUES ($1, $2)
+		RETURNING id, created_at`, userID, name).Scan(&list.ID, &list.CreatedAt)
+	if err != nil {
+		return list, fmt.Errorf("failed to insert watchlist: %w", err)
+	}
+
+	for _, w := range watches {
+		if err := tx.QueryRow(ctx, insertWatch, list.ID, w.Kind, w.Value).Scan(&w.ID, &w.CreatedAt); err != nil {
+			return list, fmt.Errorf("failed to insert watch: %w", err)
+		}
+		list.Watches = append(list.Watches, w)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return list, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return list, nil
+}
+
+// GetWatchlists returns the watchlists of userID, oldest first.
+func (r *WatchRepository) GetWatchlists(ctx context.Context, userID uuid.UUID) ([]structs.Watchlist, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, user_id, name, created_at, updated_at
+		FROM watchlist
+		WHERE user_id = $1
+		ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	lists := []structs.Watchlist{}
+	index := make(map[uuid.UUID]int)
+	for rows.Next() {
+		list := structs.Watchlist{Watches: []structs.Watch{}}
+		if err := rows.Scan(&list.ID, &list.UserID, &list.Name, &list.CreatedAt, &list.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan watchlist: %w", err)
+		}
+		index[list.ID] = len(lists)
+		lists = append(lists, list)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over results: %w", err)
+	}
+
+	rows, err = tx.Query(ctx, `
+		SELECT w.watchlist_id, w.id, w.kind, w.value, w.created_at
+		FROM watch w JOIN watchlist l ON l.id = w.watchlist_id
+		WHERE l.user_id = $1
+		ORDER BY w.kind, w.value`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var listID uuid.UUID
+		var w structs.Watch
+		if err := rows.Scan(&listID, &w.ID, &w.Kind, &w.Value, &w.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan watch: %w", err)
+		}
+		if i, ok := index[listID]; ok {
+			lists[i].Watches = append(lists[i].Watches, w)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over results: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return lists, nil
+}
+
+func (r *WatchRepository) GetWatchlist(ctx context.Context, userID uuid.UUID, id uuid.UUID) (structs.Watchlist, error) {
+	lists, err := r.GetWatchlists(ctx, userID)
+	if err != nil {
+		return structs.Watchlist{}, err
+	}
+	for _, list := range lists {
+		if list.ID == id {
+			return list, nil
+		}
+	}
+	return structs.Watchlist{}, fmt.Errorf("watchlist with ID %s not found: %w", id, pgx.ErrNoRows)
+}
+
+func (r *WatchRepository) DeleteWatchlist(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `DELETE FROM watchlist WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete watchlist: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("watchlist with ID %s not found: %w", id, pgx.ErrNoRows)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// AddWatch adds w to a watchlist of userID. Adding a watch the list already
+// has returns the existing one.
+func (r *WatchRepository) AddWatch(ctx context.Context, userID uuid.UUID, watchlistID uuid.UUID, w structs.Watch) (structs.Watch, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return w, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := ownWatchlist(ctx, tx, userID, watchlistID); err != nil {
+		return w, err
+	}
+	if err := tx.QueryRow(ctx, insertWatch, watchlistID, w.Kind, w.Value).Scan(&w.ID, &w.CreatedAt); err != nil {
+		return w, fmt.Errorf("failed to insert watch: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE watchlist SET updated_at = NOW() WHERE id = $1`, watchlistID); err != nil {
+		return w, fmt.Errorf("failed to update watchlist: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return w, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return w, nil
+}
+
+func (r *WatchRepository) DeleteWatch(ctx context.Context, userID uuid.UUID, watchlistID uuid.UUID, id uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := ownWatchlist(ctx, tx, userID, watchlistID); err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM watch WHERE id = $1 AND watchlist_id = $2`, id, watchlistID)
+	if err != nil {
+		return fmt.Errorf("failed to delete watch: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("watch with ID %s not found: %w", id, pgx.ErrNoRows)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE watchlist SET updated_at = NOW() WHERE id = $1`, watchlistID); err != nil {
+		return fmt.Errorf("failed to update watchlist: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// MatchWebhooks returns the active webhooks of the users watching any of the
+// given kind/value pairs, one row per webhook and matching watch. It runs in
+// tx, the one the changes are recorded in.
+func MatchWebhooks(ctx context.Context, tx pgx.Tx, kinds []string, values []string) ([]structs.WebhookMatch, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT DISTINCT h.id, w.watchlist_id, w.kind, w.value
+		FROM unnest($1::TEXT[], $2::TEXT[]) AS k (kind, value)
+		JOIN watch w ON w.kind = k.kind AND w.value = k.value
+		JOIN watchlist l ON l.id = w.watchlist_id
+		JOIN webhook h ON h.user_id = l.user_id AND h.active`, kinds, values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []structs.WebhookMatch
+	for rows.Next() {
+		var m structs.WebhookMatch
+		if err := rows.Scan(&m.WebhookID, &m.WatchlistID, &m.Kind, &m.Value); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook match: %w", err)
+		}
+		matches = append(matches, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over results: %w", err)
+	}
+
+	return matches, nil
+}
+
+func ownWatchlist(ctx context.Context, tx pgx.Tx, userID uuid.UUID, watchlistID uuid.UUID) error {
+	var id uuid.UUID
+	err := tx.QueryRow(ctx, `SELECT id FROM watchlist WHERE id = $1 AND user_id = $2`, watchlistID, userID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("watchlist with ID %s not found: %w", watchlistID, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read watchlist: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,364 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const deliveryColumns = `
+	d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.next_attempt_at,
+	d.last_status_code, d.last_error, d.created_at, d.delivered_at`
+
+func scanDelivery(row pgx.Row) (structs.WebhookDelivery, error) {
+	var d structs.WebhookDelivery
+	err := row.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt)
+	return d, err
+}
+
+func (r *WatchRepository) CreateWebhook(ctx context.Context, userID uuid.UUID, url string, secret string) (structs.Webhook, error) {
+	hook := structs.Webhook{UserID: userID, URL: url, Secret: secret, Active: true}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return hook, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO webhook (user_id, url, secret) VALUES ($1, $2, $3)
+		RETURNING id, created_at`, userID, url, secret).Scan(&hook.ID, &hook.CreatedAt)
+	if err != nil {
+		return hook, fmt.Errorf("failed to insert webhook: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return hook, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return hook, nil
+}
+
+// GetWebhooks returns the webhooks of userID without their secrets.
+func (r *WatchRepository) GetWebhooks(ctx context.Context, userID uuid.UUID) ([]structs.Webhook, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, user_id, url, active, created_at, updated_at
+		FROM webhook
+		WHERE user_id = $1
+		ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	hooks := []structs.Webhook{}
+	for rows.Next() {
+		var h structs.Webhook
+		if err := rows.Scan(&h.ID, &h.UserID, &h.URL, &h.Active, &h.CreatedAt, &h.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		hooks = append(hooks, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over results: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return hooks, nil
+}
+
+func (r *WatchRepository) SetWebhookActive(ctx context.Context, userID uuid.UUID, id uuid.UUID, active bool) error {
+	return r.execOwned(ctx, "webhook", id, `
+		UPDATE webhook SET active = $3, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2`, id, userID, active)
+}
+
+func (r *WatchRepository) DeleteWebhook(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	return r.execOwned(ctx, "webhook", id, `DELETE FROM webhook WHERE id = $1 AND user_id = $2`, id, userID)
+}
+
+// EnqueueDeliveries stores pending deliveries, due now, in tx, so they are
+// queued if and only if the changes they are about are recorded.
+func EnqueueDeliveries(ctx context.Context, tx pgx.Tx, deliveries []structs.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	batch := &pgx.Batch{}
+	for _, d := range deliveries {
+		batch.Queue(`
+			INSERT INTO webhook_delivery (id, webhook_id, event, payload)
+			VALUES ($1, $2, $3, $4)`, d.ID, d.WebhookID, d.Event, d.Payload)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to insert deliveries: %w", err)
+	}
+	return nil
+}
+
+// ClaimDeliveries takes up to limit due deliveries of active webhooks and
+// pushes them back until leaseUntil, so another dispatcher does not send
+// them too. A dispatcher that dies holding a claim only delays it.
+func (r *WatchRepository) ClaimDeliveries(ctx context.Context, limit int, leaseUntil time.Time) ([]structs.DueDelivery, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		UPDATE webhook_delivery AS d SET next_attempt_at = $2
+		WHERE d.id IN (
+			SELECT q.id FROM webhook_delivery q JOIN webhook h ON h.id = q.webhook_id
+			WHERE q.status = 'pending' AND q.next_attempt_at <= NOW() AND h.active
+			ORDER BY q.next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED)
+		RETURNING`+deliveryColumns, limit, leaseUntil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim deliveries: %w", err)
+	}
+	var due []structs.DueDelivery
+	var hooks []uuid.UUID
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		due = append(due, structs.DueDelivery{WebhookDelivery: d})
+		hooks = append(hooks, d.WebhookID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over results: %w", err)
+	}
+
+	if len(due) > 0 {
+		rows, err := tx.Query(ctx, `SELECT id, url, secret FROM webhook WHERE id = ANY($1)`, hooks)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute query: %w", err)
+		}
+		type target struct{ url, secret string }
+		targets := make(map[uuid.UUID]target)
+		for rows.Next() {
+			var id uuid.UUID
+			var t target
+			if err := rows.Scan(&id, &t.url, &t.secret); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan webhook: %w", err)
+			}
+			targets[id] = t
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to iterate over results: %w", err)
+		}
+		for i := range due {
+			due[i].URL = targets[due[i].WebhookID].url
+			due[i].Secret = targets[due[i].WebhookID].secret
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return due, nil
+}
+
+// RecordAttempt logs one send of a delivery and moves it to status; a
+// pending delivery is due again at next.
+func (r *WatchRepository) RecordAttempt(ctx context.Context, id uuid.UUID, attempt structs.WebhookAttempt, status string, next time.Time) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var lastError *string
+	if attempt.Error != "" {
+		lastError = &attempt.Error
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE webhook_delivery SET
+			status = $2, attempts = attempts + 1, next_attempt_at = $3,
+			last_status_code = $4, last_error = $5,
+			delivered_at = CASE WHEN $2 = 'delivered' THEN NOW() ELSE delivered_at END
+		WHERE id = $1`, id, status, next, attempt.StatusCode, lastError)
+	if err != nil {
+		return fmt.Errorf("failed to update delivery: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		INSERT INTO webhook_delivery_attempt (delivery_id, attempt, status_code, error, duration_ms, attempted_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		id, attempt.Attempt, attempt.StatusCode, lastError, attempt.DurationMs, attempt.AttemptedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert delivery attempt: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetDeliveries returns the latest deliveries of a webhook of userID.
+func (r *WatchRepository) GetDeliveries(ctx context.Context, userID uuid.UUID, webhookID uuid.UUID, filter structs.DeliveryFilter) ([]structs.WebhookDelivery, error) {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := ownWebhook(ctx, tx, userID, webhookID); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_delivery d WHERE d.webhook_id = $1`
+	args := []interface{}{webhookID}
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += ` AND d.status = $2`
+	}
+	query += ` ORDER BY d.created_at DESC, d.id`
+	if filter.Limit > 0 {
+		query += ` LIMIT ` + strconv.Itoa(filter.Limit)
+	}
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []structs.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate over results: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// GetDelivery returns a delivery of a webhook of userID with its attempts.
+func (r *WatchRepository) GetDelivery(ctx context.Context, userID uuid.UUID, webhookID uuid.UUID, id uuid.UUID) (structs.WebhookDelivery, error) {
+	var d structs.WebhookDelivery
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return d, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := ownWebhook(ctx, tx, userID, webhookID); err != nil {
+		return d, err
+	}
+	d, err = scanDelivery(tx.QueryRow(ctx, `
+		SELECT `+deliveryColumns+` FROM webhook_delivery d
+		WHERE d.id = $1 AND d.webhook_id = $2`, id, webhookID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return d, fmt.Errorf("delivery with ID %s not found: %w", id, err)
+		}
+		return d, fmt.Errorf("failed to scan delivery: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT attempt, status_code, COALESCE(error, ''), duration_ms, attempted_at
+		FROM webhook_delivery_attempt
+		WHERE delivery_id = $1
+		ORDER BY attempted_at`, id)
+	if err != nil {
+		return d, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+	d.Log = []structs.WebhookAttempt{}
+	for rows.Next() {
+		var a structs.WebhookAttempt
+		if err := rows.Scan(&a.Attempt, &a.StatusCode, &a.Error, &a.DurationMs, &a.AttemptedAt); err != nil {
+			return d, fmt.Errorf("failed to scan delivery attempt: %w", err)
+		}
+		d.Log = append(d.Log, a)
+	}
+	if err := rows.Err(); err != nil {
+		return d, fmt.Errorf("failed to iterate over results: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return d, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return d, nil
+}
+
+// RetryDelivery puts a dead delivery back in the queue with a fresh set of
+// attempts.
+func (r *WatchRepository) RetryDelivery(ctx context.Context, userID uuid.UUID, webhookID uuid.UUID, id uuid.UUID) error {
+	return r.execOwned(ctx, "dead delivery", id, `
+		UPDATE webhook_delivery SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+		WHERE id = $1 AND status = 'dead'
+		  AND webhook_id = (SELECT h.id FROM webhook h WHERE h.id = $3 AND h.user_id = $2)`,
+		id, userID, webhookID)
+}
+
+// execOwned runs a statement scoped to a row of the user and reports a
+// missing row as pgx.ErrNoRows.
+func (r *WatchRepository) execOwned(ctx context.Context, what string, id uuid.UUID, sql string, args ...interface{}) error {
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", what, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%s with ID %s not found: %w", what, id, pgx.ErrNoRows)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+func ownWebhook(ctx context.Context, tx pgx.Tx, userID uuid.UUID, webhookID uuid.UUID) error {
+	var id uuid.UUID
+	err := tx.QueryRow(ctx, `SELECT id FROM webhook WHERE id = $1 AND user_id = $2`, webhookID, userID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("webhook with ID %s not found: %w", webhookID, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read webhook: %w", err)
+	}
+	return nil
+}
@@ -2,28 +2,56 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
+	"syscall"
+	"time"
+
 	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/airline"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/airport"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/batch"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/bulk"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/flight"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/history"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/ingestion"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/integrity"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/job"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/location"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/migrations"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/outbox"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/stats"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/trash"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/user"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/version"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/watch"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/FACorreiaa/aviatoon-tracker/pkg/logs"
 	"github.com/google/uuid"
 )
 
 type Config struct {
-	postgresConfig postgres.Config
+	postgresConfig   postgres.Config
+	migrationsConfig migrations.Config
+	bulkConfig       bulk.Config
 }
 
-func NewConfig(postgresConfig postgres.Config) Config {
-	return Config{postgresConfig: postgresConfig}
+func NewConfig(postgresConfig postgres.Config, migrationsConfig migrations.Config, bulkConfig bulk.Config) Config {
+	return Config{
+		postgresConfig:   postgresConfig,
+		migrationsConfig: migrationsConfig,
+		bulkConfig:       bulkConfig,
+	}
 }
 
 type Tax interface {
 	CreateTax(ctx context.Context, t *structs.Tax) error
+	CreateTaxes(ctx context.Context, taxes []structs.Tax) (structs.BatchResult, error)
+	DiffTaxes(ctx context.Context, taxes []structs.Tax) (structs.IngestionDiff, error)
 	GetTaxs(ctx context.Context) ([]structs.Tax, error)
+	StreamTaxes(ctx context.Context, fn func(structs.Tax) error) error
 	GetTax(ctx context.Context, id uuid.UUID) (structs.Tax, error)
-	UpdateTax(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error
-	DeleteTax(ctx context.Context, id uuid.UUID) error
+	DeleteTax(ctx context.Context, id uuid.UUID, check func(structs.Tax) error) error
+	UpdateTax(ctx context.Context, id uuid.UUID, fn func(structs.Tax) (structs.Tax, error)) error
 	GetTaxesCount(ctx context.Context) (int, error)
 }
 
@@ -31,92 +59,242 @@ type Tax interface {
 
 type Airport interface {
 	CreateAirport(ctx context.Context, a *structs.Airport) error
+	CreateAirports(ctx context.Context, airports []structs.Airport) (structs.BatchResult, error)
+	DiffAirports(ctx context.Context, airports []structs.Airport) (structs.IngestionDiff, error)
 	GetAirports(ctx context.Context) ([]structs.Airport, error)
+	StreamAirports(ctx context.Context, fn func(structs.Airport) error) error
 	GetAirport(ctx context.Context, id uuid.UUID) (structs.Airport, error)
-	DeleteAirport(ctx context.Context, id uuid.UUID) error
+	DeleteAirport(ctx context.Context, id uuid.UUID, check func(structs.Airport) error) error
+	UpdateAirport(ctx context.Context, id uuid.UUID, fn func(structs.Airport) (structs.Airport, error)) error
 	GetAirportCount(ctx context.Context) (int, error)
-	UpdateAirport(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error
 	GetCitiesAirports(ctx context.Context) ([]structs.AirportInfo, error)
+	StreamCitiesAirports(ctx context.Context, fn func(structs.AirportInfo) error) error
 	GetCityNameAirport(ctx context.Context, cityName string) ([]structs.AirportInfo, error)
 	GetCityNameAirportAlternative(ctx context.Context, cityName string) ([]structs.AirportInfo, error)
 	GetCountryNameAirport(ctx context.Context, countryName string) ([]structs.AirportInfo, error)
 	GetCityIataCodeAirport(ctx context.Context, iataCode string) ([]structs.AirportInfo, error)
+	GetAirportPositions(ctx context.Context, iataCodes []string) ([]structs.AirportPosition, error)
 }
 
 type Country interface {
 	CreateCountry(ctx context.Context, t *structs.Country) error
+	CreateCountries(ctx context.Context, countries []structs.Country) (structs.BatchResult, error)
+	DiffCountries(ctx context.Context, countries []structs.Country) (structs.IngestionDiff, error)
 	GetCountries(ctx context.Context) ([]structs.Country, error)
+	StreamCountries(ctx context.Context, fn func(structs.Country) error) error
 	GetCountry(ctx context.Context, id uuid.UUID) (structs.Country, error)
-	UpdateCountry(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error
-	DeleteCountry(ctx context.Context, id uuid.UUID) error
+	DeleteCountry(ctx context.Context, id uuid.UUID, check func(structs.Country) error) error
+	UpdateCountry(ctx context.Context, id uuid.UUID, fn func(structs.Country) (structs.Country, error)) error
 	GetCountryCount(ctx context.Context) (int, error)
 }
 
 type City interface {
 	CreateCity(ctx context.Context, t *structs.City) error
+	CreateCities(ctx context.Context, cities []structs.City) (structs.BatchResult, error)
+	DiffCities(ctx context.Context, cities []structs.City) (structs.IngestionDiff, error)
 	GetCities(ctx context.Context) ([]structs.City, error)
+	StreamCities(ctx context.Context, fn func(structs.City) error) error
 	GetCity(ctx context.Context, id uuid.UUID) (structs.City, error)
-	UpdateCity(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error
-	DeleteCity(ctx context.Context, id uuid.UUID) error
+	DeleteCity(ctx context.Context, id uuid.UUID, check func(structs.City) error) error
+	UpdateCity(ctx context.Context, id uuid.UUID, fn func(structs.City) (structs.City, error)) error
 	GetCityCount(ctx context.Context) (int, error)
 	GetCitiesFromCountry(ctx context.Context) ([]structs.CityInfo, error)
+	StreamCitiesFromCountry(ctx context.Context, fn func(structs.CityInfo) error) error
 	GetCityFromCountry(ctx context.Context, id uuid.UUID) ([]structs.CityInfo, error)
 }
 
 type Aircraft interface {
 	CreateAircraft(ctx context.Context, a *structs.Aircraft) error
+	CreateAircrafts(ctx context.Context, aircrafts []structs.Aircraft) (structs.BatchResult, error)
+	DiffAircrafts(ctx context.Context, aircrafts []structs.Aircraft) (structs.IngestionDiff, error)
 	GetAircrafts(ctx context.Context) ([]structs.Aircraft, error)
+	StreamAircrafts(ctx context.Context, fn func(structs.Aircraft) error) error
 	GetAircraft(ctx context.Context, id uuid.UUID) (structs.Aircraft, error)
-	UpdateAircraft(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error
-	DeleteAircraft(ctx context.Context, id uuid.UUID) error
+	DeleteAircraft(ctx context.Context, id uuid.UUID, check func(structs.Aircraft) error) error
+	UpdateAircraft(ctx context.Context, id uuid.UUID, fn func(structs.Aircraft) (structs.Aircraft, error)) error
 	GetAircraftCount(ctx context.Context) (int, error)
 }
 
 type Airline interface {
 	CreateAirline(ctx context.Context, t *structs.Airline) error
+	CreateAirlines(ctx context.Context, airlines []structs.Airline) (structs.BatchResult, error)
+	DiffAirlines(ctx context.Context, airlines []structs.Airline) (structs.IngestionDiff, error)
 	GetAirlines(ctx context.Context) ([]structs.Airline, error)
+	StreamAirlines(ctx context.Context, fn func(structs.Airline) error) error
 	GetAirline(ctx context.Context, id uuid.UUID) (structs.Airline, error)
-	UpdateAirline(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error
-	DeleteAirline(ctx context.Context, id uuid.UUID) error
+	DeleteAirline(ctx context.Context, id uuid.UUID, check func(structs.Airline) error) error
+	UpdateAirline(ctx context.Context, id uuid.UUID, fn func(structs.Airline) (structs.Airline, error)) error
 	GetAirlineCount(ctx context.Context) (int, error)
 	GetAirlinesCountry(ctx context.Context) ([]structs.AirlineInfo, error)
+	StreamAirlinesCountry(ctx context.Context, fn func(structs.AirlineInfo) error) error
 	GetAirlineCountry(ctx context.Context, id int) ([]structs.AirlineInfo, error)
 	GetAirlineCountryName(ctx context.Context, countryName string) ([]structs.AirlineInfo, error)
 	GetAirlineCityName(ctx context.Context, cityName string) ([]structs.AirlineInfo, error)
 	GetAirlineCountryCityName(ctx context.Context, coutryName string, cityName string) ([]structs.AirlineInfo, error)
+	GetAirlineFleet(ctx context.Context, id uuid.UUID) (structs.AirlineFleet, error)
+	GetFleetLeaderboard(ctx context.Context, order string, limit int) ([]structs.FleetLeaderboardEntry, error)
+	GetAirlinesByCode(ctx context.Context, codes []string) ([]structs.Airline, error)
 }
 
 type Airplane interface {
 	CreateAirplane(ctx context.Context, a *structs.Airplane) error
+	CreateAirplanes(ctx context.Context, airplanes []structs.Airplane) (structs.BatchResult, error)
+	DiffAirplanes(ctx context.Context, airplanes []structs.Airplane) (structs.IngestionDiff, error)
 	GetAirplanes(ctx context.Context) ([]structs.Airplane, error)
+	StreamAirplanes(ctx context.Context, fn func(structs.Airplane) error) error
 	GetAirplane(ctx context.Context, id uuid.UUID) (structs.Airplane, error)
-	UpdateAirplane(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error
-	DeleteAirplane(ctx context.Context, id uuid.UUID) error
+	DeleteAirplane(ctx context.Context, id uuid.UUID, check func(structs.Airplane) error) error
+	UpdateAirplane(ctx context.Context, id uuid.UUID, fn func(structs.Airplane) (structs.Airplane, error)) error
 	GetAirplaneCount(ctx context.Context) (int, error)
 	GetAirplaneAirline(ctx context.Context) ([]structs.AirplaneInfo, error)
+	StreamAirplaneAirline(ctx context.Context, fn func(structs.AirplaneInfo) error) error
 	GetAirplanesFromAirlineName(ctx context.Context, airlineName string) ([]structs.AirplaneInfo, error)
 	GetAirplanesFromAirlineCountry(ctx context.Context, countryName string) ([]structs.AirplaneInfo, error)
+	GetAirplanesByRegistration(ctx context.Context, registrations []string) ([]structs.Airplane, error)
+}
+
+type Integrity interface {
+	LinkReferences(ctx context.Context) (structs.LinkResult, error)
+	LinkRows(ctx context.Context, table string, ids []uuid.UUID) (structs.LinkResult, error)
+	GetIntegrityReport(ctx context.Context, limit int) (structs.IntegrityReport, error)
+}
+
+type Version interface {
+	GetVersion(ctx context.Context, tables ...string) (structs.TableVersion, error)
+}
+
+type Flight interface {
+	RecordFlights(ctx context.Context, flights []structs.Flight, notify structs.Notifier) (structs.FlightIngestion, error)
+	GetRoutesSince(ctx context.Context, since time.Time) ([]structs.Route, error)
+	GetScheduledLegs(ctx context.Context, from time.Time, until time.Time, departures []string) ([]structs.Flight, error)
+	FindFlights(ctx context.Context, filter structs.FlightFilter) ([]structs.Flight, error)
+}
+
+type Stats interface {
+	ReplaceOTPDaily(ctx context.Context, from time.Time, to time.Time, rows []structs.OTPDaily) error
+	GetOTPDaily(ctx context.Context, filter structs.OTPFilter) ([]structs.OTPDaily, error)
+}
+
+type User interface {
+	CreateUser(ctx context.Context, email string, refreshTokenHash string) (structs.User, error)
+	GetUser(ctx context.Context, id uuid.UUID) (structs.User, error)
+	RotateRefreshToken(ctx context.Context, current string, next string) (structs.User, error)
+}
+
+type Watch interface {
+	CreateWatchlist(ctx context.Context, userID uuid.UUID, name string, watches []structs.Watch) (structs.Watchlist, error)
+	GetWatchlists(ctx context.Context, userID uuid.UUID) ([]structs.Watchlist, error)
+	GetWatchlist(ctx context.Context, userID uuid.UUID, id uuid.UUID) (structs.Watchlist, error)
+	DeleteWatchlist(ctx context.Context, userID uuid.UUID, id uuid.UUID) error
+	AddWatch(ctx context.Context, userID uuid.UUID, watchlistID uuid.UUID, w structs.Watch) (structs.Watch, error)
+	DeleteWatch(ctx context.Context, userID uuid.UUID, watchlistID uuid.UUID, id uuid.UUID) error
+	CreateWebhook(ctx context.Context, userID uuid.UUID, url string, secret string) (structs.Webhook, error)
+	GetWebhooks(ctx context.Context, userID uuid.UUID) ([]structs.Webhook, error)
+	SetWebhookActive(ctx context.Context, userID uuid.UUID, id uuid.UUID, active bool) error
+	DeleteWebhook(ctx context.Context, userID uuid.UUID, id uuid.UUID) error
+	ClaimDeliveries(ctx context.Context, limit int, leaseUntil time.Time) ([]structs.DueDelivery, error)
+	RecordAttempt(ctx context.Context, id uuid.UUID, attempt structs.WebhookAttempt, status string, next time.Time) error
+	GetDeliveries(ctx context.Context, userID uuid.UUID, webhookID uuid.UUID, filter structs.DeliveryFilter) ([]structs.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, userID uuid.UUID, webhookID uuid.UUID, id uuid.UUID) (structs.WebhookDelivery, error)
+	RetryDelivery(ctx context.Context, userID uuid.UUID, webhookID uuid.UUID, id uuid.UUID) error
+}
+
+type Job interface {
+	CreateJob(ctx context.Context, j structs.Job) (structs.Job, bool, error)
+	GetJobs(ctx context.Context, filter structs.JobFilter) ([]structs.Job, error)
+	GetJob(ctx context.Context, id uuid.UUID) (structs.Job, error)
+	GetJobOutput(ctx context.Context, id uuid.UUID) (structs.JobOutput, error)
+	StreamJobOutput(ctx context.Context, id uuid.UUID, fn func([]byte) error) error
+	ClearJobOutput(ctx context.Context, id uuid.UUID, worker string) error
+	AppendJobOutput(ctx context.Context, id uuid.UUID, worker string, seq int, data []byte) error
+	ClaimJobs(ctx context.Context, worker string, limit int, leaseUntil time.Time) ([]structs.Job, error)
+	RenewLease(ctx context.Context, id uuid.UUID, worker string, leaseUntil time.Time) error
+	FinishJob(ctx context.Context, id uuid.UUID, worker string, result json.RawMessage, outputType *string) error
+	FailJob(ctx context.Context, id uuid.UUID, worker string, lastError string, retryAt *time.Time) error
+	ReleaseJob(ctx context.Context, id uuid.UUID, worker string) error
+	CancelJob(ctx context.Context, id uuid.UUID) error
+	RetryJob(ctx context.Context, id uuid.UUID) error
+}
+
+type Ingestion interface {
+	CreateRun(ctx context.Context, dataset string, provider string, dryRun bool) (structs.IngestionRun, error)
+	FinishRun(ctx context.Context, run structs.IngestionRun, diff *structs.IngestionDiff) error
+	GetRuns(ctx context.Context, filter structs.IngestionFilter) ([]structs.IngestionRun, error)
+	GetRun(ctx context.Context, id uuid.UUID) (structs.IngestionRun, error)
+	GetRunDiff(ctx context.Context, id uuid.UUID) (structs.IngestionDiff, error)
+	GetSchema(ctx context.Context, endpoint string) (structs.UpstreamSchema, error)
+	SaveSchema(ctx context.Context, endpoint string, fields []structs.FieldSchema) error
+	DeleteSchema(ctx context.Context, endpoint string) error
+	CreateDrift(ctx context.Context, drift []structs.SchemaDrift) error
+	GetRunDrift(ctx context.Context, runID uuid.UUID) ([]structs.SchemaDrift, error)
+}
+
+type Trash interface {
+	Restore(ctx context.Context, table string, id uuid.UUID) error
+	Purge(ctx context.Context, table string, before time.Time) (int64, error)
+}
+
+type History interface {
+	GetHistory(ctx context.Context, table string, id uuid.UUID, limit int) ([]structs.HistoryEntry, error)
+}
+
+type Outbox interface {
+	ClaimEvents(ctx context.Context, limit int, leaseUntil time.Time) ([]structs.ChangeEvent, error)
+	DeleteEvent(ctx context.Context, id int64) error
+	FailEvent(ctx context.Context, id int64, lastError string, next time.Time) error
+}
+
+type Batch interface {
+	Apply(ctx context.Context, table string, ops []structs.BulkOperation, partial bool) ([]structs.BulkItemResult, error)
 }
 
 type Repository struct {
-	Tax      Tax
-	Airport  Airport
-	Country  Country
-	City     City
-	Aircraft Aircraft
-	Airline  Airline
-	Airplane Airplane
+	Tax       Tax
+	Airport   Airport
+	Country   Country
+	City      City
+	Aircraft  Aircraft
+	Airline   Airline
+	Airplane  Airplane
+	Integrity Integrity
+	Version   Version
+	Flight    Flight
+	Stats     Stats
+	User      User
+	Watch     Watch
+	Job       Job
+	Ingestion Ingestion
+	Trash     Trash
+	History   History
+	Outbox    Outbox
+	Batch     Batch
 }
 
 func NewRepository(config Config) *Repository {
 	psql := postgres.NewPostgres(config.postgresConfig)
+	if err := migrations.Init(context.Background(), psql.GetDB(), config.migrationsConfig); err != nil {
+		logs.DefaultLogger.WithError(err).Fatal("Error on migrations init")
+		syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+	}
 	return &Repository{
-		Tax:      airline.NewRepositoryAirline(psql.GetDB()),
-		Airport:  airport.NewRepositoryAirport(psql.GetDB()),
-		Country:  location.NewRepositoryLocation(psql.GetDB()),
-		City:     location.NewRepositoryLocation(psql.GetDB()),
-		Aircraft: airline.NewRepositoryAirline(psql.GetDB()),
-		Airline:  airline.NewRepositoryAirline(psql.GetDB()),
-		Airplane: airline.NewRepositoryAirline(psql.GetDB()),
+		Tax:       airline.NewRepositoryAirline(psql.GetDB(), config.bulkConfig),
+		Airport:   airport.NewRepositoryAirport(psql.GetDB(), config.bulkConfig),
+		Country:   location.NewRepositoryLocation(psql.GetDB(), config.bulkConfig),
+		City:      location.NewRepositoryLocation(psql.GetDB(), config.bulkConfig),
+		Aircraft:  airline.NewRepositoryAirline(psql.GetDB(), config.bulkConfig),
+		Airline:   airline.NewRepositoryAirline(psql.GetDB(), config.bulkConfig),
+		Airplane:  airline.NewRepositoryAirline(psql.GetDB(), config.bulkConfig),
+		Integrity: integrity.NewRepositoryIntegrity(psql.GetDB()),
+		Version:   version.NewRepositoryVersion(psql.GetDB()),
+		Flight:    flight.NewRepositoryFlight(psql.GetDB()),
+		Stats:     stats.NewRepositoryStats(psql.GetDB()),
+		User:      user.NewRepositoryUser(psql.GetDB()),
+		Watch:     watch.NewRepositoryWatch(psql.GetDB()),
+		Job:       job.NewRepositoryJob(psql.GetDB()),
+		Ingestion: ingestion.NewRepositoryIngestion(psql.GetDB()),
+		Trash:     trash.NewRepositoryTrash(psql.GetDB()),
+		History:   history.NewRepositoryHistory(psql.GetDB()),
+		Outbox:    outbox.NewRepositoryOutbox(psql.GetDB()),
+		Batch:     batch.NewRepositoryBatch(psql.GetDB()),
 	}
 }
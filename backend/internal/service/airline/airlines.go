@@ -25,20 +25,24 @@ func (s *Service) CreateTax(ctx context.Context, tax *structs.Tax) error {
 
 }
 
-func (s *Service) GetTaxs(ctx context.Context) ([]structs.Tax, error) {
-	return s.repo.Tax.GetTaxs(ctx)
+func (s *Service) CreateTaxes(ctx context.Context, taxes []structs.Tax) (structs.BatchResult, error) {
+	return s.repo.Tax.CreateTaxes(ctx, taxes)
 }
 
-func (s *Service) GetTax(ctx context.Context, id uuid.UUID) (structs.Tax, error) {
-	return s.repo.Tax.GetTax(ctx, id)
+func (s *Service) DiffTaxes(ctx context.Context, taxes []structs.Tax) (structs.IngestionDiff, error) {
+	return s.repo.Tax.DiffTaxes(ctx, taxes)
 }
 
-func (s *Service) DeleteTax(ctx context.Context, id uuid.UUID) error {
-	return s.repo.Tax.DeleteTax(ctx, id)
+func (s *Service) GetTaxs(ctx context.Context) ([]structs.Tax, error) {
+	return s.repo.Tax.GetTaxs(ctx)
 }
 
-func (s *Service) UpdateTax(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error {
-	return s.repo.Tax.UpdateTax(ctx, id, updates)
+func (s *Service) StreamTaxes(ctx context.Context, fn func(structs.Tax) error) error {
+	return s.repo.Tax.StreamTaxes(ctx, fn)
+}
+
+func (s *Service) GetTax(ctx context.Context, id uuid.UUID) (structs.Tax, error) {
+	return s.repo.Tax.GetTax(ctx, id)
 }
 
 func (s *Service) GetTaxesCount(ctx context.Context) (int, error) {
@@ -58,20 +62,24 @@ func (s *Service) CreateAircraft(ctx context.Context, aircraft *structs.Aircraft
 
 }
 
-func (s *Service) GetAircrafts(ctx context.Context) ([]structs.Aircraft, error) {
-	return s.repo.Aircraft.GetAircrafts(ctx)
+func (s *Service) CreateAircrafts(ctx context.Context, aircrafts []structs.Aircraft) (structs.BatchResult, error) {
+	return s.repo.Aircraft.CreateAircrafts(ctx, aircrafts)
 }
 
-func (s *Service) GetAircraft(ctx context.Context, id uuid.UUID) (structs.Aircraft, error) {
-	return s.repo.Aircraft.GetAircraft(ctx, id)
+func (s *Service) DiffAircrafts(ctx context.Context, aircrafts []structs.Aircraft) (structs.IngestionDiff, error) {
+	return s.repo.Aircraft.DiffAircrafts(ctx, aircrafts)
+}
+
+func (s *Service) GetAircrafts(ctx context.Context) ([]structs.Aircraft, error) {
+	return s.repo.Aircraft.GetAircrafts(ctx)
 }
 
-func (s *Service) DeleteAircraft(ctx context.Context, id uuid.UUID) error {
-	return s.repo.Aircraft.DeleteAircraft(ctx, id)
+func (s *Service) StreamAircrafts(ctx context.Context, fn func(structs.Aircraft) error) error {
+	return s.repo.Aircraft.StreamAircrafts(ctx, fn)
 }
 
-func (s *Service) UpdateAircraft(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error {
-	return s.repo.Aircraft.UpdateAircraft(ctx, id, updates)
+func (s *Service) GetAircraft(ctx context.Context, id uuid.UUID) (structs.Aircraft, error) {
+	return s.repo.Aircraft.GetAircraft(ctx, id)
 }
 
 func (s *Service) GetAircraftCount(ctx context.Context) (int, error) {
@@ -86,20 +94,24 @@ func (s *Service) CreateAirline(ctx context.Context, airline *structs.Airline) e
 	return s.repo.Airline.CreateAirline(ctx, airline)
 }
 
-func (s *Service) GetAirlines(ctx context.Context) ([]structs.Airline, error) {
-	return s.repo.Airline.GetAirlines(ctx)
+func (s *Service) CreateAirlines(ctx context.Context, airlines []structs.Airline) (structs.BatchResult, error) {
+	return s.repo.Airline.CreateAirlines(ctx, airlines)
 }
 
-func (s *Service) GetAirline(ctx context.Context, id uuid.UUID) (structs.Airline, error) {
-	return s.repo.Airline.GetAirline(ctx, id)
+func (s *Service) DiffAirlines(ctx context.Context, airlines []structs.Airline) (structs.IngestionDiff, error) {
+	return s.repo.Airline.DiffAirlines(ctx, airlines)
+}
+
+func (s *Service) GetAirlines(ctx context.Context) ([]structs.Airline, error) {
+	return s.repo.Airline.GetAirlines(ctx)
 }
 
-func (s *Service) UpdateAirline(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error {
-	return s.repo.Airline.UpdateAirline(ctx, id, updates)
+func (s *Service) StreamAirlines(ctx context.Context, fn func(structs.Airline) error) error {
+	return s.repo.Airline.StreamAirlines(ctx, fn)
 }
 
-func (s *Service) DeleteAirline(ctx context.Context, id uuid.UUID) error {
-	return s.repo.Airline.DeleteAirline(ctx, id)
+func (s *Service) GetAirline(ctx context.Context, id uuid.UUID) (structs.Airline, error) {
+	return s.repo.Airline.GetAirline(ctx, id)
 }
 
 func (s *Service) GetAirlineCount(ctx context.Context) (int, error) {
@@ -110,6 +122,10 @@ func (s *Service) GetAirlinesCountry(ctx context.Context) ([]structs.AirlineInfo
 	return s.repo.Airline.GetAirlinesCountry(ctx)
 }
 
+func (s *Service) StreamAirlinesCountry(ctx context.Context, fn func(structs.AirlineInfo) error) error {
+	return s.repo.Airline.StreamAirlinesCountry(ctx, fn)
+}
+
 func (s *Service) GetAirlineCountry(ctx context.Context, id int) ([]structs.AirlineInfo, error) {
 	return s.repo.Airline.GetAirlineCountry(ctx, id)
 }
@@ -126,26 +142,38 @@ func (s *Service) GetAirlineCountryCityName(ctx context.Context, coutryName stri
 	return s.repo.Airline.GetAirlineCountryCityName(ctx, coutryName, cityName)
 }
 
+func (s *Service) GetAirlineFleet(ctx context.Context, id uuid.UUID) (structs.AirlineFleet, error) {
+	return s.repo.Airline.GetAirlineFleet(ctx, id)
+}
+
+func (s *Service) GetFleetLeaderboard(ctx context.Context, order string, limit int) ([]structs.FleetLeaderboardEntry, error) {
+	return s.repo.Airline.GetFleetLeaderboard(ctx, order, limit)
+}
+
 //Airplane
 
 func (s *Service) CreateAirplane(ctx context.Context, t *structs.Airplane) error {
 	return s.repo.Airplane.CreateAirplane(ctx, t)
 }
 
-func (s *Service) GetAirplanes(ctx context.Context) ([]structs.Airplane, error) {
-	return s.repo.Airplane.GetAirplanes(ctx)
+func (s *Service) CreateAirplanes(ctx context.Context, airplanes []structs.Airplane) (structs.BatchResult, error) {
+	return s.repo.Airplane.CreateAirplanes(ctx, airplanes)
 }
 
-func (s *Service) GetAirplane(ctx context.Context, id uuid.UUID) (structs.Airplane, error) {
-	return s.repo.Airplane.GetAirplane(ctx, id)
+func (s *Service) DiffAirplanes(ctx context.Context, airplanes []structs.Airplane) (structs.IngestionDiff, error) {
+	return s.repo.Airplane.DiffAirplanes(ctx, airplanes)
+}
+
+func (s *Service) GetAirplanes(ctx context.Context) ([]structs.Airplane, error) {
+	return s.repo.Airplane.GetAirplanes(ctx)
 }
 
-func (s *Service) UpdateAirplane(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error {
-	return s.repo.Airplane.UpdateAirplane(ctx, id, updates)
+func (s *Service) StreamAirplanes(ctx context.Context, fn func(structs.Airplane) error) error {
+	return s.repo.Airplane.StreamAirplanes(ctx, fn)
 }
 
-func (s *Service) DeleteAirplane(ctx context.Context, id uuid.UUID) error {
-	return s.repo.Airplane.DeleteAirplane(ctx, id)
+func (s *Service) GetAirplane(ctx context.Context, id uuid.UUID) (structs.Airplane, error) {
+	return s.repo.Airplane.GetAirplane(ctx, id)
 }
 
 func (s *Service) GetAirplaneCount(ctx context.Context) (int, error) {
@@ -156,6 +184,10 @@ func (s *Service) GetAirplaneAirline(ctx context.Context) ([]structs.AirplaneInf
 	return s.repo.Airplane.GetAirplaneAirline(ctx)
 }
 
+func (s *Service) StreamAirplaneAirline(ctx context.Context, fn func(structs.AirplaneInfo) error) error {
+	return s.repo.Airplane.StreamAirplaneAirline(ctx, fn)
+}
+
 func (s *Service) GetAirplanesFromAirlineName(ctx context.Context, airlineName string) ([]structs.AirplaneInfo, error) {
 	return s.repo.Airplane.GetAirplanesFromAirlineName(ctx, airlineName)
 }
@@ -163,3 +195,7 @@ func (s *Service) GetAirplanesFromAirlineName(ctx context.Context, airlineName s
 func (s *Service) GetAirplanesFromAirlineCountry(ctx context.Context, countryName string) ([]structs.AirplaneInfo, error) {
 	return s.repo.Airplane.GetAirplanesFromAirlineCountry(ctx, countryName)
 }
+
+func (s *Service) GetAirplanesByRegistration(ctx context.Context, registrations []string) ([]structs.Airplane, error) {
+	return s.repo.Airplane.GetAirplanesByRegistration(ctx, registrations)
+}
@@ -23,20 +23,24 @@ func (s *Service) CreateAirport(ctx context.Context, a *structs.Airport) error {
 	return s.repo.Airport.CreateAirport(ctx, a)
 }
 
-func (s *Service) GetAirports(ctx context.Context) ([]structs.Airport, error) {
-	return s.repo.Airport.GetAirports(ctx)
+func (s *Service) CreateAirports(ctx context.Context, airports []structs.Airport) (structs.BatchResult, error) {
+	return s.repo.Airport.CreateAirports(ctx, airports)
 }
 
-func (s *Service) GetAirport(ctx context.Context, id uuid.UUID) (structs.Airport, error) {
-	return s.repo.Airport.GetAirport(ctx, id)
+func (s *Service) DiffAirports(ctx context.Context, airports []structs.Airport) (structs.IngestionDiff, error) {
+	return s.repo.Airport.DiffAirports(ctx, airports)
+}
+
+func (s *Service) GetAirports(ctx context.Context) ([]structs.Airport, error) {
+	return s.repo.Airport.GetAirports(ctx)
 }
 
-func (s *Service) DeleteAirport(ctx context.Context, id uuid.UUID) error {
-	return s.repo.Airport.DeleteAirport(ctx, id)
+func (s *Service) StreamAirports(ctx context.Context, fn func(structs.Airport) error) error {
+	return s.repo.Airport.StreamAirports(ctx, fn)
 }
 
-func (s *Service) UpdateAirport(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error {
-	return s.repo.Airport.UpdateAirport(ctx, id, updates)
+func (s *Service) GetAirport(ctx context.Context, id uuid.UUID) (structs.Airport, error) {
+	return s.repo.Airport.GetAirport(ctx, id)
 }
 
 func (s *Service) GetAirportCount(ctx context.Context) (int, error) {
@@ -47,6 +51,10 @@ func (s *Service) GetCitiesAirports(ctx context.Context) ([]structs.AirportInfo,
 	return s.repo.Airport.GetCitiesAirports(ctx)
 }
 
+func (s *Service) StreamCitiesAirports(ctx context.Context, fn func(structs.AirportInfo) error) error {
+	return s.repo.Airport.StreamCitiesAirports(ctx, fn)
+}
+
 func (s *Service) GetCityNameAirport(ctx context.Context, cityName string) ([]structs.AirportInfo, error) {
 	return s.repo.Airport.GetCityNameAirport(ctx, cityName)
 }
@@ -62,3 +70,7 @@ func (s *Service) GetCountryNameAirport(ctx context.Context, countryName string)
 func (s *Service) GetCityIataCodeAirport(ctx context.Context, iataCode string) ([]structs.AirportInfo, error) {
 	return s.repo.Airport.GetCityIataCodeAirport(ctx, iataCode)
 }
+
+func (s *Service) GetAirportPositions(ctx context.Context, iataCodes []string) ([]structs.AirportPosition, error) {
+	return s.repo.Airport.GetAirportPositions(ctx, iataCodes)
+}
@@ -0,0 +1,159 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	batchrepo "github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/batch"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+var (
+	ErrUnknownResource = errors.New("unknown resource")
+	ErrInvalidMode     = errors.New("invalid mode")
+	ErrNoOperations    = errors.New("no operations")
+	ErrTooMany         = errors.New("too many operations")
+	// ErrInvalid rejects a batch with an invalid operation, before anything
+	// is written. The result holds the errors of each operation.
+	ErrInvalid = errors.New("invalid operations")
+)
+
+type Service struct {
+	repo   *repository.Repository
+	config Config
+}
+
+func NewService(repo *repository.Repository, config Config) *Service {
+	return &Service{repo: repo, config: config}
+}
+
+// Apply validates every operation of req on resource, then writes them in
+// one transaction: all of them or none in atomic mode, the default, and the
+// ones that succeed in partial mode.
+func (s *Service) Apply(ctx context.Context, resource string, req structs.BulkRequest) (structs.BulkResult, error) {
+	result := structs.BulkResult{Mode: req.Mode}
+	if result.Mode == "" {
+		result.Mode = structs.BulkAtomic
+	}
+
+	if _, ok := batchrepo.Tables[resource]; !ok {
+		return result, fmt.Errorf("%w: %s", ErrUnknownResource, resource)
+	}
+	if result.Mode != structs.BulkAtomic && result.Mode != structs.BulkPartial {
+		return result, fmt.Errorf("%w: %s", ErrInvalidMode, result.Mode)
+	}
+	if len(req.Operations) == 0 {
+		return result, ErrNoOperations
+	}
+	if len(req.Operations) > s.config.maxOperations {
+		return result, fmt.Errorf("%w: %d, at most %d", ErrTooMany, len(req.Operations), s.config.maxOperations)
+	}
+
+	ops := make([]structs.BulkOperation, len(req.Operations))
+	result.Results = make([]structs.BulkItemResult, len(req.Operations))
+	invalid := false
+	for i, op := range req.Operations {
+		ops[i] = structs.BulkOperation{Op: op.Op, ID: op.ID}
+		var errs []string
+		ops[i].Data, errs = validate(resource, op)
+		result.Results[i] = structs.BulkItemResult{Index: i, Op: op.Op, ID: op.ID, Errors: errs}
+		invalid = invalid || len(errs) > 0
+	}
+	if invalid {
+		for i := range result.Results {
+			result.Results[i].Status = http.StatusFailedDependency
+			if len(result.Results[i].Errors) > 0 {
+				result.Results[i].Status = http.StatusUnprocessableEntity
+			} else {
+				result.Results[i].Errors = []string{"not applied, the batch is invalid"}
+			}
+		}
+		result.Failed = len(ops)
+		return result, ErrInvalid
+	}
+
+	results, err := s.repo.Batch.Apply(ctx, resource, ops, result.Mode == structs.BulkPartial)
+	if err != nil {
+		return result, err
+	}
+	result.Results = results
+	for _, r := range results {
+		if r.Status >= 200 && r.Status <= 299 {
+			result.Applied++
+		} else {
+			result.Failed++
+		}
+	}
+	return result, nil
+}
+
+// validate checks an operation against the columns of its table and
+// returns its data converted to the column types, with an error for each
+// problem found.
+func validate(table string, op structs.BulkOperation) (map[string]interface{}, []string) {
+	var errs []string
+	switch op.Op {
+	case structs.BulkCreate:
+	case structs.BulkUpdate:
+		if op.ID == nil {
+			errs = append(errs, "id is required")
+		}
+		if len(op.Data) == 0 {
+			errs = append(errs, "data is required")
+		}
+	case structs.BulkDelete:
+		if op.ID == nil {
+			errs = append(errs, "id is required")
+		}
+		if len(op.Data) > 0 {
+			errs = append(errs, "data is not allowed")
+		}
+	default:
+		return nil, []string{fmt.Sprintf("unknown op %q, expected create, update or delete", op.Op)}
+	}
+
+	data, invalid := Columns(table, op.Data, op.Op == structs.BulkCreate)
+	return data, append(errs, invalid...)
+}
+
+// Columns checks data against the writable columns of table and returns it
+// converted to the column types, with an error for each problem found. A
+// complete row must also set the required columns of the table.
+func Columns(table string, data map[string]interface{}, complete bool) (map[string]interface{}, []string) {
+	columns := batchrepo.Tables[table]
+	var errs []string
+
+	names := make([]string, 0, len(data))
+	for column := range data {
+		names = append(names, column)
+	}
+	sort.Strings(names)
+
+	converted := make(map[string]interface{}, len(data))
+	for _, column := range names {
+		kind, ok := columns[column]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("%s: unknown column", column))
+			continue
+		}
+		v, err := Value(kind, data[column])
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", column, err))
+			continue
+		}
+		converted[column] = v
+	}
+
+	if complete {
+		for _, column := range batchrepo.Required[table] {
+			if data[column] == nil || data[column] == "" {
+				errs = append(errs, fmt.Sprintf("%s: required", column))
+			}
+		}
+	}
+	return converted, errs
+}
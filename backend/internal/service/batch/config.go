@@ -0,0 +1,13 @@
+package batch
+
+type Config struct {
+	maxOperations int
+}
+
+// NewConfig sets how many operations a bulk write may hold.
+func NewConfig(maxOperations int) Config {
+	if maxOperations < 1 {
+		maxOperations = 500
+	}
+	return Config{maxOperations: maxOperations}
+}
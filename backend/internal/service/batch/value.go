@@ -0,0 +1,87 @@
+package batch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"unicode/utf8"
+
+	batchrepo "github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/batch"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+)
+
+// Value converts a decoded JSON value to the Go value written to a column of
+// kind. null is always accepted and clears the column. Numbers may come as
+// json.Number or float64.
+func Value(kind string, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	switch kind {
+	case batchrepo.Text:
+		s, ok := value.(string)
+		if !ok {
+			return nil, errors.New("expected a string")
+		}
+		if utf8.RuneCountInString(s) > batchrepo.TextLength {
+			return nil, fmt.Errorf("longer than %d characters", batchrepo.TextLength)
+		}
+		return s, nil
+
+	case batchrepo.Int:
+		f, err := number(value)
+		if err != nil {
+			return nil, err
+		}
+		if f != math.Trunc(f) || f < math.MinInt32 || f > math.MaxInt32 {
+			return nil, errors.New("expected a 32-bit integer")
+		}
+		return int32(f), nil
+
+	case batchrepo.Float:
+		return number(value)
+
+	case batchrepo.Timestamp:
+		s, ok := value.(string)
+		if !ok {
+			return nil, errors.New("expected a date or an RFC 3339 time")
+		}
+		t, err := structs.ParseTime(s)
+		if err != nil {
+			return nil, err
+		}
+		if t.IsZero() {
+			return nil, nil
+		}
+		return t, nil
+
+	case batchrepo.UUID:
+		s, ok := value.(string)
+		if !ok {
+			return nil, errors.New("expected a UUID")
+		}
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return nil, errors.New("expected a UUID")
+		}
+		return id, nil
+	}
+	return nil, fmt.Errorf("unknown column kind %s", kind)
+}
+
+func number(value interface{}) (float64, error) {
+	switch n := value.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		if err != nil {
+			return 0, errors.New("expected a number")
+		}
+		return f, nil
+	case float64:
+		return n, nil
+	}
+	return 0, errors.New("expected a number")
+}
@@ -0,0 +1,190 @@
+package board
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/localtime"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+const (
+	Departures = "departures"
+	Arrivals   = "arrivals"
+)
+
+// The default window shows what a terminal screen would: the last hour and
+// the next six.
+const (
+	defaultBefore = time.Hour
+	defaultAfter  = 6 * time.Hour
+)
+
+var ErrUnknownAirport = errors.New("unknown airport")
+
+type Service struct {
+	repo *repository.Repository
+}
+
+func NewService(repo *repository.Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// GetBoard lists the departures or arrivals of an airport scheduled between
+// from and to, both wall-clock times at the airport; zero values give the
+// default window around now. Marketing flight numbers are listed under the
+// flight that operates them.
+func (s *Service) GetBoard(ctx context.Context, iata string, direction string, from time.Time, to time.Time) (structs.Board, error) {
+	board := structs.Board{Airport: strings.ToUpper(iata), Direction: direction, Flights: []structs.BoardEntry{}}
+
+	positions, err := s.repo.Airport.GetAirportPositions(ctx, []string{board.Airport})
+	if err != nil {
+		return board, err
+	}
+	if len(positions) == 0 {
+		return board, ErrUnknownAirport
+	}
+	zone := localtime.Zones{}.Load(positions[0].Timezone)
+	board.Timezone = zone.String()
+
+	if from.IsZero() || to.IsZero() {
+		now := time.Now().In(zone)
+		board.From, board.To = now.Add(-defaultBefore), now.Add(defaultAfter)
+	} else {
+		board.From, board.To = localtime.At(from, zone), localtime.At(to, zone)
+	}
+
+	// Flights are dated by their departure, so an arrival can belong to the
+	// day before the window.
+	filter := structs.FlightFilter{
+		Date:  localtime.Stored(board.From).Truncate(24*time.Hour).AddDate(0, 0, -1),
+		Until: localtime.Stored(board.To).Truncate(24 * time.Hour),
+	}
+	if direction == Departures {
+		filter.DepIata = board.Airport
+	} else {
+		filter.ArrIata = board.Airport
+	}
+	flights, err := s.repo.Flight.FindFlights(ctx, filter)
+	if err != nil {
+		return board, err
+	}
+
+	type key struct {
+		flight    string
+		scheduled time.Time
+	}
+	entries := make(map[key]*structs.BoardEntry)
+	var marketing []structs.Flight
+	for _, f := range flights {
+		here, there := f.Departure, f.Arrival
+		if direction == Arrivals {
+			here, there = f.Arrival, f.Departure
+		}
+		if here.Scheduled == nil {
+			continue
+		}
+		scheduled := localtime.At(*here.Scheduled, zone)
+		if scheduled.Before(board.From) || scheduled.After(board.To) {
+			continue
+		}
+		if f.Codeshare != nil {
+			marketing = append(marketing, f)
+			continue
+		}
+		entries[key{f.FlightIata, scheduled}] = entry(f, direction, here, there, scheduled, zone)
+	}
+
+	for _, f := range marketing {
+		here := f.Departure
+		if direction == Arrivals {
+			here = f.Arrival
+		}
+		scheduled := localtime.At(*here.Scheduled, zone)
+		k := key{f.Codeshare.FlightIata, scheduled}
+		operating, ok := entries[k]
+		if !ok {
+			// The operating flight was not polled; show it under its own
+			// number anyway, carrier first.
+			there := f.Arrival
+			if direction == Arrivals {
+				there = f.Departure
+			}
+			operating = entry(f, direction, here, there, scheduled, zone)
+			operating.FlightIata = f.Codeshare.FlightIata
+			operating.AirlineIata = f.Codeshare.AirlineIata
+			operating.AirlineName = ""
+			entries[k] = operating
+		}
+		operating.Codeshares = append(operating.Codeshares, f.FlightIata)
+	}
+
+	for _, e := range entries {
+		sort.Strings(e.Codeshares)
+		board.Flights = append(board.Flights, *e)
+	}
+	sort.Slice(board.Flights, func(i, j int) bool {
+		if !board.Flights[i].Scheduled.Equal(board.Flights[j].Scheduled) {
+			return board.Flights[i].Scheduled.Before(board.Flights[j].Scheduled)
+		}
+		return board.Flights[i].FlightIata < board.Flights[j].FlightIata
+	})
+	return board, nil
+}
+
+func entry(f structs.Flight, direction string, here structs.FlightEndpoint, there structs.FlightEndpoint, scheduled time.Time, zone *time.Location) *structs.BoardEntry {
+	e := &structs.BoardEntry{
+		Scheduled:    scheduled,
+		FlightIata:   f.FlightIata,
+		AirlineIata:  f.AirlineIata,
+		AirlineName:  f.AirlineName,
+		Codeshares:   []string{},
+		Airport:      there.Iata,
+		AirportName:  there.Airport,
+		Terminal:     here.Terminal,
+		Gate:         here.Gate,
+		Baggage:      here.Baggage,
+		DelayMinutes: here.Delay,
+	}
+	if here.Estimated != nil {
+		estimated := localtime.At(*here.Estimated, zone)
+		e.Estimated = &estimated
+		if e.DelayMinutes == nil {
+			if minutes := int(estimated.Sub(scheduled).Minutes()); minutes > 0 {
+				e.DelayMinutes = &minutes
+			}
+		}
+	}
+	if here.Actual != nil {
+		actual := localtime.At(*here.Actual, zone)
+		e.Actual = &actual
+	}
+	e.Status = status(f.Status, direction, e.DelayMinutes)
+	return e
+}
+
+// status turns the provider's flight status into board wording.
+func status(s structs.FlightStatus, direction string, delay *int) string {
+	switch s {
+	case structs.Active:
+		if direction == Departures {
+			return "departed"
+		}
+		return "en route"
+	case structs.Landed:
+		if direction == Departures {
+			return "departed"
+		}
+		return "landed"
+	case structs.Cancelled, structs.Diverted, structs.Incident:
+		return string(s)
+	}
+	if delay != nil && *delay >= structs.OnTimeMinutes {
+		return "delayed"
+	}
+	return "scheduled"
+}
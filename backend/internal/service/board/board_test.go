@@ -0,0 +1,144 @@
+package board
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+type fakeAirports struct {
+	repository.Airport
+}
+
+func (fakeAirports) GetAirportPositions(ctx context.Context, iataCodes []string) ([]structs.AirportPosition, error) {
+	return []structs.AirportPosition{{Iata: "LHR", Latitude: 51.47, Longitude: -0.45, Timezone: "Europe/London"}}, nil
+}
+
+// fakeFlights answers with the flights of the filter's airport and dates,
+// recording the filter.
+type fakeFlights struct {
+	repository.Flight
+	flights []structs.Flight
+	filter  structs.FlightFilter
+}
+
+func (f *fakeFlights) FindFlights(ctx context.Context, filter structs.FlightFilter) ([]structs.Flight, error) {
+	f.filter = filter
+	var found []structs.Flight
+	for _, flight := range f.flights {
+		if (filter.DepIata == "" || flight.Departure.Iata == filter.DepIata) &&
+			(filter.ArrIata == "" || flight.Arrival.Iata == filter.ArrIata) &&
+			!flight.FlightDate.Before(filter.Date) && !flight.FlightDate.After(filter.Until) {
+			found = append(found, flight)
+		}
+	}
+	return found, nil
+}
+
+// wall is a time as the provider sends it: the local wall clock labelled UTC.
+func wall(day int, hour int, minute int) *time.Time {
+	t := time.Date(2024, 7, day, hour, minute, 0, 0, time.UTC)
+	return &t
+}
+
+func leg(number string, from string, to string, departure *time.Time, arrival *time.Time) structs.Flight {
+	return structs.Flight{
+		FlightDate:  departure.Truncate(24 * time.Hour),
+		FlightIata:  number,
+		AirlineIata: number[:2],
+		Status:      structs.Scheduled,
+		Departure:   structs.FlightEndpoint{Iata: from, Scheduled: departure},
+		Arrival:     structs.FlightEndpoint{Iata: to, Scheduled: arrival},
+	}
+}
+
+func codeshare(f structs.Flight, number string, operating string) structs.Flight {
+	f.FlightIata, f.AirlineIata = number, number[:2]
+	f.Codeshare = &structs.FlightCodeshare{FlightIata: operating, AirlineIata: operating[:2]}
+	return f
+}
+
+func TestGetBoard(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ba100 := leg("BA100", "LHR", "JFK", wall(1, 8, 30), wall(1, 11, 20))
+	ba100.Departure.Estimated = wall(1, 8, 50)
+	flights := &fakeFlights{flights: []structs.Flight{
+		ba100,
+		codeshare(ba100, "IB7100", "BA100"),
+		codeshare(ba100, "AA6100", "BA100"),
+		// BA200 itself was not polled: its codeshare stands in for it.
+		codeshare(leg("QR9", "LHR", "DOH", wall(1, 9, 0), wall(1, 18, 0)), "QR9", "BA200"),
+		leg("BA300", "LHR", "MAD", wall(1, 7, 59), wall(1, 11, 30)),
+		leg("BA400", "LHR", "LIS", wall(1, 10, 0), wall(1, 12, 45)),
+		leg("BA500", "LHR", "OPO", wall(1, 10, 1), wall(1, 12, 40)),
+		// Dated the day before, arriving after midnight.
+		leg("BA15", "SIN", "LHR", wall(1, 17, 0), wall(2, 0, 30)),
+		leg("VS4", "JFK", "LHR", wall(2, 0, 45), wall(2, 12, 40)),
+		leg("BA117", "JFK", "LHR", wall(1, 18, 0), wall(2, 6, 5)),
+	}}
+	s := NewService(&repository.Repository{Airport: fakeAirports{}, Flight: flights})
+	ctx := context.Background()
+
+	board, err := s.GetBoard(ctx, "lhr", Departures, *wall(1, 8, 0), *wall(1, 10, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if board.Timezone != "Europe/London" || !board.From.Equal(time.Date(2024, 7, 1, 8, 0, 0, 0, london)) {
+		t.Errorf("board from %v in %s, want 08:00 in Europe/London", board.From, board.Timezone)
+	}
+	delay := 20
+	want := []structs.BoardEntry{{
+		Scheduled:    time.Date(2024, 7, 1, 8, 30, 0, 0, london),
+		Estimated:    func() *time.Time { t := time.Date(2024, 7, 1, 8, 50, 0, 0, london); return &t }(),
+		FlightIata:   "BA100",
+		AirlineIata:  "BA",
+		Codeshares:   []string{"AA6100", "IB7100"},
+		Airport:      "JFK",
+		Status:       "delayed",
+		DelayMinutes: &delay,
+	}, {
+		Scheduled:   time.Date(2024, 7, 1, 9, 0, 0, 0, london),
+		FlightIata:  "BA200",
+		AirlineIata: "BA",
+		Codeshares:  []string{"QR9"},
+		Airport:     "DOH",
+		Status:      "scheduled",
+	}, {
+		Scheduled:   time.Date(2024, 7, 1, 10, 0, 0, 0, london),
+		FlightIata:  "BA400",
+		AirlineIata: "BA",
+		Codeshares:  []string{},
+		Airport:     "LIS",
+		Status:      "scheduled",
+	}}
+	if !reflect.DeepEqual(board.Flights, want) {
+		t.Errorf("departures\n%+v\nwant\n%+v", board.Flights, want)
+	}
+	if _, offset := board.Flights[0].Scheduled.Zone(); offset != 3600 {
+		t.Errorf("08:30 is at offset %d, want British Summer Time", offset)
+	}
+
+	// An arrivals window across local midnight reads flights dated from the
+	// day before its start.
+	board, err = s.GetBoard(ctx, "LHR", Arrivals, *wall(1, 23, 0), *wall(2, 1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (structs.FlightFilter{ArrIata: "LHR", Date: *wall(0, 0, 0), Until: *wall(2, 0, 0)}); flights.filter != want {
+		t.Errorf("read flights with %+v, want %+v", flights.filter, want)
+	}
+	var arrivals []string
+	for _, e := range board.Flights {
+		arrivals = append(arrivals, e.FlightIata+" from "+e.Airport+" "+e.Scheduled.Format("02 15:04"))
+	}
+	if want := []string{"BA15 from SIN 02 00:30"}; !reflect.DeepEqual(arrivals, want) {
+		t.Errorf("arrivals %q, want %q", arrivals, want)
+	}
+}
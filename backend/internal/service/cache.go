@@ -0,0 +1,504 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/cache"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/patch"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+)
+
+// referenceCaches holds one cache per reference dataset. The joined reads
+// (airports with their city, airlines with city and country, ...) are cached
+// with the dataset they are served from, so a write also purges the caches
+// of every dataset that joins the written table.
+//
+// The lists are not cached: the list endpoints and exports stream them
+// through the Stream* methods, so no table is ever held in memory whole.
+type referenceCaches struct {
+	tax      *cache.Cache
+	aircraft *cache.Cache
+	airline  *cache.Cache
+	airplane *cache.Cache
+	airport  *cache.Cache
+	city     *cache.Cache
+	country  *cache.Cache
+}
+
+func newReferenceCaches(config cache.Config) referenceCaches {
+	return referenceCaches{
+		tax:      cache.New("tax", config),
+		aircraft: cache.New("aircraft", config),
+		airline:  cache.New("airline", config),
+		airplane: cache.New("airplane", config),
+		airport:  cache.New("airport", config),
+		city:     cache.New("city", config),
+		country:  cache.New("country", config),
+	}
+}
+
+func (c referenceCaches) all() []*cache.Cache {
+	return []*cache.Cache{c.tax, c.aircraft, c.airline, c.airplane, c.airport, c.city, c.country}
+}
+
+func (c referenceCaches) byTable(table string) *cache.Cache {
+	switch table {
+	case "tax":
+		return c.tax
+	case "aircraft":
+		return c.aircraft
+	case "airline":
+		return c.airline
+	case "airplane":
+		return c.airplane
+	case "airport":
+		return c.airport
+	case "city":
+		return c.city
+	case "country":
+		return c.country
+	}
+	return nil
+}
+
+func purge(caches ...*cache.Cache) func() {
+	return func() {
+		for _, c := range caches {
+			c.Purge()
+		}
+	}
+}
+
+// scope keeps the reads that include soft deleted rows, or read a past
+// version, apart from the default ones in the same cache.
+func scope(ctx context.Context, key string) string {
+	if structs.IncludeDeleted(ctx) {
+		key += "+deleted"
+	}
+	if asOf, ok := structs.AsOf(ctx); ok {
+		key += "@" + asOf.UTC().Format(time.RFC3339Nano)
+	}
+	return key
+}
+
+func cached[T any](c *cache.Cache, load func() (T, error), key ...any) (T, error) {
+	parts := make([]string, len(key))
+	for i, k := range key {
+		parts[i] = fmt.Sprint(k)
+	}
+
+	value, err := c.Get(strings.Join(parts, ":"), func() (any, error) {
+		return load()
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return value.(T), nil
+}
+
+/*****************
+** TAX **
+******************/
+
+type cachedTax struct {
+	Tax
+	cache      *cache.Cache
+	invalidate func()
+}
+
+func (c cachedTax) CreateTax(ctx context.Context, t *structs.Tax) error {
+	defer c.invalidate()
+	return c.Tax.CreateTax(ctx, t)
+}
+
+func (c cachedTax) CreateTaxes(ctx context.Context, taxes []structs.Tax) (structs.BatchResult, error) {
+	defer c.invalidate()
+	return c.Tax.CreateTaxes(ctx, taxes)
+}
+
+func (c cachedTax) GetTax(ctx context.Context, id uuid.UUID) (structs.Tax, error) {
+	return cached(c.cache, func() (structs.Tax, error) { return c.Tax.GetTax(ctx, id) }, scope(ctx, "id"), id)
+}
+
+func (c cachedTax) GetTaxesCount(ctx context.Context) (int, error) {
+	return cached(c.cache, func() (int, error) { return c.Tax.GetTaxesCount(ctx) }, "count")
+}
+
+/*****************
+** AIRPORT **
+******************/
+
+type cachedAirport struct {
+	Airport
+	cache      *cache.Cache
+	invalidate func()
+}
+
+func (c cachedAirport) CreateAirport(ctx context.Context, a *structs.Airport) error {
+	defer c.invalidate()
+	return c.Airport.CreateAirport(ctx, a)
+}
+
+func (c cachedAirport) CreateAirports(ctx context.Context, airports []structs.Airport) (structs.BatchResult, error) {
+	defer c.invalidate()
+	return c.Airport.CreateAirports(ctx, airports)
+}
+
+func (c cachedAirport) GetAirport(ctx context.Context, id uuid.UUID) (structs.Airport, error) {
+	return cached(c.cache, func() (structs.Airport, error) { return c.Airport.GetAirport(ctx, id) }, scope(ctx, "id"), id)
+}
+
+func (c cachedAirport) GetAirportCount(ctx context.Context) (int, error) {
+	return cached(c.cache, func() (int, error) { return c.Airport.GetAirportCount(ctx) }, "count")
+}
+
+func (c cachedAirport) GetCitiesAirports(ctx context.Context) ([]structs.AirportInfo, error) {
+	return cached(c.cache, func() ([]structs.AirportInfo, error) { return c.Airport.GetCitiesAirports(ctx) }, "cities")
+}
+
+func (c cachedAirport) GetCityNameAirport(ctx context.Context, cityName string) ([]structs.AirportInfo, error) {
+	return cached(c.cache, func() ([]structs.AirportInfo, error) {
+		return c.Airport.GetCityNameAirport(ctx, cityName)
+	}, "city", cityName)
+}
+
+func (c cachedAirport) GetCityNameAirportAlternative(ctx context.Context, cityName string) ([]structs.AirportInfo, error) {
+	return cached(c.cache, func() ([]structs.AirportInfo, error) {
+		return c.Airport.GetCityNameAirportAlternative(ctx, cityName)
+	}, "city-alternative", cityName)
+}
+
+func (c cachedAirport) GetCountryNameAirport(ctx context.Context, countryName string) ([]structs.AirportInfo, error) {
+	return cached(c.cache, func() ([]structs.AirportInfo, error) {
+		return c.Airport.GetCountryNameAirport(ctx, countryName)
+	}, "country", countryName)
+}
+
+func (c cachedAirport) GetCityIataCodeAirport(ctx context.Context, iataCode string) ([]structs.AirportInfo, error) {
+	return cached(c.cache, func() ([]structs.AirportInfo, error) {
+		return c.Airport.GetCityIataCodeAirport(ctx, iataCode)
+	}, "iata", iataCode)
+}
+
+/*****************
+** COUNTRY **
+******************/
+
+type cachedCountry struct {
+	Country
+	cache      *cache.Cache
+	invalidate func()
+}
+
+func (c cachedCountry) CreateCountry(ctx context.Context, t *structs.Country) error {
+	defer c.invalidate()
+	return c.Country.CreateCountry(ctx, t)
+}
+
+func (c cachedCountry) CreateCountries(ctx context.Context, countries []structs.Country) (structs.BatchResult, error) {
+	defer c.invalidate()
+	return c.Country.CreateCountries(ctx, countries)
+}
+
+func (c cachedCountry) GetCountry(ctx context.Context, id uuid.UUID) (structs.Country, error) {
+	return cached(c.cache, func() (structs.Country, error) { return c.Country.GetCountry(ctx, id) }, scope(ctx, "id"), id)
+}
+
+func (c cachedCountry) GetCountryCount(ctx context.Context) (int, error) {
+	return cached(c.cache, func() (int, error) { return c.Country.GetCountryCount(ctx) }, "count")
+}
+
+/*****************
+** CITY **
+******************/
+
+type cachedCity struct {
+	City
+	cache      *cache.Cache
+	invalidate func()
+}
+
+func (c cachedCity) CreateCity(ctx context.Context, city *structs.City) error {
+	defer c.invalidate()
+	return c.City.CreateCity(ctx, city)
+}
+
+func (c cachedCity) CreateCities(ctx context.Context, cities []structs.City) (structs.BatchResult, error) {
+	defer c.invalidate()
+	return c.City.CreateCities(ctx, cities)
+}
+
+func (c cachedCity) GetCity(ctx context.Context, id uuid.UUID) (structs.City, error) {
+	return cached(c.cache, func() (structs.City, error) { return c.City.GetCity(ctx, id) }, scope(ctx, "id"), id)
+}
+
+func (c cachedCity) GetCityCount(ctx context.Context) (int, error) {
+	return cached(c.cache, func() (int, error) { return c.City.GetCityCount(ctx) }, "count")
+}
+
+func (c cachedCity) GetCitiesFromCountry(ctx context.Context) ([]structs.CityInfo, error) {
+	return cached(c.cache, func() ([]structs.CityInfo, error) { return c.City.GetCitiesFromCountry(ctx) }, "countries")
+}
+
+func (c cachedCity) GetCityFromCountry(ctx context.Context, id uuid.UUID) ([]structs.CityInfo, error) {
+	return cached(c.cache, func() ([]structs.CityInfo, error) {
+		return c.City.GetCityFromCountry(ctx, id)
+	}, "country", id)
+}
+
+/*****************
+** AIRCRAFT **
+******************/
+
+type cachedAircraft struct {
+	Aircraft
+	cache      *cache.Cache
+	invalidate func()
+}
+
+func (c cachedAircraft) CreateAircraft(ctx context.Context, t *structs.Aircraft) error {
+	defer c.invalidate()
+	return c.Aircraft.CreateAircraft(ctx, t)
+}
+
+func (c cachedAircraft) CreateAircrafts(ctx context.Context, aircrafts []structs.Aircraft) (structs.BatchResult, error) {
+	defer c.invalidate()
+	return c.Aircraft.CreateAircrafts(ctx, aircrafts)
+}
+
+func (c cachedAircraft) GetAircraft(ctx context.Context, id uuid.UUID) (structs.Aircraft, error) {
+	return cached(c.cache, func() (structs.Aircraft, error) { return c.Aircraft.GetAircraft(ctx, id) }, scope(ctx, "id"), id)
+}
+
+func (c cachedAircraft) GetAircraftCount(ctx context.Context) (int, error) {
+	return cached(c.cache, func() (int, error) { return c.Aircraft.GetAircraftCount(ctx) }, "count")
+}
+
+/*****************
+** AIRLINE **
+******************/
+
+// cachedAirline keeps fleet analytics in the airplane cache: they read both
+// tables and airline writes purge it as well.
+type cachedAirline struct {
+	Airline
+	cache      *cache.Cache
+	fleet      *cache.Cache
+	invalidate func()
+}
+
+func (c cachedAirline) CreateAirline(ctx context.Context, t *structs.Airline) error {
+	defer c.invalidate()
+	return c.Airline.CreateAirline(ctx, t)
+}
+
+func (c cachedAirline) CreateAirlines(ctx context.Context, airlines []structs.Airline) (structs.BatchResult, error) {
+	defer c.invalidate()
+	return c.Airline.CreateAirlines(ctx, airlines)
+}
+
+func (c cachedAirline) GetAirline(ctx context.Context, id uuid.UUID) (structs.Airline, error) {
+	return cached(c.cache, func() (structs.Airline, error) { return c.Airline.GetAirline(ctx, id) }, scope(ctx, "id"), id)
+}
+
+func (c cachedAirline) GetAirlineCount(ctx context.Context) (int, error) {
+	return cached(c.cache, func() (int, error) { return c.Airline.GetAirlineCount(ctx) }, "count")
+}
+
+func (c cachedAirline) GetAirlinesCountry(ctx context.Context) ([]structs.AirlineInfo, error) {
+	return cached(c.cache, func() ([]structs.AirlineInfo, error) { return c.Airline.GetAirlinesCountry(ctx) }, "countries")
+}
+
+func (c cachedAirline) GetAirlineCountry(ctx context.Context, id int) ([]structs.AirlineInfo, error) {
+	return cached(c.cache, func() ([]structs.AirlineInfo, error) {
+		return c.Airline.GetAirlineCountry(ctx, id)
+	}, "airline-country", id)
+}
+
+func (c cachedAirline) GetAirlineCountryName(ctx context.Context, countryName string) ([]structs.AirlineInfo, error) {
+	return cached(c.cache, func() ([]structs.AirlineInfo, error) {
+		return c.Airline.GetAirlineCountryName(ctx, countryName)
+	}, "country", countryName)
+}
+
+func (c cachedAirline) GetAirlineCityName(ctx context.Context, cityName string) ([]structs.AirlineInfo, error) {
+	return cached(c.cache, func() ([]structs.AirlineInfo, error) {
+		return c.Airline.GetAirlineCityName(ctx, cityName)
+	}, "city", cityName)
+}
+
+func (c cachedAirline) GetAirlineCountryCityName(ctx context.Context, countryName string, cityName string) ([]structs.AirlineInfo, error) {
+	return cached(c.cache, func() ([]structs.AirlineInfo, error) {
+		return c.Airline.GetAirlineCountryCityName(ctx, countryName, cityName)
+	}, "country-city", countryName, cityName)
+}
+
+func (c cachedAirline) GetAirlineFleet(ctx context.Context, id uuid.UUID) (structs.AirlineFleet, error) {
+	return cached(c.fleet, func() (structs.AirlineFleet, error) { return c.Airline.GetAirlineFleet(ctx, id) }, "fleet", id)
+}
+
+func (c cachedAirline) GetFleetLeaderboard(ctx context.Context, order string, limit int) ([]structs.FleetLeaderboardEntry, error) {
+	return cached(c.fleet, func() ([]structs.FleetLeaderboardEntry, error) {
+		return c.Airline.GetFleetLeaderboard(ctx, order, limit)
+	}, "fleet-leaderboard", order, limit)
+}
+
+/*****************
+** AIRPLANE **
+******************/
+
+type cachedAirplane struct {
+	Airplane
+	cache      *cache.Cache
+	invalidate func()
+}
+
+func (c cachedAirplane) CreateAirplane(ctx context.Context, t *structs.Airplane) error {
+	defer c.invalidate()
+	return c.Airplane.CreateAirplane(ctx, t)
+}
+
+func (c cachedAirplane) CreateAirplanes(ctx context.Context, airplanes []structs.Airplane) (structs.BatchResult, error) {
+	defer c.invalidate()
+	return c.Airplane.CreateAirplanes(ctx, airplanes)
+}
+
+func (c cachedAirplane) GetAirplane(ctx context.Context, id uuid.UUID) (structs.Airplane, error) {
+	return cached(c.cache, func() (structs.Airplane, error) { return c.Airplane.GetAirplane(ctx, id) }, scope(ctx, "id"), id)
+}
+
+func (c cachedAirplane) GetAirplaneCount(ctx context.Context) (int, error) {
+	return cached(c.cache, func() (int, error) { return c.Airplane.GetAirplaneCount(ctx) }, "count")
+}
+
+func (c cachedAirplane) GetAirplaneAirline(ctx context.Context) ([]structs.AirplaneInfo, error) {
+	return cached(c.cache, func() ([]structs.AirplaneInfo, error) { return c.Airplane.GetAirplaneAirline(ctx) }, "airlines")
+}
+
+func (c cachedAirplane) GetAirplanesFromAirlineName(ctx context.Context, airlineName string) ([]structs.AirplaneInfo, error) {
+	return cached(c.cache, func() ([]structs.AirplaneInfo, error) {
+		return c.Airplane.GetAirplanesFromAirlineName(ctx, airlineName)
+	}, "airline", airlineName)
+}
+
+func (c cachedAirplane) GetAirplanesFromAirlineCountry(ctx context.Context, countryName string) ([]structs.AirplaneInfo, error) {
+	return cached(c.cache, func() ([]structs.AirplaneInfo, error) {
+		return c.Airplane.GetAirplanesFromAirlineCountry(ctx, countryName)
+	}, "country", countryName)
+}
+
+/*****************
+** INTEGRITY **
+******************/
+
+// cachedIntegrity purges everything after linking, it rewrites references
+// across datasets.
+type cachedIntegrity struct {
+	Integrity
+	invalidate func()
+}
+
+func (c cachedIntegrity) LinkReferences(ctx context.Context) (structs.LinkResult, error) {
+	defer c.invalidate()
+	return c.Integrity.LinkReferences(ctx)
+}
+
+func (c cachedIntegrity) LinkRows(ctx context.Context, table string, ids []uuid.UUID) (structs.LinkResult, error) {
+	defer c.invalidate()
+	return c.Integrity.LinkRows(ctx, table, ids)
+}
+
+/*****************
+** TRASH **
+******************/
+
+// cachedTrash purges everything after a restore or a purge, the restored
+// or purged rows show up in the joined reads of other datasets.
+type cachedTrash struct {
+	Trash
+	invalidate func()
+}
+
+func (c cachedTrash) Restore(ctx context.Context, resource string, id uuid.UUID) error {
+	defer c.invalidate()
+	return c.Trash.Restore(ctx, resource, id)
+}
+
+func (c cachedTrash) Purge(ctx context.Context, resource string, before time.Time) (int64, error) {
+	defer c.invalidate()
+	return c.Trash.Purge(ctx, resource, before)
+}
+
+/*****************
+** BATCH **
+******************/
+
+// cachedBatch purges everything after a bulk write, its rows show up in the
+// joined reads of other datasets.
+type cachedBatch struct {
+	Batch
+	invalidate func()
+}
+
+func (c cachedBatch) Apply(ctx context.Context, resource string, req structs.BulkRequest) (structs.BulkResult, error) {
+	defer c.invalidate()
+	return c.Batch.Apply(ctx, resource, req)
+}
+
+/*****************
+** PATCH **
+******************/
+
+// cachedPatch purges everything after a row is replaced, patched or
+// deleted, the row shows up in the joined reads of other datasets.
+type cachedPatch struct {
+	Patch
+	invalidate func()
+}
+
+func (c cachedPatch) Replace(ctx context.Context, resource string, id uuid.UUID, check patch.Check, doc map[string]interface{}) error {
+	defer c.invalidate()
+	return c.Patch.Replace(ctx, resource, id, check, doc)
+}
+
+func (c cachedPatch) MergePatch(ctx context.Context, resource string, id uuid.UUID, check patch.Check, mergePatch interface{}) error {
+	defer c.invalidate()
+	return c.Patch.MergePatch(ctx, resource, id, check, mergePatch)
+}
+
+func (c cachedPatch) JSONPatch(ctx context.Context, resource string, id uuid.UUID, check patch.Check, ops []patch.Operation) error {
+	defer c.invalidate()
+	return c.Patch.JSONPatch(ctx, resource, id, check, ops)
+}
+
+func (c cachedPatch) Delete(ctx context.Context, resource string, id uuid.UUID, check patch.Check) error {
+	defer c.invalidate()
+	return c.Patch.Delete(ctx, resource, id, check)
+}
+
+/*****************
+** VERSION **
+******************/
+
+// cachedVersion keeps a version in the cache of its first table. Joined
+// lists name their tables so that every other one's writes purge that cache:
+// ("airplane", "airline"), ("airport", "city"), ("city", "country") and
+// ("airline", "city", "country").
+type cachedVersion struct {
+	Version
+	caches referenceCaches
+}
+
+func (c cachedVersion) GetVersion(ctx context.Context, tables ...string) (structs.TableVersion, error) {
+	load := func() (structs.TableVersion, error) { return c.Version.GetVersion(ctx, tables...) }
+
+	if len(tables) == 0 || c.caches.byTable(tables[0]) == nil {
+		return load()
+	}
+	return cached(c.caches.byTable(tables[0]), load, "version", strings.Join(tables, ","))
+}
@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"container/list"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	hits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "aviatoon_cache_hits_total",
+		Help: "Reads served from the in-process reference data cache",
+	}, []string{"cache"})
+	misses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "aviatoon_cache_misses_total",
+		Help: "Reads that had to load from the repository",
+	}, []string{"cache"})
+	evictions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "aviatoon_cache_evictions_total",
+		Help: "Entries dropped because the cache was full",
+	}, []string{"cache"})
+	invalidations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "aviatoon_cache_invalidations_total",
+		Help: "Times a cache was purged after a write",
+	}, []string{"cache"})
+)
+
+type Config struct {
+	ttl        time.Duration
+	maxEntries int
+}
+
+// NewConfig returns a cache configuration, a ttl <= 0 disables caching.
+func NewConfig(ttl time.Duration, maxEntries int) Config {
+	return Config{ttl: ttl, maxEntries: maxEntries}
+}
+
+type entry struct {
+	key     string
+	value   any
+	expires time.Time
+}
+
+// Cache is a TTL + LRU bounded read-through cache. Concurrent misses on the
+// same key share one load. Cached values are shared between callers and must
+// not be modified.
+type Cache struct {
+	name       string
+	ttl        time.Duration
+	maxEntries int
+
+	mu         sync.Mutex
+	items      map[string]*list.Element
+	order      *list.List
+	generation uint64
+	group      singleflight.Group
+	now        func() time.Time
+}
+
+func New(name string, config Config) *Cache {
+	return &Cache{
+		name:       name,
+		ttl:        config.ttl,
+		maxEntries: config.maxEntries,
+		items:      make(map[string]*list.Element),
+		order:      list.New(),
+		now:        time.Now,
+	}
+}
+
+// Get returns the cached value for key or calls load and caches its result.
+// Errors are never cached.
+func (c *Cache) Get(key string, load func() (any, error)) (any, error) {
+	if c.ttl <= 0 {
+		return load()
+	}
+
+	c.mu.Lock()
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry)
+		if c.now().Before(e.expires) {
+			c.order.MoveToFront(el)
+			c.mu.Unlock()
+			hits.WithLabelValues(c.name).Inc()
+			return e.value, nil
+		}
+		c.remove(el)
+	}
+	generation := c.generation
+	c.mu.Unlock()
+	misses.WithLabelValues(c.name).Inc()
+
+	// The generation is part of the flight key so a load that started before
+	// a Purge is neither joined nor stored by readers that come after it.
+	value, err, _ := c.group.Do(strconv.FormatUint(generation, 10)+":"+key, func() (any, error) {
+		value, err := load()
+		if err != nil {
+			return nil, err
+		}
+		c.store(key, value, generation)
+		return value, nil
+	})
+	return value, err
+}
+
+// Purge drops every entry.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+	invalidations.WithLabelValues(c.name).Inc()
+}
+
+func (c *Cache) store(key string, value any, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	c.items[key] = c.order.PushFront(&entry{key: key, value: value, expires: c.now().Add(c.ttl)})
+
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+		evictions.WithLabelValues(c.name).Inc()
+	}
+}
+
+func (c *Cache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.items, el.Value.(*entry).key)
+}
@@ -0,0 +1,169 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// loader counts its loads and returns the value of the n-th one.
+type loader struct {
+	n      atomic.Int32
+	values []string
+}
+
+func (l *loader) load() (any, error) {
+	n := l.n.Add(1)
+	return l.values[n-1], nil
+}
+
+func get(t *testing.T, c *Cache, key string, load func() (any, error)) any {
+	t.Helper()
+	v, err := c.Get(key, load)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestTTL(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	c := New("test_ttl", NewConfig(time.Minute, 0))
+	c.now = func() time.Time { return now }
+	l := &loader{values: []string{"first", "second"}}
+
+	if v := get(t, c, "k", l.load); v != "first" {
+		t.Errorf("Get = %v, want first", v)
+	}
+	now = now.Add(59 * time.Second)
+	if v := get(t, c, "k", l.load); v != "first" || l.n.Load() != 1 {
+		t.Errorf("Get before expiry = %v after %d loads, want the cached first", v, l.n.Load())
+	}
+	now = now.Add(time.Second)
+	if v := get(t, c, "k", l.load); v != "second" || l.n.Load() != 2 {
+		t.Errorf("Get after expiry = %v after %d loads, want a second load", v, l.n.Load())
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	c := New("test_disabled", NewConfig(0, 0))
+	l := &loader{values: []string{"first", "second"}}
+	get(t, c, "k", l.load)
+	if v := get(t, c, "k", l.load); v != "second" {
+		t.Errorf("Get = %v, want every read to load", v)
+	}
+}
+
+func TestErrorsNotCached(t *testing.T) {
+	c := New("test_errors", NewConfig(time.Minute, 0))
+	boom := errors.New("boom")
+	if _, err := c.Get("k", func() (any, error) { return nil, boom }); !errors.Is(err, boom) {
+		t.Errorf("Get = %v, want %v", err, boom)
+	}
+	if v := get(t, c, "k", func() (any, error) { return "value", nil }); v != "value" {
+		t.Errorf("Get after an error = %v, want a new load", v)
+	}
+}
+
+func TestLRU(t *testing.T) {
+	c := New("test_lru", NewConfig(time.Minute, 2))
+	evicted := testutil.ToFloat64(evictions.WithLabelValues("test_lru"))
+	loads := map[string]int{}
+	load := func(key string) func() (any, error) {
+		return func() (any, error) {
+			loads[key]++
+			return key, nil
+		}
+	}
+
+	get(t, c, "a", load("a"))
+	get(t, c, "b", load("b"))
+	get(t, c, "a", load("a")) // a is now the most recently used
+	get(t, c, "c", load("c")) // evicts b
+	get(t, c, "a", load("a"))
+	get(t, c, "b", load("b"))
+
+	if want := map[string]int{"a": 1, "b": 2, "c": 1}; loads["a"] != want["a"] || loads["b"] != want["b"] || loads["c"] != want["c"] {
+		t.Errorf("loads = %v, want %v", loads, want)
+	}
+	if got := testutil.ToFloat64(evictions.WithLabelValues("test_lru")) - evicted; got != 2 {
+		t.Errorf("evictions = %v, want 2", got)
+	}
+}
+
+func TestConcurrentMisses(t *testing.T) {
+	c := New("test_singleflight", NewConfig(time.Minute, 0))
+	const readers = 8
+	missed := testutil.ToFloat64(misses.WithLabelValues("test_singleflight"))
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func() (any, error) {
+		loads.Add(1)
+		<-release
+		return "value", nil
+	}
+
+	var wg sync.WaitGroup
+	values := make([]any, readers)
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			values[i], _ = c.Get("k", load)
+		}(i)
+	}
+
+	// Every reader has missed before the load is let go; give the last
+	// ones the moment they need to join the load in flight.
+	for testutil.ToFloat64(misses.WithLabelValues("test_singleflight"))-missed < readers {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("%d loads, want the readers to share one", n)
+	}
+	for i, v := range values {
+		if v != "value" {
+			t.Errorf("reader %d got %v", i, v)
+		}
+	}
+}
+
+func TestPurgeDuringLoad(t *testing.T) {
+	c := New("test_purge", NewConfig(time.Minute, 0))
+	started, release := make(chan struct{}), make(chan struct{})
+
+	stale := make(chan any)
+	go func() {
+		v, _ := c.Get("k", func() (any, error) {
+			close(started)
+			<-release
+			return "stale", nil
+		})
+		stale <- v
+	}()
+	<-started
+
+	// A write lands while the load is in flight: readers after it must
+	// neither join that load nor get its result from the cache.
+	c.Purge()
+	if v := get(t, c, "k", func() (any, error) { return "fresh", nil }); v != "fresh" {
+		t.Errorf("Get after the purge = %v, want its own load", v)
+	}
+
+	close(release)
+	if v := <-stale; v != "stale" {
+		t.Errorf("the reader before the purge got %v", v)
+	}
+	if v := get(t, c, "k", func() (any, error) { return "reloaded", nil }); v != "fresh" {
+		t.Errorf("Get = %v, want the value loaded after the purge", v)
+	}
+}
@@ -0,0 +1,136 @@
+package calendar
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/localtime"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+)
+
+// Feeds cover the flights dated from a week ago to a month ahead, which is
+// what calendar apps keep in view.
+const (
+	pastDays   = 7
+	futureDays = 30
+)
+
+type Service struct {
+	repo *repository.Repository
+}
+
+func NewService(repo *repository.Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// FlightCalendar returns the recorded legs of a flight number.
+func (s *Service) FlightCalendar(ctx context.Context, flightIata string) (structs.Calendar, error) {
+	flightIata = strings.ToUpper(flightIata)
+	flights, err := s.flights(ctx, []string{flightIata})
+	if err != nil {
+		return structs.Calendar{}, err
+	}
+	events, err := s.events(ctx, flights)
+	return structs.Calendar{Name: flightIata, Events: events}, err
+}
+
+// UserCalendar returns the recorded legs of every flight number in the
+// user's watchlists.
+func (s *Service) UserCalendar(ctx context.Context, userID uuid.UUID) (structs.Calendar, error) {
+	calendar := structs.Calendar{Name: "Followed flights"}
+
+	lists, err := s.repo.Watch.GetWatchlists(ctx, userID)
+	if err != nil {
+		return calendar, err
+	}
+	seen := make(map[string]bool)
+	var numbers []string
+	for _, list := range lists {
+		for _, w := range list.Watches {
+			if w.Kind == structs.WatchFlight && !seen[w.Value] {
+				seen[w.Value] = true
+				numbers = append(numbers, w.Value)
+			}
+		}
+	}
+
+	flights, err := s.flights(ctx, numbers)
+	if err != nil {
+		return calendar, err
+	}
+	calendar.Events, err = s.events(ctx, flights)
+	return calendar, err
+}
+
+func (s *Service) flights(ctx context.Context, numbers []string) ([]structs.Flight, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	var flights []structs.Flight
+	for _, number := range numbers {
+		legs, err := s.repo.Flight.FindFlights(ctx, structs.FlightFilter{
+			FlightIata: number,
+			Date:       today.AddDate(0, 0, -pastDays),
+			Until:      today.AddDate(0, 0, futureDays),
+		})
+		if err != nil {
+			return nil, err
+		}
+		flights = append(flights, legs...)
+	}
+	return flights, nil
+}
+
+func (s *Service) events(ctx context.Context, flights []structs.Flight) ([]structs.CalendarEvent, error) {
+	events := []structs.CalendarEvent{}
+	if len(flights) == 0 {
+		return events, nil
+	}
+
+	var airports []string
+	for _, f := range flights {
+		airports = append(airports, f.Departure.Iata, f.Arrival.Iata)
+	}
+	positions, err := s.repo.Airport.GetAirportPositions(ctx, airports)
+	if err != nil {
+		return nil, err
+	}
+	byIata := make(map[string]structs.AirportPosition, len(positions))
+	for _, p := range positions {
+		byIata[p.Iata] = p
+	}
+
+	zones := localtime.Zones{}
+	for _, f := range flights {
+		e := structs.CalendarEvent{
+			Flight:        f,
+			Start:         best(f.Departure),
+			End:           best(f.Arrival),
+			DepartureName: f.Departure.Airport,
+			ArrivalName:   f.Arrival.Airport,
+			Departure:     byIata[f.Departure.Iata],
+			Arrival:       byIata[f.Arrival.Iata],
+		}
+		if e.Start == nil {
+			continue
+		}
+		e.StartZone, _ = zones.Find(e.Departure.Timezone, f.Departure.Timezone)
+		e.EndZone, _ = zones.Find(e.Arrival.Timezone, f.Arrival.Timezone)
+		events = append(events, e)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Start.Before(*events[j].Start) })
+	return events, nil
+}
+
+// best is the most accurate time known for one end of a flight.
+func best(e structs.FlightEndpoint) *time.Time {
+	switch {
+	case e.Actual != nil:
+		return e.Actual
+	case e.Estimated != nil:
+		return e.Estimated
+	}
+	return e.Scheduled
+}
@@ -0,0 +1,46 @@
+package emissions
+
+import (
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/FACorreiaa/aviatoon-tracker/pkg/logs"
+)
+
+type Config struct {
+	table           fuelBurnTable
+	loadFactor      float64
+	defaultAircraft string
+}
+
+// NewConfig loads the embedded fuel-burn table and, when fuelBurnFile is
+// set, the rows of that file on top of it. defaultAircraft is used for
+// flights whose aircraft is unknown.
+func NewConfig(fuelBurnFile string, loadFactor float64, defaultAircraft string) Config {
+	table := fuelBurnTable{}
+	if err := table.load(strings.NewReader(defaultFuelBurn)); err != nil {
+		logs.DefaultLogger.WithError(err).Fatal("Embedded fuel-burn table is invalid")
+		syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+	}
+	if fuelBurnFile != "" {
+		file, err := os.Open(fuelBurnFile)
+		if err == nil {
+			err = table.load(file)
+			file.Close()
+		}
+		if err != nil {
+			logs.DefaultLogger.WithError(err).Fatal("Fuel-burn table could not be loaded")
+			syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+		}
+	}
+
+	if loadFactor <= 0 || loadFactor > 1 {
+		loadFactor = 0.82
+	}
+	if _, ok := table.lookup(defaultAircraft); !ok {
+		logs.DefaultLogger.Fatal("Default aircraft " + defaultAircraft + " is not in the fuel-burn table")
+		syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+	}
+	return Config{table: table, loadFactor: loadFactor, defaultAircraft: defaultAircraft}
+}
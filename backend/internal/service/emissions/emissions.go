@@ -0,0 +1,247 @@
+package emissions
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+// co2PerKgFuel is the CO2 released by burning one kilogram of jet fuel.
+const co2PerKgFuel = 3.16
+
+// maxFlights caps how many stored flights a route or airline estimate reads.
+const maxFlights = 5000
+
+var ErrUnknownAirport = errors.New("airport has no coordinates")
+
+type Service struct {
+	repo   *repository.Repository
+	config Config
+}
+
+func NewService(repo *repository.Repository, config Config) *Service {
+	return &Service{repo: repo, config: config}
+}
+
+// EstimateRoute estimates a flight from one airport to the other. Without an
+// aircraft it gives one estimate per type seen operating the route, or the
+// default type when none was seen.
+func (s *Service) EstimateRoute(ctx context.Context, from string, to string, aircraft string) (structs.RouteEmissions, error) {
+	route := structs.RouteEmissions{From: strings.ToUpper(from), To: strings.ToUpper(to)}
+
+	positions, err := s.positions(ctx, []string{route.From, route.To})
+	if err != nil {
+		return route, err
+	}
+	distance := positions[route.From].DistanceKm(positions[route.To])
+	if distance == 0 {
+		return route, ErrUnknownAirport
+	}
+
+	types := []string{aircraft}
+	if aircraft == "" {
+		flights, err := s.repo.Flight.FindFlights(ctx, structs.FlightFilter{
+			DepIata:      route.From,
+			ArrIata:      route.To,
+			OperatedOnly: true,
+			Limit:        maxFlights,
+		})
+		if err != nil {
+			return route, err
+		}
+		observed, err := s.aircraftTypes(ctx, flights)
+		if err != nil {
+			return route, err
+		}
+		types = types[:0]
+		for _, code := range observed {
+			if code != "" {
+				types = append(types, code)
+			}
+		}
+		if len(types) == 0 {
+			types = append(types, "")
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, code := range types {
+		estimate := s.estimate(distance, code)
+		if seen[estimate.Aircraft] {
+			continue
+		}
+		seen[estimate.Aircraft] = true
+		route.Estimates = append(route.Estimates, estimate)
+	}
+	sort.SliceStable(route.Estimates, func(i, j int) bool {
+		return route.Estimates[i].Co2PerPassengerKg < route.Estimates[j].Co2PerPassengerKg
+	})
+	return route, nil
+}
+
+// EstimateFlight estimates every stored leg of a flight number, on date when
+// it is not zero.
+func (s *Service) EstimateFlight(ctx context.Context, flightIata string, date time.Time) ([]structs.FlightEmissions, error) {
+	flights, err := s.repo.Flight.FindFlights(ctx, structs.FlightFilter{FlightIata: flightIata, Date: date})
+	if err != nil {
+		return nil, err
+	}
+	estimates, _, err := s.estimateFlights(ctx, flights)
+	return estimates, err
+}
+
+// EstimateAirline estimates the flights an airline operated, on date when it
+// is not zero.
+func (s *Service) EstimateAirline(ctx context.Context, airlineIata string, date time.Time) (structs.AirlineEmissions, error) {
+	result := structs.AirlineEmissions{AirlineIata: strings.ToUpper(airlineIata)}
+
+	flights, err := s.repo.Flight.FindFlights(ctx, structs.FlightFilter{
+		AirlineIata:  result.AirlineIata,
+		Date:         date,
+		OperatedOnly: true,
+		Limit:        maxFlights,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.Estimates, result.Unestimated, err = s.estimateFlights(ctx, flights)
+	if err != nil {
+		return result, err
+	}
+	result.Flights = len(result.Estimates)
+	for _, e := range result.Estimates {
+		result.Co2Kg += e.Estimate.Co2Kg
+	}
+	result.Co2Kg = math.Round(result.Co2Kg)
+	return result, nil
+}
+
+func (s *Service) estimateFlights(ctx context.Context, flights []structs.Flight) ([]structs.FlightEmissions, int, error) {
+	estimates := []structs.FlightEmissions{}
+	if len(flights) == 0 {
+		return estimates, 0, nil
+	}
+
+	var airports []string
+	for _, f := range flights {
+		airports = append(airports, f.Departure.Iata, f.Arrival.Iata)
+	}
+	positions, err := s.positions(ctx, airports)
+	if err != nil {
+		return nil, 0, err
+	}
+	types, err := s.aircraftTypes(ctx, flights)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	unestimated := 0
+	for i, f := range flights {
+		distance := positions[f.Departure.Iata].DistanceKm(positions[f.Arrival.Iata])
+		if distance == 0 {
+			unestimated++
+			continue
+		}
+		estimates = append(estimates, structs.FlightEmissions{
+			FlightIata:  f.FlightIata,
+			FlightDate:  f.FlightDate,
+			AirlineIata: f.AirlineIata,
+			From:        f.Departure.Iata,
+			To:          f.Arrival.Iata,
+			Estimate:    s.estimate(distance, types[i]),
+		})
+	}
+	return estimates, unestimated, nil
+}
+
+func (s *Service) positions(ctx context.Context, airports []string) (map[string]structs.AirportPosition, error) {
+	positions, err := s.repo.Airport.GetAirportPositions(ctx, airports)
+	if err != nil {
+		return nil, err
+	}
+	byIata := make(map[string]structs.AirportPosition, len(positions))
+	for _, p := range positions {
+		byIata[p.Iata] = p
+	}
+	return byIata, nil
+}
+
+// aircraftTypes returns, for every flight, the type code to estimate it
+// with: the one in the feed, else the type of the airplane registered under
+// the reported registration, else "" for the default type.
+func (s *Service) aircraftTypes(ctx context.Context, flights []structs.Flight) ([]string, error) {
+	var registrations []string
+	for _, f := range flights {
+		if f.Aircraft != nil && f.Aircraft.Registration != "" {
+			registrations = append(registrations, f.Aircraft.Registration)
+		}
+	}
+
+	byRegistration := make(map[string]structs.Airplane)
+	if len(registrations) > 0 {
+		airplanes, err := s.repo.Airplane.GetAirplanesByRegistration(ctx, registrations)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range airplanes {
+			byRegistration[a.RegistrationNumber] = a
+		}
+	}
+
+	types := make([]string, len(flights))
+	for i, f := range flights {
+		if f.Aircraft == nil {
+			continue
+		}
+		if row, ok := s.config.table.lookup(f.Aircraft.Iata, f.Aircraft.Icao); ok {
+			types[i] = row.code
+			continue
+		}
+		a := byRegistration[f.Aircraft.Registration]
+		if row, ok := s.config.table.lookup(a.IataCodeShort, a.IataType, a.IataCodeLong, a.ModelCode); ok {
+			types[i] = row.code
+		}
+	}
+	return types, nil
+}
+
+// estimate follows the ICAO calculator: the great-circle distance gets a
+// routing allowance, fuel is the LTO cycle plus a per kilometre burn, and
+// every seat is economy.
+func (s *Service) estimate(greatCircleKm float64, aircraft string) structs.EmissionEstimate {
+	row, ok := s.config.table.lookup(aircraft)
+	if !ok {
+		row, _ = s.config.table.lookup(s.config.defaultAircraft)
+	}
+
+	distance := greatCircleKm
+	switch {
+	case distance < 550:
+		distance += 50
+	case distance < 5500:
+		distance += 100
+	default:
+		distance += 125
+	}
+
+	fuel := row.lto + row.perKm*distance
+	co2 := fuel * co2PerKgFuel
+	return structs.EmissionEstimate{
+		Aircraft:          row.code,
+		AircraftName:      row.name,
+		AircraftAssumed:   !ok,
+		DistanceKm:        math.Round(distance),
+		FuelKg:            math.Round(fuel),
+		Co2Kg:             math.Round(co2),
+		Seats:             row.seats,
+		LoadFactor:        s.config.loadFactor,
+		Co2PerPassengerKg: math.Round(co2/(float64(row.seats)*s.config.loadFactor)*10) / 10,
+	}
+}
@@ -0,0 +1,149 @@
+package emissions
+
+import (
+	"context"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+type fakeAirports struct {
+	repository.Airport
+	positions map[string]structs.AirportPosition
+}
+
+func (f *fakeAirports) GetAirportPositions(ctx context.Context, iataCodes []string) ([]structs.AirportPosition, error) {
+	var positions []structs.AirportPosition
+	for _, iata := range iataCodes {
+		if p, ok := f.positions[iata]; ok {
+			positions = append(positions, p)
+		}
+	}
+	return positions, nil
+}
+
+func TestEstimateRoute(t *testing.T) {
+	// A quarter of the equator apart: 6371.0088 * π/2 = 10007.56 km.
+	s := NewService(&repository.Repository{Airport: &fakeAirports{positions: map[string]structs.AirportPosition{
+		"AAA": {Iata: "AAA", Longitude: 1},
+		"BBB": {Iata: "BBB", Longitude: 91},
+		"NUL": {Iata: "NUL"},
+	}}}, NewConfig("", 0.8, "320"))
+	ctx := context.Background()
+
+	route, err := s.EstimateRoute(ctx, "aaa", "bbb", "A320-200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := structs.EmissionEstimate{
+		Aircraft:          "320",
+		AircraftName:      "Airbus A320",
+		DistanceKm:        10133, // plus the 125 km allowance of long flights
+		FuelKg:            30691, // 800 + 2.95 * 10132.56
+		Co2Kg:             96984, // 3.16 kg per kg of fuel
+		Seats:             180,
+		LoadFactor:        0.8,
+		Co2PerPassengerKg: 673.5, // over 144 passengers
+	}
+	if route.From != "AAA" || route.To != "BBB" || len(route.Estimates) != 1 || route.Estimates[0] != want {
+		t.Errorf("EstimateRoute = %+v, want AAA-BBB %+v", route, want)
+	}
+
+	if _, err := s.EstimateRoute(ctx, "AAA", "NUL", "320"); err != ErrUnknownAirport {
+		t.Errorf("EstimateRoute to an airport at 0,0 = %v, want %v", err, ErrUnknownAirport)
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	s := NewService(nil, NewConfig("", 0.8, "320"))
+	tests := []struct {
+		name     string
+		km       float64
+		aircraft string
+		want     float64
+		code     string
+		assumed  bool
+	}{
+		{"short", 500, "320", 550, "320", false},
+		{"medium", 1000, "A320", 1100, "320", false},
+		{"long", 6000, "a320 200", 6125, "320", false},
+		{"unknown aircraft", 1000, "ZZZ", 1100, "320", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := s.estimate(tt.km, tt.aircraft)
+			if e.DistanceKm != tt.want || e.Aircraft != tt.code || e.AircraftAssumed != tt.assumed {
+				t.Errorf("estimate = %+v, want %v km on %s, assumed %v", e, tt.want, tt.code, tt.assumed)
+			}
+			if fuel := math.Round(800 + 2.95*tt.want); e.FuelKg != fuel {
+				t.Errorf("fuel = %v, want %v", e.FuelKg, fuel)
+			}
+		})
+	}
+}
+
+func writeTable(t *testing.T, rows string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fuel_burn.csv")
+	if err := os.WriteFile(path, []byte(rows), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewConfigOverride(t *testing.T) {
+	config := NewConfig(writeTable(t, `codes,name,seats,lto_fuel_kg,cruise_fuel_kg_per_km
+320 A320,Airbus A320 (dense),186,820,3
+XYZ,Test jet,100,500,2
+`), 0, "xyz")
+
+	if config.loadFactor != 0.82 {
+		t.Errorf("loadFactor = %v, want the 0.82 default", config.loadFactor)
+	}
+	if row, ok := config.table.lookup("A320"); !ok || row.seats != 186 || row.perKm != 3 {
+		t.Errorf("A320 = %+v, want the row of the file", row)
+	}
+	if row, ok := config.table.lookup("A321"); !ok || row.seats != 200 {
+		t.Errorf("A321 = %+v, want the embedded row", row)
+	}
+	if e := (&Service{config: config}).estimate(1000, "unknown"); e.Aircraft != "XYZ" || e.FuelKg != 2700 {
+		t.Errorf("estimate = %+v, want the default XYZ from the file burning 500 + 2 * 1100 kg", e)
+	}
+}
+
+// TestNewConfigInvalidTable runs NewConfig in a child process, which must
+// stop: the server does not start with a fuel-burn table it cannot read.
+func TestNewConfigInvalidTable(t *testing.T) {
+	if file := os.Getenv("EMISSIONS_TEST_FUEL_BURN_FILE"); file != "" {
+		NewConfig(file, 0, "320")
+		return
+	}
+
+	tests := []struct {
+		name string
+		file string
+	}{
+		{"missing", filepath.Join(t.TempDir(), "missing.csv")},
+		{"invalid seats", writeTable(t, "320,Airbus A320,many,800,2.95\n")},
+		{"missing column", writeTable(t, "320,Airbus A320,180,800\n")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command(os.Args[0], "-test.run=^TestNewConfigInvalidTable$")
+			cmd.Env = append(os.Environ(), "EMISSIONS_TEST_FUEL_BURN_FILE="+tt.file)
+			out, err := cmd.CombinedOutput()
+			if _, ok := err.(*exec.ExitError); !ok {
+				t.Fatalf("NewConfig returned (%v), want the process to stop:\n%s", err, out)
+			}
+			if !strings.Contains(string(out), "Fuel-burn table could not be loaded") {
+				t.Errorf("output does not log the failure:\n%s", out)
+			}
+		})
+	}
+}
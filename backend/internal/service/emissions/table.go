@@ -0,0 +1,80 @@
+package emissions
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+//go:embed fuel_burn.csv
+var defaultFuelBurn string
+
+// fuelBurn is one aircraft type of the fuel-burn table.
+type fuelBurn struct {
+	code    string
+	name    string
+	seats   int
+	lto     float64
+	perKm   float64
+	aliases []string
+}
+
+// fuelBurnTable maps every normalized code to its aircraft type.
+type fuelBurnTable map[string]fuelBurn
+
+// normalize lets "B737-800", "b737 800" and "B737800" find the same row.
+func normalize(code string) string {
+	return strings.NewReplacer("-", "", " ", "", "_", "").Replace(strings.ToUpper(code))
+}
+
+func (t fuelBurnTable) lookup(codes ...string) (fuelBurn, bool) {
+	for _, code := range codes {
+		if code == "" {
+			continue
+		}
+		if row, ok := t[normalize(code)]; ok {
+			return row, true
+		}
+	}
+	return fuelBurn{}, false
+}
+
+// load adds the rows read from r, replacing rows that share a code.
+func (t fuelBurnTable) load(r io.Reader) error {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 5
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return fmt.Errorf("failed to read fuel-burn table: %w", err)
+	}
+	for i, record := range records {
+		if i == 0 && record[0] == "codes" {
+			continue
+		}
+
+		row := fuelBurn{name: record[1], aliases: strings.Fields(record[0])}
+		if len(row.aliases) == 0 {
+			return fmt.Errorf("fuel-burn row %d has no codes", i+1)
+		}
+		row.code = row.aliases[0]
+		if row.seats, err = strconv.Atoi(record[2]); err != nil || row.seats <= 0 {
+			return fmt.Errorf("fuel-burn row %d has invalid seats %q", i+1, record[2])
+		}
+		if row.lto, err = strconv.ParseFloat(record[3], 64); err != nil || row.lto < 0 {
+			return fmt.Errorf("fuel-burn row %d has invalid lto_fuel_kg %q", i+1, record[3])
+		}
+		if row.perKm, err = strconv.ParseFloat(record[4], 64); err != nil || row.perKm <= 0 {
+			return fmt.Errorf("fuel-burn row %d has invalid cruise_fuel_kg_per_km %q", i+1, record[4])
+		}
+		for _, alias := range row.aliases {
+			t[normalize(alias)] = row
+		}
+	}
+	return nil
+}
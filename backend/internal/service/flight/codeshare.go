@@ -0,0 +1,212 @@
+package flight
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+// Dedupe folds marketing legs into the flight that operates them, so every
+// physical flight appears once. A marketing leg whose operating leg is not
+// among flights stands in for it under the operating flight number. Both
+// carriers are resolved against the airline table. The order of flights is
+// kept.
+func (s *Service) Dedupe(ctx context.Context, flights []structs.Flight) ([]structs.OperatedFlight, error) {
+	type key struct {
+		date   time.Time
+		flight string
+	}
+	operated := []structs.OperatedFlight{}
+	index := make(map[key]int)
+
+	for _, f := range flights {
+		k := key{f.FlightDate, f.FlightIata}
+		if _, ok := index[k]; ok || f.Codeshare != nil {
+			continue
+		}
+		index[k] = len(operated)
+		operated = append(operated, structs.OperatedFlight{Flight: f, Marketing: []structs.FlightNumber{}})
+	}
+	for _, f := range flights {
+		if f.Codeshare == nil {
+			continue
+		}
+		k := key{f.FlightDate, f.Codeshare.FlightIata}
+		i, ok := index[k]
+		if !ok {
+			i = len(operated)
+			index[k] = i
+			operated = append(operated, structs.OperatedFlight{Flight: operatingLeg(f), Marketing: []structs.FlightNumber{}})
+		}
+		if !hasNumber(operated[i].Marketing, f.FlightIata) {
+			operated[i].Marketing = append(operated[i].Marketing, structs.FlightNumber{
+				FlightIata: f.FlightIata,
+				FlightIcao: f.FlightIcao,
+				Airline:    structs.Carrier{Iata: f.AirlineIata, Icao: f.AirlineIcao, Name: f.AirlineName},
+			})
+		}
+	}
+
+	var codes []string
+	for _, o := range operated {
+		codes = append(codes, o.AirlineIata, o.AirlineIcao)
+		for _, m := range o.Marketing {
+			codes = append(codes, m.Airline.Iata, m.Airline.Icao)
+		}
+	}
+	carriers, err := s.carriers(ctx, codes)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range operated {
+		o := &operated[i]
+		o.Operator = carriers.resolve(structs.Carrier{Iata: o.AirlineIata, Icao: o.AirlineIcao, Name: o.AirlineName})
+		if o.AirlineName == "" {
+			o.AirlineName = o.Operator.Name
+		}
+		for j := range o.Marketing {
+			o.Marketing[j].Airline = carriers.resolve(o.Marketing[j].Airline)
+		}
+		sort.Slice(o.Marketing, func(a, b int) bool { return o.Marketing[a].FlightIata < o.Marketing[b].FlightIata })
+	}
+	return operated, nil
+}
+
+// GetCodeshares returns the operating flight and every flight number sold
+// on it for each stored leg of flightIata on date. flightIata may be the
+// operating or a marketing number.
+func (s *Service) GetCodeshares(ctx context.Context, flightIata string, date time.Time) ([]structs.Codeshares, error) {
+	flights, err := s.repo.Flight.FindFlights(ctx, structs.FlightFilter{FlightIata: flightIata, Date: date})
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		date   time.Time
+		flight string
+	}
+	seen := make(map[key]bool)
+	var legs []structs.Flight
+	for _, f := range flights {
+		operating := f.FlightIata
+		if f.Codeshare != nil {
+			operating = f.Codeshare.FlightIata
+		}
+		if seen[key{f.FlightDate, operating}] {
+			continue
+		}
+		seen[key{f.FlightDate, operating}] = true
+
+		operated, err := s.repo.Flight.FindFlights(ctx, structs.FlightFilter{FlightIata: operating, Date: f.FlightDate})
+		if err != nil {
+			return nil, err
+		}
+		marketing, err := s.repo.Flight.FindFlights(ctx, structs.FlightFilter{OperatingIata: operating, Date: f.FlightDate})
+		if err != nil {
+			return nil, err
+		}
+		legs = append(legs, operated...)
+		legs = append(legs, marketing...)
+	}
+
+	grouped, err := s.Dedupe(ctx, legs)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]structs.Codeshares, len(grouped))
+	for i, o := range grouped {
+		views[i] = structs.Codeshares{
+			FlightDate: o.FlightDate,
+			From:       o.Departure.Iata,
+			To:         o.Arrival.Iata,
+			Operating:  structs.FlightNumber{FlightIata: o.FlightIata, FlightIcao: o.FlightIcao, Airline: o.Operator},
+			Marketing:  o.Marketing,
+		}
+	}
+	sort.SliceStable(views, func(i, j int) bool { return views[i].FlightDate.Before(views[j].FlightDate) })
+	return views, nil
+}
+
+// operatingLeg rebuilds the operating leg from a marketing one.
+func operatingLeg(f structs.Flight) structs.Flight {
+	f.FlightIata = f.Codeshare.FlightIata
+	f.FlightIcao = f.Codeshare.FlightIcao
+	f.FlightNumber = ""
+	f.AirlineIata = f.Codeshare.AirlineIata
+	f.AirlineIcao = f.Codeshare.AirlineIcao
+	f.AirlineName = ""
+	f.Codeshare = nil
+	return f
+}
+
+func hasNumber(numbers []structs.FlightNumber, flightIata string) bool {
+	for _, n := range numbers {
+		if n.FlightIata == flightIata {
+			return true
+		}
+	}
+	return false
+}
+
+// carrierIndex finds airlines by IATA or ICAO code.
+type carrierIndex map[string]structs.Airline
+
+func (s *Service) carriers(ctx context.Context, codes []string) (carrierIndex, error) {
+	unique := make(map[string]bool)
+	var lookup []string
+	for _, code := range codes {
+		code = strings.ToUpper(code)
+		if code != "" && !unique[code] {
+			unique[code] = true
+			lookup = append(lookup, code)
+		}
+	}
+	index := make(carrierIndex)
+	if len(lookup) == 0 {
+		return index, nil
+	}
+
+	airlines, err := s.repo.Airline.GetAirlinesByCode(ctx, lookup)
+	if err != nil {
+		return nil, err
+	}
+	// Rows come best first, so the first airline seen for a code wins.
+	for _, a := range airlines {
+		if _, ok := index["iata:"+a.IataCode]; !ok && a.IataCode != "" {
+			index["iata:"+a.IataCode] = a
+		}
+		if _, ok := index["icao:"+a.IcaoCode]; !ok && a.IcaoCode != "" {
+			index["icao:"+a.IcaoCode] = a
+		}
+	}
+	return index, nil
+}
+
+// resolve completes c from the airline table, matching the ICAO code first
+// since IATA codes are shared by more carriers.
+func (idx carrierIndex) resolve(c structs.Carrier) structs.Carrier {
+	a, ok := idx["icao:"+strings.ToUpper(c.Icao)]
+	if !ok {
+		a, ok = idx["iata:"+strings.ToUpper(c.Iata)]
+	}
+	if !ok {
+		return c
+	}
+	id := a.ID
+	c.AirlineID = &id
+	if c.Iata == "" {
+		c.Iata = a.IataCode
+	}
+	if c.Icao == "" {
+		c.Icao = a.IcaoCode
+	}
+	if a.AirlineName != "" {
+		c.Name = a.AirlineName
+	}
+	c.Country = a.CountryName
+	return c
+}
@@ -0,0 +1,101 @@
+package flight
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+)
+
+// fakeAirlines returns its airlines, best first, whatever the codes.
+type fakeAirlines struct {
+	repository.Airline
+	airlines []structs.Airline
+}
+
+func (f *fakeAirlines) GetAirlinesByCode(ctx context.Context, codes []string) ([]structs.Airline, error) {
+	return f.airlines, nil
+}
+
+func TestDedupe(t *testing.T) {
+	british, defunct, american := uuid.New(), uuid.New(), uuid.New()
+	s := NewService(&repository.Repository{Airline: &fakeAirlines{airlines: []structs.Airline{
+		// Best first for IATA BA, but not the carrier BAW names.
+		{ID: defunct, IataCode: "BA", IcaoCode: "BAX", AirlineName: "Defunct BA"},
+		{ID: british, IataCode: "BA", IcaoCode: "BAW", AirlineName: "British Airways", CountryName: "United Kingdom"},
+		{ID: american, IataCode: "AA", IcaoCode: "AAL", AirlineName: "American Airlines", CountryName: "United States"},
+	}}}, nil)
+
+	day := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	operating := func(date time.Time, number string) structs.Flight {
+		return structs.Flight{FlightDate: date, FlightIata: number, AirlineIata: "BA", AirlineIcao: "BAW"}
+	}
+	marketing := func(date time.Time, number string, icao string, name string, operatedBy string) structs.Flight {
+		return structs.Flight{
+			FlightDate:  date,
+			FlightIata:  number,
+			AirlineIata: number[:2],
+			AirlineIcao: icao,
+			AirlineName: name,
+			Codeshare:   &structs.FlightCodeshare{FlightIata: operatedBy, AirlineIata: "BA", AirlineIcao: "BAW"},
+		}
+	}
+	flights := []structs.Flight{
+		marketing(day, "IB7100", "IBE", "Iberia", "BA100"),
+		operating(day, "BA100"),
+		marketing(day, "AA6100", "AAL", "", "BA100"),
+		// The feed repeats a leg when it is polled twice.
+		marketing(day, "AA6100", "AAL", "", "BA100"),
+		operating(day, "BA100"),
+		// The same numbers the next day are another flight.
+		operating(day.AddDate(0, 0, 1), "BA100"),
+		marketing(day.AddDate(0, 0, 1), "AA6100", "AAL", "", "BA100"),
+		// BA200 was not polled: its only codeshare stands in for it.
+		marketing(day, "QR9", "QTR", "Qatar Airways", "BA200"),
+	}
+
+	operated, err := s.Dedupe(context.Background(), flights)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type view struct {
+		date      string
+		flight    string
+		operator  string
+		marketing []string
+	}
+	var got []view
+	for _, o := range operated {
+		v := view{date: o.FlightDate.Format("2006-01-02"), flight: o.FlightIata, operator: o.AirlineName}
+		for _, m := range o.Marketing {
+			v.marketing = append(v.marketing, m.FlightIata+" "+m.Airline.Name)
+		}
+		got = append(got, v)
+	}
+	want := []view{
+		{"2024-07-01", "BA100", "British Airways", []string{"AA6100 American Airlines", "IB7100 Iberia"}},
+		{"2024-07-02", "BA100", "British Airways", []string{"AA6100 American Airlines"}},
+		{"2024-07-01", "BA200", "British Airways", []string{"QR9 Qatar Airways"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Dedupe =\n%+v\nwant\n%+v", got, want)
+	}
+
+	// The operator is matched on ICAO, not on the IATA code BA shares with
+	// the defunct carrier; carriers missing from the table keep the feed's
+	// name and no id.
+	if id := operated[0].Operator.AirlineID; id == nil || *id != british {
+		t.Errorf("operator id = %v, want %s", id, british)
+	}
+	if id := operated[0].Marketing[0].Airline.AirlineID; id == nil || *id != american {
+		t.Errorf("AA6100 carrier id = %v, want %s", id, american)
+	}
+	if c := operated[0].Marketing[1].Airline; c.AirlineID != nil || c.Icao != "IBE" {
+		t.Errorf("IB7100 carrier = %+v, want Iberia from the feed", c)
+	}
+}
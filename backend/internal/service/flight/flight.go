@@ -0,0 +1,42 @@
+package flight
+
+import (
+	"context"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+// notifier makes the webhook deliveries of the flights whose live fields
+// changed.
+type notifier interface {
+	Deliveries(changes []structs.FlightChange, match func(kinds []string, values []string) ([]structs.WebhookMatch, error)) ([]structs.WebhookDelivery, error)
+}
+
+type Service struct {
+	repo     *repository.Repository
+	notifier notifier
+}
+
+func NewService(repo *repository.Repository, notifier notifier) *Service {
+	return &Service{repo: repo, notifier: notifier}
+}
+
+// RecordFlights stores observed flights together with the webhook
+// deliveries of the changes found: neither is stored without the other.
+func (s *Service) RecordFlights(ctx context.Context, flights []structs.Flight) (structs.FlightIngestion, error) {
+	return s.repo.Flight.RecordFlights(ctx, flights, s.notifier.Deliveries)
+}
+
+func (s *Service) GetRoutesSince(ctx context.Context, since time.Time) ([]structs.Route, error) {
+	return s.repo.Flight.GetRoutesSince(ctx, since)
+}
+
+func (s *Service) GetScheduledLegs(ctx context.Context, from time.Time, until time.Time, departures []string) ([]structs.Flight, error) {
+	return s.repo.Flight.GetScheduledLegs(ctx, from, until, departures)
+}
+
+func (s *Service) FindFlights(ctx context.Context, filter structs.FlightFilter) ([]structs.Flight, error) {
+	return s.repo.Flight.FindFlights(ctx, filter)
+}
@@ -0,0 +1,43 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/trash"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+)
+
+var ErrNotFound = errors.New("not found")
+
+type Service struct {
+	repo *repository.Repository
+}
+
+func NewService(repo *repository.Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// GetHistory returns the latest versions of the row id of resource, newest
+// first. A row without any recorded version is not found.
+func (s *Service) GetHistory(ctx context.Context, resource string, id uuid.UUID, limit int) ([]structs.HistoryEntry, error) {
+	known := false
+	for _, r := range trash.Resources {
+		known = known || r == resource
+	}
+	if !known {
+		return nil, fmt.Errorf("%w: %s", trash.ErrUnknownResource, resource)
+	}
+
+	entries, err := s.repo.History.GetHistory(ctx, resource, id, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, ErrNotFound
+	}
+	return entries, nil
+}
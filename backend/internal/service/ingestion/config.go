@@ -0,0 +1,11 @@
+package ingestion
+
+type Config struct {
+	strictSchema bool
+}
+
+// NewConfig sets whether a payload that drifted from the expected schema of
+// its endpoint fails the ingestion run instead of only being recorded.
+func NewConfig(strictSchema bool) Config {
+	return Config{strictSchema: strictSchema}
+}
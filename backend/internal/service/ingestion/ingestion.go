@@ -0,0 +1,54 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrNotFound = errors.New("not found")
+
+type Service struct {
+	repo   *repository.Repository
+	config Config
+}
+
+func NewService(repo *repository.Repository, config Config) *Service {
+	return &Service{repo: repo, config: config}
+}
+
+// StartRun records that an import of dataset from the upstream began.
+func (s *Service) StartRun(ctx context.Context, dataset string, dryRun bool) (structs.IngestionRun, error) {
+	return s.repo.Ingestion.CreateRun(ctx, dataset, structs.ProviderAviationStack, dryRun)
+}
+
+// FinishRun records the counts, errors and status of run with its diff,
+// nil when the import failed before computing one.
+func (s *Service) FinishRun(ctx context.Context, run structs.IngestionRun, diff *structs.IngestionDiff) error {
+	return s.repo.Ingestion.FinishRun(ctx, run, diff)
+}
+
+func (s *Service) GetRuns(ctx context.Context, filter structs.IngestionFilter) ([]structs.IngestionRun, error) {
+	return s.repo.Ingestion.GetRuns(ctx, filter)
+}
+
+func (s *Service) GetRun(ctx context.Context, id uuid.UUID) (structs.IngestionRun, error) {
+	run, err := s.repo.Ingestion.GetRun(ctx, id)
+	return run, notFound(err)
+}
+
+func (s *Service) GetRunDiff(ctx context.Context, id uuid.UUID) (structs.IngestionDiff, error) {
+	diff, err := s.repo.Ingestion.GetRunDiff(ctx, id)
+	return diff, notFound(err)
+}
+
+func notFound(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,198 @@
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/FACorreiaa/aviatoon-tracker/pkg/logs"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// ErrSchemaDrift fails runs whose payload drifted in strict mode.
+var ErrSchemaDrift = errors.New("schema drift")
+
+var driftEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "aviatoon_schema_drift_total",
+	Help: "Fields of upstream payloads that did not match the expected schema",
+}, []string{"endpoint", "kind"})
+
+// CheckSchema compares the data rows of a payload fetched from endpoint for
+// run with the expected schema of the endpoint, learning it from the
+// payload when there is none. The drift is logged and recorded; in strict
+// mode any drift is also an ErrSchemaDrift.
+func (s *Service) CheckSchema(ctx context.Context, run structs.IngestionRun, endpoint string, body []byte) ([]structs.SchemaDrift, error) {
+	observed, err := observe(body)
+	if err != nil {
+		return nil, err
+	}
+
+	expected, err := s.repo.Ingestion.GetSchema(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if len(expected.Fields) == 0 {
+		return nil, s.repo.Ingestion.SaveSchema(ctx, endpoint, observed.schema())
+	}
+
+	drift := observed.compare(expected.Fields)
+	if len(drift) == 0 {
+		return drift, nil
+	}
+	for i := range drift {
+		drift[i].RunID = run.ID
+		drift[i].Endpoint = endpoint
+		driftEvents.WithLabelValues(endpoint, drift[i].Kind).Inc()
+		logs.DefaultLogger.WithFields(map[string]interface{}{
+			"run":      run.ID,
+			"endpoint": endpoint,
+			"field":    drift[i].Field,
+			"kind":     drift[i].Kind,
+			"observed": drift[i].Observed,
+			"rows":     drift[i].Rows,
+		}).Warn("Upstream schema drift")
+	}
+	if err := s.repo.Ingestion.CreateDrift(ctx, drift); err != nil {
+		return drift, err
+	}
+
+	if s.config.strictSchema {
+		return drift, fmt.Errorf("%w: %s sent %d fields that differ from the expected schema", ErrSchemaDrift, endpoint, len(drift))
+	}
+	return drift, nil
+}
+
+func (s *Service) GetSchema(ctx context.Context, endpoint string) (structs.UpstreamSchema, error) {
+	schema, err := s.repo.Ingestion.GetSchema(ctx, endpoint)
+	if err == nil && len(schema.Fields) == 0 {
+		return schema, ErrNotFound
+	}
+	return schema, err
+}
+
+// DeleteSchema forgets the expected schema of endpoint, so that the next
+// payload is accepted as the new one.
+func (s *Service) DeleteSchema(ctx context.Context, endpoint string) error {
+	return notFound(s.repo.Ingestion.DeleteSchema(ctx, endpoint))
+}
+
+func (s *Service) GetRunDrift(ctx context.Context, id uuid.UUID) ([]structs.SchemaDrift, error) {
+	if _, err := s.GetRun(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.Ingestion.GetRunDrift(ctx, id)
+}
+
+// observation counts the data rows of a payload by field and JSON type.
+type observation map[string]map[string]int64
+
+func observe(body []byte) (observation, error) {
+	var payload struct {
+		Data []map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("failed to decode payload: %w", err)
+	}
+
+	o := observation{}
+	for _, row := range payload.Data {
+		for field, value := range row {
+			types, ok := o[field]
+			if !ok {
+				types = map[string]int64{}
+				o[field] = types
+			}
+			types[jsonType(value)]++
+		}
+	}
+	return o, nil
+}
+
+func jsonType(value json.RawMessage) string {
+	if len(value) == 0 {
+		return "null"
+	}
+	switch value[0] {
+	case '"':
+		return "string"
+	case '{':
+		return "object"
+	case '[':
+		return "array"
+	case 't', 'f':
+		return "boolean"
+	case 'n':
+		return "null"
+	default:
+		return "number"
+	}
+}
+
+// schema is the schema learnt from o: the most common type of each field
+// besides null, nullable when any row had a null.
+func (o observation) schema() []structs.FieldSchema {
+	fields := make([]structs.FieldSchema, 0, len(o))
+	for _, field := range o.fields() {
+		f := structs.FieldSchema{Field: field, Type: "null", Nullable: o[field]["null"] > 0}
+		var most int64
+		for _, typ := range sortedTypes(o[field]) {
+			if typ != "null" && o[field][typ] > most {
+				f.Type, most = typ, o[field][typ]
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// compare lists the fields of o that are unknown to expected, have another
+// type or are null where expected never was. Fields only seen as null are
+// not typed yet and take any type.
+func (o observation) compare(expected []structs.FieldSchema) []structs.SchemaDrift {
+	known := make(map[string]structs.FieldSchema, len(expected))
+	for _, f := range expected {
+		known[f.Field] = f
+	}
+
+	var drift []structs.SchemaDrift
+	for _, field := range o.fields() {
+		f, ok := known[field]
+		for _, typ := range sortedTypes(o[field]) {
+			rows := o[field][typ]
+			switch {
+			case !ok:
+				drift = append(drift, structs.SchemaDrift{Field: field, Kind: structs.DriftUnknownField, Observed: typ, Rows: rows})
+			case typ == "null":
+				if !f.Nullable {
+					drift = append(drift, structs.SchemaDrift{Field: field, Kind: structs.DriftNewlyNull, Expected: &f.Type, Observed: typ, Rows: rows})
+				}
+			case f.Type != "null" && typ != f.Type:
+				drift = append(drift, structs.SchemaDrift{Field: field, Kind: structs.DriftTypeChanged, Expected: &f.Type, Observed: typ, Rows: rows})
+			}
+		}
+	}
+	return drift
+}
+
+func (o observation) fields() []string {
+	fields := make([]string, 0, len(o))
+	for field := range o {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func sortedTypes(types map[string]int64) []string {
+	names := make([]string, 0, len(types))
+	for typ := range types {
+		names = append(names, typ)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,124 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+)
+
+type fakeIngestion struct {
+	repository.Ingestion
+	schema structs.UpstreamSchema
+	saved  []structs.FieldSchema
+	drift  []structs.SchemaDrift
+}
+
+func (f *fakeIngestion) GetSchema(ctx context.Context, endpoint string) (structs.UpstreamSchema, error) {
+	return f.schema, nil
+}
+
+func (f *fakeIngestion) SaveSchema(ctx context.Context, endpoint string, fields []structs.FieldSchema) error {
+	f.saved = fields
+	return nil
+}
+
+func (f *fakeIngestion) CreateDrift(ctx context.Context, drift []structs.SchemaDrift) error {
+	f.drift = drift
+	return nil
+}
+
+var taxSchema = []structs.FieldSchema{
+	{Field: "iata_code", Type: "string", Nullable: true},
+	{Field: "tax_id", Type: "number"},
+	{Field: "tax_name", Type: "string"},
+}
+
+func TestCheckSchemaLearns(t *testing.T) {
+	repo := &fakeIngestion{}
+	s := NewService(&repository.Repository{Ingestion: repo}, NewConfig(true))
+	body := `{"data":[
+		{"tax_id":1,"tax_name":"Airport Tax","iata_code":null},
+		{"tax_id":2,"tax_name":"Security Fee","iata_code":"SF"}
+	]}`
+
+	drift, err := s.CheckSchema(context.Background(), structs.IngestionRun{}, "taxes", []byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drift) != 0 {
+		t.Errorf("drift = %+v, want none on the first payload", drift)
+	}
+	if !reflect.DeepEqual(repo.saved, taxSchema) {
+		t.Errorf("learnt %+v, want %+v", repo.saved, taxSchema)
+	}
+}
+
+func TestCheckSchemaDrift(t *testing.T) {
+	str, num := "string", "number"
+	tests := []struct {
+		name string
+		body string
+		want []structs.SchemaDrift
+	}{{
+		name: "unchanged",
+		body: `{"data":[{"tax_id":1,"tax_name":"Airport Tax","iata_code":null}]}`,
+	}, {
+		name: "unknown field",
+		body: `{"data":[
+			{"tax_id":1,"tax_name":"Airport Tax","iata_code":"AT","country":"PT"},
+			{"tax_id":2,"tax_name":"Security Fee","iata_code":"SF","country":"ES"}
+		]}`,
+		want: []structs.SchemaDrift{{Field: "country", Kind: structs.DriftUnknownField, Observed: "string", Rows: 2}},
+	}, {
+		name: "number sent as a string",
+		body: `{"data":[
+			{"tax_id":"1","tax_name":"Airport Tax","iata_code":"AT"},
+			{"tax_id":2,"tax_name":"Security Fee","iata_code":"SF"}
+		]}`,
+		want: []structs.SchemaDrift{{Field: "tax_id", Kind: structs.DriftTypeChanged, Expected: &num, Observed: "string", Rows: 1}},
+	}, {
+		name: "newly null",
+		body: `{"data":[
+			{"tax_id":1,"tax_name":null,"iata_code":null},
+			{"tax_id":2,"tax_name":null,"iata_code":"SF"}
+		]}`,
+		want: []structs.SchemaDrift{{Field: "tax_name", Kind: structs.DriftNewlyNull, Expected: &str, Observed: "null", Rows: 2}},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := structs.IngestionRun{ID: uuid.New()}
+			for i := range tt.want {
+				tt.want[i].RunID = run.ID
+				tt.want[i].Endpoint = "taxes"
+			}
+
+			repo := &fakeIngestion{schema: structs.UpstreamSchema{Endpoint: "taxes", Fields: taxSchema}}
+			s := NewService(&repository.Repository{Ingestion: repo}, NewConfig(false))
+			drift, err := s.CheckSchema(context.Background(), run, "taxes", []byte(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(drift, tt.want) {
+				t.Errorf("drift = %+v, want %+v", drift, tt.want)
+			}
+			if !reflect.DeepEqual(repo.drift, tt.want) {
+				t.Errorf("stored %+v, want %+v", repo.drift, tt.want)
+			}
+			if repo.saved != nil {
+				t.Errorf("saved %+v over the expected schema", repo.saved)
+			}
+
+			strict := NewService(&repository.Repository{Ingestion: repo}, NewConfig(true))
+			_, err = strict.CheckSchema(context.Background(), run, "taxes", []byte(tt.body))
+			if got := errors.Is(err, ErrSchemaDrift); got != (len(tt.want) > 0) {
+				t.Errorf("strict mode error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package integrity
+
+import (
+	"context"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+)
+
+type Service struct {
+	repo *repository.Repository
+}
+
+func NewService(repo *repository.Repository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) LinkReferences(ctx context.Context) (structs.LinkResult, error) {
+	return s.repo.Integrity.LinkReferences(ctx)
+}
+
+// LinkRows links the references that a write of the rows ids of table may
+// have changed.
+func (s *Service) LinkRows(ctx context.Context, table string, ids []uuid.UUID) (structs.LinkResult, error) {
+	return s.repo.Integrity.LinkRows(ctx, table, ids)
+}
+
+func (s *Service) GetIntegrityReport(ctx context.Context, limit int) (structs.IntegrityReport, error) {
+	return s.repo.Integrity.GetIntegrityReport(ctx, limit)
+}
@@ -0,0 +1,34 @@
+package itinerary
+
+import (
+	"strings"
+	"time"
+)
+
+type Config struct {
+	minConnection  time.Duration
+	minConnections map[string]time.Duration
+	maxLayover     time.Duration
+}
+
+// NewConfig sets the minimum connection time used at every airport, the
+// per-airport overrides keyed by IATA code and the longest layover an
+// itinerary may contain.
+func NewConfig(minConnection time.Duration, minConnections map[string]time.Duration, maxLayover time.Duration) Config {
+	overrides := make(map[string]time.Duration, len(minConnections))
+	for iata, d := range minConnections {
+		overrides[strings.ToUpper(iata)] = d
+	}
+	return Config{
+		minConnection:  minConnection,
+		minConnections: overrides,
+		maxLayover:     maxLayover,
+	}
+}
+
+func (c Config) connection(iata string) time.Duration {
+	if d, ok := c.minConnections[iata]; ok {
+		return d
+	}
+	return c.minConnection
+}
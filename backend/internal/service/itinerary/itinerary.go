@@ -0,0 +1,353 @@
+package itinerary
+
+import (
+	"container/heap"
+	"context"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/localtime"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+// defaultLimit is the number of itineraries searched for when the query
+// does not limit them.
+const defaultLimit = 10
+
+// network is the part of the route network the search is pruned with.
+type network interface {
+	GetAirportsBetween(ctx context.Context, from string, to string, maxHops int) ([]string, error)
+}
+
+type Service struct {
+	repo    *repository.Repository
+	network network
+	config  Config
+}
+
+func NewService(repo *repository.Repository, network network, config Config) *Service {
+	return &Service{repo: repo, network: network, config: config}
+}
+
+// leg is a scheduled flight with both times resolved to instants.
+type leg struct {
+	flight    structs.Flight
+	departure time.Time
+	arrival   time.Time
+}
+
+// FindItineraries searches the flights observed around q.Date for the
+// q.Limit best connections from q.From to q.To with at most q.MaxStops
+// stops, best by q.Sort. Only airports that lie on a short enough path in
+// the route network are considered, and every connection leaves at least
+// the minimum connection time of the airport it happens at.
+func (s *Service) FindItineraries(ctx context.Context, q structs.ItineraryQuery) ([]structs.Itinerary, error) {
+	q.From, q.To = strings.ToUpper(q.From), strings.ToUpper(q.To)
+	if q.Limit <= 0 {
+		q.Limit = defaultLimit
+	}
+
+	airports, err := s.network.GetAirportsBetween(ctx, q.From, q.To, q.MaxStops+1)
+	if err != nil {
+		return nil, err
+	}
+	itineraries := []structs.Itinerary{}
+	if len(airports) == 0 {
+		return itineraries, nil
+	}
+
+	positions, err := s.repo.Airport.GetAirportPositions(ctx, airports)
+	if err != nil {
+		return nil, err
+	}
+	byIata := make(map[string]structs.AirportPosition, len(positions))
+	for _, p := range positions {
+		byIata[p.Iata] = p
+	}
+
+	flights, err := s.repo.Flight.GetScheduledLegs(ctx, q.Date, q.Date.AddDate(0, 0, q.MaxStops+1), airports)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := make(map[string]bool, len(airports))
+	for _, iata := range airports {
+		allowed[iata] = true
+	}
+	zones := localtime.Zones{}
+	departures := make(map[string][]leg)
+	for _, f := range flights {
+		if !allowed[f.Arrival.Iata] {
+			continue
+		}
+		l := leg{
+			flight:    f,
+			departure: localtime.At(*f.Departure.Scheduled, zones.Load(byIata[f.Departure.Iata].Timezone, f.Departure.Timezone)),
+			arrival:   localtime.At(*f.Arrival.Scheduled, zones.Load(byIata[f.Arrival.Iata].Timezone, f.Arrival.Timezone)),
+		}
+		if !l.arrival.After(l.departure) {
+			continue
+		}
+		departures[f.Departure.Iata] = append(departures[f.Departure.Iata], l)
+	}
+	for _, legs := range departures {
+		sort.Slice(legs, func(i, j int) bool { return legs[i].departure.Before(legs[j].departure) })
+	}
+
+	for _, path := range s.search(q, departures, byIata) {
+		itineraries = append(itineraries, itineraryOf(path, byIata))
+	}
+	rank(itineraries, q.Sort)
+	return itineraries, nil
+}
+
+// search returns the q.Limit best paths by q.Sort, with a best-first search
+// on the partial itineraries: the k shortest paths variant of Dijkstra's
+// algorithm. Every cost only grows as a path is extended, so complete paths
+// come off the queue best first. Each flight is extended from at most
+// q.Limit times, since the extensions of a worse path through it rank
+// behind those of the better ones.
+func (s *Service) search(q structs.ItineraryQuery, departures map[string][]leg, positions map[string]structs.AirportPosition) [][]leg {
+	queue := &labels{order: labelCriteria(q.Sort)}
+	for i := range departures[q.From] {
+		first := &departures[q.From][i]
+		if !first.flight.FlightDate.Equal(q.Date) || first.flight.Arrival.Iata == q.From {
+			continue
+		}
+		heap.Push(queue, (*label)(nil).extend(first, positions))
+	}
+
+	var paths [][]leg
+	settled := make(map[*leg]int)
+	for queue.Len() > 0 && len(paths) < q.Limit {
+		l := heap.Pop(queue).(*label)
+		if settled[l.leg] >= q.Limit {
+			continue
+		}
+		settled[l.leg]++
+
+		at := l.leg.flight.Arrival.Iata
+		if at == q.To {
+			paths = append(paths, l.path())
+			continue
+		}
+		if l.legs > q.MaxStops {
+			continue
+		}
+
+		earliest := l.leg.arrival.Add(s.config.connection(at))
+		latest := l.leg.arrival.Add(s.config.maxLayover)
+		legs := departures[at]
+		for i := sort.Search(len(legs), func(i int) bool { return !legs[i].departure.Before(earliest) }); i < len(legs); i++ {
+			next := &legs[i]
+			if next.departure.After(latest) {
+				break
+			}
+			if to := next.flight.Arrival.Iata; to == q.From || l.visits(to) {
+				continue
+			}
+			heap.Push(queue, l.extend(next, positions))
+		}
+	}
+	return paths
+}
+
+// label is a partial itinerary: its last leg, the label of the legs before
+// it and what they cost so far.
+type label struct {
+	leg     *leg
+	prev    *label
+	legs    int
+	start   time.Time
+	km      float64
+	located bool
+}
+
+// extend is the itinerary l followed by next; a nil l starts one.
+func (l *label) extend(next *leg, positions map[string]structs.AirportPosition) *label {
+	from, fromOk := positions[next.flight.Departure.Iata]
+	to, toOk := positions[next.flight.Arrival.Iata]
+	located := fromOk && toOk && from.HasCoordinates() && to.HasCoordinates()
+
+	if l == nil {
+		return &label{leg: next, legs: 1, start: next.departure, km: from.DistanceKm(to), located: located}
+	}
+	return &label{
+		leg:     next,
+		prev:    l,
+		legs:    l.legs + 1,
+		start:   l.start,
+		km:      l.km + from.DistanceKm(to),
+		located: l.located && located,
+	}
+}
+
+// visits reports whether the itinerary already arrives at iata.
+func (l *label) visits(iata string) bool {
+	for ; l != nil; l = l.prev {
+		if l.leg.flight.Arrival.Iata == iata {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *label) path() []leg {
+	path := make([]leg, l.legs)
+	for i := l.legs - 1; l != nil; i, l = i-1, l.prev {
+		path[i] = *l.leg
+	}
+	return path
+}
+
+// labelCriteria orders partial itineraries the way rank orders complete
+// ones. The flown distance stands in for the detour, the direct distance
+// being the same for all of them.
+func labelCriteria(by string) []func(a, b *label) int {
+	duration := func(a, b *label) int {
+		da, db := a.leg.arrival.Sub(a.start), b.leg.arrival.Sub(b.start)
+		switch {
+		case da < db:
+			return -1
+		case da > db:
+			return 1
+		}
+		return 0
+	}
+	stops := func(a, b *label) int { return a.legs - b.legs }
+	distance := func(a, b *label) int {
+		km := func(l *label) float64 {
+			if !l.located {
+				return math.Inf(1)
+			}
+			return l.km
+		}
+		switch ka, kb := km(a), km(b); {
+		case ka < kb:
+			return -1
+		case ka > kb:
+			return 1
+		}
+		return 0
+	}
+	switch by {
+	case "stops":
+		return []func(a, b *label) int{stops, duration, distance}
+	case "detour":
+		return []func(a, b *label) int{distance, duration, stops}
+	}
+	return []func(a, b *label) int{duration, stops, distance}
+}
+
+// labels is a heap of partial itineraries, the best first.
+type labels struct {
+	items []*label
+	order []func(a, b *label) int
+}
+
+func (h *labels) Len() int { return len(h.items) }
+
+func (h *labels) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	for _, compare := range h.order {
+		if c := compare(a, b); c != 0 {
+			return c < 0
+		}
+	}
+	return a.start.Before(b.start)
+}
+
+func (h *labels) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *labels) Push(x interface{}) { h.items = append(h.items, x.(*label)) }
+
+func (h *labels) Pop() interface{} {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+func itineraryOf(path []leg, positions map[string]structs.AirportPosition) structs.Itinerary {
+	itinerary := structs.Itinerary{
+		Legs:            make([]structs.ItineraryLeg, len(path)),
+		Stops:           len(path) - 1,
+		DurationMinutes: int(path[len(path)-1].arrival.Sub(path[0].departure).Minutes()),
+	}
+
+	located := true
+	for i, l := range path {
+		from, fromOk := positions[l.flight.Departure.Iata]
+		to, toOk := positions[l.flight.Arrival.Iata]
+		located = located && fromOk && toOk && from.HasCoordinates() && to.HasCoordinates()
+
+		itinerary.Legs[i] = structs.ItineraryLeg{
+			FlightIata:  l.flight.FlightIata,
+			AirlineIata: l.flight.AirlineIata,
+			From:        l.flight.Departure.Iata,
+			To:          l.flight.Arrival.Iata,
+			Departure:   l.departure,
+			Arrival:     l.arrival,
+			DistanceKm:  math.Round(from.DistanceKm(to)),
+		}
+		if i > 0 {
+			itinerary.Legs[i].LayoverMinutes = int(l.departure.Sub(path[i-1].arrival).Minutes())
+		}
+		itinerary.DistanceKm += from.DistanceKm(to)
+	}
+
+	direct := positions[path[0].flight.Departure.Iata].DistanceKm(positions[path[len(path)-1].flight.Arrival.Iata])
+	if located && direct > 0 {
+		detour := math.Round(itinerary.DistanceKm/direct*1000) / 1000
+		itinerary.Detour = &detour
+	}
+	itinerary.DistanceKm = math.Round(itinerary.DistanceKm)
+	return itinerary
+}
+
+// rank orders itineraries by the requested criterion first and the other two
+// after it. Unknown detours rank behind known ones.
+func rank(itineraries []structs.Itinerary, by string) {
+	detour := func(it structs.Itinerary) float64 {
+		if it.Detour == nil {
+			return math.Inf(1)
+		}
+		return *it.Detour
+	}
+	criteria := map[string][]func(a, b structs.Itinerary) int{
+		"duration": {byDuration, byStops, byDetour(detour)},
+		"stops":    {byStops, byDuration, byDetour(detour)},
+		"detour":   {byDetour(detour), byDuration, byStops},
+	}
+	order, ok := criteria[by]
+	if !ok {
+		order = criteria["duration"]
+	}
+
+	sort.SliceStable(itineraries, func(i, j int) bool {
+		for _, compare := range order {
+			if c := compare(itineraries[i], itineraries[j]); c != 0 {
+				return c < 0
+			}
+		}
+		return itineraries[i].Legs[0].Departure.Before(itineraries[j].Legs[0].Departure)
+	})
+}
+
+func byDuration(a, b structs.Itinerary) int { return a.DurationMinutes - b.DurationMinutes }
+
+func byStops(a, b structs.Itinerary) int { return a.Stops - b.Stops }
+
+func byDetour(detour func(structs.Itinerary) float64) func(a, b structs.Itinerary) int {
+	return func(a, b structs.Itinerary) int {
+		switch da, db := detour(a), detour(b); {
+		case da < db:
+			return -1
+		case da > db:
+			return 1
+		}
+		return 0
+	}
+}
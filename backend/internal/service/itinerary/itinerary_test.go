@@ -0,0 +1,204 @@
+package itinerary
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+type fakeNetwork []string
+
+func (n fakeNetwork) GetAirportsBetween(ctx context.Context, from string, to string, maxHops int) ([]string, error) {
+	return n, nil
+}
+
+type fakeAirports struct {
+	repository.Airport
+	positions []structs.AirportPosition
+}
+
+func (f fakeAirports) GetAirportPositions(ctx context.Context, iataCodes []string) ([]structs.AirportPosition, error) {
+	return f.positions, nil
+}
+
+type fakeFlights struct {
+	repository.Flight
+	legs []structs.Flight
+}
+
+func (f fakeFlights) GetScheduledLegs(ctx context.Context, from time.Time, until time.Time, departures []string) ([]structs.Flight, error) {
+	return f.legs, nil
+}
+
+var positions = []structs.AirportPosition{
+	{Iata: "LIS", Latitude: 38.7813, Longitude: -9.1359, Timezone: "Europe/Lisbon"},
+	{Iata: "MAD", Latitude: 40.4719, Longitude: -3.5626, Timezone: "Europe/Madrid"},
+	{Iata: "FRA", Latitude: 50.0333, Longitude: 8.5706, Timezone: "Europe/Berlin"},
+	{Iata: "JFK", Latitude: 40.6398, Longitude: -73.7789, Timezone: "America/New_York"},
+	{Iata: "LHR", Latitude: 51.4706, Longitude: -0.4619, Timezone: "Europe/London"},
+}
+
+var day = time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+// flight is a leg scheduled at the local wall-clock times dep and arr of
+// its airports, stored as the provider sends them: labelled UTC.
+func flight(iata, from, to, dep, arr string) structs.Flight {
+	wall := func(s string) *time.Time {
+		t, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			panic(err)
+		}
+		return &t
+	}
+	return structs.Flight{
+		FlightDate: day,
+		FlightIata: iata,
+		Departure:  structs.FlightEndpoint{Iata: from, Scheduled: wall(dep)},
+		Arrival:    structs.FlightEndpoint{Iata: to, Scheduled: wall(arr)},
+	}
+}
+
+func search(t *testing.T, config Config, legs []structs.Flight, q structs.ItineraryQuery) []structs.Itinerary {
+	t.Helper()
+	repo := &repository.Repository{Airport: fakeAirports{positions: positions}, Flight: fakeFlights{legs: legs}}
+	s := NewService(repo, fakeNetwork{"LIS", "MAD", "FRA", "JFK", "LHR"}, config)
+	q.Date = day
+	if q.Sort == "" {
+		q.Sort = "duration"
+	}
+	itineraries, err := s.FindItineraries(context.Background(), q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return itineraries
+}
+
+func flightsOf(itineraries []structs.Itinerary) [][]string {
+	var flights [][]string
+	for _, it := range itineraries {
+		var legs []string
+		for _, l := range it.Legs {
+			legs = append(legs, l.FlightIata)
+		}
+		flights = append(flights, legs)
+	}
+	return flights
+}
+
+func TestMinimumConnection(t *testing.T) {
+	legs := []structs.Flight{
+		flight("TP1", "LIS", "MAD", "2024-05-01 08:00", "2024-05-01 10:15"),
+		flight("IB1", "MAD", "FRA", "2024-05-01 10:45", "2024-05-01 13:15"),
+		flight("IB2", "MAD", "FRA", "2024-05-01 11:30", "2024-05-01 14:00"),
+	}
+	q := structs.ItineraryQuery{From: "lis", To: "fra", MaxStops: 1}
+
+	got := flightsOf(search(t, NewConfig(30*time.Minute, nil, 6*time.Hour), legs, q))
+	if want := [][]string{{"TP1", "IB1"}, {"TP1", "IB2"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("with a 30 minute connection got %v, want %v", got, want)
+	}
+
+	// Madrid needs an hour, which the 10:45 connection does not leave.
+	config := NewConfig(30*time.Minute, map[string]time.Duration{"mad": time.Hour}, 6*time.Hour)
+	got = flightsOf(search(t, config, legs, q))
+	if want := [][]string{{"TP1", "IB2"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("with an hour at MAD got %v, want %v", got, want)
+	}
+
+	// The layover may not exceed maxLayover either.
+	got = flightsOf(search(t, NewConfig(30*time.Minute, nil, time.Hour), legs, q))
+	if want := [][]string{{"TP1", "IB1"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("with an hour of layover at most got %v, want %v", got, want)
+	}
+}
+
+func TestTimezones(t *testing.T) {
+	// 22:10 in New York is 03:10 in London, so the 10:05 arrival the next
+	// morning is a 6h55 flight.
+	legs := []structs.Flight{flight("BA178", "JFK", "LHR", "2024-05-01 22:10", "2024-05-02 10:05")}
+	got := search(t, NewConfig(time.Hour, nil, 6*time.Hour), legs, structs.ItineraryQuery{From: "JFK", To: "LHR"})
+	if len(got) != 1 {
+		t.Fatalf("got %d itineraries, want 1", len(got))
+	}
+
+	if got[0].DurationMinutes != 415 {
+		t.Errorf("duration = %d minutes, want 415", got[0].DurationMinutes)
+	}
+	l := got[0].Legs[0]
+	if _, offset := l.Departure.Zone(); offset != -4*3600 {
+		t.Errorf("departure %v, want New York time", l.Departure)
+	}
+	if _, offset := l.Arrival.Zone(); offset != 3600 {
+		t.Errorf("arrival %v, want London time", l.Arrival)
+	}
+	if want := time.Date(2024, 5, 2, 9, 5, 0, 0, time.UTC); !l.Arrival.Equal(want) {
+		t.Errorf("arrival = %v, want %v", l.Arrival, want)
+	}
+}
+
+func TestMaxStops(t *testing.T) {
+	legs := []structs.Flight{
+		flight("TP1", "LIS", "MAD", "2024-05-01 07:00", "2024-05-01 09:15"),
+		flight("IB1", "MAD", "FRA", "2024-05-01 10:30", "2024-05-01 13:00"),
+		flight("LH1", "FRA", "LHR", "2024-05-01 14:00", "2024-05-01 14:45"),
+		flight("IB2", "MAD", "LHR", "2024-05-01 16:00", "2024-05-01 17:30"),
+		flight("TP2", "LIS", "LHR", "2024-05-01 18:00", "2024-05-01 20:40"),
+	}
+	config := NewConfig(time.Hour, nil, 8*time.Hour)
+
+	tests := []struct {
+		maxStops int
+		want     [][]string
+	}{
+		{0, [][]string{{"TP2"}}},
+		{1, [][]string{{"TP2"}, {"TP1", "IB2"}}},
+		{2, [][]string{{"TP2"}, {"TP1", "IB1", "LH1"}, {"TP1", "IB2"}}},
+	}
+	for _, tt := range tests {
+		got := search(t, config, legs, structs.ItineraryQuery{From: "LIS", To: "LHR", MaxStops: tt.maxStops})
+		if !reflect.DeepEqual(flightsOf(got), tt.want) {
+			t.Errorf("max_stops=%d: got %v, want %v", tt.maxStops, flightsOf(got), tt.want)
+		}
+		for _, it := range got {
+			if it.Stops > tt.maxStops {
+				t.Errorf("max_stops=%d: %v has %d stops", tt.maxStops, flightsOf([]structs.Itinerary{it}), it.Stops)
+			}
+		}
+	}
+}
+
+func TestLimit(t *testing.T) {
+	legs := []structs.Flight{
+		flight("TP1", "LIS", "MAD", "2024-05-01 07:00", "2024-05-01 09:15"),
+		flight("IB1", "MAD", "FRA", "2024-05-01 10:30", "2024-05-01 13:00"),
+		flight("LH1", "FRA", "LHR", "2024-05-01 14:00", "2024-05-01 14:45"),
+		flight("IB2", "MAD", "LHR", "2024-05-01 16:00", "2024-05-01 17:30"),
+		flight("TP2", "LIS", "LHR", "2024-05-01 18:00", "2024-05-01 20:40"),
+		flight("TP3", "LIS", "LHR", "2024-05-01 06:00", "2024-05-01 11:00"),
+	}
+	config := NewConfig(time.Hour, nil, 8*time.Hour)
+
+	tests := []struct {
+		sort string
+		want [][]string
+	}{
+		{"duration", [][]string{{"TP2"}, {"TP3"}}},
+		{"stops", [][]string{{"TP2"}, {"TP3"}}},
+		{"detour", [][]string{{"TP2"}, {"TP3"}}},
+	}
+	for _, tt := range tests {
+		got := search(t, config, legs, structs.ItineraryQuery{From: "LIS", To: "LHR", MaxStops: 2, Sort: tt.sort, Limit: 2})
+		if !reflect.DeepEqual(flightsOf(got), tt.want) {
+			t.Errorf("sort=%s: got %v, want %v", tt.sort, flightsOf(got), tt.want)
+		}
+	}
+
+	got := search(t, config, legs, structs.ItineraryQuery{From: "LIS", To: "LHR", MaxStops: 2, Limit: 3})
+	if want := [][]string{{"TP2"}, {"TP3"}, {"TP1", "IB1", "LH1"}}; !reflect.DeepEqual(flightsOf(got), want) {
+		t.Errorf("limit=3: got %v, want %v", flightsOf(got), want)
+	}
+}
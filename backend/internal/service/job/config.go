@@ -0,0 +1,39 @@
+package job
+
+import "time"
+
+type Config struct {
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+}
+
+// NewConfig sets how many times a job is tried before it fails for good and
+// the wait after its first failure, doubled after every further one up to
+// maxBackoff.
+func NewConfig(maxAttempts int, backoff time.Duration, maxBackoff time.Duration) Config {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	if maxBackoff < backoff {
+		maxBackoff = backoff
+	}
+	return Config{
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+		maxBackoff:  maxBackoff,
+	}
+}
+
+// retryAfter is the wait before the next run of a job that failed attempts
+// times.
+func (c Config) retryAfter(attempts int) time.Duration {
+	wait := c.backoff
+	for i := 1; i < attempts && wait < c.maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > c.maxBackoff {
+		wait = c.maxBackoff
+	}
+	return wait
+}
@@ -0,0 +1,151 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	jobrepo "github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/job"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrActiveKey = jobrepo.ErrActiveKey
+	// ErrLeaseLost is returned to a worker that no longer holds a job,
+	// because its lease ran out and another worker claimed it.
+	ErrLeaseLost = errors.New("job lease lost")
+)
+
+// permanent marks an error that retrying cannot fix.
+type permanent struct{ err error }
+
+func (p permanent) Error() string { return p.err.Error() }
+func (p permanent) Unwrap() error { return p.err }
+
+// Permanent wraps err so the job fails at once instead of being retried,
+// e.g. for a payload that can never be processed.
+func Permanent(err error) error {
+	return permanent{err: err}
+}
+
+type Service struct {
+	repo   *repository.Repository
+	config Config
+}
+
+func NewService(repo *repository.Repository, config Config) *Service {
+	return &Service{repo: repo, config: config}
+}
+
+// Enqueue queues a job of kind, due now. A non-empty key keeps the job from
+// being queued twice: while a job with the same key is queued or running,
+// that job is returned and created is false.
+func (s *Service) Enqueue(ctx context.Context, kind string, key string, payload interface{}) (structs.Job, bool, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return structs.Job{}, false, fmt.Errorf("failed to encode job payload: %w", err)
+	}
+	j := structs.Job{
+		Kind:        kind,
+		Payload:     body,
+		MaxAttempts: s.config.maxAttempts,
+		RunAt:       time.Now(),
+	}
+	if key != "" {
+		j.Key = &key
+	}
+	return s.repo.Job.CreateJob(ctx, j)
+}
+
+func (s *Service) GetJobs(ctx context.Context, filter structs.JobFilter) ([]structs.Job, error) {
+	return s.repo.Job.GetJobs(ctx, filter)
+}
+
+func (s *Service) GetJob(ctx context.Context, id uuid.UUID) (structs.Job, error) {
+	j, err := s.repo.Job.GetJob(ctx, id)
+	return j, notFound(err)
+}
+
+func (s *Service) GetJobOutput(ctx context.Context, id uuid.UUID) (structs.JobOutput, error) {
+	out, err := s.repo.Job.GetJobOutput(ctx, id)
+	return out, notFound(err)
+}
+
+// StreamJobOutput passes the file of a job to fn a chunk at a time.
+func (s *Service) StreamJobOutput(ctx context.Context, id uuid.UUID, fn func([]byte) error) error {
+	return s.repo.Job.StreamJobOutput(ctx, id, fn)
+}
+
+func (s *Service) CancelJob(ctx context.Context, id uuid.UUID) error {
+	return notFound(s.repo.Job.CancelJob(ctx, id))
+}
+
+func (s *Service) RetryJob(ctx context.Context, id uuid.UUID) error {
+	return notFound(s.repo.Job.RetryJob(ctx, id))
+}
+
+// Claim hands up to limit due jobs to worker for lease.
+func (s *Service) Claim(ctx context.Context, worker string, limit int, lease time.Duration) ([]structs.Job, error) {
+	return s.repo.Job.ClaimJobs(ctx, worker, limit, time.Now().Add(lease))
+}
+
+// Renew extends the lease of worker on a job it is running.
+func (s *Service) Renew(ctx context.Context, id uuid.UUID, worker string, lease time.Duration) error {
+	return leaseLost(s.repo.Job.RenewLease(ctx, id, worker, time.Now().Add(lease)))
+}
+
+// Finish records the result of a job, and the content type of the file it
+// wrote to its Output, if any.
+func (s *Service) Finish(ctx context.Context, id uuid.UUID, worker string, result interface{}, outputType string) error {
+	var body json.RawMessage
+	if result != nil {
+		b, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("failed to encode job result: %w", err)
+		}
+		body = b
+	}
+	var contentType *string
+	if outputType != "" {
+		contentType = &outputType
+	}
+	return leaseLost(s.repo.Job.FinishJob(ctx, id, worker, body, contentType))
+}
+
+// Fail records a failed run of j. The job is retried with exponential
+// backoff until its last attempt, unless the error is Permanent.
+func (s *Service) Fail(ctx context.Context, j structs.Job, worker string, cause error) error {
+	var retryAt *time.Time
+	var p permanent
+	if j.Attempts < j.MaxAttempts && !errors.As(cause, &p) {
+		at := time.Now().Add(s.config.retryAfter(j.Attempts))
+		retryAt = &at
+	}
+	return leaseLost(s.repo.Job.FailJob(ctx, j.ID, worker, cause.Error(), retryAt))
+}
+
+// Release gives a job back to the queue without counting the run, for
+// workers shutting down before it finished.
+func (s *Service) Release(ctx context.Context, id uuid.UUID, worker string) error {
+	return leaseLost(s.repo.Job.ReleaseJob(ctx, id, worker))
+}
+
+func notFound(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func leaseLost(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrLeaseLost
+	}
+	return err
+}
@@ -0,0 +1,130 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// fakeJobs keeps the lease and failure asked of the repository.
+type fakeJobs struct {
+	repository.Job
+	leaseUntil time.Time
+	retryAt    *time.Time
+	err        error
+}
+
+func (f *fakeJobs) ClaimJobs(_ context.Context, _ string, _ int, leaseUntil time.Time) ([]structs.Job, error) {
+	f.leaseUntil = leaseUntil
+	return nil, f.err
+}
+
+func (f *fakeJobs) RenewLease(_ context.Context, _ uuid.UUID, _ string, leaseUntil time.Time) error {
+	f.leaseUntil = leaseUntil
+	return f.err
+}
+
+func (f *fakeJobs) FailJob(_ context.Context, _ uuid.UUID, _ string, _ string, retryAt *time.Time) error {
+	f.retryAt = retryAt
+	return f.err
+}
+
+func (f *fakeJobs) ReleaseJob(context.Context, uuid.UUID, string) error { return f.err }
+
+func testService(jobs *fakeJobs) *Service {
+	return NewService(&repository.Repository{Job: jobs}, NewConfig(3, time.Minute, 10*time.Minute))
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		config   Config
+		attempts int
+		want     time.Duration
+	}{
+		{NewConfig(5, time.Minute, time.Hour), 1, time.Minute},
+		{NewConfig(5, time.Minute, time.Hour), 2, 2 * time.Minute},
+		{NewConfig(5, time.Minute, time.Hour), 4, 8 * time.Minute},
+		{NewConfig(5, time.Minute, 5*time.Minute), 4, 5 * time.Minute},
+		{NewConfig(5, time.Minute, 5*time.Minute), 100, 5 * time.Minute},
+		// A maxBackoff below backoff is raised to it.
+		{NewConfig(5, time.Minute, time.Second), 3, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := tt.config.retryAfter(tt.attempts); got != tt.want {
+			t.Errorf("%+v.retryAfter(%d) = %s, want %s", tt.config, tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		cause    error
+		retry    time.Duration
+	}{
+		{"first failure", 1, errors.New("boom"), time.Minute},
+		{"second failure", 2, errors.New("boom"), 2 * time.Minute},
+		{"last attempt", 3, errors.New("boom"), 0},
+		{"permanent", 1, Permanent(errors.New("bad payload")), 0},
+		{"wrapped permanent", 1, fmt.Errorf("import: %w", Permanent(errors.New("bad payload"))), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs := &fakeJobs{}
+			before := time.Now()
+			j := structs.Job{ID: uuid.New(), Attempts: tt.attempts, MaxAttempts: 3}
+			if err := testService(jobs).Fail(context.Background(), j, "w", tt.cause); err != nil {
+				t.Fatal(err)
+			}
+
+			switch {
+			case tt.retry == 0 && jobs.retryAt != nil:
+				t.Errorf("retried at %s, want no retry", jobs.retryAt)
+			case tt.retry == 0:
+			case jobs.retryAt == nil:
+				t.Errorf("not retried, want a retry after %s", tt.retry)
+			case jobs.retryAt.Before(before.Add(tt.retry)) || jobs.retryAt.After(time.Now().Add(tt.retry)):
+				t.Errorf("retried at %s, want %s after %s", jobs.retryAt, tt.retry, before)
+			}
+		})
+	}
+}
+
+func TestLease(t *testing.T) {
+	jobs := &fakeJobs{}
+	s := testService(jobs)
+	before := time.Now()
+
+	if _, err := s.Claim(context.Background(), "w", 1, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if jobs.leaseUntil.Before(before.Add(time.Minute)) || jobs.leaseUntil.After(time.Now().Add(time.Minute)) {
+		t.Errorf("claimed until %s, want a minute after %s", jobs.leaseUntil, before)
+	}
+	if err := s.Renew(context.Background(), uuid.New(), "w", 2*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if jobs.leaseUntil.Before(before.Add(2 * time.Minute)) {
+		t.Errorf("renewed until %s, want two minutes after %s", jobs.leaseUntil, before)
+	}
+
+	// A job whose lease another worker holds now is lost to this one.
+	jobs.err = fmt.Errorf("failed to renew lease: %w", pgx.ErrNoRows)
+	if err := s.Renew(context.Background(), uuid.New(), "w", time.Minute); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Renew = %v, want %v", err, ErrLeaseLost)
+	}
+	if err := s.Release(context.Background(), uuid.New(), "w"); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Release = %v, want %v", err, ErrLeaseLost)
+	}
+	if err := s.Fail(context.Background(), structs.Job{MaxAttempts: 1}, "w", errors.New("boom")); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Fail = %v, want %v", err, ErrLeaseLost)
+	}
+}
@@ -0,0 +1,78 @@
+package job
+
+import (
+	"context"
+	"io"
+
+	"github.com/google/uuid"
+)
+
+// outputChunk is the size of the chunks a job file is stored in.
+const outputChunk = 1 << 20
+
+// output writes the file of a running job to the database a chunk at a
+// time. The first write drops what an earlier attempt left behind.
+type output struct {
+	ctx     context.Context
+	s       *Service
+	id      uuid.UUID
+	worker  string
+	buf     []byte
+	seq     int
+	started bool
+}
+
+// Output opens the file of a job held by worker. It is stored as it is
+// written, in chunks of a megabyte, and complete once closed.
+func (s *Service) Output(ctx context.Context, id uuid.UUID, worker string) io.WriteCloser {
+	return &output{ctx: ctx, s: s, id: id, worker: worker}
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	if err := o.start(); err != nil {
+		return 0, err
+	}
+	n := len(p)
+	for len(p) > 0 {
+		room := outputChunk - len(o.buf)
+		if room > len(p) {
+			room = len(p)
+		}
+		o.buf = append(o.buf, p[:room]...)
+		p = p[room:]
+		if len(o.buf) == outputChunk {
+			if err := o.flush(); err != nil {
+				return n - len(p), err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Close stores the last chunk.
+func (o *output) Close() error {
+	if err := o.start(); err != nil {
+		return err
+	}
+	if len(o.buf) == 0 {
+		return nil
+	}
+	return o.flush()
+}
+
+func (o *output) start() error {
+	if o.started {
+		return nil
+	}
+	o.started = true
+	return o.s.repo.Job.ClearJobOutput(o.ctx, o.id, o.worker)
+}
+
+func (o *output) flush() error {
+	if err := leaseLost(o.s.repo.Job.AppendJobOutput(o.ctx, o.id, o.worker, o.seq, o.buf)); err != nil {
+		return err
+	}
+	o.seq++
+	o.buf = o.buf[:0]
+	return nil
+}
@@ -0,0 +1,95 @@
+package job
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// fakeOutput keeps the chunks a job stored.
+type fakeOutput struct {
+	repository.Job
+	cleared int
+	chunks  [][]byte
+	seqs    []int
+	lost    bool
+}
+
+func (f *fakeOutput) ClearJobOutput(context.Context, uuid.UUID, string) error {
+	f.cleared++
+	f.chunks, f.seqs = nil, nil
+	return nil
+}
+
+func (f *fakeOutput) AppendJobOutput(_ context.Context, _ uuid.UUID, _ string, seq int, data []byte) error {
+	if f.lost {
+		return pgx.ErrNoRows
+	}
+	f.seqs = append(f.seqs, seq)
+	f.chunks = append(f.chunks, append([]byte(nil), data...))
+	return nil
+}
+
+func TestOutput(t *testing.T) {
+	repo := &fakeOutput{}
+	s := NewService(&repository.Repository{Job: repo}, NewConfig(3, time.Minute, time.Minute))
+	file := bytes.Repeat([]byte("0123456789"), outputChunk/4)
+
+	out := s.Output(context.Background(), uuid.New(), "w")
+	// Writes smaller and larger than a chunk.
+	for _, part := range [][]byte{file[:10], file[10 : outputChunk+20], file[outputChunk+20:]} {
+		if n, err := out.Write(part); err != nil || n != len(part) {
+			t.Fatalf("Write = %d, %v", n, err)
+		}
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if repo.cleared != 1 {
+		t.Errorf("output cleared %d times, want once", repo.cleared)
+	}
+	if len(repo.chunks) != 3 {
+		t.Fatalf("%d chunks, want 3", len(repo.chunks))
+	}
+	for i, seq := range repo.seqs {
+		if seq != i {
+			t.Errorf("chunk %d has seq %d", i, seq)
+		}
+		if i < 2 && len(repo.chunks[i]) != outputChunk {
+			t.Errorf("chunk %d holds %d bytes, want %d", i, len(repo.chunks[i]), outputChunk)
+		}
+	}
+	if got := bytes.Join(repo.chunks, nil); !bytes.Equal(got, file) {
+		t.Errorf("stored %d bytes that differ from the %d written", len(got), len(file))
+	}
+}
+
+func TestOutputEmpty(t *testing.T) {
+	repo := &fakeOutput{chunks: [][]byte{[]byte("left by an earlier attempt")}}
+	s := NewService(&repository.Repository{Job: repo}, NewConfig(3, time.Minute, time.Minute))
+	if err := s.Output(context.Background(), uuid.New(), "w").Close(); err != nil {
+		t.Fatal(err)
+	}
+	if repo.cleared != 1 || len(repo.chunks) != 0 {
+		t.Errorf("cleared %d times, %d chunks left; want the earlier file gone", repo.cleared, len(repo.chunks))
+	}
+}
+
+func TestOutputLeaseLost(t *testing.T) {
+	repo := &fakeOutput{lost: true}
+	s := NewService(&repository.Repository{Job: repo}, NewConfig(3, time.Minute, time.Minute))
+	out := s.Output(context.Background(), uuid.New(), "w")
+	if _, err := out.Write([]byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Close(); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("Close = %v, want ErrLeaseLost", err)
+	}
+}
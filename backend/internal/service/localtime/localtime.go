@@ -0,0 +1,48 @@
+// Package localtime deals with the provider's scheduled, estimated and
+// actual times: they are the local wall clock at the airport, published with
+// a +00:00 offset and stored as such.
+package localtime
+
+import "time"
+
+// At reads the wall clock of t as local time in zone.
+func At(t time.Time, zone *time.Location) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), zone)
+}
+
+// Stored is the inverse of At: the wall clock of t labelled UTC, the way
+// the provider's times are stored, for comparing against stored columns.
+func Stored(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// Zones caches IANA zone lookups, remembering names that failed to load.
+type Zones map[string]*time.Location
+
+// Load returns the first of names that is a known zone, or UTC.
+func (z Zones) Load(names ...string) *time.Location {
+	if zone, ok := z.Find(names...); ok {
+		return zone
+	}
+	return time.UTC
+}
+
+// Find is Load without the fallback: ok is false when none of names is a
+// known zone.
+func (z Zones) Find(names ...string) (*time.Location, bool) {
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		zone, ok := z[name]
+		if !ok {
+			zone, _ = time.LoadLocation(name)
+			z[name] = zone
+		}
+		if zone != nil {
+			return zone, true
+		}
+	}
+	return nil, false
+}
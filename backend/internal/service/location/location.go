@@ -28,20 +28,24 @@ func (s *Service) CreateCountry(ctx context.Context, country *structs.Country) e
 	return s.repo.Country.CreateCountry(ctx, country)
 }
 
-func (s *Service) GetCountries(ctx context.Context) ([]structs.Country, error) {
-	return s.repo.Country.GetCountries(ctx)
+func (s *Service) CreateCountries(ctx context.Context, countries []structs.Country) (structs.BatchResult, error) {
+	return s.repo.Country.CreateCountries(ctx, countries)
 }
 
-func (s *Service) GetCountry(ctx context.Context, id uuid.UUID) (structs.Country, error) {
-	return s.repo.Country.GetCountry(ctx, id)
+func (s *Service) DiffCountries(ctx context.Context, countries []structs.Country) (structs.IngestionDiff, error) {
+	return s.repo.Country.DiffCountries(ctx, countries)
 }
 
-func (s *Service) DeleteCountry(ctx context.Context, id uuid.UUID) error {
-	return s.repo.Country.DeleteCountry(ctx, id)
+func (s *Service) GetCountries(ctx context.Context) ([]structs.Country, error) {
+	return s.repo.Country.GetCountries(ctx)
 }
 
-func (s *Service) UpdateCountry(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error {
-	return s.repo.Country.UpdateCountry(ctx, id, updates)
+func (s *Service) StreamCountries(ctx context.Context, fn func(structs.Country) error) error {
+	return s.repo.Country.StreamCountries(ctx, fn)
+}
+
+func (s *Service) GetCountry(ctx context.Context, id uuid.UUID) (structs.Country, error) {
+	return s.repo.Country.GetCountry(ctx, id)
 }
 
 func (s *Service) GetCountryCount(ctx context.Context) (int, error) {
@@ -57,26 +61,34 @@ func (s *Service) CreateCity(ctx context.Context, city *structs.City) error {
 
 }
 
-func (s *Service) GetCities(ctx context.Context) ([]structs.City, error) {
-	return s.repo.City.GetCities(ctx)
+func (s *Service) CreateCities(ctx context.Context, cities []structs.City) (structs.BatchResult, error) {
+	return s.repo.City.CreateCities(ctx, cities)
 }
 
-func (s *Service) GetCity(ctx context.Context, id uuid.UUID) (structs.City, error) {
-	return s.repo.City.GetCity(ctx, id)
+func (s *Service) DiffCities(ctx context.Context, cities []structs.City) (structs.IngestionDiff, error) {
+	return s.repo.City.DiffCities(ctx, cities)
 }
 
-func (s *Service) DeleteCity(ctx context.Context, id uuid.UUID) error {
-	return s.repo.City.DeleteCity(ctx, id)
+func (s *Service) GetCities(ctx context.Context) ([]structs.City, error) {
+	return s.repo.City.GetCities(ctx)
 }
 
-func (s *Service) UpdateCity(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error {
-	return s.repo.City.UpdateCity(ctx, id, updates)
+func (s *Service) StreamCities(ctx context.Context, fn func(structs.City) error) error {
+	return s.repo.City.StreamCities(ctx, fn)
+}
+
+func (s *Service) GetCity(ctx context.Context, id uuid.UUID) (structs.City, error) {
+	return s.repo.City.GetCity(ctx, id)
 }
 
 func (s *Service) GetCitiesFromCountry(ctx context.Context) ([]structs.CityInfo, error) {
 	return s.repo.City.GetCitiesFromCountry(ctx)
 }
 
+func (s *Service) StreamCitiesFromCountry(ctx context.Context, fn func(structs.CityInfo) error) error {
+	return s.repo.City.StreamCitiesFromCountry(ctx, fn)
+}
+
 func (s *Service) GetCityFromCountry(ctx context.Context, id uuid.UUID) ([]structs.CityInfo, error) {
 	return s.repo.City.GetCityFromCountry(ctx, id)
 }
@@ -0,0 +1,150 @@
+package network
+
+import (
+	"container/heap"
+	"math"
+)
+
+// graph is the airport level view of the route network: one node per
+// airport, one edge per airport pair served by any airline.
+type graph struct {
+	nodes []string
+	index map[string]int
+	out   [][]int
+	in    [][]int
+}
+
+func (g *graph) node(iata string) int {
+	if i, ok := g.index[iata]; ok {
+		return i
+	}
+	g.index[iata] = len(g.nodes)
+	g.nodes = append(g.nodes, iata)
+	g.out = append(g.out, nil)
+	g.in = append(g.in, nil)
+	return len(g.nodes) - 1
+}
+
+// betweenness is Brandes' algorithm on the directed graph weighted by
+// length, with Dijkstra's algorithm for the shortest paths, normalised by
+// (n-1)(n-2). The network passes the inverse of the flights of a leg as its
+// length: a hub is an airport many of the best served connections pass
+// through.
+func (g *graph) betweenness(length func(v, w int) float64) []float64 {
+	n := len(g.nodes)
+	centrality := make([]float64, n)
+
+	sigma := make([]float64, n)
+	dist := make([]float64, n)
+	delta := make([]float64, n)
+	settled := make([]bool, n)
+	pred := make([][]int, n)
+	stack := make([]int, 0, n)
+
+	for s := 0; s < n; s++ {
+		for i := range dist {
+			sigma[i], dist[i], delta[i], settled[i] = 0, math.Inf(1), 0, false
+			pred[i] = pred[i][:0]
+		}
+		sigma[s], dist[s] = 1, 0
+		stack = stack[:0]
+
+		queue := &nodes{{s, 0}}
+		for queue.Len() > 0 {
+			v := heap.Pop(queue).(entry).node
+			if settled[v] {
+				continue
+			}
+			settled[v] = true
+			stack = append(stack, v)
+			for _, w := range g.out[v] {
+				d := dist[v] + length(v, w)
+				switch {
+				case shorter(d, dist[w]):
+					dist[w], sigma[w] = d, sigma[v]
+					pred[w] = append(pred[w][:0], v)
+					heap.Push(queue, entry{w, d})
+				case !shorter(dist[w], d):
+					sigma[w] += sigma[v]
+					pred[w] = append(pred[w], v)
+				}
+			}
+		}
+
+		for i := len(stack) - 1; i >= 0; i-- {
+			w := stack[i]
+			for _, v := range pred[w] {
+				delta[v] += sigma[v] / sigma[w] * (1 + delta[w])
+			}
+			if w != s {
+				centrality[w] += delta[w]
+			}
+		}
+	}
+
+	if n > 2 {
+		scale := 1 / float64((n-1)*(n-2))
+		for i := range centrality {
+			centrality[i] *= scale
+		}
+	}
+	return centrality
+}
+
+// shorter reports whether a is shorter than b by more than the rounding of
+// summed lengths, so equal paths count as equal however they were summed.
+func shorter(a, b float64) bool {
+	return a < b-1e-9*math.Max(1, math.Abs(a))
+}
+
+// entry is a node with the tentative distance it was queued at.
+type entry struct {
+	node int
+	dist float64
+}
+
+// nodes is a heap of queued nodes, the nearest first. A node is queued
+// again when its distance shrinks; its stale entries are skipped once it
+// is settled.
+type nodes []entry
+
+func (h nodes) Len() int { return len(h) }
+
+func (h nodes) Less(i, j int) bool { return h[i].dist < h[j].dist }
+
+func (h nodes) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *nodes) Push(x interface{}) { *h = append(*h, x.(entry)) }
+
+func (h *nodes) Pop() interface{} {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
+
+// hops returns the number of legs from start to every node, or to start from
+// every node when reverse is set; -1 marks unreachable nodes.
+func (g *graph) hops(start int, reverse bool) []int {
+	edges := g.out
+	if reverse {
+		edges = g.in
+	}
+
+	dist := make([]int, len(g.nodes))
+	for i := range dist {
+		dist[i] = -1
+	}
+	dist[start] = 0
+	queue := []int{start}
+	for head := 0; head < len(queue); head++ {
+		v := queue[head]
+		for _, w := range edges[v] {
+			if dist[w] < 0 {
+				dist[w] = dist[v] + 1
+				queue = append(queue, w)
+			}
+		}
+	}
+	return dist
+}
@@ -0,0 +1,62 @@
+package network
+
+import (
+	"math"
+	"testing"
+)
+
+// testGraph builds the graph of five airports A to E with edges, keyed by
+// departure and arrival, and the length of an edge: the inverse of its
+// flights.
+func testGraph(edges map[[2]string]float64) (*graph, func(v, w int) float64) {
+	g := &graph{index: make(map[string]int)}
+	for _, iata := range []string{"A", "B", "C", "D", "E"} {
+		g.node(iata)
+	}
+	for e := range edges {
+		from, to := g.index[e[0]], g.index[e[1]]
+		g.out[from] = append(g.out[from], to)
+		g.in[to] = append(g.in[to], from)
+	}
+	return g, func(v, w int) float64 { return 1 / edges[[2]string{g.nodes[v], g.nodes[w]}] }
+}
+
+func TestBetweenness(t *testing.T) {
+	tests := []struct {
+		name  string
+		edges map[[2]string]float64
+		want  map[string]float64
+	}{{
+		// A -> B -> C -> D: B lies on A-C and A-D, C on A-D and B-D.
+		name:  "chain",
+		edges: map[[2]string]float64{{"A", "B"}: 1, {"B", "C"}: 1, {"C", "D"}: 1},
+		want:  map[string]float64{"A": 0, "B": 2.0 / 12, "C": 2.0 / 12, "D": 0},
+	}, {
+		// Two equally served connections from A to D share the pair.
+		name:  "equal diamond",
+		edges: map[[2]string]float64{{"A", "B"}: 4, {"B", "D"}: 4, {"A", "C"}: 4, {"C", "D"}: 4},
+		want:  map[string]float64{"A": 0, "B": 0.5 / 12, "C": 0.5 / 12, "D": 0},
+	}, {
+		// The better served connection through B takes the whole pair.
+		name:  "weighted diamond",
+		edges: map[[2]string]float64{{"A", "B"}: 10, {"B", "D"}: 10, {"A", "C"}: 2, {"C", "D"}: 2},
+		want:  map[string]float64{"A": 0, "B": 1.0 / 12, "C": 0, "D": 0},
+	}, {
+		// Two busy legs through E are shorter than the quiet direct one.
+		name:  "busy detour",
+		edges: map[[2]string]float64{{"A", "D"}: 1, {"A", "E"}: 100, {"E", "D"}: 100},
+		want:  map[string]float64{"A": 0, "D": 0, "E": 1.0 / 12},
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, length := testGraph(tt.edges)
+			centrality := g.betweenness(length)
+			for iata, want := range tt.want {
+				if got := centrality[g.index[iata]]; math.Abs(got-want) > 1e-12 {
+					t.Errorf("betweenness(%s) = %v, want %v", iata, got, want)
+				}
+			}
+		})
+	}
+}
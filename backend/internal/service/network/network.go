@@ -0,0 +1,287 @@
+package network
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+const (
+	// staleAfter bounds how old the in-memory network may get on instances
+	// that do not run the flight poller themselves.
+	staleAfter = 5 * time.Minute
+	// overlap re-reads routes touched shortly before the last refresh, so a
+	// transaction that committed late is not missed. Applying a route twice
+	// is harmless, rows carry totals.
+	overlap = time.Minute
+)
+
+// Service keeps the route network in memory and applies the routes changed
+// since the last refresh, instead of reloading the table after every poll.
+type Service struct {
+	repo *repository.Repository
+
+	mu        sync.RWMutex
+	routes    map[string]map[string]map[string]structs.Route // dep -> arr -> airline
+	watermark time.Time
+	refreshed time.Time
+	hubs      []structs.Hub
+	graph     *graph
+}
+
+func NewService(repo *repository.Repository) *Service {
+	return &Service{repo: repo, routes: make(map[string]map[string]map[string]structs.Route)}
+}
+
+func (s *Service) Refresh(ctx context.Context) error {
+	s.mu.RLock()
+	since := s.watermark
+	s.mu.RUnlock()
+	if !since.IsZero() {
+		since = since.Add(-overlap)
+	}
+
+	routes, err := s.repo.Flight.GetRoutesSince(ctx, since)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, route := range routes {
+		s.apply(route)
+	}
+	s.refreshed = time.Now()
+	return nil
+}
+
+func (s *Service) apply(route structs.Route) {
+	arrivals, ok := s.routes[route.DepIata]
+	if !ok {
+		arrivals = make(map[string]map[string]structs.Route)
+		s.routes[route.DepIata] = arrivals
+	}
+	airlines, ok := arrivals[route.ArrIata]
+	if !ok {
+		airlines = make(map[string]structs.Route)
+		arrivals[route.ArrIata] = airlines
+	}
+
+	if current, ok := airlines[route.AirlineIata]; !ok || current.Flights != route.Flights {
+		s.hubs = nil
+		if !ok {
+			s.graph = nil
+		}
+	}
+	airlines[route.AirlineIata] = route
+	if route.LastSeen.After(s.watermark) {
+		s.watermark = route.LastSeen
+	}
+}
+
+func (s *Service) ensure(ctx context.Context) error {
+	s.mu.RLock()
+	fresh := time.Since(s.refreshed) < staleAfter
+	s.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+	return s.Refresh(ctx)
+}
+
+func (s *Service) GetDestinations(ctx context.Context, iata string) ([]structs.Destination, error) {
+	if err := s.ensure(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	destinations := []structs.Destination{}
+	for arr, airlines := range s.routes[strings.ToUpper(iata)] {
+		destination := structs.Destination{Iata: arr}
+		for airline, route := range airlines {
+			destination.Flights += route.Flights
+			destination.Airlines = append(destination.Airlines, airline)
+		}
+		sort.Strings(destination.Airlines)
+		destinations = append(destinations, destination)
+	}
+	sort.Slice(destinations, func(i, j int) bool {
+		if destinations[i].Flights != destinations[j].Flights {
+			return destinations[i].Flights > destinations[j].Flights
+		}
+		return destinations[i].Iata < destinations[j].Iata
+	})
+	return destinations, nil
+}
+
+func (s *Service) GetAirlineRoutes(ctx context.Context, airlineIata string) ([]structs.Route, error) {
+	if err := s.ensure(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	airlineIata = strings.ToUpper(airlineIata)
+	routes := []structs.Route{}
+	for _, arrivals := range s.routes {
+		for _, airlines := range arrivals {
+			if route, ok := airlines[airlineIata]; ok {
+				routes = append(routes, route)
+			}
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].DepIata != routes[j].DepIata {
+			return routes[i].DepIata < routes[j].DepIata
+		}
+		return routes[i].ArrIata < routes[j].ArrIata
+	})
+	return routes, nil
+}
+
+func (s *Service) GetDirectRoute(ctx context.Context, from string, to string) (structs.DirectRoute, error) {
+	direct := structs.DirectRoute{From: strings.ToUpper(from), To: strings.ToUpper(to), Airlines: []string{}}
+	if err := s.ensure(ctx); err != nil {
+		return direct, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for airline, route := range s.routes[direct.From][direct.To] {
+		direct.Direct = true
+		direct.Flights += route.Flights
+		direct.Airlines = append(direct.Airlines, airline)
+	}
+	sort.Strings(direct.Airlines)
+	return direct, nil
+}
+
+// GetAirportsBetween returns the airports lying on some route of at most
+// maxHops legs from one airport to the other, both ends included. It is
+// empty when no such route was observed.
+func (s *Service) GetAirportsBetween(ctx context.Context, from string, to string, maxHops int) ([]string, error) {
+	if err := s.ensure(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g := s.airportGraph()
+	origin, ok := g.index[strings.ToUpper(from)]
+	if !ok {
+		return []string{}, nil
+	}
+	destination, ok := g.index[strings.ToUpper(to)]
+	if !ok {
+		return []string{}, nil
+	}
+
+	forward, backward := g.hops(origin, false), g.hops(destination, true)
+	airports := []string{}
+	for i, iata := range g.nodes {
+		if forward[i] >= 0 && backward[i] >= 0 && forward[i]+backward[i] <= maxHops {
+			airports = append(airports, iata)
+		}
+	}
+	sort.Strings(airports)
+	return airports, nil
+}
+
+// GetHubs ranks airports by "degree" (distinct airports served in either
+// direction), "betweenness" or "flights" (observed legs in and out).
+func (s *Service) GetHubs(ctx context.Context, by string, limit int) ([]structs.Hub, error) {
+	if err := s.ensure(ctx); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if s.hubs == nil {
+		s.hubs = s.computeHubs()
+	}
+	hubs := make([]structs.Hub, len(s.hubs))
+	copy(hubs, s.hubs)
+	s.mu.Unlock()
+
+	sort.SliceStable(hubs, func(i, j int) bool {
+		switch by {
+		case "betweenness":
+			return hubs[i].Betweenness > hubs[j].Betweenness
+		case "flights":
+			return hubs[i].Flights > hubs[j].Flights
+		}
+		return hubs[i].Degree > hubs[j].Degree
+	})
+	if limit > 0 && len(hubs) > limit {
+		hubs = hubs[:limit]
+	}
+	return hubs, nil
+}
+
+// airportGraph returns the airport graph, rebuilt only when a new airport pair
+// appeared since the last call. Callers hold s.mu.
+func (s *Service) airportGraph() *graph {
+	if s.graph != nil {
+		return s.graph
+	}
+
+	g := &graph{index: make(map[string]int)}
+	deps := make([]string, 0, len(s.routes))
+	for dep := range s.routes {
+		deps = append(deps, dep)
+	}
+	sort.Strings(deps)
+	for _, dep := range deps {
+		from := g.node(dep)
+		for arr := range s.routes[dep] {
+			to := g.node(arr)
+			g.out[from] = append(g.out[from], to)
+			g.in[to] = append(g.in[to], from)
+		}
+	}
+
+	s.graph = g
+	return g
+}
+
+func (s *Service) computeHubs() []structs.Hub {
+	g := s.airportGraph()
+	centrality := g.betweenness(func(v, w int) float64 {
+		var flights int
+		for _, route := range s.routes[g.nodes[v]][g.nodes[w]] {
+			flights += route.Flights
+		}
+		if flights <= 0 {
+			return 1
+		}
+		return 1 / float64(flights)
+	})
+
+	hubs := make([]structs.Hub, len(g.nodes))
+	for i, iata := range g.nodes {
+		hubs[i] = structs.Hub{
+			Iata:        iata,
+			OutDegree:   len(g.out[i]),
+			InDegree:    len(g.in[i]),
+			Betweenness: centrality[i],
+		}
+		hubs[i].Degree = hubs[i].InDegree + hubs[i].OutDegree
+	}
+	for dep, arrivals := range s.routes {
+		for arr, airlines := range arrivals {
+			for _, route := range airlines {
+				hubs[g.index[dep]].Flights += route.Flights
+				hubs[g.index[arr]].Flights += route.Flights
+			}
+		}
+	}
+
+	sort.Slice(hubs, func(i, j int) bool { return hubs[i].Iata < hubs[j].Iata })
+	return hubs
+}
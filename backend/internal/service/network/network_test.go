@@ -0,0 +1,107 @@
+package network
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+// fakeFlights answers each GetRoutesSince with the next batch of routes
+// and records the since it was asked for.
+type fakeFlights struct {
+	repository.Flight
+	batches [][]structs.Route
+	since   []time.Time
+}
+
+func (f *fakeFlights) GetRoutesSince(ctx context.Context, since time.Time) ([]structs.Route, error) {
+	f.since = append(f.since, since)
+	batch := f.batches[0]
+	f.batches = f.batches[1:]
+	return batch, nil
+}
+
+func TestRefresh(t *testing.T) {
+	t0 := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	route := func(dep, arr, airline string, flights int, lastSeen time.Duration) structs.Route {
+		return structs.Route{DepIata: dep, ArrIata: arr, AirlineIata: airline, Flights: flights, LastSeen: t0.Add(lastSeen)}
+	}
+	flights := &fakeFlights{batches: [][]structs.Route{
+		{route("LIS", "MAD", "TP", 3, 10*time.Minute), route("LIS", "OPO", "TP", 1, 5*time.Minute)},
+		// LIS-MAD TP is read again within the overlap, with the same total.
+		{route("LIS", "MAD", "TP", 3, 10*time.Minute), route("LIS", "MAD", "IB", 2, 12*time.Minute)},
+		{route("LIS", "MAD", "TP", 4, 13*time.Minute)},
+	}}
+	s := NewService(&repository.Repository{Flight: flights})
+	ctx := context.Background()
+
+	destinations := func() []structs.Destination {
+		t.Helper()
+		d, err := s.GetDestinations(ctx, "lis")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	hub := func(iata string) structs.Hub {
+		t.Helper()
+		hubs, err := s.GetHubs(ctx, "flights", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, h := range hubs {
+			if h.Iata == iata {
+				return h
+			}
+		}
+		t.Fatalf("no hub %s in %+v", iata, hubs)
+		return structs.Hub{}
+	}
+
+	if err := s.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want := []structs.Destination{
+		{Iata: "MAD", Flights: 3, Airlines: []string{"TP"}},
+		{Iata: "OPO", Flights: 1, Airlines: []string{"TP"}},
+	}
+	if got := destinations(); !reflect.DeepEqual(got, want) {
+		t.Errorf("after the first refresh got %+v, want %+v", got, want)
+	}
+	if got := hub("LIS").Flights; got != 4 {
+		t.Errorf("LIS has %d flights, want 4", got)
+	}
+
+	if err := s.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want = []structs.Destination{
+		{Iata: "MAD", Flights: 5, Airlines: []string{"IB", "TP"}},
+		{Iata: "OPO", Flights: 1, Airlines: []string{"TP"}},
+	}
+	if got := destinations(); !reflect.DeepEqual(got, want) {
+		t.Errorf("after the second refresh got %+v, want %+v", got, want)
+	}
+	if got := hub("LIS").Flights; got != 6 {
+		t.Errorf("LIS has %d flights, want 6: the overlap was counted twice", got)
+	}
+
+	if err := s.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := destinations()[0]; got.Iata != "MAD" || got.Flights != 6 {
+		t.Errorf("after the third refresh MAD is %+v, want 6 flights", got)
+	}
+	if got := hub("MAD").Flights; got != 6 {
+		t.Errorf("MAD has %d flights, want 6", got)
+	}
+
+	wantSince := []time.Time{{}, t0.Add(10*time.Minute - overlap), t0.Add(12*time.Minute - overlap)}
+	if !reflect.DeepEqual(flights.since, wantSince) {
+		t.Errorf("read routes since %v, want %v", flights.since, wantSince)
+	}
+}
@@ -0,0 +1,41 @@
+package outbox
+
+import "time"
+
+type Config struct {
+	backoff    time.Duration
+	maxBackoff time.Duration
+	timeout    time.Duration
+	sinks      []Sink
+}
+
+// NewConfig sets the wait after the first failed publish of an event,
+// doubled after every further one up to maxBackoff, how long a sink gets to
+// take an event, and the sinks every event is published to.
+func NewConfig(backoff time.Duration, maxBackoff time.Duration, timeout time.Duration, sinks []Sink) Config {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	if maxBackoff < backoff {
+		maxBackoff = backoff
+	}
+	return Config{
+		backoff:    backoff,
+		maxBackoff: maxBackoff,
+		timeout:    timeout,
+		sinks:      sinks,
+	}
+}
+
+// retryAfter is the wait before the next publish of an event that failed
+// attempts times.
+func (c Config) retryAfter(attempts int) time.Duration {
+	wait := c.backoff
+	for i := 1; i < attempts && wait < c.maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > c.maxBackoff {
+		wait = c.maxBackoff
+	}
+	return wait
+}
@@ -0,0 +1,65 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/watch"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+// WebhookSink POSTs every event as JSON to a URL, with the headers of the
+// watch webhooks: the event is "<entity>.<operation>", the delivery the
+// event id, and the body is signed when there is a secret.
+type WebhookSink struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func NewWebhookSink(url string, secret string, timeout time.Duration) *WebhookSink {
+	return &WebhookSink{url: url, secret: secret, client: &http.Client{Timeout: timeout}}
+}
+
+func (s *WebhookSink) Name() string {
+	return "webhook"
+}
+
+func (s *WebhookSink) Publish(ctx context.Context, e structs.ChangeEvent) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "aviatoon-tracker-outbox")
+	req.Header.Set(watch.HeaderEvent, e.Entity+"."+e.Operation)
+	req.Header.Set(watch.HeaderDelivery, strconv.FormatInt(e.ID, 10))
+	req.Header.Set(watch.HeaderTimestamp, timestamp)
+	if s.secret != "" {
+		req.Header.Set(watch.HeaderSignature, "sha256="+watch.Sign(s.secret, timestamp, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return nil
+}
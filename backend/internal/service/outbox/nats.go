@@ -0,0 +1,69 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/nats-io/nats.go"
+)
+
+// NATSSink publishes every event to "<subject>.<entity>.<operation>" on a
+// NATS server. An event counts as taken once a flush after its publish
+// returns, that is once the server has processed it.
+type NATSSink struct {
+	conn    *nats.Conn
+	subject string
+	timeout time.Duration
+}
+
+// NewNATSSink connects to a nats://[user[:pass]@]host[:port] URL, or
+// tls:// for TLS; a user without a password is sent as a token. A server
+// that is down is retried in the background, and publishes fail until it is
+// back. An empty subject is "aviatoon.changes".
+func NewNATSSink(url string, subject string, timeout time.Duration) (*NATSSink, error) {
+	if subject == "" {
+		subject = "aviatoon.changes"
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	conn, err := nats.Connect(url,
+		nats.Name("aviatoon-tracker"),
+		nats.Timeout(timeout),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+	return &NATSSink{conn: conn, subject: subject, timeout: timeout}, nil
+}
+
+func (s *NATSSink) Name() string {
+	return "nats"
+}
+
+func (s *NATSSink) Publish(ctx context.Context, e structs.ChangeEvent) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := s.conn.Publish(s.subject+"."+e.Entity+"."+e.Operation, payload); err != nil {
+		return err
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+	return s.conn.FlushWithContext(ctx)
+}
+
+// Close drops the connection to the server.
+func (s *NATSSink) Close() error {
+	s.conn.Close()
+	return nil
+}
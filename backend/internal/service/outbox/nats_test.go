@@ -0,0 +1,185 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+)
+
+// fakeOutbox claims like the postgres one: only the oldest event of each
+// entity, once it is due and not leased.
+type fakeOutbox struct {
+	repository.Outbox
+	events []structs.ChangeEvent
+	due    map[int64]time.Time
+}
+
+func (f *fakeOutbox) ClaimEvents(ctx context.Context, limit int, leaseUntil time.Time) ([]structs.ChangeEvent, error) {
+	var claimed []structs.ChangeEvent
+	seen := map[string]bool{}
+	for _, e := range f.events {
+		if seen[e.Entity] || len(claimed) == limit {
+			continue
+		}
+		seen[e.Entity] = true
+		if f.due[e.ID].After(time.Now()) {
+			continue
+		}
+		f.due[e.ID] = leaseUntil
+		claimed = append(claimed, e)
+	}
+	return claimed, nil
+}
+
+func (f *fakeOutbox) DeleteEvent(ctx context.Context, id int64) error {
+	for i, e := range f.events {
+		if e.ID == id {
+			f.events = append(f.events[:i], f.events[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (f *fakeOutbox) FailEvent(ctx context.Context, id int64, lastError string, next time.Time) error {
+	for i, e := range f.events {
+		if e.ID == id {
+			f.events[i].Attempts++
+			f.due[id] = next
+		}
+	}
+	return nil
+}
+
+func TestNATSRelay(t *testing.T) {
+	opts := test.DefaultTestOptions
+	opts.Port = server.RANDOM_PORT
+	srv := test.RunServer(&opts)
+	// The restarted server has to come back on the same port.
+	opts.Port = srv.Addr().(*net.TCPAddr).Port
+	url := srv.ClientURL()
+
+	sink, err := NewNATSSink(url, "", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	var mu sync.Mutex
+	received := map[string][]int64{}
+	reconnected := make(chan struct{}, 1)
+	sub, err := nats.Connect(url,
+		nats.MaxReconnects(-1),
+		nats.ReconnectWait(50*time.Millisecond),
+		nats.ReconnectHandler(func(*nats.Conn) { reconnected <- struct{}{} }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	if _, err := sub.Subscribe("aviatoon.changes.>", func(m *nats.Msg) {
+		var e structs.ChangeEvent
+		if err := json.Unmarshal(m.Data, &e); err != nil {
+			t.Error(err)
+			return
+		}
+		mu.Lock()
+		received[e.Entity] = append(received[e.Entity], e.ID)
+		mu.Unlock()
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &fakeOutbox{due: map[int64]time.Time{}}
+	for id, entity := range []string{"airline", "airport", "airline", "city", "airport", "airline"} {
+		repo.events = append(repo.events, structs.ChangeEvent{ID: int64(id + 1), Entity: entity, Operation: "update"})
+	}
+	total := len(repo.events)
+	s := NewService(&repository.Repository{Outbox: repo}, NewConfig(time.Millisecond, time.Millisecond, 200*time.Millisecond, []Sink{sink}))
+	ctx := context.Background()
+
+	// The first batch goes out; the second fails with the server down and
+	// stays in the outbox.
+	if _, err := s.Relay(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.events) != total-3 {
+		t.Fatalf("%d events left after the first batch, want %d", len(repo.events), total-3)
+	}
+	srv.Shutdown()
+	if _, err := s.Relay(ctx, 10); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.events) != total-3 {
+		t.Fatalf("%d events left with the server down, want %d", len(repo.events), total-3)
+	}
+	// Event 6 waits behind event 3 of the same airline.
+	attempts := map[int64]int{3: 1, 5: 1, 6: 0}
+	for _, e := range repo.events {
+		if e.Attempts != attempts[e.ID] {
+			t.Errorf("event %d has %d attempts, want %d", e.ID, e.Attempts, attempts[e.ID])
+		}
+	}
+
+	srv = test.RunServer(&opts)
+	defer srv.Shutdown()
+	select {
+	case <-reconnected:
+	case <-time.After(10 * time.Second):
+		t.Fatal("subscriber did not reconnect")
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for len(repo.events) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d events left in the outbox", len(repo.events))
+		}
+		if _, err := s.Relay(ctx, 10); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := sub.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := map[string][]int64{"airline": {1, 3, 6}, "airport": {2, 5}, "city": {4}}
+	for entity, ids := range want {
+		// A publish that timed out may still reach the server once it is
+		// back, so an event can repeat, but never ahead of an earlier one.
+		var got []int64
+		for _, id := range received[entity] {
+			if len(got) > 0 && id < got[len(got)-1] {
+				t.Errorf("%s events out of order: %v", entity, received[entity])
+				break
+			}
+			if len(got) == 0 || id != got[len(got)-1] {
+				got = append(got, id)
+			}
+		}
+		if len(got) != len(ids) {
+			t.Errorf("%s events %v, want %v", entity, got, ids)
+			continue
+		}
+		for i := range ids {
+			if got[i] != ids[i] {
+				t.Errorf("%s events %v, want %v", entity, got, ids)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/FACorreiaa/aviatoon-tracker/pkg/logs"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var published = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "aviatoon_outbox_published_total",
+	Help: "Change events published to the outbox sinks",
+}, []string{"sink", "result"})
+
+// Sink is where the relay publishes change events. Publish returns once the
+// sink has taken the event; an error makes the relay publish it again later,
+// to every sink.
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, e structs.ChangeEvent) error
+}
+
+type Service struct {
+	repo   *repository.Repository
+	config Config
+}
+
+func NewService(repo *repository.Repository, config Config) *Service {
+	return &Service{repo: repo, config: config}
+}
+
+// Relay publishes up to limit due change events to every sink and returns
+// how many it tried. An event every sink took is dropped from the outbox;
+// any other is retried with exponential backoff, and holds back the later
+// events of its entity until then.
+func (s *Service) Relay(ctx context.Context, limit int) (int, error) {
+	if len(s.config.sinks) == 0 {
+		return 0, nil
+	}
+
+	// The claim outlives the slowest possible batch, so events are not
+	// published twice while this one is still working through them.
+	lease := time.Duration(limit*len(s.config.sinks))*s.config.timeout + time.Minute
+	events, err := s.repo.Outbox.ClaimEvents(ctx, limit, time.Now().Add(lease))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, e := range events {
+		if err := s.publish(ctx, e); err != nil {
+			logs.DefaultLogger.WithError(err).WithFields(map[string]any{
+				"event":    e.ID,
+				"entity":   e.Entity,
+				"attempts": e.Attempts + 1,
+			}).Warn("Failed to publish change event")
+			next := time.Now().Add(s.config.retryAfter(e.Attempts + 1))
+			if err := s.repo.Outbox.FailEvent(ctx, e.ID, err.Error(), next); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if err := s.repo.Outbox.DeleteEvent(ctx, e.ID); err != nil {
+			return 0, err
+		}
+	}
+	return len(events), nil
+}
+
+func (s *Service) publish(ctx context.Context, e structs.ChangeEvent) error {
+	for _, sink := range s.config.sinks {
+		sctx, cancel := context.WithTimeout(ctx, s.config.timeout)
+		err := sink.Publish(sctx, e)
+		cancel()
+		if err != nil {
+			published.WithLabelValues(sink.Name(), "error").Inc()
+			return fmt.Errorf("%s sink: %w", sink.Name(), err)
+		}
+		published.WithLabelValues(sink.Name(), "ok").Inc()
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package patch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+)
+
+// entity writes the rows of one resource through their typed struct.
+type entity interface {
+	update(ctx context.Context, id uuid.UUID, check Check, fn func(doc interface{}) (interface{}, error)) error
+	delete(ctx context.Context, id uuid.UUID, check Check) error
+}
+
+// typed is the entity of the rows read and written as T. Patches apply to
+// the JSON representation of T, the one GET returns, and the result is
+// decoded back into a T before it is validated and written.
+type typed[T any] struct {
+	write func(ctx context.Context, id uuid.UUID, fn func(T) (T, error)) error
+	// remove soft deletes the row once its check accepts it.
+	remove func(ctx context.Context, id uuid.UUID, check func(T) error) error
+	// keep copies the fields the representation of T leaves out from the
+	// current row to the next one.
+	keep func(current T, next *T)
+	// validate lists what is wrong with a row about to be written.
+	validate func(T) []string
+}
+
+func (e typed[T]) update(ctx context.Context, id uuid.UUID, check Check, fn func(doc interface{}) (interface{}, error)) error {
+	return e.write(ctx, id, func(current T) (T, error) {
+		var next T
+		if err := check(current); err != nil {
+			return next, err
+		}
+
+		doc, err := document(current)
+		if err != nil {
+			return next, err
+		}
+		doc, err = fn(doc)
+		if err != nil {
+			return next, err
+		}
+		if _, ok := doc.(map[string]interface{}); !ok {
+			return next, Invalid{Errors: []string{"the row is not an object"}}
+		}
+		raw, err := json.Marshal(doc)
+		if err != nil {
+			return next, err
+		}
+		if err := json.Unmarshal(raw, &next); err != nil {
+			return next, Invalid{Errors: []string{strings.TrimPrefix(err.Error(), "json: ")}}
+		}
+
+		if e.keep != nil {
+			e.keep(current, &next)
+		}
+		if errs := e.validate(next); len(errs) > 0 {
+			return next, Invalid{Errors: errs}
+		}
+		return next, nil
+	})
+}
+
+func (e typed[T]) delete(ctx context.Context, id uuid.UUID, check Check) error {
+	return e.remove(ctx, id, func(current T) error { return check(current) })
+}
+
+// document is the JSON representation of v decoded as a generic value,
+// with numbers kept as json.Number.
+func document(v interface{}) (interface{}, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func entities(repo *repository.Repository) map[string]entity {
+	return map[string]entity{
+		"tax":      typed[structs.Tax]{write: repo.Tax.UpdateTax, remove: repo.Tax.DeleteTax, validate: validateTax},
+		"aircraft": typed[structs.Aircraft]{write: repo.Aircraft.UpdateAircraft, remove: repo.Aircraft.DeleteAircraft, validate: validateAircraft},
+		"airline":  typed[structs.Airline]{write: repo.Airline.UpdateAirline, remove: repo.Airline.DeleteAirline, validate: validateAirline},
+		"airplane": typed[structs.Airplane]{write: repo.Airplane.UpdateAirplane, remove: repo.Airplane.DeleteAirplane, validate: validateAirplane},
+		"airport":  typed[structs.Airport]{write: repo.Airport.UpdateAirport, remove: repo.Airport.DeleteAirport, keep: keepAirport, validate: validateAirport},
+		"city":     typed[structs.City]{write: repo.City.UpdateCity, remove: repo.City.DeleteCity, validate: validateCity},
+		"country":  typed[structs.Country]{write: repo.Country.UpdateCountry, remove: repo.Country.DeleteCountry, validate: validateCountry},
+	}
+}
+
+func validateTax(t structs.Tax) []string {
+	return required(field{"tax_id", t.TaxId != 0}, field{"tax_name", t.TaxName != ""})
+}
+
+func validateAircraft(a structs.Aircraft) []string {
+	return required(field{"plane_type_id", a.PlaneTypeId != 0}, field{"aircraft_name", a.AircraftName != ""})
+}
+
+func validateAirline(a structs.Airline) []string {
+	return required(field{"airline_id", a.AirlineId != 0}, field{"airline_name", a.AirlineName != ""})
+}
+
+func validateAirplane(a structs.Airplane) []string {
+	errs := required(field{"airplane_id", a.AirplaneId != 0}, field{"registration_number", a.RegistrationNumber != ""})
+	return append(errs, text(
+		field{"airline_icao_code", isText(a.AirlineIcaoCode)},
+		field{"line_number", isText(a.LineNumber)},
+		field{"test_registration_number", isText(a.TestRegistrationNumber)},
+		field{"plane_class", isText(a.PlaneClass)},
+		field{"plane_owner", isText(a.PlaneOwner)},
+	)...)
+}
+
+// keepAirport keeps airport_id, which is not part of the representation.
+func keepAirport(current structs.Airport, next *structs.Airport) {
+	next.AirportId = current.AirportId
+}
+
+func validateAirport(a structs.Airport) []string {
+	errs := required(field{"airport_name", a.AirportName != ""}, field{"iata_code", a.IataCode != ""})
+	return append(errs, text(field{"phone_number", isText(a.PhoneNumber)})...)
+}
+
+func validateCity(c structs.City) []string {
+	return required(field{"city_id", c.CityId != 0}, field{"city_name", c.CityName != ""})
+}
+
+func validateCountry(c structs.Country) []string {
+	return required(field{"country_name", c.CountryName != ""}, field{"country_iso2", c.CountryIso2 != ""})
+}
+
+// field is a field of a representation, by its JSON name, and whether it
+// passes a check.
+type field struct {
+	name string
+	ok   bool
+}
+
+// required lists the fields that must be set and are not.
+func required(fields ...field) []string {
+	var errs []string
+	for _, f := range fields {
+		if !f.ok {
+			errs = append(errs, fmt.Sprintf("%s: required", f.name))
+		}
+	}
+	return errs
+}
+
+// text lists the untyped text fields that hold something else than a
+// string or null.
+func text(fields ...field) []string {
+	var errs []string
+	for _, f := range fields {
+		if !f.ok {
+			errs = append(errs, fmt.Sprintf("%s: expected a string", f.name))
+		}
+	}
+	return errs
+}
+
+func isText(v interface{}) bool {
+	switch v.(type) {
+	case nil, string:
+		return true
+	}
+	return false
+}
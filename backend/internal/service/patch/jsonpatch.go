@@ -0,0 +1,301 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Operation is one operation of an RFC 6902 JSON Patch. Value stays raw so
+// that an absent value can be told apart from null.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// applyJSONPatch applies ops to doc in order. A malformed operation is an
+// ErrBadPatch, a failed test an ErrTestFailed, and a path that does not
+// exist makes the row Invalid.
+func applyJSONPatch(doc interface{}, ops []Operation) (interface{}, error) {
+	doc = clone(doc)
+	for i, op := range ops {
+		var err error
+		doc, err = applyOperation(doc, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %w", i, err)
+		}
+	}
+	return doc, nil
+}
+
+func applyOperation(doc interface{}, op Operation) (interface{}, error) {
+	path, err := pointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: %s needs a value", ErrBadPatch, op.Op)
+		}
+		value, err := decode(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		switch op.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			if doc, _, err = remove(doc, path); err != nil {
+				return nil, err
+			}
+			return add(doc, path, value)
+		}
+		current, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(current, value) {
+			return nil, fmt.Errorf("%w: %s", ErrTestFailed, op.Path)
+		}
+		return doc, nil
+
+	case "remove":
+		doc, _, err = remove(doc, path)
+		return doc, err
+
+	case "move", "copy":
+		from, err := pointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "move" && len(from) < len(path) && strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, fmt.Errorf("%w: cannot move %s into itself", ErrBadPatch, op.From)
+		}
+		var value interface{}
+		if op.Op == "move" {
+			doc, value, err = remove(doc, from)
+		} else {
+			value, err = get(doc, from)
+			value = clone(value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	}
+	return nil, fmt.Errorf("%w: unknown op %q", ErrBadPatch, op.Op)
+}
+
+// pointer splits an RFC 6901 JSON pointer into its unescaped tokens.
+func pointer(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("%w: path %q does not start with /", ErrBadPatch, path)
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(t)
+	}
+	return tokens, nil
+}
+
+func missing(tokens []string) error {
+	return Invalid{Errors: []string{fmt.Sprintf("path /%s does not exist", strings.Join(tokens, "/"))}}
+}
+
+// index parses an array index token; "-", past the last element, is only
+// valid where adding is.
+func index(token string, length int, adding bool) (int, bool) {
+	if token == "-" && adding {
+		return length, true
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, false
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > length || (i == length && !adding) {
+		return 0, false
+	}
+	return i, true
+}
+
+func get(node interface{}, tokens []string) (interface{}, error) {
+	for i, t := range tokens {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			v, ok := n[t]
+			if !ok {
+				return nil, missing(tokens[:i+1])
+			}
+			node = v
+		case []interface{}:
+			j, ok := index(t, len(n), false)
+			if !ok {
+				return nil, missing(tokens[:i+1])
+			}
+			node = n[j]
+		default:
+			return nil, missing(tokens[:i+1])
+		}
+	}
+	return node, nil
+}
+
+// add sets the value at tokens, inserting it when the parent is an array,
+// and returns the new node.
+func add(node interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if len(tokens) == 1 {
+			n[tokens[0]] = value
+			return n, nil
+		}
+		child, ok := n[tokens[0]]
+		if !ok {
+			return nil, missing(tokens[:1])
+		}
+		child, err := add(child, tokens[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		n[tokens[0]] = child
+		return n, nil
+	case []interface{}:
+		i, ok := index(tokens[0], len(n), len(tokens) == 1)
+		if !ok {
+			return nil, missing(tokens[:1])
+		}
+		if len(tokens) == 1 {
+			n = append(n, nil)
+			copy(n[i+1:], n[i:])
+			n[i] = value
+			return n, nil
+		}
+		child, err := add(n[i], tokens[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		n[i] = child
+		return n, nil
+	}
+	return nil, missing(tokens[:1])
+}
+
+// remove takes out the value at tokens and returns the new node with the
+// removed value.
+func remove(node interface{}, tokens []string) (interface{}, interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, node, nil
+	}
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, ok := n[tokens[0]]
+		if !ok {
+			return nil, nil, missing(tokens[:1])
+		}
+		if len(tokens) == 1 {
+			delete(n, tokens[0])
+			return n, child, nil
+		}
+		child, removed, err := remove(child, tokens[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		n[tokens[0]] = child
+		return n, removed, nil
+	case []interface{}:
+		i, ok := index(tokens[0], len(n), false)
+		if !ok {
+			return nil, nil, missing(tokens[:1])
+		}
+		if len(tokens) == 1 {
+			removed := n[i]
+			return append(n[:i], n[i+1:]...), removed, nil
+		}
+		child, removed, err := remove(n[i], tokens[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		n[i] = child
+		return n, removed, nil
+	}
+	return nil, nil, missing(tokens[:1])
+}
+
+func decode(raw json.RawMessage) (interface{}, error) {
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadPatch, err)
+	}
+	return v, nil
+}
+
+func clone(v interface{}) interface{} {
+	switch n := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(n))
+		for k, v := range n {
+			c[k] = clone(v)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(n))
+		for i, v := range n {
+			c[i] = clone(v)
+		}
+		return c
+	}
+	return v
+}
+
+// equal compares two decoded JSON values as RFC 6902 tests do: numbers by
+// value, objects regardless of member order.
+func equal(a interface{}, b interface{}) bool {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err1 := x.Float64()
+		g, err2 := y.Float64()
+		return err1 == nil && err2 == nil && f == g
+	}
+	return a == b
+}
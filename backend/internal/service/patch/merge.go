@@ -0,0 +1,28 @@
+package patch
+
+// mergePatch returns target with patch merged in as RFC 7396 describes: an
+// object patch merges member by member, a null member removes it, and any
+// other patch replaces target.
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	members, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	doc, ok := target.(map[string]interface{})
+	if !ok {
+		doc = map[string]interface{}{}
+	}
+	merged := make(map[string]interface{}, len(doc))
+	for k, v := range doc {
+		merged[k] = v
+	}
+	for k, v := range members {
+		if v == nil {
+			delete(merged, k)
+			continue
+		}
+		merged[k] = mergePatch(merged[k], v)
+	}
+	return merged
+}
@@ -0,0 +1,98 @@
+package patch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	batchrepo "github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/batch"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrUnknownResource = errors.New("unknown resource")
+	// ErrBadPatch rejects a patch document that is not valid JSON Patch.
+	ErrBadPatch = errors.New("invalid patch")
+	// ErrTestFailed rejects a JSON Patch whose test operation did not hold.
+	ErrTestFailed = errors.New("patch test failed")
+	// ErrRejected is a row the database refused, e.g. for a missing
+	// reference.
+	ErrRejected = batchrepo.ErrRejected
+)
+
+// Invalid lists why the row a write would leave behind was rejected.
+type Invalid struct {
+	Errors []string `json:"errors"`
+}
+
+func (e Invalid) Error() string {
+	return "invalid row: " + strings.Join(e.Errors, "; ")
+}
+
+// Check is run on the current row, under the lock of the write, before it
+// is changed. An error aborts the write and is returned as is.
+type Check func(current interface{}) error
+
+type Service struct {
+	entities map[string]entity
+}
+
+func NewService(repo *repository.Repository) *Service {
+	return &Service{entities: entities(repo)}
+}
+
+// Replace overwrites the row id of resource with doc, its representation as
+// GET returns it. Fields doc leaves out are cleared, and the required ones
+// must be set.
+func (s *Service) Replace(ctx context.Context, resource string, id uuid.UUID, check Check, doc map[string]interface{}) error {
+	return s.update(ctx, resource, id, check, func(interface{}) (interface{}, error) {
+		return doc, nil
+	})
+}
+
+// MergePatch applies an RFC 7396 merge patch to the representation of the
+// row id of resource. A null removes a field, which clears it.
+func (s *Service) MergePatch(ctx context.Context, resource string, id uuid.UUID, check Check, patch interface{}) error {
+	return s.update(ctx, resource, id, check, func(doc interface{}) (interface{}, error) {
+		return mergePatch(doc, patch), nil
+	})
+}
+
+// JSONPatch applies the operations of an RFC 6902 JSON Patch, in order, to
+// the representation of the row id of resource. Removing a field clears it.
+func (s *Service) JSONPatch(ctx context.Context, resource string, id uuid.UUID, check Check, ops []Operation) error {
+	return s.update(ctx, resource, id, check, func(doc interface{}) (interface{}, error) {
+		return applyJSONPatch(doc, ops)
+	})
+}
+
+// Delete soft deletes the row id of resource once check accepts it.
+func (s *Service) Delete(ctx context.Context, resource string, id uuid.UUID, check Check) error {
+	e, ok := s.entities[resource]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownResource, resource)
+	}
+
+	err := e.delete(ctx, id, check)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *Service) update(ctx context.Context, resource string, id uuid.UUID, check Check, fn func(doc interface{}) (interface{}, error)) error {
+	e, ok := s.entities[resource]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownResource, resource)
+	}
+
+	err := e.update(ctx, id, check, fn)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,342 @@
+package patch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func parse(t *testing.T, s string) interface{} {
+	t.Helper()
+	v, err := decode(json.RawMessage(s))
+	if err != nil {
+		t.Fatalf("decode %s: %v", s, err)
+	}
+	return v
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		patch  string
+		want   string
+	}{
+		{"adds a member", `{"a":1}`, `{"b":2}`, `{"a":1,"b":2}`},
+		{"replaces a member", `{"a":1}`, `{"a":"x"}`, `{"a":"x"}`},
+		{"null removes a member", `{"a":1,"b":2}`, `{"a":null}`, `{"b":2}`},
+		{"null on a missing member", `{"a":1}`, `{"b":null}`, `{"a":1}`},
+		{"merges nested objects", `{"a":{"b":1,"c":2}}`, `{"a":{"c":null,"d":3}}`, `{"a":{"b":1,"d":3}}`},
+		{"arrays are replaced", `{"a":[1,2]}`, `{"a":[3]}`, `{"a":[3]}`},
+		{"non-object patch replaces", `{"a":1}`, `"x"`, `"x"`},
+		{"object patch on a non-object", `[1]`, `{"a":1}`, `{"a":1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergePatch(parse(t, tt.target), parse(t, tt.patch))
+			if want := parse(t, tt.want); !equal(got, want) {
+				t.Errorf("mergePatch = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestMergePatchLeavesTarget(t *testing.T) {
+	target := parse(t, `{"a":1}`)
+	mergePatch(target, parse(t, `{"a":null}`))
+	if !equal(target, parse(t, `{"a":1}`)) {
+		t.Errorf("target changed to %v", target)
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		ops  string
+		want string
+		err  error
+	}{
+		{"add a member", `{"a":1}`, `[{"op":"add","path":"/b","value":2}]`, `{"a":1,"b":2}`, nil},
+		{"add to an array", `{"a":[1,3]}`, `[{"op":"add","path":"/a/1","value":2}]`, `{"a":[1,2,3]}`, nil},
+		{"append to an array", `{"a":[1]}`, `[{"op":"add","path":"/a/-","value":2}]`, `{"a":[1,2]}`, nil},
+		{"add null", `{}`, `[{"op":"add","path":"/a","value":null}]`, `{"a":null}`, nil},
+		{"remove", `{"a":1,"b":2}`, `[{"op":"remove","path":"/a"}]`, `{"b":2}`, nil},
+		{"replace", `{"a":1}`, `[{"op":"replace","path":"/a","value":"x"}]`, `{"a":"x"}`, nil},
+		{"move", `{"a":1}`, `[{"op":"move","from":"/a","path":"/b"}]`, `{"b":1}`, nil},
+		{"copy", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"}]`, `{"a":{"b":1},"c":{"b":1}}`, nil},
+		{"escaped tokens", `{"a/b":1,"c~d":2}`, `[{"op":"remove","path":"/a~1b"},{"op":"remove","path":"/c~0d"}]`, `{}`, nil},
+		{"test numbers by value", `{"a":1}`, `[{"op":"test","path":"/a","value":1.0}]`, `{"a":1}`, nil},
+		{"test objects regardless of order", `{"a":{"b":1,"c":2}}`, `[{"op":"test","path":"/a","value":{"c":2,"b":1}}]`, `{"a":{"b":1,"c":2}}`, nil},
+		{"failed test", `{"a":1}`, `[{"op":"test","path":"/a","value":2}]`, ``, ErrTestFailed},
+		{"test a string against a number", `{"a":"1"}`, `[{"op":"test","path":"/a","value":1}]`, ``, ErrTestFailed},
+		{"unknown op", `{}`, `[{"op":"frobnicate","path":"/a"}]`, ``, ErrBadPatch},
+		{"missing value", `{}`, `[{"op":"add","path":"/a"}]`, ``, ErrBadPatch},
+		{"relative path", `{}`, `[{"op":"add","path":"a","value":1}]`, ``, ErrBadPatch},
+		{"move into itself", `{"a":{}}`, `[{"op":"move","from":"/a","path":"/a/b"}]`, ``, ErrBadPatch},
+		{"remove a missing member", `{}`, `[{"op":"remove","path":"/a"}]`, ``, Invalid{}},
+		{"replace a missing member", `{}`, `[{"op":"replace","path":"/a","value":1}]`, ``, Invalid{}},
+		{"index past the end", `{"a":[1]}`, `[{"op":"add","path":"/a/2","value":2}]`, ``, Invalid{}},
+		{"leading zero index", `{"a":[1,2]}`, `[{"op":"remove","path":"/a/01"}]`, ``, Invalid{}},
+		{"operations in order", `{"a":1}`, `[{"op":"copy","from":"/a","path":"/b"},{"op":"replace","path":"/a","value":2},{"op":"test","path":"/b","value":1}]`, `{"a":2,"b":1}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ops []Operation
+			if err := json.Unmarshal([]byte(tt.ops), &ops); err != nil {
+				t.Fatal(err)
+			}
+			doc := parse(t, tt.doc)
+			got, err := applyJSONPatch(doc, ops)
+
+			var invalid Invalid
+			switch {
+			case tt.err == nil && err != nil:
+				t.Fatalf("applyJSONPatch: %v", err)
+			case tt.err == nil:
+				if want := parse(t, tt.want); !equal(got, want) {
+					t.Errorf("applyJSONPatch = %v, want %v", got, want)
+				}
+				if !equal(doc, parse(t, tt.doc)) {
+					t.Errorf("document changed to %v", doc)
+				}
+			case errors.As(tt.err, &invalid):
+				if !errors.As(err, &invalid) {
+					t.Errorf("applyJSONPatch error = %v, want an Invalid", err)
+				}
+			case !errors.Is(err, tt.err):
+				t.Errorf("applyJSONPatch error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+// fakeWrite runs fn on current like the repository would, keeping what it
+// writes.
+func fakeWrite[T any](current T, written *T) func(context.Context, uuid.UUID, func(T) (T, error)) error {
+	return func(_ context.Context, _ uuid.UUID, fn func(T) (T, error)) error {
+		next, err := fn(current)
+		if err != nil {
+			return err
+		}
+		*written = next
+		return nil
+	}
+}
+
+func noCheck(interface{}) error { return nil }
+
+func airportEntity(current structs.Airport, written *structs.Airport) typed[structs.Airport] {
+	return typed[structs.Airport]{write: fakeWrite(current, written), keep: keepAirport, validate: validateAirport}
+}
+
+func testAirport() structs.Airport {
+	return structs.Airport{
+		ID:          uuid.New(),
+		GMT:         1,
+		AirportId:   42,
+		IataCode:    "LIS",
+		IcaoCode:    "LPPT",
+		Latitude:    38.78,
+		Longitude:   -9.13,
+		AirportName: "Humberto Delgado",
+		Timezone:    "Europe/Lisbon",
+	}
+}
+
+func TestReplaceRoundTrip(t *testing.T) {
+	current := testAirport()
+	var written structs.Airport
+	e := airportEntity(current, &written)
+
+	// What GET serves is a valid PUT body.
+	doc, err := document(current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = e.update(context.Background(), current.ID, noCheck, func(interface{}) (interface{}, error) { return doc, nil })
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if !reflect.DeepEqual(written, current) {
+		t.Errorf("written %+v, want %+v", written, current)
+	}
+}
+
+func TestTypedUpdate(t *testing.T) {
+	tests := []struct {
+		name   string
+		apply  func(doc interface{}) (interface{}, error)
+		want   func(a *structs.Airport)
+		errors []string
+	}{
+		{
+			name: "patch a number sent as a string",
+			apply: func(doc interface{}) (interface{}, error) {
+				return mergePatch(doc, map[string]interface{}{"gmt": "2.5"}), nil
+			},
+			want: func(a *structs.Airport) { a.GMT = 2.5 },
+		},
+		{
+			name: "json patch tests the representation",
+			apply: func(doc interface{}) (interface{}, error) {
+				return applyJSONPatch(doc, []Operation{
+					{Op: "test", Path: "/latitude", Value: json.RawMessage(`"38.78"`)},
+					{Op: "replace", Path: "/icao_code", Value: json.RawMessage(`"XXXX"`)},
+				})
+			},
+			want: func(a *structs.Airport) { a.IcaoCode = "XXXX" },
+		},
+		{
+			name: "removing a field clears it",
+			apply: func(doc interface{}) (interface{}, error) {
+				return mergePatch(doc, map[string]interface{}{"timezone": nil}), nil
+			},
+			want: func(a *structs.Airport) { a.Timezone = "" },
+		},
+		{
+			name: "hidden airport_id is kept",
+			apply: func(interface{}) (interface{}, error) {
+				return map[string]interface{}{"airport_name": "Porto", "iata_code": "OPO"}, nil
+			},
+			want: func(a *structs.Airport) {
+				*a = structs.Airport{AirportId: a.AirportId, AirportName: "Porto", IataCode: "OPO"}
+			},
+		},
+		{
+			name: "missing required fields",
+			apply: func(interface{}) (interface{}, error) {
+				return map[string]interface{}{"timezone": "UTC"}, nil
+			},
+			errors: []string{"airport_name: required", "iata_code: required"},
+		},
+		{
+			name: "wrong type",
+			apply: func(doc interface{}) (interface{}, error) {
+				return mergePatch(doc, map[string]interface{}{"gmt": json.Number("2")}), nil
+			},
+			errors: []string{"cannot unmarshal"},
+		},
+		{
+			name: "untyped text field",
+			apply: func(doc interface{}) (interface{}, error) {
+				return mergePatch(doc, map[string]interface{}{"phone_number": json.Number("123")}), nil
+			},
+			errors: []string{"phone_number: expected a string"},
+		},
+		{
+			name: "not an object",
+			apply: func(interface{}) (interface{}, error) {
+				return []interface{}{}, nil
+			},
+			errors: []string{"the row is not an object"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := testAirport()
+			var written structs.Airport
+			err := airportEntity(current, &written).update(context.Background(), current.ID, noCheck, tt.apply)
+
+			if tt.errors != nil {
+				var invalid Invalid
+				if !errors.As(err, &invalid) {
+					t.Fatalf("update error = %v, want an Invalid", err)
+				}
+				if len(invalid.Errors) != len(tt.errors) {
+					t.Fatalf("errors = %q, want %q", invalid.Errors, tt.errors)
+				}
+				for i, want := range tt.errors {
+					if !strings.Contains(invalid.Errors[i], want) {
+						t.Errorf("error %d = %q, want %q", i, invalid.Errors[i], want)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("update: %v", err)
+			}
+			want := current
+			tt.want(&want)
+			want.ID, want.CreatedAt = written.ID, written.CreatedAt
+			if !reflect.DeepEqual(written, want) {
+				t.Errorf("written %+v, want %+v", written, want)
+			}
+		})
+	}
+}
+
+func TestTypedUpdateCheck(t *testing.T) {
+	current := testAirport()
+	var written structs.Airport
+	modified := errors.New("modified")
+
+	var checked interface{}
+	err := airportEntity(current, &written).update(context.Background(), current.ID,
+		func(c interface{}) error {
+			checked = c
+			return modified
+		},
+		func(doc interface{}) (interface{}, error) {
+			t.Error("the patch was applied after a failed check")
+			return doc, nil
+		})
+	if !errors.Is(err, modified) {
+		t.Errorf("update error = %v, want %v", err, modified)
+	}
+	if !reflect.DeepEqual(checked, current) {
+		t.Errorf("checked %+v, want the current row", checked)
+	}
+	if !reflect.DeepEqual(written, structs.Airport{}) {
+		t.Errorf("written %+v after a failed check", written)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	current := testAirport()
+	modified := errors.New("modified")
+	var deleted bool
+	s := &Service{entities: map[string]entity{
+		"airport": typed[structs.Airport]{remove: func(ctx context.Context, id uuid.UUID, check func(structs.Airport) error) error {
+			if id != current.ID {
+				return fmt.Errorf("airport with ID %s not found: %w", id, pgx.ErrNoRows)
+			}
+			if err := check(current); err != nil {
+				return err
+			}
+			deleted = true
+			return nil
+		}},
+	}}
+	ctx := context.Background()
+
+	var checked interface{}
+	err := s.Delete(ctx, "airport", current.ID, func(c interface{}) error {
+		checked = c
+		return modified
+	})
+	if !errors.Is(err, modified) || deleted {
+		t.Errorf("Delete with a failed check = %v, deleted %v", err, deleted)
+	}
+	if !reflect.DeepEqual(checked, current) {
+		t.Errorf("checked %+v, want the current row", checked)
+	}
+
+	if err := s.Delete(ctx, "airport", current.ID, func(interface{}) error { return nil }); err != nil || !deleted {
+		t.Errorf("Delete = %v, deleted %v", err, deleted)
+	}
+	if err := s.Delete(ctx, "airport", uuid.New(), func(interface{}) error { return nil }); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete of a missing row = %v, want %v", err, ErrNotFound)
+	}
+	if err := s.Delete(ctx, "gate", current.ID, func(interface{}) error { return nil }); !errors.Is(err, ErrUnknownResource) {
+		t.Errorf("Delete of an unknown resource = %v, want %v", err, ErrUnknownResource)
+	}
+}
@@ -2,112 +2,345 @@ package service
 
 import (
 	"context"
+	"io"
+	"time"
 
 	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/service/airline"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/service/airport"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/batch"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/board"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/cache"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/calendar"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/emissions"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/flight"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/history"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/ingestion"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/integrity"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/itinerary"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/job"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/service/location"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/network"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/outbox"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/patch"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/stats"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/trash"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/user"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/version"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/watch"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
 	"github.com/google/uuid"
 )
 
 type Tax interface {
 	CreateTax(ctx context.Context, t *structs.Tax) error
+	CreateTaxes(ctx context.Context, taxes []structs.Tax) (structs.BatchResult, error)
+	DiffTaxes(ctx context.Context, taxes []structs.Tax) (structs.IngestionDiff, error)
 	GetTaxs(ctx context.Context) ([]structs.Tax, error)
+	StreamTaxes(ctx context.Context, fn func(structs.Tax) error) error
 	GetTax(ctx context.Context, id uuid.UUID) (structs.Tax, error)
-	UpdateTax(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error
-	DeleteTax(ctx context.Context, id uuid.UUID) error
 	GetTaxesCount(ctx context.Context) (int, error)
 	//GetTaxName(ctx context.Context, name string) ([]structs.Tax, error)
 }
 
 type Airport interface {
 	CreateAirport(ctx context.Context, a *structs.Airport) error
+	CreateAirports(ctx context.Context, airports []structs.Airport) (structs.BatchResult, error)
+	DiffAirports(ctx context.Context, airports []structs.Airport) (structs.IngestionDiff, error)
 	GetAirports(ctx context.Context) ([]structs.Airport, error)
+	StreamAirports(ctx context.Context, fn func(structs.Airport) error) error
 	GetAirport(ctx context.Context, id uuid.UUID) (structs.Airport, error)
-	DeleteAirport(ctx context.Context, id uuid.UUID) error
-	UpdateAirport(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error
 	GetAirportCount(ctx context.Context) (int, error)
 	GetCitiesAirports(ctx context.Context) ([]structs.AirportInfo, error)
+	StreamCitiesAirports(ctx context.Context, fn func(structs.AirportInfo) error) error
 	GetCityNameAirport(ctx context.Context, cityName string) ([]structs.AirportInfo, error)
 	GetCityNameAirportAlternative(ctx context.Context, cityName string) ([]structs.AirportInfo, error)
 	GetCountryNameAirport(ctx context.Context, countryName string) ([]structs.AirportInfo, error)
 	GetCityIataCodeAirport(ctx context.Context, iataCode string) ([]structs.AirportInfo, error)
+	GetAirportPositions(ctx context.Context, iataCodes []string) ([]structs.AirportPosition, error)
 }
 
 type Country interface {
 	CreateCountry(ctx context.Context, t *structs.Country) error
+	CreateCountries(ctx context.Context, countries []structs.Country) (structs.BatchResult, error)
+	DiffCountries(ctx context.Context, countries []structs.Country) (structs.IngestionDiff, error)
 	GetCountries(ctx context.Context) ([]structs.Country, error)
+	StreamCountries(ctx context.Context, fn func(structs.Country) error) error
 	GetCountry(ctx context.Context, id uuid.UUID) (structs.Country, error)
-	UpdateCountry(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error
-	DeleteCountry(ctx context.Context, id uuid.UUID) error
 	GetCountryCount(ctx context.Context) (int, error)
 }
 
 type City interface {
 	CreateCity(ctx context.Context, city *structs.City) error
+	CreateCities(ctx context.Context, cities []structs.City) (structs.BatchResult, error)
+	DiffCities(ctx context.Context, cities []structs.City) (structs.IngestionDiff, error)
 	GetCities(ctx context.Context) ([]structs.City, error)
+	StreamCities(ctx context.Context, fn func(structs.City) error) error
 	GetCity(ctx context.Context, id uuid.UUID) (structs.City, error)
-	UpdateCity(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error
-	DeleteCity(ctx context.Context, id uuid.UUID) error
 	GetCityCount(ctx context.Context) (int, error)
 	GetCitiesFromCountry(ctx context.Context) ([]structs.CityInfo, error)
+	StreamCitiesFromCountry(ctx context.Context, fn func(structs.CityInfo) error) error
 	GetCityFromCountry(ctx context.Context, id uuid.UUID) ([]structs.CityInfo, error)
 }
 
 type Aircraft interface {
 	CreateAircraft(ctx context.Context, t *structs.Aircraft) error
+	CreateAircrafts(ctx context.Context, aircrafts []structs.Aircraft) (structs.BatchResult, error)
+	DiffAircrafts(ctx context.Context, aircrafts []structs.Aircraft) (structs.IngestionDiff, error)
 	GetAircrafts(ctx context.Context) ([]structs.Aircraft, error)
+	StreamAircrafts(ctx context.Context, fn func(structs.Aircraft) error) error
 	GetAircraft(ctx context.Context, id uuid.UUID) (structs.Aircraft, error)
-	UpdateAircraft(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error
-	DeleteAircraft(ctx context.Context, id uuid.UUID) error
 	GetAircraftCount(ctx context.Context) (int, error)
 }
 
 type Airline interface {
 	CreateAirline(ctx context.Context, t *structs.Airline) error
+	CreateAirlines(ctx context.Context, airlines []structs.Airline) (structs.BatchResult, error)
+	DiffAirlines(ctx context.Context, airlines []structs.Airline) (structs.IngestionDiff, error)
 	GetAirlines(ctx context.Context) ([]structs.Airline, error)
+	StreamAirlines(ctx context.Context, fn func(structs.Airline) error) error
 	GetAirline(ctx context.Context, id uuid.UUID) (structs.Airline, error)
-	UpdateAirline(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error
-	DeleteAirline(ctx context.Context, id uuid.UUID) error
 	GetAirlineCount(ctx context.Context) (int, error)
 	GetAirlinesCountry(ctx context.Context) ([]structs.AirlineInfo, error)
+	StreamAirlinesCountry(ctx context.Context, fn func(structs.AirlineInfo) error) error
 	GetAirlineCountry(ctx context.Context, id int) ([]structs.AirlineInfo, error)
 	GetAirlineCountryName(ctx context.Context, countryName string) ([]structs.AirlineInfo, error)
 	GetAirlineCityName(ctx context.Context, cityName string) ([]structs.AirlineInfo, error)
 	GetAirlineCountryCityName(ctx context.Context, coutryName string, cityName string) ([]structs.AirlineInfo, error)
+	GetAirlineFleet(ctx context.Context, id uuid.UUID) (structs.AirlineFleet, error)
+	GetFleetLeaderboard(ctx context.Context, order string, limit int) ([]structs.FleetLeaderboardEntry, error)
 }
 
 type Airplane interface {
 	CreateAirplane(ctx context.Context, t *structs.Airplane) error
+	CreateAirplanes(ctx context.Context, airplanes []structs.Airplane) (structs.BatchResult, error)
+	DiffAirplanes(ctx context.Context, airplanes []structs.Airplane) (structs.IngestionDiff, error)
 	GetAirplanes(ctx context.Context) ([]structs.Airplane, error)
+	StreamAirplanes(ctx context.Context, fn func(structs.Airplane) error) error
 	GetAirplane(ctx context.Context, id uuid.UUID) (structs.Airplane, error)
-	UpdateAirplane(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error
-	DeleteAirplane(ctx context.Context, id uuid.UUID) error
 	GetAirplaneCount(ctx context.Context) (int, error)
 	GetAirplaneAirline(ctx context.Context) ([]structs.AirplaneInfo, error)
+	StreamAirplaneAirline(ctx context.Context, fn func(structs.AirplaneInfo) error) error
 	GetAirplanesFromAirlineName(ctx context.Context, airlineName string) ([]structs.AirplaneInfo, error)
 	GetAirplanesFromAirlineCountry(ctx context.Context, countryName string) ([]structs.AirplaneInfo, error)
+	GetAirplanesByRegistration(ctx context.Context, registrations []string) ([]structs.Airplane, error)
+}
+
+type Integrity interface {
+	LinkReferences(ctx context.Context) (structs.LinkResult, error)
+	LinkRows(ctx context.Context, table string, ids []uuid.UUID) (structs.LinkResult, error)
+	GetIntegrityReport(ctx context.Context, limit int) (structs.IntegrityReport, error)
+}
+
+type Version interface {
+	GetVersion(ctx context.Context, tables ...string) (structs.TableVersion, error)
+}
+
+type Flight interface {
+	RecordFlights(ctx context.Context, flights []structs.Flight) (structs.FlightIngestion, error)
+	GetRoutesSince(ctx context.Context, since time.Time) ([]structs.Route, error)
+	GetScheduledLegs(ctx context.Context, from time.Time, until time.Time, departures []string) ([]structs.Flight, error)
+	FindFlights(ctx context.Context, filter structs.FlightFilter) ([]structs.Flight, error)
+	Dedupe(ctx context.Context, flights []structs.Flight) ([]structs.OperatedFlight, error)
+	GetCodeshares(ctx context.Context, flightIata string, date time.Time) ([]structs.Codeshares, error)
+}
+
+type Network interface {
+	Refresh(ctx context.Context) error
+	GetDestinations(ctx context.Context, iata string) ([]structs.Destination, error)
+	GetAirlineRoutes(ctx context.Context, airlineIata string) ([]structs.Route, error)
+	GetHubs(ctx context.Context, by string, limit int) ([]structs.Hub, error)
+	GetDirectRoute(ctx context.Context, from string, to string) (structs.DirectRoute, error)
+	GetAirportsBetween(ctx context.Context, from string, to string, maxHops int) ([]string, error)
+}
+
+type Itinerary interface {
+	FindItineraries(ctx context.Context, query structs.ItineraryQuery) ([]structs.Itinerary, error)
+}
+
+type Emissions interface {
+	EstimateRoute(ctx context.Context, from string, to string, aircraft string) (structs.RouteEmissions, error)
+	EstimateFlight(ctx context.Context, flightIata string, date time.Time) ([]structs.FlightEmissions, error)
+	EstimateAirline(ctx context.Context, airlineIata string, date time.Time) (structs.AirlineEmissions, error)
+}
+
+type Stats interface {
+	RefreshOTP(ctx context.Context, from time.Time, to time.Time) (int, error)
+	GetOTP(ctx context.Context, filter structs.OTPFilter) ([]structs.OTPStats, error)
+}
+
+type Board interface {
+	GetBoard(ctx context.Context, iata string, direction string, from time.Time, to time.Time) (structs.Board, error)
+}
+
+type User interface {
+	CreateUser(ctx context.Context, email string, refreshToken string) (structs.User, error)
+	GetUser(ctx context.Context, id uuid.UUID) (structs.User, error)
+	RefreshToken(ctx context.Context, refreshToken string, next string) (structs.User, error)
+}
+
+type Watch interface {
+	CreateWatchlist(ctx context.Context, userID uuid.UUID, name string, watches []structs.Watch) (structs.Watchlist, error)
+	GetWatchlists(ctx context.Context, userID uuid.UUID) ([]structs.Watchlist, error)
+	GetWatchlist(ctx context.Context, userID uuid.UUID, id uuid.UUID) (structs.Watchlist, error)
+	DeleteWatchlist(ctx context.Context, userID uuid.UUID, id uuid.UUID) error
+	AddWatch(ctx context.Context, userID uuid.UUID, watchlistID uuid.UUID, w structs.Watch) (structs.Watch, error)
+	DeleteWatch(ctx context.Context, userID uuid.UUID, watchlistID uuid.UUID, id uuid.UUID) error
+	CreateWebhook(ctx context.Context, userID uuid.UUID, rawURL string) (structs.Webhook, error)
+	GetWebhooks(ctx context.Context, userID uuid.UUID) ([]structs.Webhook, error)
+	SetWebhookActive(ctx context.Context, userID uuid.UUID, id uuid.UUID, active bool) error
+	DeleteWebhook(ctx context.Context, userID uuid.UUID, id uuid.UUID) error
+	GetDeliveries(ctx context.Context, userID uuid.UUID, webhookID uuid.UUID, filter structs.DeliveryFilter) ([]structs.WebhookDelivery, error)
+	GetDelivery(ctx context.Context, userID uuid.UUID, webhookID uuid.UUID, id uuid.UUID) (structs.WebhookDelivery, error)
+	RetryDelivery(ctx context.Context, userID uuid.UUID, webhookID uuid.UUID, id uuid.UUID) error
+	Deliveries(changes []structs.FlightChange, match func(kinds []string, values []string) ([]structs.WebhookMatch, error)) ([]structs.WebhookDelivery, error)
+	Deliver(ctx context.Context, limit int) (int, error)
+}
+
+type Calendar interface {
+	FlightCalendar(ctx context.Context, flightIata string) (structs.Calendar, error)
+	UserCalendar(ctx context.Context, userID uuid.UUID) (structs.Calendar, error)
+}
+
+type Job interface {
+	Enqueue(ctx context.Context, kind string, key string, payload interface{}) (structs.Job, bool, error)
+	GetJobs(ctx context.Context, filter structs.JobFilter) ([]structs.Job, error)
+	GetJob(ctx context.Context, id uuid.UUID) (structs.Job, error)
+	GetJobOutput(ctx context.Context, id uuid.UUID) (structs.JobOutput, error)
+	StreamJobOutput(ctx context.Context, id uuid.UUID, fn func([]byte) error) error
+	CancelJob(ctx context.Context, id uuid.UUID) error
+	RetryJob(ctx context.Context, id uuid.UUID) error
+	Claim(ctx context.Context, worker string, limit int, lease time.Duration) ([]structs.Job, error)
+	Renew(ctx context.Context, id uuid.UUID, worker string, lease time.Duration) error
+	Output(ctx context.Context, id uuid.UUID, worker string) io.WriteCloser
+	Finish(ctx context.Context, id uuid.UUID, worker string, result interface{}, outputType string) error
+	Fail(ctx context.Context, j structs.Job, worker string, cause error) error
+	Release(ctx context.Context, id uuid.UUID, worker string) error
+}
+
+type Ingestion interface {
+	StartRun(ctx context.Context, dataset string, dryRun bool) (structs.IngestionRun, error)
+	FinishRun(ctx context.Context, run structs.IngestionRun, diff *structs.IngestionDiff) error
+	GetRuns(ctx context.Context, filter structs.IngestionFilter) ([]structs.IngestionRun, error)
+	GetRun(ctx context.Context, id uuid.UUID) (structs.IngestionRun, error)
+	GetRunDiff(ctx context.Context, id uuid.UUID) (structs.IngestionDiff, error)
+	CheckSchema(ctx context.Context, run structs.IngestionRun, endpoint string, body []byte) ([]structs.SchemaDrift, error)
+	GetSchema(ctx context.Context, endpoint string) (structs.UpstreamSchema, error)
+	DeleteSchema(ctx context.Context, endpoint string) error
+	GetRunDrift(ctx context.Context, id uuid.UUID) ([]structs.SchemaDrift, error)
+}
+
+type Trash interface {
+	Restore(ctx context.Context, resource string, id uuid.UUID) error
+	Purge(ctx context.Context, resource string, before time.Time) (int64, error)
+}
+
+type History interface {
+	GetHistory(ctx context.Context, resource string, id uuid.UUID, limit int) ([]structs.HistoryEntry, error)
+}
+
+type Outbox interface {
+	Relay(ctx context.Context, limit int) (int, error)
+}
+
+type Batch interface {
+	Apply(ctx context.Context, resource string, req structs.BulkRequest) (structs.BulkResult, error)
+}
+
+type Patch interface {
+	Replace(ctx context.Context, resource string, id uuid.UUID, check patch.Check, doc map[string]interface{}) error
+	MergePatch(ctx context.Context, resource string, id uuid.UUID, check patch.Check, patch interface{}) error
+	JSONPatch(ctx context.Context, resource string, id uuid.UUID, check patch.Check, ops []patch.Operation) error
+	Delete(ctx context.Context, resource string, id uuid.UUID, check patch.Check) error
 }
 
 type Service struct {
-	Tax      Tax
-	Airport  Airport
-	Country  Country
-	City     City
-	Aircraft Aircraft
-	Airline  Airline
-	Airplane Airplane
+	Tax       Tax
+	Airport   Airport
+	Country   Country
+	City      City
+	Aircraft  Aircraft
+	Airline   Airline
+	Airplane  Airplane
+	Integrity Integrity
+	Version   Version
+	Flight    Flight
+	Network   Network
+	Itinerary Itinerary
+	Emissions Emissions
+	Stats     Stats
+	Board     Board
+	User      User
+	Watch     Watch
+	Calendar  Calendar
+	Job       Job
+	Ingestion Ingestion
+	Trash     Trash
+	History   History
+	Outbox    Outbox
+	Batch     Batch
+	Patch     Patch
+}
+
+type Config struct {
+	cacheConfig     cache.Config
+	itineraryConfig itinerary.Config
+	emissionsConfig emissions.Config
+	watchConfig     watch.Config
+	jobConfig       job.Config
+	ingestionConfig ingestion.Config
+	outboxConfig    outbox.Config
+	batchConfig     batch.Config
 }
 
-func NewService(repo *repository.Repository) *Service {
+func NewConfig(cacheConfig cache.Config, itineraryConfig itinerary.Config, emissionsConfig emissions.Config, watchConfig watch.Config, jobConfig job.Config, ingestionConfig ingestion.Config, outboxConfig outbox.Config, batchConfig batch.Config) Config {
+	return Config{
+		cacheConfig:     cacheConfig,
+		itineraryConfig: itineraryConfig,
+		emissionsConfig: emissionsConfig,
+		watchConfig:     watchConfig,
+		jobConfig:       jobConfig,
+		ingestionConfig: ingestionConfig,
+		outboxConfig:    outboxConfig,
+		batchConfig:     batchConfig,
+	}
+}
+
+func NewService(repo *repository.Repository, config Config) *Service {
+	caches := newReferenceCaches(config.cacheConfig)
+	routes := network.NewService(repo)
+	watches := watch.NewService(repo, config.watchConfig)
+
 	return &Service{
-		Tax:      airline.NewService(repo),
-		Airport:  airport.NewService(repo),
-		Country:  location.NewService(repo),
-		City:     location.NewService(repo),
-		Aircraft: airline.NewService(repo),
-		Airline:  airline.NewService(repo),
-		Airplane: airline.NewService(repo),
+		Tax: cachedTax{airline.NewService(repo), caches.tax, purge(caches.tax)},
+		Airport: cachedAirport{airport.NewService(repo), caches.airport,
+			purge(caches.airport)},
+		Country: cachedCountry{location.NewService(repo), caches.country,
+			purge(caches.country, caches.city, caches.airline, caches.airport)},
+		City: cachedCity{location.NewService(repo), caches.city,
+			purge(caches.city, caches.airport, caches.airline)},
+		Aircraft: cachedAircraft{airline.NewService(repo), caches.aircraft, purge(caches.aircraft)},
+		Airline: cachedAirline{airline.NewService(repo), caches.airline, caches.airplane,
+			purge(caches.airline, caches.airplane)},
+		Airplane: cachedAirplane{airline.NewService(repo), caches.airplane,
+			purge(caches.airplane)},
+		Integrity: cachedIntegrity{integrity.NewService(repo), purge(caches.all()...)},
+		Version:   cachedVersion{version.NewService(repo), caches},
+		Flight:    flight.NewService(repo, watches),
+		Network:   routes,
+		Itinerary: itinerary.NewService(repo, routes, config.itineraryConfig),
+		Emissions: emissions.NewService(repo, config.emissionsConfig),
+		Stats:     stats.NewService(repo),
+		Board:     board.NewService(repo),
+		User:      user.NewService(repo),
+		Watch:     watches,
+		Calendar:  calendar.NewService(repo),
+		Job:       job.NewService(repo, config.jobConfig),
+		Ingestion: ingestion.NewService(repo, config.ingestionConfig),
+		Trash:     cachedTrash{trash.NewService(repo), purge(caches.all()...)},
+		History:   history.NewService(repo),
+		Outbox:    outbox.NewService(repo, config.outboxConfig),
+		Batch:     cachedBatch{batch.NewService(repo, config.batchConfig), purge(caches.all()...)},
+		Patch:     cachedPatch{patch.NewService(repo), purge(caches.all()...)},
 	}
 }
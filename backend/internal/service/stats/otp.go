@@ -0,0 +1,209 @@
+package stats
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+// Dimensions are the groupings on-time performance is kept for. Airports
+// are measured on their departures, everything else on arrivals; hours are
+// the local hour of the scheduled departure.
+var Dimensions = []string{"airline", "airport", "route", "hour"}
+
+type Service struct {
+	repo *repository.Repository
+}
+
+func NewService(repo *repository.Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// RefreshOTP recomputes the daily on-time performance of every dimension for
+// the flights dated between from and to, inclusive, and returns how many
+// daily rows were written.
+func (s *Service) RefreshOTP(ctx context.Context, from time.Time, to time.Time) (int, error) {
+	flights, err := s.repo.Flight.FindFlights(ctx, structs.FlightFilter{
+		Date:         from,
+		Until:        to,
+		OperatedOnly: true,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	days := make(map[[3]string]*structs.OTPDaily)
+	day := func(dimension string, key string, date time.Time) *structs.OTPDaily {
+		id := [3]string{dimension, key, date.Format("2006-01-02")}
+		d, ok := days[id]
+		if !ok {
+			d = &structs.OTPDaily{
+				Dimension:      dimension,
+				Key:            key,
+				FlightDate:     date,
+				DelayHistogram: make([]int32, structs.DelayBuckets),
+			}
+			days[id] = d
+		}
+		return d
+	}
+
+	for _, f := range flights {
+		var hour string
+		if f.Departure.Scheduled != nil {
+			hour = f.Departure.Scheduled.UTC().Format("15")
+		}
+		record(day("airport", f.Departure.Iata, f.FlightDate), f, f.Departure)
+		record(day("route", f.Departure.Iata+"-"+f.Arrival.Iata, f.FlightDate), f, f.Arrival)
+		if f.AirlineIata != "" {
+			record(day("airline", f.AirlineIata, f.FlightDate), f, f.Arrival)
+		}
+		if hour != "" {
+			record(day("hour", hour, f.FlightDate), f, f.Arrival)
+		}
+	}
+
+	rows := make([]structs.OTPDaily, 0, len(days))
+	for _, d := range days {
+		rows = append(rows, *d)
+	}
+	if err := s.repo.Stats.ReplaceOTPDaily(ctx, from, to, rows); err != nil {
+		return 0, err
+	}
+	return len(rows), nil
+}
+
+// record counts f into d, measuring the delay at endpoint.
+func record(d *structs.OTPDaily, f structs.Flight, endpoint structs.FlightEndpoint) {
+	d.Flights++
+	switch f.Status {
+	case structs.Cancelled:
+		d.Cancelled++
+		return
+	case structs.Diverted:
+		d.Diverted++
+		return
+	}
+	if f.Status != structs.Landed && f.Arrival.Actual == nil {
+		return
+	}
+
+	d.Landed++
+	if minutes, ok := delayOf(endpoint); ok {
+		d.AddDelay(minutes)
+	}
+}
+
+// delayOf prefers actual minus scheduled time, both local to the same
+// airport, over the delay the provider reports.
+func delayOf(endpoint structs.FlightEndpoint) (int, bool) {
+	if endpoint.Actual != nil && endpoint.Scheduled != nil {
+		return int(math.Round(endpoint.Actual.Sub(*endpoint.Scheduled).Minutes())), true
+	}
+	if endpoint.Delay != nil {
+		return *endpoint.Delay, true
+	}
+	return 0, false
+}
+
+// GetOTP sums the daily rows of each key over the filter's range, busiest
+// key first.
+func (s *Service) GetOTP(ctx context.Context, filter structs.OTPFilter) ([]structs.OTPStats, error) {
+	days, err := s.repo.Stats.GetOTPDaily(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]*structs.OTPDaily)
+	var keys []string
+	for _, d := range days {
+		total, ok := byKey[d.Key]
+		if !ok {
+			total = &structs.OTPDaily{Dimension: d.Dimension, Key: d.Key, DelayHistogram: make([]int32, structs.DelayBuckets)}
+			byKey[d.Key] = total
+			keys = append(keys, d.Key)
+		}
+		total.Flights += d.Flights
+		total.Landed += d.Landed
+		total.OnTime += d.OnTime
+		total.Cancelled += d.Cancelled
+		total.Diverted += d.Diverted
+		total.DelayCount += d.DelayCount
+		total.DelaySum += d.DelaySum
+		for i, n := range d.DelayHistogram {
+			if i < structs.DelayBuckets {
+				total.DelayHistogram[i] += n
+			}
+		}
+	}
+
+	stats := make([]structs.OTPStats, 0, len(keys))
+	for _, key := range keys {
+		stats = append(stats, summarize(*byKey[key], filter.From, filter.To))
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].Flights != stats[j].Flights {
+			return stats[i].Flights > stats[j].Flights
+		}
+		return stats[i].Key < stats[j].Key
+	})
+	if filter.Limit > 0 && len(stats) > filter.Limit {
+		stats = stats[:filter.Limit]
+	}
+	return stats, nil
+}
+
+func summarize(total structs.OTPDaily, from time.Time, to time.Time) structs.OTPStats {
+	stats := structs.OTPStats{
+		Dimension: total.Dimension,
+		Key:       total.Key,
+		From:      from,
+		To:        to,
+		Flights:   total.Flights,
+		Landed:    total.Landed,
+		Cancelled: total.Cancelled,
+		Diverted:  total.Diverted,
+	}
+
+	if total.DelayCount > 0 {
+		stats.OTPPercent = rounded(100 * float64(total.OnTime) / float64(total.DelayCount))
+		stats.MeanDelay = rounded(float64(total.DelaySum) / float64(total.DelayCount))
+		stats.MedianDelay = rounded(percentile(total.DelayHistogram, total.DelayCount, 0.5))
+		stats.P90Delay = rounded(percentile(total.DelayHistogram, total.DelayCount, 0.9))
+	}
+	if finished := total.Landed + total.Cancelled + total.Diverted; finished > 0 {
+		stats.CancellationRate = rounded(float64(total.Cancelled) / float64(finished))
+		stats.DiversionRate = rounded(float64(total.Diverted) / float64(finished))
+	}
+	return stats
+}
+
+// percentile interpolates linearly inside the 5 minute bucket the p-th delay
+// falls in; the open-ended last bucket reports its lower bound.
+func percentile(histogram []int32, count int, p float64) float64 {
+	rank := p * float64(count)
+	seen := 0.0
+	for i, n := range histogram {
+		if n == 0 {
+			continue
+		}
+		if seen+float64(n) >= rank {
+			lower := float64(i * structs.DelayBucketMinutes)
+			if i == len(histogram)-1 {
+				return lower
+			}
+			return lower + (rank-seen)/float64(n)*structs.DelayBucketMinutes
+		}
+		seen += float64(n)
+	}
+	return float64((len(histogram) - 1) * structs.DelayBucketMinutes)
+}
+
+func rounded(v float64) *float64 {
+	v = math.Round(v*100) / 100
+	return &v
+}
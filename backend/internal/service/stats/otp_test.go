@@ -0,0 +1,153 @@
+package stats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+type fakeFlights struct {
+	repository.Flight
+	flights []structs.Flight
+}
+
+func (f *fakeFlights) FindFlights(ctx context.Context, filter structs.FlightFilter) ([]structs.Flight, error) {
+	var found []structs.Flight
+	for _, flight := range f.flights {
+		if !flight.FlightDate.Before(filter.Date) && !flight.FlightDate.After(filter.Until) {
+			found = append(found, flight)
+		}
+	}
+	return found, nil
+}
+
+// fakeStats keeps otp_daily in memory.
+type fakeStats struct {
+	repository.Stats
+	days []structs.OTPDaily
+}
+
+func (f *fakeStats) ReplaceOTPDaily(ctx context.Context, from time.Time, to time.Time, rows []structs.OTPDaily) error {
+	kept := f.days[:0]
+	for _, d := range f.days {
+		if d.FlightDate.Before(from) || d.FlightDate.After(to) {
+			kept = append(kept, d)
+		}
+	}
+	f.days = append(kept, rows...)
+	return nil
+}
+
+func (f *fakeStats) GetOTPDaily(ctx context.Context, filter structs.OTPFilter) ([]structs.OTPDaily, error) {
+	var days []structs.OTPDaily
+	for _, d := range f.days {
+		if d.Dimension == filter.Dimension && (filter.Key == "" || d.Key == filter.Key) &&
+			!d.FlightDate.Before(filter.From) && !d.FlightDate.After(filter.To) {
+			days = append(days, d)
+		}
+	}
+	return days, nil
+}
+
+func TestOTP(t *testing.T) {
+	may1 := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	may8 := may1.AddDate(0, 0, 7)
+	flight := func(date time.Time, status structs.FlightStatus, delay *int) structs.Flight {
+		scheduled := date.Add(8 * time.Hour)
+		f := structs.Flight{
+			FlightDate:  date,
+			Status:      status,
+			AirlineIata: "TP",
+			Departure:   structs.FlightEndpoint{Iata: "LIS", Scheduled: &scheduled},
+			Arrival:     structs.FlightEndpoint{Iata: "OPO", Scheduled: &scheduled},
+		}
+		if delay != nil {
+			actual := scheduled.Add(time.Duration(*delay) * time.Minute)
+			f.Arrival.Actual = &actual
+		}
+		return f
+	}
+	minutes := func(n int) *int { return &n }
+
+	var flights []structs.Flight
+	for _, delay := range []int{0, 2, 4, 6, 8, 10, 12, 14, 30, 60} {
+		flights = append(flights, flight(may1, structs.Landed, minutes(delay)))
+	}
+	flights = append(flights,
+		flight(may1, structs.Cancelled, nil),
+		flight(may1, structs.Diverted, nil),
+		flight(may8, structs.Landed, minutes(-4)), // early counts as on time
+		flight(may8, structs.Landed, minutes(100)),
+		flight(may8, structs.Active, nil), // still flying: counted, no delay
+	)
+	stats := &fakeStats{}
+	s := NewService(&repository.Repository{Flight: &fakeFlights{flights: flights}, Stats: stats})
+	ctx := context.Background()
+
+	rows, err := s.RefreshOTP(ctx, may1, may8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows != 8 {
+		t.Errorf("wrote %d daily rows, want the 4 dimensions of 2 days", rows)
+	}
+	// A second refresh replaces the days instead of adding to them.
+	if _, err := s.RefreshOTP(ctx, may1, may8); err != nil {
+		t.Fatal(err)
+	}
+
+	value := func(v *float64) interface{} {
+		if v == nil {
+			return nil
+		}
+		return *v
+	}
+	tests := []struct {
+		name              string
+		from, to          time.Time
+		flights           int
+		otp, mean         interface{}
+		median, p90       interface{}
+		cancelled, divert interface{}
+	}{
+		// Delays 0-14 are on time. The median, the 5th of 10 delays, closes
+		// the 5-10 bucket; the p90, the 9th, is alone in the 30-35 bucket.
+		{"one day", may1, may1, 12, 80.0, 14.6, 10.0, 35.0, 0.08, 0.08},
+		// The 7 days ending May 8 leave May 1 out.
+		{"7 day window", may8.AddDate(0, 0, -6), may8, 3, 50.0, 50.0, 5.0, 104.0, 0.0, 0.0},
+		{"30 day window", may8.AddDate(0, 0, -29), may8, 15, 75.0, 20.5, 10.0, 64.0, 0.07, 0.07},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			otp, err := s.GetOTP(ctx, structs.OTPFilter{Dimension: "airline", From: tt.from, To: tt.to})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(otp) != 1 || otp[0].Key != "TP" {
+				t.Fatalf("GetOTP = %+v, want TP alone", otp)
+			}
+			got := otp[0]
+			if got.Flights != tt.flights {
+				t.Errorf("flights = %d, want %d", got.Flights, tt.flights)
+			}
+			for _, c := range []struct {
+				name      string
+				got, want interface{}
+			}{
+				{"otp", value(got.OTPPercent), tt.otp},
+				{"mean", value(got.MeanDelay), tt.mean},
+				{"median", value(got.MedianDelay), tt.median},
+				{"p90", value(got.P90Delay), tt.p90},
+				{"cancellation rate", value(got.CancellationRate), tt.cancelled},
+				{"diversion rate", value(got.DiversionRate), tt.divert},
+			} {
+				if c.got != c.want {
+					t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package trash
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrNotFound        = errors.New("not found")
+	ErrUnknownResource = errors.New("unknown resource")
+)
+
+// Resources are the soft deletable tables, purged in this order when no
+// resource is given.
+var Resources = []string{"tax", "aircraft", "airplane", "airline", "airport", "city", "country"}
+
+type Service struct {
+	repo *repository.Repository
+}
+
+func NewService(repo *repository.Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// Restore brings back the soft deleted row id of resource.
+func (s *Service) Restore(ctx context.Context, resource string, id uuid.UUID) error {
+	if !known(resource) {
+		return fmt.Errorf("%w: %s", ErrUnknownResource, resource)
+	}
+	return notFound(s.repo.Trash.Restore(ctx, resource, id))
+}
+
+// Purge hard deletes the rows of resource, or of every resource when it is
+// empty, that were soft deleted before the given time.
+func (s *Service) Purge(ctx context.Context, resource string, before time.Time) (int64, error) {
+	resources := Resources
+	if resource != "" {
+		if !known(resource) {
+			return 0, fmt.Errorf("%w: %s", ErrUnknownResource, resource)
+		}
+		resources = []string{resource}
+	}
+
+	var purged int64
+	for _, r := range resources {
+		n, err := s.repo.Trash.Purge(ctx, r, before)
+		if err != nil {
+			return purged, err
+		}
+		purged += n
+	}
+	return purged, nil
+}
+
+func known(resource string) bool {
+	for _, r := range Resources {
+		if r == resource {
+			return true
+		}
+	}
+	return false
+}
+
+func notFound(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,61 @@
+package user
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/mail"
+	"strings"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/user"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidEmail = errors.New("invalid email address")
+	ErrEmailTaken   = user.ErrDuplicateEmail
+	// ErrInvalidRefreshToken is a refresh token that was never issued or
+	// was already used.
+	ErrInvalidRefreshToken = user.ErrUnknownRefreshToken
+)
+
+type Service struct {
+	repo *repository.Repository
+}
+
+func NewService(repo *repository.Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// CreateUser registers an email address with the refresh token its access
+// tokens are renewed with; addresses are stored lowercased.
+func (s *Service) CreateUser(ctx context.Context, email string, refreshToken string) (structs.User, error) {
+	address, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil || address.Name != "" {
+		return structs.User{}, ErrInvalidEmail
+	}
+	return s.repo.User.CreateUser(ctx, strings.ToLower(address.Address), hash(refreshToken))
+}
+
+// RefreshToken exchanges refreshToken for next and returns the user they
+// belong to. Each refresh token can be used once.
+func (s *Service) RefreshToken(ctx context.Context, refreshToken string, next string) (structs.User, error) {
+	if refreshToken == "" {
+		return structs.User{}, ErrInvalidRefreshToken
+	}
+	return s.repo.User.RotateRefreshToken(ctx, hash(refreshToken), hash(next))
+}
+
+// hash is what is stored of a refresh token, so that a leaked table does not
+// leak tokens.
+func hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Service) GetUser(ctx context.Context, id uuid.UUID) (structs.User, error) {
+	return s.repo.User.GetUser(ctx, id)
+}
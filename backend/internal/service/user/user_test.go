@@ -0,0 +1,28 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	a := hash("token")
+	if len(a) != 64 {
+		t.Errorf("hash is %d characters, the column holds 64", len(a))
+	}
+	if a != hash("token") {
+		t.Error("hash is not stable")
+	}
+	if a == hash("other") {
+		t.Error("different tokens share a hash")
+	}
+}
+
+func TestRefreshTokenEmpty(t *testing.T) {
+	// An empty token must not match the users that have none.
+	_, err := NewService(nil).RefreshToken(context.Background(), "", "next")
+	if !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("RefreshToken(\"\") = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+}
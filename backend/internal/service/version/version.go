@@ -0,0 +1,20 @@
+package version
+
+import (
+	"context"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+type Service struct {
+	repo *repository.Repository
+}
+
+func NewService(repo *repository.Repository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) GetVersion(ctx context.Context, tables ...string) (structs.TableVersion, error) {
+	return s.repo.Version.GetVersion(ctx, tables...)
+}
@@ -0,0 +1,71 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateURL is a webhook URL whose host resolves to an address of this
+// machine or of a private network, which receivers must not be on.
+var ErrPrivateURL = errors.New("invalid webhook URL, the host must resolve to public addresses")
+
+// checkHost resolves host and fails with ErrPrivateURL when any of its
+// addresses is not public.
+func checkHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return ErrInvalidURL
+	}
+	for _, addr := range addrs {
+		if !public(addr.IP) {
+			return ErrPrivateURL
+		}
+	}
+	return nil
+}
+
+// public reports whether ip may be sent webhooks: not loopback, link-local
+// (which has the cloud metadata services), RFC 1918 or RFC 4193 private,
+// unspecified or multicast.
+func public(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsPrivate() || ip.IsUnspecified())
+}
+
+// control refuses connections to addresses that are not public. It runs on
+// the address actually dialed, so a host that resolved to a public address
+// when the webhook was created cannot be pointed elsewhere later.
+func control(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !public(ip) {
+		return fmt.Errorf("refusing to deliver to %s: %w", host, ErrPrivateURL)
+	}
+	return nil
+}
+
+// newClient is the client deliveries are sent with. It only dials public
+// addresses, does not go through a proxy, which would dial for it, and does
+// not follow redirects: the redirect answer is recorded as a failed attempt.
+func newClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: control}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
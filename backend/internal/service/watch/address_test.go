@@ -0,0 +1,92 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPublic(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1::1", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"224.0.0.1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := public(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("public(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	tests := []struct {
+		host string
+		err  error
+	}{
+		{"93.184.216.34", nil},
+		{"127.0.0.1", ErrPrivateURL},
+		{"localhost", ErrPrivateURL},
+		{"169.254.169.254", ErrPrivateURL},
+		{"::1", ErrPrivateURL},
+		{"host.invalid", ErrInvalidURL},
+	}
+	for _, tt := range tests {
+		if err := checkHost(context.Background(), tt.host); !errors.Is(err, tt.err) {
+			t.Errorf("checkHost(%s) = %v, want %v", tt.host, err, tt.err)
+		}
+	}
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the client reached a loopback receiver")
+	}))
+	defer server.Close()
+
+	_, err := newClient(time.Second).Post(server.URL, "application/json", nil)
+	if !errors.Is(err, ErrPrivateURL) {
+		t.Errorf("Post to %s = %v, want %v", server.URL, err, ErrPrivateURL)
+	}
+}
+
+func TestClientDoesNotFollowRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			t.Errorf("the client followed a redirect to %s", r.URL.Path)
+		}
+		http.Redirect(w, r, "/elsewhere", http.StatusFound)
+	}))
+	defer server.Close()
+
+	// The redirect policy is tested on its own: this receiver is on loopback.
+	client := newClient(time.Second)
+	client.Transport = server.Client().Transport
+	resp, err := client.Post(server.URL, "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusFound)
+	}
+}
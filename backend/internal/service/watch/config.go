@@ -0,0 +1,44 @@
+package watch
+
+import "time"
+
+type Config struct {
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	timeout     time.Duration
+}
+
+// NewConfig sets how many times a delivery is sent before it is dead, the
+// wait after the first failure, doubled after every further one up to
+// maxBackoff, and how long a receiver gets to answer.
+func NewConfig(maxAttempts int, backoff time.Duration, maxBackoff time.Duration, timeout time.Duration) Config {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	if maxBackoff < backoff {
+		maxBackoff = backoff
+	}
+	return Config{
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+		maxBackoff:  maxBackoff,
+		timeout:     timeout,
+	}
+}
+
+// retryAfter is the wait before the next send of a delivery that failed
+// attempts times.
+func (c Config) retryAfter(attempts int) time.Duration {
+	wait := c.backoff
+	for i := 1; i < attempts && wait < c.maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > c.maxBackoff {
+		wait = c.maxBackoff
+	}
+	return wait
+}
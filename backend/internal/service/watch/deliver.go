@@ -0,0 +1,100 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+)
+
+// Headers sent with every delivery. The signature is the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the webhook secret.
+const (
+	HeaderEvent     = "X-Aviatoon-Event"
+	HeaderDelivery  = "X-Aviatoon-Delivery"
+	HeaderTimestamp = "X-Aviatoon-Timestamp"
+	HeaderSignature = "X-Aviatoon-Signature"
+)
+
+// Deliver sends up to limit due deliveries and returns how many it sent,
+// successfully or not. A 2xx answer marks a delivery delivered; anything
+// else schedules a retry with exponential backoff until the last attempt,
+// after which it is dead.
+func (s *Service) Deliver(ctx context.Context, limit int) (int, error) {
+	// The claim outlives the slowest possible batch, so deliveries are not
+	// sent twice while this one is still working through them.
+	lease := time.Duration(limit)*s.config.timeout + time.Minute
+	due, err := s.repo.Watch.ClaimDeliveries(ctx, limit, time.Now().Add(lease))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, d := range due {
+		attempt := s.send(ctx, d)
+
+		status, next := structs.DeliveryDelivered, time.Now()
+		if attempt.StatusCode == nil || *attempt.StatusCode < 200 || *attempt.StatusCode > 299 {
+			status = structs.DeliveryPending
+			next = next.Add(s.config.retryAfter(attempt.Attempt))
+			if attempt.Attempt >= s.config.maxAttempts {
+				status = structs.DeliveryDead
+			}
+		}
+		if err := s.repo.Watch.RecordAttempt(ctx, d.ID, attempt, status, next); err != nil {
+			return 0, err
+		}
+	}
+	return len(due), nil
+}
+
+func (s *Service) send(ctx context.Context, d structs.DueDelivery) structs.WebhookAttempt {
+	attempt := structs.WebhookAttempt{Attempt: d.Attempts + 1, AttemptedAt: time.Now()}
+	defer func() {
+		attempt.DurationMs = int(time.Since(attempt.AttemptedAt).Milliseconds())
+	}()
+
+	timestamp := strconv.FormatInt(attempt.AttemptedAt.Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "aviatoon-tracker-webhooks")
+	req.Header.Set(HeaderEvent, d.Event)
+	req.Header.Set(HeaderDelivery, d.ID.String())
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, "sha256="+Sign(d.Secret, timestamp, d.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	attempt.StatusCode = &resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = fmt.Sprintf("receiver answered %s", resp.Status)
+	}
+	return attempt
+}
+
+// Sign returns the hex signature of a delivery body sent at timestamp, for
+// receivers to compare against the X-Aviatoon-Signature header.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,160 @@
+package watch
+
+import (
+	"context"
+	"crypto/hmac"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+)
+
+// fakeWatch hands out due deliveries and keeps the attempts recorded.
+type fakeWatch struct {
+	repository.Watch
+	due      []structs.DueDelivery
+	recorded []recorded
+}
+
+type recorded struct {
+	id      uuid.UUID
+	attempt structs.WebhookAttempt
+	status  string
+	next    time.Time
+}
+
+func (f *fakeWatch) ClaimDeliveries(_ context.Context, limit int, _ time.Time) ([]structs.DueDelivery, error) {
+	due := f.due
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+func (f *fakeWatch) RecordAttempt(_ context.Context, id uuid.UUID, attempt structs.WebhookAttempt, status string, next time.Time) error {
+	f.recorded = append(f.recorded, recorded{id, attempt, status, next})
+	return nil
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '1700000000.{"a":1}' | openssl dgst -sha256 -hmac secret
+	const want = "49f24e537407743fa4a0242bb63b94b9a47ee99cbbe071ccd8a22550ae411686"
+	if got := Sign("secret", "1700000000", []byte(`{"a":1}`)); got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	var requests []*http.Request
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests, bodies = append(requests, r), append(bodies, body)
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusNoContent)
+		case "/redirect":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	delivery := func(path string, attempts int) structs.DueDelivery {
+		return structs.DueDelivery{
+			WebhookDelivery: structs.WebhookDelivery{
+				ID: uuid.New(), Event: structs.EventFlightChanged, Payload: []byte(`{"id":1}`), Attempts: attempts,
+			},
+			URL:    server.URL + path,
+			Secret: "secret",
+		}
+	}
+	repo := &fakeWatch{due: []structs.DueDelivery{
+		delivery("/ok", 0),
+		delivery("/fail", 0),
+		delivery("/fail", 2),
+		delivery("/redirect", 0),
+	}}
+	config := NewConfig(3, time.Minute, time.Hour, time.Second)
+	s := &Service{repo: &repository.Repository{Watch: repo}, config: config, client: newClient(time.Second)}
+	// The receiver is on loopback, which the delivery client refuses.
+	s.client.Transport = server.Client().Transport
+
+	before := time.Now()
+	sent, err := s.Deliver(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent != 4 || len(repo.recorded) != 4 {
+		t.Fatalf("sent %d, recorded %d, want 4", sent, len(repo.recorded))
+	}
+
+	tests := []struct {
+		status string
+		code   int
+		retry  bool
+	}{
+		{structs.DeliveryDelivered, http.StatusNoContent, false},
+		{structs.DeliveryPending, http.StatusInternalServerError, true},
+		{structs.DeliveryDead, http.StatusInternalServerError, true},
+		{structs.DeliveryPending, http.StatusFound, true},
+	}
+	for i, tt := range tests {
+		r := repo.recorded[i]
+		if r.id != repo.due[i].ID || r.status != tt.status {
+			t.Errorf("delivery %d recorded %s as %s, want %s", i, r.id, r.status, tt.status)
+		}
+		if r.attempt.Attempt != repo.due[i].Attempts+1 {
+			t.Errorf("delivery %d attempt = %d, want %d", i, r.attempt.Attempt, repo.due[i].Attempts+1)
+		}
+		if r.attempt.StatusCode == nil || *r.attempt.StatusCode != tt.code {
+			t.Errorf("delivery %d status code = %v, want %d", i, r.attempt.StatusCode, tt.code)
+		}
+		if tt.retry && (r.attempt.Error == "" || r.next.Before(before.Add(config.retryAfter(r.attempt.Attempt)))) {
+			t.Errorf("delivery %d failed with %q, next attempt at %s", i, r.attempt.Error, r.next)
+		}
+	}
+
+	// The redirect was answered, not followed.
+	if len(requests) != 4 {
+		t.Fatalf("receiver got %d requests, want 4", len(requests))
+	}
+	for i, r := range requests {
+		d := repo.due[i]
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("request %d: %s %s", i, r.Method, r.Header.Get("Content-Type"))
+		}
+		if r.Header.Get(HeaderEvent) != d.Event || r.Header.Get(HeaderDelivery) != d.ID.String() {
+			t.Errorf("request %d: event %s, delivery %s", i, r.Header.Get(HeaderEvent), r.Header.Get(HeaderDelivery))
+		}
+		if string(bodies[i]) != string(d.Payload) {
+			t.Errorf("request %d body = %s, want %s", i, bodies[i], d.Payload)
+		}
+
+		timestamp := r.Header.Get(HeaderTimestamp)
+		if _, err := strconv.ParseInt(timestamp, 10, 64); err != nil {
+			t.Errorf("request %d timestamp %q: %v", i, timestamp, err)
+		}
+		want := "sha256=" + Sign(d.Secret, timestamp, bodies[i])
+		if !hmac.Equal([]byte(r.Header.Get(HeaderSignature)), []byte(want)) {
+			t.Errorf("request %d signature = %s, want %s", i, r.Header.Get(HeaderSignature), want)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	config := NewConfig(5, time.Minute, 5*time.Minute, 0)
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for i, w := range want {
+		if got := config.retryAfter(i + 1); got != w {
+			t.Errorf("retryAfter(%d) = %s, want %s", i+1, got, w)
+		}
+	}
+}
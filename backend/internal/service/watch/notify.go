@@ -0,0 +1,108 @@
+package watch
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+)
+
+// payload is the JSON body posted to a webhook.
+type payload struct {
+	ID         uuid.UUID             `json:"id"`
+	Event      string                `json:"event"`
+	OccurredAt time.Time             `json:"occurred_at"`
+	Flight     structs.Flight        `json:"flight"`
+	Changes    []structs.FieldChange `json:"changes"`
+	Watches    []matchedWatch        `json:"watches"`
+}
+
+type matchedWatch struct {
+	WatchlistID uuid.UUID `json:"watchlist_id"`
+	Kind        string    `json:"kind"`
+	Value       string    `json:"value"`
+}
+
+// Deliveries makes a delivery to every active webhook of the users watching
+// the flight, route or airports of a change, one per webhook and change
+// however many of their watches match. It is the structs.Notifier flights
+// are recorded with.
+func (s *Service) Deliveries(changes []structs.FlightChange, match func(kinds []string, values []string) ([]structs.WebhookMatch, error)) ([]structs.WebhookDelivery, error) {
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	var kinds, values []string
+	seen := make(map[[2]string]bool)
+	for _, c := range changes {
+		for _, w := range watchesOf(c.Flight) {
+			if !seen[w] {
+				seen[w] = true
+				kinds, values = append(kinds, w[0]), append(values, w[1])
+			}
+		}
+	}
+	matches, err := match(kinds, values)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, nil
+	}
+	byWatch := make(map[[2]string][]structs.WebhookMatch)
+	for _, m := range matches {
+		w := [2]string{m.Kind, m.Value}
+		byWatch[w] = append(byWatch[w], m)
+	}
+
+	now := time.Now().UTC()
+	var deliveries []structs.WebhookDelivery
+	for _, c := range changes {
+		watches := make(map[uuid.UUID][]matchedWatch)
+		var hooks []uuid.UUID
+		for _, w := range watchesOf(c.Flight) {
+			for _, m := range byWatch[w] {
+				if _, ok := watches[m.WebhookID]; !ok {
+					hooks = append(hooks, m.WebhookID)
+				}
+				watches[m.WebhookID] = append(watches[m.WebhookID], matchedWatch{m.WatchlistID, m.Kind, m.Value})
+			}
+		}
+
+		for _, hook := range hooks {
+			p := payload{
+				ID:         uuid.New(),
+				Event:      structs.EventFlightChanged,
+				OccurredAt: now,
+				Flight:     c.Flight,
+				Changes:    c.Changes,
+				Watches:    watches[hook],
+			}
+			body, err := json.Marshal(p)
+			if err != nil {
+				return nil, err
+			}
+			deliveries = append(deliveries, structs.WebhookDelivery{
+				ID:        p.ID,
+				WebhookID: hook,
+				Event:     p.Event,
+				Payload:   body,
+			})
+		}
+	}
+	return deliveries, nil
+}
+
+// watchesOf lists the kind/value pairs a watch on f can have.
+func watchesOf(f structs.Flight) [][2]string {
+	watches := [][2]string{
+		{structs.WatchFlight, f.FlightIata},
+		{structs.WatchRoute, f.Departure.Iata + "-" + f.Arrival.Iata},
+		{structs.WatchAirport, f.Departure.Iata},
+	}
+	if f.Arrival.Iata != f.Departure.Iata {
+		watches = append(watches, [2]string{structs.WatchAirport, f.Arrival.Iata})
+	}
+	return watches
+}
@@ -0,0 +1,76 @@
+package watch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+)
+
+func change(flight string, dep string, arr string) structs.FlightChange {
+	f := structs.Flight{FlightIata: flight}
+	f.Departure.Iata, f.Arrival.Iata = dep, arr
+	return structs.FlightChange{Flight: f, Changes: []structs.FieldChange{{Field: "status", New: "active"}}}
+}
+
+func TestDeliveries(t *testing.T) {
+	hookA, hookB := uuid.New(), uuid.New()
+	listA, listB := uuid.New(), uuid.New()
+	watches := map[[2]string][]structs.WebhookMatch{
+		{structs.WatchFlight, "TP1350"}: {{WebhookID: hookA, WatchlistID: listA, Kind: structs.WatchFlight, Value: "TP1350"}},
+		{structs.WatchAirport, "LIS"}:   {{WebhookID: hookA, WatchlistID: listA, Kind: structs.WatchAirport, Value: "LIS"}},
+		{structs.WatchRoute, "LHR-JFK"}: {{WebhookID: hookB, WatchlistID: listB, Kind: structs.WatchRoute, Value: "LHR-JFK"}},
+		{structs.WatchAirport, "LHR"}:   {{WebhookID: hookB, WatchlistID: listB, Kind: structs.WatchAirport, Value: "LHR"}},
+	}
+	match := func(kinds []string, values []string) ([]structs.WebhookMatch, error) {
+		var matches []structs.WebhookMatch
+		for i := range kinds {
+			matches = append(matches, watches[[2]string{kinds[i], values[i]}]...)
+		}
+		return matches, nil
+	}
+
+	changes := []structs.FlightChange{
+		change("TP1350", "LIS", "LHR"),
+		change("BA1", "LHR", "JFK"),
+		change("XX9", "OPO", "FAO"),
+	}
+	deliveries, err := (&Service{}).Deliveries(changes, match)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// TP1350 is watched by A twice and B through LHR; BA1 by B twice.
+	type sent struct {
+		hook    uuid.UUID
+		flight  string
+		watches int
+	}
+	var got []sent
+	for _, d := range deliveries {
+		var p payload
+		if err := json.Unmarshal(d.Payload, &p); err != nil {
+			t.Fatal(err)
+		}
+		if p.ID != d.ID || p.Event != structs.EventFlightChanged || d.Event != p.Event {
+			t.Errorf("delivery %s carries payload %s, event %s", d.ID, p.ID, p.Event)
+		}
+		got = append(got, sent{d.WebhookID, p.Flight.FlightIata, len(p.Watches)})
+	}
+	want := []sent{{hookA, "TP1350", 2}, {hookB, "TP1350", 1}, {hookB, "BA1", 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("deliveries = %+v, want %+v", got, want)
+	}
+}
+
+func TestDeliveriesMatchFails(t *testing.T) {
+	failed := errors.New("boom")
+	_, err := (&Service{}).Deliveries([]structs.FlightChange{change("TP1350", "LIS", "LHR")},
+		func([]string, []string) ([]structs.WebhookMatch, error) { return nil, failed })
+	if !errors.Is(err, failed) {
+		t.Errorf("Deliveries = %v, want %v", err, failed)
+	}
+}
@@ -0,0 +1,95 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrInvalidWatch = errors.New("invalid watch, expected a flight (TP1350), route (LIS-LHR) or airport (LIS)")
+	ErrInvalidName  = errors.New("watchlist name is required")
+)
+
+var watchValues = map[string]*regexp.Regexp{
+	structs.WatchFlight:  regexp.MustCompile(`^[A-Z0-9]{2}[0-9]{1,4}[A-Z]?$`),
+	structs.WatchRoute:   regexp.MustCompile(`^[A-Z]{3}-[A-Z]{3}$`),
+	structs.WatchAirport: regexp.MustCompile(`^[A-Z]{3}$`),
+}
+
+type Service struct {
+	repo   *repository.Repository
+	config Config
+	client *http.Client
+}
+
+func NewService(repo *repository.Repository, config Config) *Service {
+	return &Service{repo: repo, config: config, client: newClient(config.timeout)}
+}
+
+func (s *Service) CreateWatchlist(ctx context.Context, userID uuid.UUID, name string, watches []structs.Watch) (structs.Watchlist, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return structs.Watchlist{}, ErrInvalidName
+	}
+	for i := range watches {
+		w, err := normalizeWatch(watches[i])
+		if err != nil {
+			return structs.Watchlist{}, err
+		}
+		watches[i] = w
+	}
+	return s.repo.Watch.CreateWatchlist(ctx, userID, name, watches)
+}
+
+func (s *Service) GetWatchlists(ctx context.Context, userID uuid.UUID) ([]structs.Watchlist, error) {
+	return s.repo.Watch.GetWatchlists(ctx, userID)
+}
+
+func (s *Service) GetWatchlist(ctx context.Context, userID uuid.UUID, id uuid.UUID) (structs.Watchlist, error) {
+	list, err := s.repo.Watch.GetWatchlist(ctx, userID, id)
+	return list, notFound(err)
+}
+
+func (s *Service) DeleteWatchlist(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	return notFound(s.repo.Watch.DeleteWatchlist(ctx, userID, id))
+}
+
+func (s *Service) AddWatch(ctx context.Context, userID uuid.UUID, watchlistID uuid.UUID, w structs.Watch) (structs.Watch, error) {
+	w, err := normalizeWatch(w)
+	if err != nil {
+		return w, err
+	}
+	w, err = s.repo.Watch.AddWatch(ctx, userID, watchlistID, w)
+	return w, notFound(err)
+}
+
+func (s *Service) DeleteWatch(ctx context.Context, userID uuid.UUID, watchlistID uuid.UUID, id uuid.UUID) error {
+	return notFound(s.repo.Watch.DeleteWatch(ctx, userID, watchlistID, id))
+}
+
+func normalizeWatch(w structs.Watch) (structs.Watch, error) {
+	w.Kind = strings.ToLower(strings.TrimSpace(w.Kind))
+	w.Value = strings.ToUpper(strings.TrimSpace(w.Value))
+	pattern, ok := watchValues[w.Kind]
+	if !ok || !pattern.MatchString(w.Value) {
+		return w, ErrInvalidWatch
+	}
+	return w, nil
+}
+
+// notFound turns the repository's missing row into ErrNotFound.
+func notFound(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package watch
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/FACorreiaa/aviatoon-tracker/internal/structs"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidURL    = errors.New("invalid webhook URL, expected an absolute http or https URL")
+	ErrInvalidStatus = errors.New("invalid delivery status, expected pending, delivered or dead")
+)
+
+// CreateWebhook registers a URL for the user's flight changes. Its host
+// must resolve to public addresses only. The returned webhook carries the
+// signing secret; it is not shown again.
+func (s *Service) CreateWebhook(ctx context.Context, userID uuid.UUID, rawURL string) (structs.Webhook, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return structs.Webhook{}, ErrInvalidURL
+	}
+	if err := checkHost(ctx, u.Hostname()); err != nil {
+		return structs.Webhook{}, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return structs.Webhook{}, err
+	}
+	return s.repo.Watch.CreateWebhook(ctx, userID, u.String(), hex.EncodeToString(secret))
+}
+
+func (s *Service) GetWebhooks(ctx context.Context, userID uuid.UUID) ([]structs.Webhook, error) {
+	return s.repo.Watch.GetWebhooks(ctx, userID)
+}
+
+// SetWebhookActive pauses or resumes a webhook. Deliveries of a paused
+// webhook wait in the queue until it is resumed.
+func (s *Service) SetWebhookActive(ctx context.Context, userID uuid.UUID, id uuid.UUID, active bool) error {
+	return notFound(s.repo.Watch.SetWebhookActive(ctx, userID, id, active))
+}
+
+func (s *Service) DeleteWebhook(ctx context.Context, userID uuid.UUID, id uuid.UUID) error {
+	return notFound(s.repo.Watch.DeleteWebhook(ctx, userID, id))
+}
+
+func (s *Service) GetDeliveries(ctx context.Context, userID uuid.UUID, webhookID uuid.UUID, filter structs.DeliveryFilter) ([]structs.WebhookDelivery, error) {
+	switch filter.Status {
+	case "", structs.DeliveryPending, structs.DeliveryDelivered, structs.DeliveryDead:
+	default:
+		return nil, ErrInvalidStatus
+	}
+	deliveries, err := s.repo.Watch.GetDeliveries(ctx, userID, webhookID, filter)
+	return deliveries, notFound(err)
+}
+
+func (s *Service) GetDelivery(ctx context.Context, userID uuid.UUID, webhookID uuid.UUID, id uuid.UUID) (structs.WebhookDelivery, error) {
+	delivery, err := s.repo.Watch.GetDelivery(ctx, userID, webhookID, id)
+	return delivery, notFound(err)
+}
+
+// RetryDelivery takes a dead delivery out of the dead-letter state and
+// queues it again.
+func (s *Service) RetryDelivery(ctx context.Context, userID uuid.UUID, webhookID uuid.UUID, id uuid.UUID) error {
+	return notFound(s.repo.Watch.RetryDelivery(ctx, userID, webhookID, id))
+}
@@ -1,9 +1,6 @@
 package structs
 
 import (
-	"database/sql/driver"
-	"encoding/json"
-	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -27,8 +24,10 @@ type Airline struct {
 	FleetSize            int        `json:"fleet_size,string"`
 	Status               string     `json:"status"`
 	Type                 string     `json:"type"`
-	CreatedAt            CustomTime `db:"created_at" json:"created_at"`
+	CountryRef           *uuid.UUID `db:"country_ref" json:"country_ref"`
+	CreatedAt            Instant    `db:"created_at" json:"created_at"`
 	UpdatedAt            *time.Time `db:"updated_at" json:"updated_at"`
+	DeletedAt            *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
 }
 
 type AirlineInfo struct {
@@ -57,7 +56,7 @@ type AirlineInfo struct {
 	CurrencyCode         string     `json:"currency_code"`
 	Continent            string     `json:"continent"`
 	PhonePrefix          string     `json:"phone_prefix"`
-	CreatedAt            CustomTime `db:"created_at" json:"created_at"`
+	CreatedAt            Instant    `db:"created_at" json:"created_at"`
 	UpdatedAt            *time.Time `db:"updated_at" json:"updated_at"`
 }
 
@@ -70,8 +69,9 @@ type Aircraft struct {
 	IataCode     string     `json:"iata_code"`
 	AircraftName string     `json:"aircraft_name"`
 	PlaneTypeId  int        `json:"plane_type_id,string"`
-	CreatedAt    CustomTime `db:"created_at" json:"created_at"`
+	CreatedAt    Instant    `db:"created_at" json:"created_at"`
 	UpdatedAt    *time.Time `db:"updated_at" json:"updated_at"`
+	DeletedAt    *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
 }
 
 type AircraftResponse []Aircraft
@@ -87,10 +87,10 @@ type Airplane struct {
 	IataCodeShort          string      `json:"iata_code_short"`
 	AirlineIcaoCode        interface{} `json:"airline_icao_code"`
 	ConstructionNumber     string      `json:"construction_number"`
-	DeliveryDate           CustomTime  `json:"delivery_date"`
+	DeliveryDate           Date        `json:"delivery_date"`
 	EnginesCount           int         `json:"engines_count,string"`
 	EnginesType            string      `json:"engines_type"`
-	FirstFlightDate        CustomTime  `json:"first_flight_date"`
+	FirstFlightDate        Date        `json:"first_flight_date"`
 	IcaoCodeHex            string      `json:"icao_code_hex"`
 	LineNumber             interface{} `json:"line_number"`
 	ModelCode              string      `json:"model_code"`
@@ -103,10 +103,12 @@ type Airplane struct {
 	PlaneSeries            string      `json:"plane_series"`
 	PlaneStatus            string      `json:"plane_status"`
 	ProductionLine         string      `json:"production_line"`
-	RegistrationDate       CustomTime  `json:"registration_date"`
-	RolloutDate            CustomTime  `json:"rollout_date"`
-	CreatedAt              CustomTime  `json:"created_at"`
+	RegistrationDate       Date        `json:"registration_date"`
+	RolloutDate            Date        `json:"rollout_date"`
+	AirlineRef             *uuid.UUID  `db:"airline_ref" json:"airline_ref"`
+	CreatedAt              Instant     `json:"created_at"`
 	UpdatedAt              *time.Time  `json:"updated_at"`
+	DeletedAt              *time.Time  `db:"deleted_at" json:"deleted_at,omitempty"`
 }
 
 type AirplaneInfo struct {
@@ -118,10 +120,10 @@ type AirplaneInfo struct {
 	IataCodeShort          string      `json:"iata_code_short"`
 	AirlineIcaoCode        interface{} `json:"airline_icao_code"`
 	ConstructionNumber     string      `json:"construction_number"`
-	DeliveryDate           CustomTime  `db:"delivery_date" json:"delivery_date"`
+	DeliveryDate           Date        `db:"delivery_date" json:"delivery_date"`
 	EnginesCount           int         `json:"engines_count"`
 	EnginesType            string      `json:"engines_type"`
-	FirstFlightDate        CustomTime  `db:"first_flight_date" json:"first_flight_date"`
+	FirstFlightDate        Date        `db:"first_flight_date" json:"first_flight_date"`
 	IcaoCodeHex            string      `json:"icao_code_hex"`
 	LineNumber             interface{} `json:"line_number"`
 	ModelCode              string      `json:"model_code"`
@@ -134,9 +136,9 @@ type AirplaneInfo struct {
 	PlaneSeries            string      `json:"plane_series"`
 	PlaneStatus            string      `json:"plane_status"`
 	ProductionLine         string      `json:"production_line"`
-	RegistrationDate       CustomTime  `db:"registration_date" json:"registration_date"`
-	RolloutDate            CustomTime  `db:"rollout_date" json:"rollout_date"`
-	CreatedAt              CustomTime  `db:"created_at" json:"created_at"`
+	RegistrationDate       Date        `db:"registration_date" json:"registration_date"`
+	RolloutDate            Date        `db:"rollout_date" json:"rollout_date"`
+	CreatedAt              Instant     `db:"created_at" json:"created_at"`
 	UpdatedAt              *time.Time  `db:"updated_at" json:"updated_at"`
 	AirlineName            string      `json:"airline_name"`
 	CountryName            string      `json:"country_name"`
@@ -155,8 +157,9 @@ type Tax struct {
 	TaxId     int        `json:"tax_id,string"`
 	TaxName   string     `json:"tax_name"`
 	IataCode  string     `json:"iata_code"`
-	CreatedAt CustomTime `db:"created_at" json:"created_at"`
+	CreatedAt Instant    `db:"created_at" json:"created_at"`
 	UpdatedAt *time.Time `db:"updated_at" json:"updated_at"`
+	DeletedAt *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
 }
 
 type TaxPerCityInfo struct {
@@ -175,87 +178,17 @@ type TaxPerCityInfo struct {
 type TaxListResponse []Tax
 
 type TaxApiData struct {
-	Data []Tax `json:".data"`
+	Data []Tax `json:"data"`
 }
 
 type AircraftApiData struct {
-	Data []Aircraft `json:".data"`
+	Data []Aircraft `json:"data"`
 }
 
 type AirlineApiData struct {
-	Data []Airline `json:".data"`
+	Data []Airline `json:"data"`
 }
 
 type AirplaneApiData struct {
-	Data []Airplane `json:".data"`
-}
-
-type CustomTime struct {
-	time.Time
-}
-
-func (ct *CustomTime) UnmarshalJSON(data []byte) error {
-	var dateStr string
-	err := json.Unmarshal(data, &dateStr)
-	if err != nil {
-		return err
-	}
-
-	// Check if the date is "0000-00-00" and set it to a default value
-	if dateStr == "0000-00-00" {
-		ct.Time = time.Time{} // Assign zero value of CustomTime
-		return nil
-	}
-
-	// Check if the date string is empty and set it to a default value
-	if dateStr == "" {
-		ct.Time = time.Time{} // Assign zero value of CustomTime
-		return nil
-	}
-
-	// Parse the date using the predefined time layout
-	t, err := time.Parse(time.RFC3339, dateStr)
-	if err != nil {
-		return err
-	}
-
-	ct.Time = t
-	return nil
-}
-
-// Implement driver.Valuer interface
-func (ct CustomTime) Value() (driver.Value, error) {
-	// Return the underlying time value as a string in the format "2006-01-02"
-	return ct.Time.Format("2006-01-02"), nil
-}
-
-// Implement sql.Scanner interface
-func (ct *CustomTime) Scan(value interface{}) error {
-	if value == nil {
-		// Handle NULL values by setting the time to the zero value
-		ct.Time = time.Time{}
-		return nil
-	}
-
-	switch t := value.(type) {
-	case time.Time:
-		ct.Time = t
-		return nil
-	case []byte:
-		parsedTime, err := time.Parse("2006-01-02", string(t))
-		if err != nil {
-			return err
-		}
-		ct.Time = parsedTime
-		return nil
-	case string:
-		parsedTime, err := time.Parse("2006-01-02", t)
-		if err != nil {
-			return err
-		}
-		ct.Time = parsedTime
-		return nil
-	default:
-		return fmt.Errorf("unsupported Scan value for CustomTime: %T", value)
-	}
+	Data []Airplane `json:"data"`
 }
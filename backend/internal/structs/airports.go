@@ -24,13 +24,16 @@ type Airport struct {
 	CountryName  string      ` json:"country_name"`
 	PhoneNumber  interface{} ` json:"phone_number"`
 	Timezone     string      ` json:"timezone"`
-	CreatedAt    CustomTime  `db:"created_at" json:"created_at"`
+	CityRef      *uuid.UUID  `db:"city_ref" json:"city_ref"`
+	CountryRef   *uuid.UUID  `db:"country_ref" json:"country_ref"`
+	CreatedAt    Instant     `db:"created_at" json:"created_at"`
 	UpdatedAt    *time.Time  `db:"updated_at" json:"updated_at"`
+	DeletedAt    *time.Time  `db:"deleted_at" json:"deleted_at,omitempty"`
 }
 
 //create an intermediate type & then convert to a concrete one
 // type Deez struct {
-//   Data map[string]string `json:".data"`
+//   Data map[string]string `json:"data"`
 // }
 
 // func (d Deez) MyBeautifulWellFormattedStruct() (Airport, error) {...}
@@ -48,6 +51,10 @@ func (a *Airport) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
+	// The representation the API serves leaves airport_id out.
+	if aux.AirportId == "" {
+		return nil
+	}
 	airportId, err := strconv.ParseInt(aux.AirportId, 10, 64)
 	if err != nil {
 		return err
@@ -71,7 +78,7 @@ type AirportInfo struct {
 	CountryName  string      `json:"country_name"`
 	PhoneNumber  interface{} `json:"phone_number"`
 	Timezone     string      `json:"timezone"`
-	CreatedAt    CustomTime  `db:"created_at" json:"created_at"`
+	CreatedAt    Instant     `db:"created_at" json:"created_at"`
 	UpdatedAt    *time.Time  `db:"updated_at" json:"updated_at"`
 	CityName     string      `json:"city_name"`
 }
@@ -79,5 +86,5 @@ type AirportInfo struct {
 type AirportResponse []Airport
 
 type AirportApiData struct {
-	Data []Airport `json:".data"`
+	Data []Airport `json:"data"`
 }
@@ -0,0 +1,33 @@
+package structs
+
+import "time"
+
+// BoardEntry is one line of a departures or arrivals board. Airport is the
+// other end of the flight; times carry the board airport's offset.
+type BoardEntry struct {
+	Scheduled    time.Time  `json:"scheduled"`
+	Estimated    *time.Time `json:"estimated"`
+	Actual       *time.Time `json:"actual"`
+	FlightIata   string     `json:"flight_iata"`
+	AirlineIata  string     `json:"airline_iata"`
+	AirlineName  string     `json:"airline_name"`
+	Codeshares   []string   `json:"codeshares"`
+	Airport      string     `json:"airport"`
+	AirportName  string     `json:"airport_name"`
+	Terminal     string     `json:"terminal"`
+	Gate         string     `json:"gate"`
+	Baggage      string     `json:"baggage,omitempty"`
+	Status       string     `json:"status"`
+	DelayMinutes *int       `json:"delay_minutes"`
+}
+
+// Board lists the flights of Airport scheduled between From and To, in the
+// airport's local time. Direction is "departures" or "arrivals".
+type Board struct {
+	Airport   string       `json:"airport"`
+	Direction string       `json:"direction"`
+	Timezone  string       `json:"timezone"`
+	From      time.Time    `json:"from"`
+	To        time.Time    `json:"to"`
+	Flights   []BoardEntry `json:"flights"`
+}
@@ -0,0 +1,73 @@
+package structs
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BatchResult summarises a bulk write: rows received, rows that were new,
+// rows that changed an existing record matched on its upstream id and rows
+// left unwritten by a failed page.
+type BatchResult struct {
+	Rows     int64         `json:"rows"`
+	Inserted int64         `json:"inserted"`
+	Updated  int64         `json:"updated"`
+	Failed   int64         `json:"failed"`
+	Pages    int           `json:"pages"`
+	Duration time.Duration `json:"duration"`
+}
+
+func (b *BatchResult) Add(other BatchResult) {
+	b.Rows += other.Rows
+	b.Inserted += other.Inserted
+	b.Updated += other.Updated
+	b.Failed += other.Failed
+	b.Pages += other.Pages
+	b.Duration += other.Duration
+}
+
+// Operations of a bulk write request.
+const (
+	BulkCreate = "create"
+	BulkUpdate = "update"
+	BulkDelete = "delete"
+)
+
+// Modes of a bulk write request: atomic applies every operation or none,
+// partial applies the ones that succeed.
+const (
+	BulkAtomic  = "atomic"
+	BulkPartial = "partial"
+)
+
+// BulkOperation is one item of a bulk write. Data maps column names to
+// values: every column to set on create, the changed ones on update.
+type BulkOperation struct {
+	Op   string                 `json:"op"`
+	ID   *uuid.UUID             `json:"id,omitempty"`
+	Data map[string]interface{} `json:"data,omitempty"`
+}
+
+type BulkRequest struct {
+	Mode       string          `json:"mode"`
+	Operations []BulkOperation `json:"operations"`
+}
+
+// BulkItemResult is the outcome of one operation, with the HTTP status it
+// would have had on its own. When an atomic request fails, the operations
+// rolled back with the failed one are 424.
+type BulkItemResult struct {
+	Index  int        `json:"index"`
+	Op     string     `json:"op"`
+	Status int        `json:"status"`
+	ID     *uuid.UUID `json:"id,omitempty"`
+	Errors []string   `json:"errors,omitempty"`
+}
+
+type BulkResult struct {
+	Mode    string           `json:"mode"`
+	Applied int              `json:"applied"`
+	Failed  int              `json:"failed"`
+	Results []BulkItemResult `json:"results"`
+}
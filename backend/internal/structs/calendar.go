@@ -0,0 +1,25 @@
+package structs
+
+import "time"
+
+// Calendar is a set of flights to publish as an iCalendar feed.
+type Calendar struct {
+	Name   string
+	Events []CalendarEvent
+}
+
+// CalendarEvent is a flight placed in the time zones of its airports. Start
+// and End are the best known departure and arrival times in the stored
+// wall-clock form; a nil zone means the airport's zone is unknown and the
+// time is floating.
+type CalendarEvent struct {
+	Flight        Flight
+	Start         *time.Time
+	End           *time.Time
+	StartZone     *time.Location
+	EndZone       *time.Location
+	DepartureName string
+	ArrivalName   string
+	Departure     AirportPosition
+	Arrival       AirportPosition
+}
@@ -0,0 +1,41 @@
+package structs
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Carrier is an airline as the feed names it, completed from the airline
+// table when a row with its codes exists. AirlineID is nil otherwise.
+type Carrier struct {
+	Iata      string     `json:"iata"`
+	Icao      string     `json:"icao"`
+	Name      string     `json:"name"`
+	Country   string     `json:"country,omitempty"`
+	AirlineID *uuid.UUID `json:"airline_id"`
+}
+
+// FlightNumber is one flight number sold or operated by a carrier.
+type FlightNumber struct {
+	FlightIata string  `json:"flight_iata"`
+	FlightIcao string  `json:"flight_icao"`
+	Airline    Carrier `json:"airline"`
+}
+
+// OperatedFlight is one physical flight: the operating leg together with
+// the marketing flight numbers sold on it.
+type OperatedFlight struct {
+	Flight
+	Operator  Carrier        `json:"operating_carrier"`
+	Marketing []FlightNumber `json:"marketing"`
+}
+
+// Codeshares is the codeshare view of a flight on one day.
+type Codeshares struct {
+	FlightDate time.Time      `json:"flight_date"`
+	From       string         `json:"from"`
+	To         string         `json:"to"`
+	Operating  FlightNumber   `json:"operating"`
+	Marketing  []FlightNumber `json:"marketing"`
+}
@@ -0,0 +1,192 @@
+package structs
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeLayouts are the formats upstream dates and times come in, tried in
+// order. Fractional seconds are accepted after the seconds of any of them.
+var timeLayouts = []string{
+	time.RFC3339,                // 2023-04-19T06:25:00+00:00, 2019-05-24T00:00:00.000Z
+	"2006-01-02T15:04:05Z0700",  // 2023-04-19T06:25:00+0000
+	"2006-01-02T15:04:05",       // 2023-04-19T06:25:00, taken as UTC
+	"2006-01-02 15:04:05Z07:00", // 2023-04-19 06:25:00+00:00
+	"2006-01-02 15:04:05Z0700",  // 2023-04-19 06:25:00+0000
+	"2006-01-02 15:04:05-07",    // 2023-04-19 06:25:00+00, as Postgres prints it
+	"2006-01-02 15:04:05",       // 2023-04-19 06:25:00, taken as UTC
+	"2006-01-02T15:04Z07:00",    // 2023-04-19T06:25+00:00
+	"2006-01-02T15:04",          // 2023-04-19T06:25, taken as UTC
+	"2006-01-02",                // 2023-04-19
+	"20060102",                  // 20230419
+	"2006/01/02",                // 2023/04/19
+}
+
+// ParseTime parses an upstream date or time in any of the known formats.
+// Empty strings and the "0000-00-00" placeholder are NULL: the zero time.
+func ParseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.HasPrefix(s, "0000-00-00") {
+		return time.Time{}, nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unsupported date or time %q", s)
+}
+
+// parseJSONTime reads a JSON date or time: null, a string ParseTime accepts,
+// or a number of seconds since the Unix epoch.
+func parseJSONTime(data []byte) (time.Time, error) {
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) == 0 || bytes.Equal(data, []byte("null")):
+		return time.Time{}, nil
+	case data[0] == '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return time.Time{}, err
+		}
+		return ParseTime(s)
+	}
+	secs, err := strconv.ParseFloat(string(data), 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unsupported date or time %s", data)
+	}
+	if secs == 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(0, int64(secs*float64(time.Second))).UTC(), nil
+}
+
+// scanTime reads a date or time column.
+func scanTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case nil:
+		return time.Time{}, nil
+	case time.Time:
+		return v, nil
+	case []byte:
+		return ParseTime(string(v))
+	case string:
+		return ParseTime(v)
+	default:
+		return time.Time{}, fmt.Errorf("unsupported Scan value for a date or time: %T", value)
+	}
+}
+
+// Date is a calendar day without a time of day or zone, such as the delivery
+// date of an airplane. It is kept as midnight UTC; the zero value is NULL.
+type Date struct {
+	time.Time
+}
+
+// NewDate returns the day of t in its own zone.
+func NewDate(t time.Time) Date {
+	if t.IsZero() {
+		return Date{}
+	}
+	return Date{time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)}
+}
+
+func (d Date) String() string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.Format("2006-01-02")
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	if d.IsZero() {
+		return []byte("null"), nil
+	}
+	return []byte(`"` + d.String() + `"`), nil
+}
+
+// UnmarshalJSON accepts the formats of ParseTime, keeping only the day of
+// values that carry a time.
+func (d *Date) UnmarshalJSON(data []byte) error {
+	t, err := parseJSONTime(data)
+	if err != nil {
+		return err
+	}
+	*d = NewDate(t)
+	return nil
+}
+
+func (d Date) Value() (driver.Value, error) {
+	if d.IsZero() {
+		return nil, nil
+	}
+	return d.Time, nil
+}
+
+func (d *Date) Scan(value interface{}) error {
+	t, err := scanTime(value)
+	if err != nil {
+		return err
+	}
+	*d = NewDate(t)
+	return nil
+}
+
+// Instant is a point in time, such as a scheduled departure, with the zone
+// it was given in. Postgres keeps the instant but not the zone, so values
+// read back are in UTC. The zero value is NULL.
+type Instant struct {
+	time.Time
+}
+
+func NewInstant(t time.Time) Instant {
+	return Instant{t}
+}
+
+func (i Instant) String() string {
+	if i.IsZero() {
+		return ""
+	}
+	return i.Format(time.RFC3339Nano)
+}
+
+func (i Instant) MarshalJSON() ([]byte, error) {
+	if i.IsZero() {
+		return []byte("null"), nil
+	}
+	return []byte(`"` + i.String() + `"`), nil
+}
+
+// UnmarshalJSON accepts the formats of ParseTime; values without a zone are
+// taken as UTC.
+func (i *Instant) UnmarshalJSON(data []byte) error {
+	t, err := parseJSONTime(data)
+	if err != nil {
+		return err
+	}
+	*i = Instant{t}
+	return nil
+}
+
+// Value is the instant in UTC, so that it is stored the same in timestamp
+// columns, which drop the zone, and timestamptz ones.
+func (i Instant) Value() (driver.Value, error) {
+	if i.IsZero() {
+		return nil, nil
+	}
+	return i.UTC(), nil
+}
+
+func (i *Instant) Scan(value interface{}) error {
+	t, err := scanTime(value)
+	if err != nil {
+		return err
+	}
+	*i = Instant{t}
+	return nil
+}
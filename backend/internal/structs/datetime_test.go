@@ -0,0 +1,126 @@
+package structs
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	utc := func(year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"", time.Time{}},
+		{"  ", time.Time{}},
+		{"0000-00-00", time.Time{}},
+		{"0000-00-00 00:00:00", time.Time{}},
+		{"2023-04-19T06:25:00+00:00", utc(2023, 4, 19, 6, 25)},
+		{"2023-04-19T07:25:00+01:00", utc(2023, 4, 19, 6, 25)},
+		{"2019-05-24T00:00:00.000Z", utc(2019, 5, 24, 0, 0)},
+		{"2023-04-19T06:25:00+0000", utc(2023, 4, 19, 6, 25)},
+		{"2023-04-19T06:25:00", utc(2023, 4, 19, 6, 25)},
+		{"2023-04-19T06:25:00.5", utc(2023, 4, 19, 6, 25).Add(500 * time.Millisecond)},
+		{"2023-04-19 06:25:00+00:00", utc(2023, 4, 19, 6, 25)},
+		{"2023-04-19 06:25:00+0000", utc(2023, 4, 19, 6, 25)},
+		{"2023-04-19 06:25:00+00", utc(2023, 4, 19, 6, 25)},
+		{"2023-04-19 06:25:00.123456+00", utc(2023, 4, 19, 6, 25).Add(123456 * time.Microsecond)},
+		{"2023-04-19 06:25:00", utc(2023, 4, 19, 6, 25)},
+		{"2023-04-19T06:25+00:00", utc(2023, 4, 19, 6, 25)},
+		{"2023-04-19T06:25", utc(2023, 4, 19, 6, 25)},
+		{"2023-04-19", utc(2023, 4, 19, 0, 0)},
+		{"20230419", utc(2023, 4, 19, 0, 0)},
+		{"2023/04/19", utc(2023, 4, 19, 0, 0)},
+		{" 2023-04-19 ", utc(2023, 4, 19, 0, 0)},
+	}
+	for _, tt := range tests {
+		got, err := ParseTime(tt.in)
+		if err != nil {
+			t.Errorf("ParseTime(%q): %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseTime(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseTimeInvalid(t *testing.T) {
+	for _, in := range []string{"yesterday", "19/04/2023", "2023-13-01", "2023-04-19T25:00"} {
+		if got, err := ParseTime(in); err == nil {
+			t.Errorf("ParseTime(%q) = %s, want an error", in, got)
+		}
+	}
+}
+
+func TestDateJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`null`, `null`},
+		{`""`, `null`},
+		{`"0000-00-00"`, `null`},
+		{`"2023-04-19"`, `"2023-04-19"`},
+		{`"2023-04-19T23:30:00-02:00"`, `"2023-04-19"`},
+		{`1681885500`, `"2023-04-19"`},
+		{`0`, `null`},
+	}
+	for _, tt := range tests {
+		var d Date
+		if err := json.Unmarshal([]byte(tt.in), &d); err != nil {
+			t.Errorf("Date %s: %v", tt.in, err)
+			continue
+		}
+		got, err := json.Marshal(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("Date %s = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestInstantJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`null`, `null`},
+		{`"2023-04-19T06:25:00+01:00"`, `"2023-04-19T06:25:00+01:00"`},
+		{`"2023-04-19 06:25:00"`, `"2023-04-19T06:25:00Z"`},
+		{`1681885500`, `"2023-04-19T06:25:00Z"`},
+	}
+	for _, tt := range tests {
+		var i Instant
+		if err := json.Unmarshal([]byte(tt.in), &i); err != nil {
+			t.Errorf("Instant %s: %v", tt.in, err)
+			continue
+		}
+		got, err := json.Marshal(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("Instant %s = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestInstantValue(t *testing.T) {
+	lisbon := time.FixedZone("WEST", 3600)
+	v, err := NewInstant(time.Date(2023, 4, 19, 7, 25, 0, 0, lisbon)).Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := v.(time.Time); got.Location() != time.UTC || !got.Equal(time.Date(2023, 4, 19, 6, 25, 0, 0, time.UTC)) {
+		t.Errorf("Value = %s, want 2023-04-19 06:25 UTC", got)
+	}
+	if v, _ := (Instant{}).Value(); v != nil {
+		t.Errorf("Value of the zero Instant = %v, want nil", v)
+	}
+}
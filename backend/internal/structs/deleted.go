@@ -0,0 +1,17 @@
+package structs
+
+import "context"
+
+type includeDeletedKey struct{}
+
+// WithDeleted makes the reads of reference data done with ctx return soft
+// deleted rows too, with their DeletedAt set.
+func WithDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, includeDeletedKey{}, true)
+}
+
+// IncludeDeleted reports whether ctx went through WithDeleted.
+func IncludeDeleted(ctx context.Context) bool {
+	include, _ := ctx.Value(includeDeletedKey{}).(bool)
+	return include
+}
@@ -0,0 +1,43 @@
+package structs
+
+import "time"
+
+// EmissionEstimate is the CO2 of one flight of Aircraft over DistanceKm, the
+// great-circle distance plus the ICAO routing allowance. AircraftAssumed is
+// set when the aircraft type was not known and the default type was used.
+type EmissionEstimate struct {
+	Aircraft          string  `json:"aircraft"`
+	AircraftName      string  `json:"aircraft_name"`
+	AircraftAssumed   bool    `json:"aircraft_assumed"`
+	DistanceKm        float64 `json:"distance_km"`
+	FuelKg            float64 `json:"fuel_kg"`
+	Co2Kg             float64 `json:"co2_kg"`
+	Seats             int     `json:"seats"`
+	LoadFactor        float64 `json:"load_factor"`
+	Co2PerPassengerKg float64 `json:"co2_per_passenger_kg"`
+}
+
+type RouteEmissions struct {
+	From      string             `json:"from"`
+	To        string             `json:"to"`
+	Estimates []EmissionEstimate `json:"estimates"`
+}
+
+type FlightEmissions struct {
+	FlightIata  string           `json:"flight_iata"`
+	FlightDate  time.Time        `json:"flight_date"`
+	AirlineIata string           `json:"airline_iata"`
+	From        string           `json:"from"`
+	To          string           `json:"to"`
+	Estimate    EmissionEstimate `json:"estimate"`
+}
+
+// AirlineEmissions sums the flights of an airline that could be estimated;
+// Unestimated counts those whose airports have no coordinates.
+type AirlineEmissions struct {
+	AirlineIata string            `json:"airline_iata"`
+	Flights     int               `json:"flights"`
+	Unestimated int               `json:"unestimated"`
+	Co2Kg       float64           `json:"co2_kg"`
+	Estimates   []FlightEmissions `json:"estimates"`
+}
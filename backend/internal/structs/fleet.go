@@ -0,0 +1,46 @@
+package structs
+
+import "github.com/google/uuid"
+
+// FleetShare counts the airplanes of a fleet sharing one value of a column
+// (model_name, engines_type, plane_status).
+type FleetShare struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// FleetAgeBucket counts airplanes whose age in years is in [From, To). To is
+// nil for the open-ended last bucket.
+type FleetAgeBucket struct {
+	From  int  `json:"from"`
+	To    *int `json:"to"`
+	Count int  `json:"count"`
+}
+
+// AirlineFleet compares what an airline reports about its fleet with the
+// airplane rows matched to it by IATA or ICAO code.
+type AirlineFleet struct {
+	AirlineID          uuid.UUID        `json:"airline_id"`
+	AirlineName        string           `json:"airline_name"`
+	IataCode           string           `json:"iata_code"`
+	IcaoCode           string           `json:"icao_code"`
+	ReportedFleetSize  int              `json:"reported_fleet_size"`
+	ObservedFleetSize  int              `json:"observed_fleet_size"`
+	ReportedAverageAge float64          `json:"reported_average_age"`
+	ObservedAverageAge *float64         `json:"observed_average_age"`
+	UnknownAge         int              `json:"unknown_age"`
+	AgeDistribution    []FleetAgeBucket `json:"age_distribution"`
+	Models             []FleetShare     `json:"models"`
+	EngineTypes        []FleetShare     `json:"engine_types"`
+	Statuses           []FleetShare     `json:"statuses"`
+}
+
+type FleetLeaderboardEntry struct {
+	AirlineID          uuid.UUID `json:"airline_id"`
+	AirlineName        string    `json:"airline_name"`
+	IataCode           string    `json:"iata_code"`
+	ReportedFleetSize  int       `json:"reported_fleet_size"`
+	ObservedFleetSize  int       `json:"observed_fleet_size"`
+	ReportedAverageAge float64   `json:"reported_average_age"`
+	ObservedAverageAge *float64  `json:"observed_average_age"`
+}
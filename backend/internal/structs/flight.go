@@ -0,0 +1,253 @@
+package structs
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type FlightApiData struct {
+	Data []LiveFlights `json:"data"`
+}
+
+// FlightEndpoint is the departure or arrival side of a flight. Delay is in
+// minutes; times are nil when the provider has not published them.
+type FlightEndpoint struct {
+	Iata      string     `json:"iata"`
+	Icao      string     `json:"icao"`
+	Airport   string     `json:"airport"`
+	Timezone  string     `json:"timezone"`
+	Terminal  string     `json:"terminal"`
+	Gate      string     `json:"gate"`
+	Baggage   string     `json:"baggage,omitempty"`
+	Delay     *int       `json:"delay"`
+	Scheduled *time.Time `json:"scheduled"`
+	Estimated *time.Time `json:"estimated"`
+	Actual    *time.Time `json:"actual"`
+}
+
+// FlightCodeshare is set on marketing legs and names the operating flight.
+type FlightCodeshare struct {
+	AirlineIata string `json:"airline_iata"`
+	AirlineIcao string `json:"airline_icao"`
+	FlightIata  string `json:"flight_iata"`
+	FlightIcao  string `json:"flight_icao"`
+}
+
+// FlightAircraft is the aircraft the feed reports for a leg.
+type FlightAircraft struct {
+	Registration string `json:"registration"`
+	Iata         string `json:"iata"`
+	Icao         string `json:"icao"`
+}
+
+// Flight is one leg observed in the upstream flights feed, keyed by date,
+// flight IATA number and departure airport.
+type Flight struct {
+	ID           uuid.UUID        `json:"id"`
+	FlightDate   time.Time        `json:"flight_date"`
+	FlightNumber string           `json:"flight_number"`
+	FlightIata   string           `json:"flight_iata"`
+	FlightIcao   string           `json:"flight_icao"`
+	Status       FlightStatus     `json:"status"`
+	AirlineName  string           `json:"airline_name"`
+	AirlineIata  string           `json:"airline_iata"`
+	AirlineIcao  string           `json:"airline_icao"`
+	Departure    FlightEndpoint   `json:"departure"`
+	Arrival      FlightEndpoint   `json:"arrival"`
+	Codeshare    *FlightCodeshare `json:"codeshare"`
+	Aircraft     *FlightAircraft  `json:"aircraft"`
+	Sequence     int              `json:"sequence"`
+	FirstSeen    time.Time        `json:"first_seen"`
+	LastSeen     time.Time        `json:"last_seen"`
+}
+
+// FlightIngestion summarises one batch of observed flights.
+type FlightIngestion struct {
+	Observed int            `json:"observed"`
+	Skipped  int            `json:"skipped"`
+	Inserted int            `json:"inserted"`
+	Updated  int            `json:"updated"`
+	Routes   int            `json:"routes"`
+	Changes  []FlightChange `json:"-"`
+}
+
+func (f *FlightIngestion) Add(other FlightIngestion) {
+	f.Observed += other.Observed
+	f.Skipped += other.Skipped
+	f.Inserted += other.Inserted
+	f.Updated += other.Updated
+	f.Routes += other.Routes
+	f.Changes = append(f.Changes, other.Changes...)
+}
+
+// FlightChange is a re-observed flight whose status, a gate or a delay
+// differs from what was stored.
+type FlightChange struct {
+	Flight  Flight        `json:"flight"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// FieldChange is one changed field; Old is nil when it was not known.
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// ToFlight flattens the provider payload. The loosely typed fields (gate,
+// delay, actual times, ...) are converted here so nothing downstream has to
+// deal with interface{}.
+func (f LiveFlights) ToFlight() Flight {
+	flight := Flight{
+		FlightNumber: f.Flight.Number,
+		FlightIata:   f.Flight.Iata,
+		FlightIcao:   f.Flight.Icao,
+		Status:       f.FlightStatus,
+		AirlineName:  f.Airline.Name,
+		AirlineIata:  f.Airline.Iata,
+		AirlineIcao:  f.Airline.Icao,
+		Departure: FlightEndpoint{
+			Iata:      f.Departure.Iata,
+			Icao:      f.Departure.Icao,
+			Airport:   f.Departure.Airport,
+			Timezone:  f.Departure.Timezone,
+			Terminal:  f.Departure.Terminal,
+			Gate:      looseString(f.Departure.Gate),
+			Delay:     looseMinutes(f.Departure.Delay),
+			Scheduled: optionalTime(f.Departure.Scheduled),
+			Estimated: optionalTime(f.Departure.Estimated),
+			Actual:    looseTime(f.Departure.Actual),
+		},
+		Arrival: FlightEndpoint{
+			Iata:      f.Arrival.Iata,
+			Icao:      f.Arrival.Icao,
+			Airport:   f.Arrival.Airport,
+			Timezone:  f.Arrival.Timezone,
+			Terminal:  looseString(f.Arrival.Terminal),
+			Gate:      looseString(f.Arrival.Gate),
+			Baggage:   looseString(f.Arrival.Baggage),
+			Delay:     looseMinutes(f.Arrival.Delay),
+			Scheduled: optionalTime(f.Arrival.Scheduled),
+			Estimated: optionalTime(f.Arrival.Estimated),
+			Actual:    looseTime(f.Arrival.Actual),
+		},
+	}
+	if date, err := time.Parse("2006-01-02", f.FlightDate); err == nil {
+		flight.FlightDate = date
+	}
+	if c := f.Flight.Codeshared; c.FlightIata != "" {
+		flight.Codeshare = &FlightCodeshare{
+			AirlineIata: strings.ToUpper(c.AirlineIata),
+			AirlineIcao: strings.ToUpper(c.AirlineIcao),
+			FlightIata:  strings.ToUpper(c.FlightIata),
+			FlightIcao:  strings.ToUpper(c.FlightIcao),
+		}
+	}
+	if a, ok := f.Aircraft.(map[string]interface{}); ok {
+		aircraft := FlightAircraft{
+			Registration: strings.ToUpper(looseString(a["registration"])),
+			Iata:         strings.ToUpper(looseString(a["iata"])),
+			Icao:         strings.ToUpper(looseString(a["icao"])),
+		}
+		if aircraft != (FlightAircraft{}) {
+			flight.Aircraft = &aircraft
+		}
+	}
+	return flight
+}
+
+func optionalTime(t Instant) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t.Time
+}
+
+func looseString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+func looseMinutes(v interface{}) *int {
+	var minutes int
+	switch v := v.(type) {
+	case float64:
+		minutes = int(v)
+	case string:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil
+		}
+		minutes = n
+	default:
+		return nil
+	}
+	return &minutes
+}
+
+func looseTime(v interface{}) *time.Time {
+	s, ok := v.(string)
+	if !ok {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// Route is a directed edge of the route network for one operating airline.
+type Route struct {
+	DepIata     string    `json:"dep_iata"`
+	ArrIata     string    `json:"arr_iata"`
+	AirlineIata string    `json:"airline_iata"`
+	Flights     int       `json:"flights"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+type Destination struct {
+	Iata     string   `json:"iata"`
+	Flights  int      `json:"flights"`
+	Airlines []string `json:"airlines"`
+}
+
+type Hub struct {
+	Iata        string  `json:"iata"`
+	InDegree    int     `json:"in_degree"`
+	OutDegree   int     `json:"out_degree"`
+	Degree      int     `json:"degree"`
+	Flights     int     `json:"flights"`
+	Betweenness float64 `json:"betweenness"`
+}
+
+type DirectRoute struct {
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	Direct   bool     `json:"direct"`
+	Flights  int      `json:"flights"`
+	Airlines []string `json:"airlines"`
+}
+
+// FlightFilter selects stored flights; zero fields match everything. With
+// Until set, Date is the first day of a range rather than a single day.
+type FlightFilter struct {
+	Date          time.Time
+	Until         time.Time
+	FlightIata    string
+	AirlineIata   string
+	DepIata       string
+	ArrIata       string
+	OperatingIata string
+	OperatedOnly  bool
+	Limit         int
+}
@@ -0,0 +1,46 @@
+package structs
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Operations recorded in the history of a reference row. A snapshot is the
+// version a row had when history started to be recorded.
+const (
+	HistoryCreate   = "create"
+	HistoryUpdate   = "update"
+	HistoryDelete   = "delete"
+	HistoryRestore  = "restore"
+	HistoryPurge    = "purge"
+	HistorySnapshot = "snapshot"
+)
+
+// HistoryEntry is one version of a reference row, as stored columns. Before
+// is null on create and snapshot, After on purge.
+type HistoryEntry struct {
+	ID        int64           `json:"id"`
+	Resource  string          `json:"resource"`
+	RowID     uuid.UUID       `json:"row_id"`
+	Operation string          `json:"operation"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+	ChangedAt time.Time       `json:"changed_at"`
+}
+
+type asOfKey struct{}
+
+// WithAsOf makes the single row reads done with ctx return the version the
+// row had at t.
+func WithAsOf(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, asOfKey{}, t)
+}
+
+// AsOf returns the time set by WithAsOf, if any.
+func AsOf(ctx context.Context) (time.Time, bool) {
+	t, ok := ctx.Value(asOfKey{}).(time.Time)
+	return t, ok
+}
@@ -0,0 +1,103 @@
+package structs
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProviderAviationStack is the upstream every dataset is imported from.
+const ProviderAviationStack = "aviationstack"
+
+// Ingestion run states.
+const (
+	IngestionRunning   = "running"
+	IngestionSucceeded = "succeeded"
+	IngestionFailed    = "failed"
+)
+
+// IngestionRun is the audit record of one import of a dataset.
+type IngestionRun struct {
+	ID           uuid.UUID  `json:"id"`
+	Dataset      string     `json:"dataset"`
+	Provider     string     `json:"provider"`
+	DryRun       bool       `json:"dry_run"`
+	Status       string     `json:"status"`
+	StartedAt    time.Time  `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at"`
+	Pages        int        `json:"pages"`
+	RowsFetched  int64      `json:"rows_fetched"`
+	RowsInserted int64      `json:"rows_inserted"`
+	RowsUpdated  int64      `json:"rows_updated"`
+	RowsDeleted  int64      `json:"rows_deleted"`
+	RowsFailed   int64      `json:"rows_failed"`
+	DriftEvents  int        `json:"drift_events"`
+	Errors       []string   `json:"errors"`
+}
+
+// IngestionFilter selects runs; empty fields match every run.
+type IngestionFilter struct {
+	Dataset string
+	DryRun  *bool
+	Limit   int
+}
+
+// IngestionDiff compares a fetched dataset with the table, matching rows on
+// their upstream id. Changes, AddedKeys and MissingKeys are capped; the
+// counts are not. Tombstoned counts the fetched rows that were deleted
+// here and that the import leaves alone.
+type IngestionDiff struct {
+	Added       int64            `json:"added"`
+	Changed     int64            `json:"changed"`
+	Unchanged   int64            `json:"unchanged"`
+	Missing     int64            `json:"missing"`
+	Tombstoned  int64            `json:"tombstoned"`
+	Fields      map[string]int64 `json:"fields"`
+	Changes     []FieldDiff      `json:"changes"`
+	AddedKeys   []string         `json:"added_keys"`
+	MissingKeys []string         `json:"missing_keys"`
+	Truncated   bool             `json:"truncated"`
+}
+
+// FieldDiff is one column of one row that an import would change.
+type FieldDiff struct {
+	Key   string  `json:"key"`
+	Field string  `json:"field"`
+	Old   *string `json:"old"`
+	New   *string `json:"new"`
+}
+
+// Kinds of schema drift.
+const (
+	DriftUnknownField = "unknown_field"
+	DriftTypeChanged  = "type_changed"
+	DriftNewlyNull    = "newly_null"
+)
+
+// FieldSchema is the JSON type an upstream endpoint sends for a field of its
+// data rows: string, number, boolean, object, array, or null when no other
+// value was seen.
+type FieldSchema struct {
+	Field    string `json:"field"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+}
+
+// UpstreamSchema is the expected schema of an upstream endpoint.
+type UpstreamSchema struct {
+	Endpoint  string        `json:"endpoint"`
+	Fields    []FieldSchema `json:"fields"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// SchemaDrift is a field of a fetched payload that no longer matches the
+// expected schema, in Rows data rows. Expected is nil for unknown fields.
+type SchemaDrift struct {
+	RunID    uuid.UUID `json:"run_id"`
+	Endpoint string    `json:"endpoint"`
+	Field    string    `json:"field"`
+	Kind     string    `json:"kind"`
+	Expected *string   `json:"expected"`
+	Observed string    `json:"observed"`
+	Rows     int64     `json:"rows"`
+}
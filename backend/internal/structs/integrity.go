@@ -0,0 +1,23 @@
+package structs
+
+import "github.com/google/uuid"
+
+// OrphanRow is a row whose upstream code did not resolve to a referenced row.
+type OrphanRow struct {
+	ID   uuid.UUID `json:"id"`
+	Code string    `json:"code"`
+}
+
+type RelationshipIntegrity struct {
+	Relationship string      `json:"relationship"`
+	Column       string      `json:"column"`
+	Linked       int         `json:"linked"`
+	Orphaned     int         `json:"orphaned"`
+	Samples      []OrphanRow `json:"samples"`
+}
+
+type IntegrityReport []RelationshipIntegrity
+
+// LinkResult holds how many rows were linked, relinked or unlinked per
+// relationship by one run of the linker.
+type LinkResult map[string]int64
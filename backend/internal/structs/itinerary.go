@@ -0,0 +1,72 @@
+package structs
+
+import (
+	"math"
+	"time"
+)
+
+const earthRadiusKm = 6371.0088
+
+// AirportPosition is what itinerary search needs to know about an airport.
+type AirportPosition struct {
+	Iata      string  `json:"iata"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Timezone  string  `json:"timezone"`
+}
+
+// HasCoordinates reports false for the 0,0 default of airports imported
+// without a position.
+func (p AirportPosition) HasCoordinates() bool {
+	return p.Latitude != 0 || p.Longitude != 0
+}
+
+// DistanceKm is the great-circle (haversine) distance to other, zero when
+// either airport has no coordinates.
+func (p AirportPosition) DistanceKm(other AirportPosition) float64 {
+	if !p.HasCoordinates() || !other.HasCoordinates() {
+		return 0
+	}
+	lat1, lat2 := p.Latitude*math.Pi/180, other.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (other.Longitude - p.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(h))
+}
+
+// ItineraryQuery asks for itineraries leaving From on Date, the local date at
+// the origin airport. Sort is "duration", "stops" or "detour".
+type ItineraryQuery struct {
+	From     string
+	To       string
+	Date     time.Time
+	MaxStops int
+	Sort     string
+	Limit    int
+}
+
+// ItineraryLeg is one flight of an itinerary. Departure and Arrival carry the
+// offset of the airport they refer to; LayoverMinutes is the wait before
+// this leg and zero on the first one.
+type ItineraryLeg struct {
+	FlightIata     string    `json:"flight_iata"`
+	AirlineIata    string    `json:"airline_iata"`
+	From           string    `json:"from"`
+	To             string    `json:"to"`
+	Departure      time.Time `json:"departure"`
+	Arrival        time.Time `json:"arrival"`
+	LayoverMinutes int       `json:"layover_minutes"`
+	DistanceKm     float64   `json:"distance_km"`
+}
+
+// Itinerary is a sequence of connecting legs. Detour is the flown distance
+// over the great-circle distance between origin and destination, nil when
+// an airport has no coordinates.
+type Itinerary struct {
+	Legs            []ItineraryLeg `json:"legs"`
+	Stops           int            `json:"stops"`
+	DurationMinutes int            `json:"duration_minutes"`
+	DistanceKm      float64        `json:"distance_km"`
+	Detour          *float64       `json:"detour"`
+}
@@ -0,0 +1,89 @@
+package structs
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Job states. A queued job runs once run_at has passed; a failed attempt
+// puts it back in the queue until it has used all of its attempts.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Job is a unit of background work. Result is what the job reported on
+// success; jobs that produce a file (exports) also store it as their output.
+type Job struct {
+	ID          uuid.UUID       `json:"id"`
+	Kind        string          `json:"kind"`
+	Key         *string         `json:"key,omitempty"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+	LastError   *string         `json:"last_error"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	OutputType  *string         `json:"output_type,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	StartedAt   *time.Time      `json:"started_at"`
+	FinishedAt  *time.Time      `json:"finished_at"`
+	UpdatedAt   *time.Time      `json:"updated_at"`
+}
+
+// JobOutput describes the file a job produced; its chunks are read with
+// the job service.
+type JobOutput struct {
+	ContentType string
+	Size        int64
+}
+
+// JobFilter selects jobs; empty fields match every job.
+type JobFilter struct {
+	Kind   string
+	Status string
+	Limit  int
+}
+
+// ImportKind is the kind of the jobs that run an ImportJob.
+const ImportKind = "import"
+
+// ImportJob refreshes a reference table from the upstream API. A dry run
+// only records the diff the import would apply.
+type ImportJob struct {
+	Resource string `json:"resource"`
+	DryRun   bool   `json:"dry_run"`
+}
+
+// Key names the work of an import, so the same one is not queued twice.
+func (p ImportJob) Key() string {
+	if p.DryRun {
+		return ImportKind + ":" + p.Resource + ":dry-run"
+	}
+	return ImportKind + ":" + p.Resource
+}
+
+// ExportJob renders a reference table as json, ndjson, csv or protobuf.
+type ExportJob struct {
+	Resource string `json:"resource"`
+	Format   string `json:"format"`
+}
+
+// PurgeJob hard deletes the rows of a reference table, or of every one when
+// Resource is empty, that were soft deleted more than OlderThanDays ago.
+type PurgeJob struct {
+	Resource      string `json:"resource"`
+	OlderThanDays int    `json:"older_than_days"`
+}
+
+// OTPJob rebuilds the daily on-time performance between two dates.
+type OTPJob struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
@@ -22,8 +22,8 @@ type LiveFlights struct {
 		Terminal        string      `json:"terminal"`
 		Gate            interface{} `json:"gate"`
 		Delay           interface{} `json:"delay"`
-		Scheduled       CustomTime  `json:"scheduled"`
-		Estimated       CustomTime  `json:"estimated"`
+		Scheduled       Instant     `json:"scheduled"`
+		Estimated       Instant     `json:"estimated"`
 		Actual          interface{} `json:"actual"`
 		EstimatedRunway interface{} `json:"estimated_runway"`
 		ActualRunway    interface{} `json:"actual_runway"`
@@ -37,8 +37,8 @@ type LiveFlights struct {
 		Gate            interface{} `json:"gate"`
 		Baggage         interface{} `json:"baggage"`
 		Delay           interface{} `json:"delay"`
-		Scheduled       CustomTime  `json:"scheduled"`
-		Estimated       CustomTime  `json:"estimated"`
+		Scheduled       Instant     `json:"scheduled"`
+		Estimated       Instant     `json:"estimated"`
 		Actual          interface{} `json:"actual"`
 		EstimatedRunway interface{} `json:"estimated_runway"`
 		ActualRunway    interface{} `json:"actual_runway"`
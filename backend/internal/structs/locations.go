@@ -18,8 +18,10 @@ type City struct {
 	Longitude   float64    `json:"longitude,string"`
 	CityName    string     `json:"city_name"`
 	Timezone    string     `json:"timezone"`
-	CreatedAt   CustomTime `db:"created_at" json:"created_at"`
+	CountryRef  *uuid.UUID `db:"country_ref" json:"country_ref"`
+	CreatedAt   Instant    `db:"created_at" json:"created_at"`
 	UpdatedAt   *time.Time `db:"updated_at" json:"updated_at"`
+	DeletedAt   *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
 }
 
 type CityInfo struct {
@@ -38,7 +40,7 @@ type CityListResponse []City
 //countries
 
 type Country struct {
-	ID                uuid.UUID  `json:"id, string" pg:"default:gen_random_uuid()"`
+	ID                uuid.UUID  `json:"id" pg:"default:gen_random_uuid()"`
 	CountryName       string     `json:"country_name"`
 	CountryIso2       string     `json:"country_iso2"`
 	CountryIso3       string     `json:"country_iso3"`
@@ -50,16 +52,17 @@ type Country struct {
 	CurrencyCode      string     `json:"currency_code"`
 	FipsCode          string     `json:"fips_code"`
 	PhonePrefix       string     `json:"phone_prefix"`
-	CreatedAt         CustomTime `db:"created_at" json:"created_at"`
+	CreatedAt         Instant    `db:"created_at" json:"created_at"`
 	UpdatedAt         *time.Time `db:"updated_at" json:"updated_at"`
+	DeletedAt         *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
 }
 
 type CountryListResponse []Country
 
 type CountryApiData struct {
-	Data []Country `json:".data"`
+	Data []Country `json:"data"`
 }
 
 type CityApiData struct {
-	Data []City `json:".data"`
+	Data []City `json:"data"`
 }
@@ -0,0 +1,78 @@
+package structs
+
+import "time"
+
+const (
+	// OnTimeMinutes is the A15 standard: a flight is on time when it arrives,
+	// or for airports departs, less than 15 minutes late.
+	OnTimeMinutes = 15
+	// DelayBucketMinutes and DelayBuckets shape OTPDaily.DelayHistogram; the
+	// last bucket holds every delay of DelayBucketMinutes*(DelayBuckets-1)
+	// minutes and more.
+	DelayBucketMinutes = 5
+	DelayBuckets       = 61
+)
+
+// OTPDaily is the on-time performance of one key of a dimension on one day.
+// Delays count late minutes only, early flights count as zero.
+type OTPDaily struct {
+	Dimension      string    `json:"dimension"`
+	Key            string    `json:"key"`
+	FlightDate     time.Time `json:"flight_date"`
+	Flights        int       `json:"flights"`
+	Landed         int       `json:"landed"`
+	OnTime         int       `json:"on_time"`
+	Cancelled      int       `json:"cancelled"`
+	Diverted       int       `json:"diverted"`
+	DelayCount     int       `json:"delay_count"`
+	DelaySum       int64     `json:"delay_sum"`
+	DelayHistogram []int32   `json:"delay_histogram"`
+}
+
+// AddDelay records a delay in minutes; negative delays are early arrivals.
+func (d *OTPDaily) AddDelay(minutes int) {
+	if minutes < 0 {
+		minutes = 0
+	}
+	if len(d.DelayHistogram) != DelayBuckets {
+		d.DelayHistogram = make([]int32, DelayBuckets)
+	}
+	bucket := minutes / DelayBucketMinutes
+	if bucket >= DelayBuckets {
+		bucket = DelayBuckets - 1
+	}
+	d.DelayHistogram[bucket]++
+	d.DelayCount++
+	d.DelaySum += int64(minutes)
+	if minutes < OnTimeMinutes {
+		d.OnTime++
+	}
+}
+
+type OTPFilter struct {
+	Dimension string
+	Key       string
+	From      time.Time
+	To        time.Time
+	Limit     int
+}
+
+// OTPStats is the on-time performance of one key over a date range. OTP is
+// the share of flights with a known delay that were on time; the rates are
+// over the flights that landed, were cancelled or were diverted.
+type OTPStats struct {
+	Dimension        string    `json:"dimension"`
+	Key              string    `json:"key"`
+	From             time.Time `json:"from"`
+	To               time.Time `json:"to"`
+	Flights          int       `json:"flights"`
+	Landed           int       `json:"landed"`
+	Cancelled        int       `json:"cancelled"`
+	Diverted         int       `json:"diverted"`
+	OTPPercent       *float64  `json:"otp_percent"`
+	MeanDelay        *float64  `json:"mean_delay_minutes"`
+	MedianDelay      *float64  `json:"median_delay_minutes"`
+	P90Delay         *float64  `json:"p90_delay_minutes"`
+	CancellationRate *float64  `json:"cancellation_rate"`
+	DiversionRate    *float64  `json:"diversion_rate"`
+}
@@ -0,0 +1,21 @@
+package structs
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ChangeEvent is published to the outbox sinks when a reference row is
+// created, updated, deleted, restored or purged. Fields are the columns the
+// change set. Consumers may see an event more than once, never out of order
+// for one entity.
+type ChangeEvent struct {
+	ID         int64     `json:"id"`
+	Entity     string    `json:"entity"`
+	EntityID   uuid.UUID `json:"entity_id"`
+	Operation  string    `json:"operation"`
+	Fields     []string  `json:"fields"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Attempts   int       `json:"-"`
+}
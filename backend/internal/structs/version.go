@@ -0,0 +1,22 @@
+package structs
+
+import (
+	"strconv"
+	"time"
+)
+
+// TableVersion changes whenever a row of the tables it covers is inserted,
+// updated or deleted, so list responses can be validated without reading
+// the rows themselves.
+type TableVersion struct {
+	Rows         int64
+	LastModified time.Time
+}
+
+// ETag is a weak validator: two versions with the same row count and latest
+// modification serve equivalent, not byte identical, bodies. variant tells
+// apart the representations of the same rows (json, csv, ...).
+func (v TableVersion) ETag(variant string) string {
+	return `W/"` + strconv.FormatInt(v.Rows, 36) + "-" +
+		strconv.FormatInt(v.LastModified.UnixNano(), 36) + "-" + variant + `"`
+}
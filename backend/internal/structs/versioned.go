@@ -0,0 +1,24 @@
+package structs
+
+import "time"
+
+func lastModified(created Instant, updated *time.Time) time.Time {
+	if updated != nil && updated.After(created.Time) {
+		return *updated
+	}
+	return created.Time
+}
+
+func (t Tax) LastModified() time.Time { return lastModified(t.CreatedAt, t.UpdatedAt) }
+
+func (a Aircraft) LastModified() time.Time { return lastModified(a.CreatedAt, a.UpdatedAt) }
+
+func (a Airline) LastModified() time.Time { return lastModified(a.CreatedAt, a.UpdatedAt) }
+
+func (a Airplane) LastModified() time.Time { return lastModified(a.CreatedAt, a.UpdatedAt) }
+
+func (a Airport) LastModified() time.Time { return lastModified(a.CreatedAt, a.UpdatedAt) }
+
+func (c City) LastModified() time.Time { return lastModified(c.CreatedAt, c.UpdatedAt) }
+
+func (c Country) LastModified() time.Time { return lastModified(c.CreatedAt, c.UpdatedAt) }
@@ -0,0 +1,112 @@
+package structs
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Watch kinds and the form of their value.
+const (
+	WatchFlight  = "flight"  // flight IATA number, TP1350
+	WatchRoute   = "route"   // departure and arrival IATA codes, LIS-LHR
+	WatchAirport = "airport" // airport IATA code, either end of the flight
+)
+
+// Webhook delivery states.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// EventFlightChanged is the event of deliveries queued for flight changes.
+const EventFlightChanged = "flight.changed"
+
+type User struct {
+	ID        uuid.UUID `json:"id"`
+	Email     string    `json:"email"`
+	Admin     bool      `json:"admin"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Watchlist struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Name      string     `json:"name"`
+	Watches   []Watch    `json:"watches"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+type Watch struct {
+	ID        uuid.UUID `json:"id"`
+	Kind      string    `json:"kind"`
+	Value     string    `json:"value"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Webhook is a URL flight changes are posted to. Secret signs the payloads
+// and is only returned when the webhook is created.
+type Webhook struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	URL       string     `json:"url"`
+	Secret    string     `json:"secret,omitempty"`
+	Active    bool       `json:"active"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+// WebhookMatch pairs a flight change with an active webhook of a user
+// watching it.
+type WebhookMatch struct {
+	WebhookID   uuid.UUID
+	WatchlistID uuid.UUID
+	Kind        string
+	Value       string
+}
+
+// Notifier makes the webhook deliveries of the flight changes found while
+// flights are recorded; they are queued in the same transaction. match
+// returns the active webhooks watching any of the kind/value pairs.
+type Notifier func(changes []FlightChange, match func(kinds []string, values []string) ([]WebhookMatch, error)) ([]WebhookDelivery, error)
+
+type WebhookDelivery struct {
+	ID             uuid.UUID        `json:"id"`
+	WebhookID      uuid.UUID        `json:"webhook_id"`
+	Event          string           `json:"event"`
+	Payload        json.RawMessage  `json:"payload"`
+	Status         string           `json:"status"`
+	Attempts       int              `json:"attempts"`
+	NextAttemptAt  time.Time        `json:"next_attempt_at"`
+	LastStatusCode *int             `json:"last_status_code"`
+	LastError      *string          `json:"last_error"`
+	CreatedAt      time.Time        `json:"created_at"`
+	DeliveredAt    *time.Time       `json:"delivered_at"`
+	Log            []WebhookAttempt `json:"log,omitempty"`
+}
+
+// DueDelivery is a claimed delivery with where and how to send it.
+type DueDelivery struct {
+	WebhookDelivery
+	URL    string
+	Secret string
+}
+
+// WebhookAttempt is one try at sending a delivery.
+type WebhookAttempt struct {
+	Attempt     int       `json:"attempt"`
+	StatusCode  *int      `json:"status_code"`
+	Error       string    `json:"error,omitempty"`
+	DurationMs  int       `json:"duration_ms"`
+	AttemptedAt time.Time `json:"attempted_at"`
+}
+
+// DeliveryFilter selects the deliveries of a webhook; an empty Status
+// matches every state.
+type DeliveryFilter struct {
+	Status string
+	Limit  int
+}
@@ -1,7 +1,7 @@
 package utils
 
 import (
-	"crypto/sha256"
+	"crypto/rand"
 	"encoding/hex"
 	"github.com/google/uuid"
 	"os"
@@ -41,18 +41,13 @@ func GenerateNewJWTAccessToken(credentials []string, id uuid.UUID) (string, erro
 
 // GenerateNewJWTRefreshToken func for generate a new JWT refresh (public) token.
 func GenerateNewJWTRefreshToken() (string, error) {
-	// Create a new SHA256 hash.
-	hash := sha256.New()
-
-	// Create a new now date and time string with salt.
-	refresh := os.Getenv("JWT_REFRESH_KEY") + time.Now().String()
-
-	// See: https://pkg.go.dev/io#Writer.Write
-	_, err := hash.Write([]byte(refresh))
-	if err != nil {
+	// 32 random bytes: a refresh token must not be guessable from the time
+	// it was issued at.
+	refresh := make([]byte, 32)
+	if _, err := rand.Read(refresh); err != nil {
 		// Return error, it refresh token generation failed.
 		return "", err
 	}
 
-	return hex.EncodeToString(hash.Sum(nil)), nil
+	return hex.EncodeToString(refresh), nil
 }
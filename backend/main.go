@@ -2,12 +2,19 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
 
 	"github.com/FACorreiaa/aviatoon-tracker/configs"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/handler"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/dispatcher"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/external_api"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/poller"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/pprof"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/prometheus"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/relay"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/handler/worker"
 
 	"os"
 	"os/signal"
@@ -16,8 +23,19 @@ import (
 
 	"github.com/FACorreiaa/aviatoon-tracker/internal/repository"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/bulk"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/repository/postgres/migrations"
 	"github.com/FACorreiaa/aviatoon-tracker/internal/service"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/batch"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/cache"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/emissions"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/ingestion"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/itinerary"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/job"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/outbox"
+	"github.com/FACorreiaa/aviatoon-tracker/internal/service/watch"
 	"github.com/FACorreiaa/aviatoon-tracker/pkg/logs"
+	"github.com/FACorreiaa/aviatoon-tracker/schema"
 	"github.com/joho/godotenv"
 )
 
@@ -39,22 +57,68 @@ func main() {
 	}
 	logs.DefaultLogger.Info("Dotenv file was successfully loaded")
 
+	postgresConfig := postgres.NewConfig(
+		config.Repositories.Postgres.Host,
+		config.Repositories.Postgres.Port,
+		config.Repositories.Postgres.Username,
+		os.Getenv("POSTGRES_PASSWORD"),
+		config.Repositories.Postgres.DB,
+		config.Repositories.Postgres.SSLMODE,
+		10*time.Second,
+		postgres.CacheStatement,
+	)
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrations(postgresConfig, os.Args[2:])
+		return
+	}
+
 	repositories := repository.NewRepository(
 		repository.NewConfig(
-			postgres.NewConfig(
-				config.Repositories.Postgres.Host,
-				config.Repositories.Postgres.Port,
-				config.Repositories.Postgres.Username,
-				os.Getenv("POSTGRES_PASSWORD"),
-				config.Repositories.Postgres.DB,
-				config.Repositories.Postgres.SSLMODE,
-				10*time.Second,
-				postgres.CacheStatement,
-			),
+			postgresConfig,
+			migrations.NewConfig(config.Repositories.Postgres.AutoMigrate),
+			bulk.NewConfig(config.Repositories.Postgres.BatchSize),
 		),
 	)
 	logs.DefaultLogger.Info("Repository was initialized")
-	services := service.NewService(repositories)
+	services := service.NewService(
+		repositories,
+		service.NewConfig(
+			cache.NewConfig(
+				time.Duration(config.Services.Cache.TTL)*time.Second,
+				config.Services.Cache.MaxEntries,
+			),
+			itinerary.NewConfig(
+				time.Duration(config.Services.Itinerary.MinConnection)*time.Minute,
+				minutes(config.Services.Itinerary.MinConnections),
+				time.Duration(config.Services.Itinerary.MaxLayover)*time.Minute,
+			),
+			emissions.NewConfig(
+				config.Services.Emissions.FuelBurnFile,
+				config.Services.Emissions.LoadFactor,
+				config.Services.Emissions.DefaultAircraft,
+			),
+			watch.NewConfig(
+				config.Services.Webhooks.MaxAttempts,
+				time.Duration(config.Services.Webhooks.Backoff)*time.Second,
+				time.Duration(config.Services.Webhooks.MaxBackoff)*time.Second,
+				time.Duration(config.Services.Webhooks.Timeout)*time.Second,
+			),
+			job.NewConfig(
+				config.Services.Jobs.MaxAttempts,
+				time.Duration(config.Services.Jobs.Backoff)*time.Second,
+				time.Duration(config.Services.Jobs.MaxBackoff)*time.Second,
+			),
+			ingestion.NewConfig(config.Services.Ingestion.StrictSchema),
+			outbox.NewConfig(
+				time.Duration(config.Services.Outbox.Backoff)*time.Second,
+				time.Duration(config.Services.Outbox.MaxBackoff)*time.Second,
+				time.Duration(config.Services.Outbox.Timeout)*time.Second,
+				outboxSinks(config),
+			),
+			batch.NewConfig(config.Services.Bulk.MaxOperations),
+		),
+	)
 	logs.DefaultLogger.Info("Service was initialized")
 	handlers := handler.NewHandler(
 		handler.NewConfig(
@@ -76,6 +140,24 @@ func main() {
 				config.Handlers.Prometheus.CertFile,
 				config.Handlers.Prometheus.EnableTLS,
 			),
+			poller.NewConfig(
+				time.Duration(config.Handlers.Poller.Interval)*time.Second,
+				config.Handlers.Poller.Pages,
+				config.Handlers.Poller.Limit,
+			),
+			dispatcher.NewConfig(
+				time.Duration(config.Handlers.Dispatcher.Interval)*time.Second,
+				config.Handlers.Dispatcher.Batch,
+			),
+			worker.NewConfig(
+				config.Handlers.Jobs.Workers,
+				time.Duration(config.Handlers.Jobs.Interval)*time.Second,
+				time.Duration(config.Handlers.Jobs.Lease)*time.Second,
+			),
+			relay.NewConfig(
+				time.Duration(config.Handlers.Relay.Interval)*time.Second,
+				config.Handlers.Relay.Batch,
+			),
 		),
 		services,
 	)
@@ -91,10 +173,75 @@ func main() {
 	logs.DefaultLogger.Info("Handler was successfully started")
 	exitSignal = <-quit
 	logs.DefaultLogger.Info("Exit...")
-	handlers.Shutdown(context.Background())
+	drain := time.Duration(config.Handlers.ShutdownTimeout) * time.Second
+	if drain <= 0 {
+		drain = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), drain)
+	defer cancel()
+	handlers.Shutdown(ctx)
 	logs.DefaultLogger.Info("Handlers are shutdown")
 }
 
+// runMigrations implements `migrate up [N] | down [N] | status | force <version>`
+// against the migrations embedded in the binary.
+func runMigrations(config postgres.Config, args []string) {
+	psql := postgres.NewPostgres(config)
+	defer psql.GetDB().Close()
+
+	m, err := migrations.NewMigrator(psql.GetDB(), schema.Migrations)
+	if err != nil {
+		logs.DefaultLogger.WithError(err).Fatal("Migrations could not be loaded")
+	}
+
+	if len(args) == 0 {
+		logs.DefaultLogger.Fatal("Usage: migrate up [N] | down [N] | status | force <version>")
+	}
+
+	steps := 0
+	if len(args) > 1 {
+		steps, err = strconv.Atoi(args[1])
+		if err != nil || steps < 0 {
+			logs.DefaultLogger.Fatal("Invalid migration argument " + args[1])
+		}
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		err = m.Up(ctx, steps)
+		if errors.Is(err, migrations.ErrNoChange) {
+			logs.DefaultLogger.Info("Database schema is up to date")
+			err = nil
+		}
+	case "down":
+		err = m.Down(ctx, steps)
+	case "force":
+		if len(args) != 2 {
+			logs.DefaultLogger.Fatal("Usage: migrate force <version>")
+		}
+		err = m.Force(ctx, uint(steps))
+	case "status":
+		var status migrations.Status
+		status, err = m.Status(ctx)
+		if err == nil {
+			fmt.Printf("version: %d (latest %d), dirty: %t\n", status.Version, status.Latest, status.Dirty)
+			for _, migration := range status.Applied {
+				fmt.Printf("  applied  %06d_%s\n", migration.Version, migration.Name)
+			}
+			for _, migration := range status.Pending {
+				fmt.Printf("  pending  %06d_%s\n", migration.Version, migration.Name)
+			}
+		}
+	default:
+		logs.DefaultLogger.Fatal("Unknown migrate command " + args[0])
+	}
+
+	if err != nil {
+		logs.DefaultLogger.WithError(err).Fatal("Migration failed")
+	}
+}
+
 // func getHandlerMode(mode string) handler.Mode {
 // 	switch mode {
 // 	case "prod":
@@ -109,6 +256,36 @@ func main() {
 // 	}
 // }
 
+// outboxSinks builds the sinks change events are published to, one for
+// every one configured.
+func outboxSinks(config configs.Config) []outbox.Sink {
+	timeout := time.Duration(config.Services.Outbox.Timeout) * time.Second
+	var sinks []outbox.Sink
+	if url := config.Services.Outbox.Webhook.URL; url != "" {
+		sinks = append(sinks, outbox.NewWebhookSink(url, os.Getenv("OUTBOX_WEBHOOK_SECRET"), timeout))
+	}
+	if path := config.Services.Outbox.File.Path; path != "" {
+		sinks = append(sinks, outbox.NewFileSink(path))
+	}
+	if url := config.Services.Outbox.NATS.URL; url != "" {
+		sink, err := outbox.NewNATSSink(url, config.Services.Outbox.NATS.Subject, timeout)
+		if err != nil {
+			logs.DefaultLogger.WithError(err).Fatal("NATS sink was not configured")
+			os.Exit(1)
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks
+}
+
+func minutes(values map[string]int) map[string]time.Duration {
+	durations := make(map[string]time.Duration, len(values))
+	for key, value := range values {
+		durations[key] = time.Duration(value) * time.Minute
+	}
+	return durations
+}
+
 func getLogFormatter(mode string) logs.Formatter {
 	switch mode {
 	case "prod":
@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_airline_icao_code;
+DROP INDEX IF EXISTS idx_airline_iata_code;
+DROP INDEX IF EXISTS idx_city_iata_code;
+DROP INDEX IF EXISTS idx_country_iso_2;
+
+ALTER TABLE airplane DROP COLUMN IF EXISTS airline_ref;
+ALTER TABLE airport DROP COLUMN IF EXISTS country_ref;
+ALTER TABLE airport DROP COLUMN IF EXISTS city_ref;
+ALTER TABLE airline DROP COLUMN IF EXISTS country_ref;
+ALTER TABLE city DROP COLUMN IF EXISTS country_ref;
+
+ALTER TABLE country ALTER COLUMN id DROP DEFAULT;
+ALTER TABLE country ALTER COLUMN id TYPE varchar(255) USING id::text;
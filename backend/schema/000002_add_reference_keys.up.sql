@@ -0,0 +1,57 @@
+-- Country ids were created as varchar but the API has always written UUIDs.
+ALTER TABLE country ALTER COLUMN id TYPE UUID USING id::uuid;
+ALTER TABLE country ALTER COLUMN id SET DEFAULT gen_random_uuid();
+
+-- Upstream codes stay as the source of truth; the *_ref columns hold the
+-- resolved row and stay NULL when the provider data has no match.
+ALTER TABLE city ADD COLUMN country_ref UUID NULL REFERENCES country (id) ON DELETE SET NULL;
+ALTER TABLE airline ADD COLUMN country_ref UUID NULL REFERENCES country (id) ON DELETE SET NULL;
+ALTER TABLE airport ADD COLUMN city_ref UUID NULL REFERENCES city (id) ON DELETE SET NULL;
+ALTER TABLE airport ADD COLUMN country_ref UUID NULL REFERENCES country (id) ON DELETE SET NULL;
+ALTER TABLE airplane ADD COLUMN airline_ref UUID NULL REFERENCES airline (id) ON DELETE SET NULL;
+
+CREATE INDEX idx_city_country_ref ON city (country_ref);
+CREATE INDEX idx_airline_country_ref ON airline (country_ref);
+CREATE INDEX idx_airport_city_ref ON airport (city_ref);
+CREATE INDEX idx_airport_country_ref ON airport (country_ref);
+CREATE INDEX idx_airplane_airline_ref ON airplane (airline_ref);
+
+CREATE INDEX idx_country_iso_2 ON country (country_iso_2);
+CREATE INDEX idx_city_iata_code ON city (iata_code);
+CREATE INDEX idx_airline_iata_code ON airline (iata_code);
+CREATE INDEX idx_airline_icao_code ON airline (icao_code);
+
+-- Backfill existing rows, later imports are linked by the repository.
+UPDATE city ct SET country_ref = c.id
+FROM country c
+WHERE ct.country_ref IS NULL AND ct.country_iso2 = c.country_iso_2;
+
+UPDATE airline a SET country_ref = c.id
+FROM country c
+WHERE a.country_ref IS NULL AND a.country_iso_2 = c.country_iso_2;
+
+UPDATE airport ap SET city_ref = ct.id
+FROM city ct
+WHERE ap.city_ref IS NULL AND ap.city_iata_code = ct.iata_code;
+
+UPDATE airport ap SET country_ref = c.id
+FROM country c
+WHERE ap.country_ref IS NULL AND ap.country_iso2 = c.country_iso_2;
+
+UPDATE airplane ap SET airline_ref = al.id
+FROM (
+  SELECT DISTINCT ON (iata_code) id, iata_code
+  FROM airline
+  WHERE iata_code <> ''
+  ORDER BY iata_code, (status = 'active') DESC, airline_id
+) al
+WHERE ap.airline_ref IS NULL AND ap.airline_iata_code = al.iata_code;
+
+UPDATE airplane ap SET airline_ref = al.id
+FROM (
+  SELECT DISTINCT ON (icao_code) id, icao_code
+  FROM airline
+  WHERE icao_code <> ''
+  ORDER BY icao_code, (status = 'active') DESC, airline_id
+) al
+WHERE ap.airline_ref IS NULL AND ap.airline_icao_code = al.icao_code;
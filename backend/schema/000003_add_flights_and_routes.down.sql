@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS route;
+DROP TABLE IF EXISTS flight;
@@ -0,0 +1,63 @@
+-- One row per flight leg seen in the upstream flights feed. Marketing
+-- (codeshare) legs are kept as their own rows and point at the operating
+-- flight through the codeshare_* columns.
+CREATE TABLE flight (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  flight_date DATE NOT NULL,
+  flight_number varchar(16),
+  flight_iata varchar(16) NOT NULL,
+  flight_icao varchar(16),
+  status varchar(32),
+  airline_name varchar(255),
+  airline_iata varchar(8),
+  airline_icao varchar(8),
+  dep_iata varchar(8) NOT NULL,
+  dep_icao varchar(8),
+  dep_airport varchar(255),
+  dep_timezone varchar(64),
+  dep_terminal varchar(32),
+  dep_gate varchar(32),
+  dep_delay INT,
+  dep_scheduled TIMESTAMPTZ,
+  dep_estimated TIMESTAMPTZ,
+  dep_actual TIMESTAMPTZ,
+  arr_iata varchar(8) NOT NULL,
+  arr_icao varchar(8),
+  arr_airport varchar(255),
+  arr_timezone varchar(64),
+  arr_terminal varchar(32),
+  arr_gate varchar(32),
+  arr_baggage varchar(32),
+  arr_delay INT,
+  arr_scheduled TIMESTAMPTZ,
+  arr_estimated TIMESTAMPTZ,
+  arr_actual TIMESTAMPTZ,
+  codeshare_airline_iata varchar(8),
+  codeshare_airline_icao varchar(8),
+  codeshare_flight_iata varchar(16),
+  codeshare_flight_icao varchar(16),
+  first_seen TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+  last_seen TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+  UNIQUE (flight_date, flight_iata, dep_iata)
+);
+
+CREATE INDEX idx_flight_dep ON flight (dep_iata, dep_scheduled);
+CREATE INDEX idx_flight_arr ON flight (arr_iata, arr_scheduled);
+CREATE INDEX idx_flight_airline ON flight (airline_iata, flight_date);
+
+-- Directed edges of the route network, one per operating carrier. flights
+-- counts the distinct operated legs observed, so it only grows when a new
+-- flight row is inserted.
+CREATE TABLE route (
+  dep_iata varchar(8) NOT NULL,
+  arr_iata varchar(8) NOT NULL,
+  airline_iata varchar(8) NOT NULL DEFAULT '',
+  flights INT NOT NULL DEFAULT 0,
+  first_seen TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+  last_seen TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+  PRIMARY KEY (dep_iata, arr_iata, airline_iata)
+);
+
+CREATE INDEX idx_route_arr ON route (arr_iata);
+CREATE INDEX idx_route_airline ON route (airline_iata);
+CREATE INDEX idx_route_last_seen ON route (last_seen);
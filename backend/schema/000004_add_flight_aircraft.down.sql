@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_flight_number;
+ALTER TABLE flight DROP COLUMN IF EXISTS aircraft_registration;
+ALTER TABLE flight DROP COLUMN IF EXISTS aircraft_icao;
+ALTER TABLE flight DROP COLUMN IF EXISTS aircraft_iata;
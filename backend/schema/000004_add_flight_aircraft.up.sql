@@ -0,0 +1,7 @@
+-- Aircraft reported by the flights feed, when it reports one. The
+-- registration links the leg to airplane.registration_number.
+ALTER TABLE flight ADD COLUMN aircraft_iata varchar(8);
+ALTER TABLE flight ADD COLUMN aircraft_icao varchar(8);
+ALTER TABLE flight ADD COLUMN aircraft_registration varchar(16);
+
+CREATE INDEX idx_flight_number ON flight (flight_iata, flight_date);
@@ -0,0 +1,21 @@
+-- Daily on-time performance per dimension (airline, airport, route, hour)
+-- and key, recomputed from the flight table. delay_histogram counts delays
+-- in 5 minute buckets, the last bucket holding 300 minutes and more, so
+-- percentiles can be read over any range of days.
+CREATE TABLE otp_daily (
+  dimension varchar(16) NOT NULL,
+  key varchar(32) NOT NULL,
+  flight_date DATE NOT NULL,
+  flights INT NOT NULL DEFAULT 0,
+  landed INT NOT NULL DEFAULT 0,
+  on_time INT NOT NULL DEFAULT 0,
+  cancelled INT NOT NULL DEFAULT 0,
+  diverted INT NOT NULL DEFAULT 0,
+  delay_count INT NOT NULL DEFAULT 0,
+  delay_sum BIGINT NOT NULL DEFAULT 0,
+  delay_histogram INT[] NOT NULL,
+  computed_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+  PRIMARY KEY (dimension, key, flight_date)
+);
+
+CREATE INDEX idx_otp_daily_date ON otp_daily (flight_date);
@@ -0,0 +1,67 @@
+-- Watchlists name the flights (TP1350), routes (LIS-LHR) and airports (LIS)
+-- a user follows. When a polled flight matching a watch changes status, gate
+-- or delay, a delivery is queued for every active webhook of the user.
+CREATE TABLE watchlist (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  name varchar(255) NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+  updated_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX idx_watchlist_user ON watchlist (user_id);
+
+CREATE TABLE watch (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  watchlist_id UUID NOT NULL REFERENCES watchlist (id) ON DELETE CASCADE,
+  kind varchar(16) NOT NULL,
+  value varchar(16) NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+  UNIQUE (watchlist_id, kind, value)
+);
+
+CREATE INDEX idx_watch_kind_value ON watch (kind, value);
+
+CREATE TABLE webhook (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  url TEXT NOT NULL,
+  secret varchar(64) NOT NULL,
+  active BOOL NOT NULL DEFAULT true,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+  updated_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX idx_webhook_user ON webhook (user_id);
+
+-- status is pending until a 2xx response (delivered) or until the last
+-- attempt failed (dead).
+CREATE TABLE webhook_delivery (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  webhook_id UUID NOT NULL REFERENCES webhook (id) ON DELETE CASCADE,
+  event varchar(32) NOT NULL,
+  payload JSONB NOT NULL,
+  status varchar(16) NOT NULL DEFAULT 'pending',
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+  last_status_code INT NULL,
+  last_error TEXT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+  delivered_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX idx_webhook_delivery_due ON webhook_delivery (status, next_attempt_at);
+CREATE INDEX idx_webhook_delivery_webhook ON webhook_delivery (webhook_id, created_at);
+
+-- One row per send, kept across manual retries of dead deliveries.
+CREATE TABLE webhook_delivery_attempt (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  delivery_id UUID NOT NULL REFERENCES webhook_delivery (id) ON DELETE CASCADE,
+  attempt INT NOT NULL,
+  status_code INT NULL,
+  error TEXT NULL,
+  duration_ms INT NOT NULL,
+  attempted_at TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+);
+
+CREATE INDEX idx_webhook_delivery_attempt_delivery ON webhook_delivery_attempt (delivery_id, attempted_at);
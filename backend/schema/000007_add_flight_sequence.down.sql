@@ -0,0 +1 @@
+ALTER TABLE flight DROP COLUMN IF EXISTS sequence;
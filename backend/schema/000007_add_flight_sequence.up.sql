@@ -0,0 +1,3 @@
+-- Revision of a flight's times, bumped whenever a poll moves a scheduled,
+-- estimated or actual time. Calendar feeds publish it as the event SEQUENCE.
+ALTER TABLE flight ADD COLUMN sequence INT NOT NULL DEFAULT 0;
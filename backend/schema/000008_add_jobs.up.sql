@@ -0,0 +1,29 @@
+-- Background work that has to survive a restart: imports, exports and
+-- maintenance. Workers claim queued jobs with FOR UPDATE SKIP LOCKED and hold
+-- them with a lease they keep renewing; a job whose lease ran out belongs to
+-- a worker that died and is claimed again.
+CREATE TABLE jobs (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  kind varchar(64) NOT NULL,
+  -- Jobs sharing a key are not queued twice while one is queued or running.
+  key varchar(255) NULL,
+  payload JSONB NOT NULL DEFAULT '{}',
+  status varchar(16) NOT NULL DEFAULT 'queued',
+  attempts INT NOT NULL DEFAULT 0,
+  max_attempts INT NOT NULL,
+  run_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+  locked_by varchar(255) NULL,
+  locked_until TIMESTAMPTZ NULL,
+  last_error TEXT NULL,
+  result JSONB NULL,
+  output BYTEA NULL,
+  output_type varchar(255) NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+  started_at TIMESTAMPTZ NULL,
+  finished_at TIMESTAMPTZ NULL,
+  updated_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX idx_jobs_due ON jobs (status, run_at);
+CREATE INDEX idx_jobs_created ON jobs (created_at);
+CREATE UNIQUE INDEX idx_jobs_active_key ON jobs (key) WHERE status IN ('queued', 'running');
@@ -0,0 +1,25 @@
+-- One row per import of an upstream dataset. Dry runs fetch the dataset and
+-- compute the diff against the table without writing; real runs store the
+-- diff of what they were about to change. Imports merge and never delete,
+-- so rows_deleted stays 0 and rows gone upstream show up in the diff as
+-- missing.
+CREATE TABLE ingestion_runs (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  dataset varchar(32) NOT NULL,
+  provider varchar(32) NOT NULL,
+  dry_run BOOL NOT NULL DEFAULT false,
+  status varchar(16) NOT NULL DEFAULT 'running',
+  started_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+  finished_at TIMESTAMPTZ NULL,
+  pages INT NOT NULL DEFAULT 0,
+  rows_fetched BIGINT NOT NULL DEFAULT 0,
+  rows_inserted BIGINT NOT NULL DEFAULT 0,
+  rows_updated BIGINT NOT NULL DEFAULT 0,
+  rows_deleted BIGINT NOT NULL DEFAULT 0,
+  rows_failed BIGINT NOT NULL DEFAULT 0,
+  errors TEXT[] NOT NULL DEFAULT '{}',
+  diff JSONB NULL
+);
+
+CREATE INDEX idx_ingestion_runs_dataset ON ingestion_runs (dataset, started_at);
+CREATE INDEX idx_ingestion_runs_started ON ingestion_runs (started_at);
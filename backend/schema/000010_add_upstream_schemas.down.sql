@@ -0,0 +1,5 @@
+ALTER TABLE ingestion_runs DROP COLUMN IF EXISTS drift_events;
+
+DROP TABLE IF EXISTS schema_drift;
+
+DROP TABLE IF EXISTS upstream_schemas;
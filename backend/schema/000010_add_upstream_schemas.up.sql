@@ -0,0 +1,29 @@
+-- The schema each upstream endpoint is expected to send: the JSON type of
+-- every field of its data rows and whether it may be null. It is learnt
+-- from the first payload and relearnt after it is deleted.
+CREATE TABLE upstream_schemas (
+  endpoint varchar(32) NOT NULL,
+  field varchar(64) NOT NULL,
+  type varchar(16) NOT NULL,
+  nullable BOOL NOT NULL DEFAULT false,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+  PRIMARY KEY (endpoint, field)
+);
+
+-- Differences between a fetched payload and the expected schema, one row per
+-- field, kind and observed type with the number of data rows showing it.
+CREATE TABLE schema_drift (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  run_id UUID NOT NULL REFERENCES ingestion_runs (id) ON DELETE CASCADE,
+  endpoint varchar(32) NOT NULL,
+  field varchar(64) NOT NULL,
+  kind varchar(16) NOT NULL,
+  expected varchar(16) NULL,
+  observed varchar(16) NOT NULL,
+  rows BIGINT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+);
+
+CREATE INDEX idx_schema_drift_run ON schema_drift (run_id);
+
+ALTER TABLE ingestion_runs ADD COLUMN drift_events INT NOT NULL DEFAULT 0;
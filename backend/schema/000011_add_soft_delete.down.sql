@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS tombstones;
+
+ALTER TABLE country DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE city DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE airport DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE airplane DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE airline DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE aircraft DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE tax DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,26 @@
+-- Deleting reference rows only marks them: reads skip them, imports leave
+-- them alone and they can be restored until they are purged.
+ALTER TABLE tax ADD COLUMN deleted_at TIMESTAMPTZ NULL;
+ALTER TABLE aircraft ADD COLUMN deleted_at TIMESTAMPTZ NULL;
+ALTER TABLE airline ADD COLUMN deleted_at TIMESTAMPTZ NULL;
+ALTER TABLE airplane ADD COLUMN deleted_at TIMESTAMPTZ NULL;
+ALTER TABLE airport ADD COLUMN deleted_at TIMESTAMPTZ NULL;
+ALTER TABLE city ADD COLUMN deleted_at TIMESTAMPTZ NULL;
+ALTER TABLE country ADD COLUMN deleted_at TIMESTAMPTZ NULL;
+
+CREATE INDEX idx_tax_deleted_at ON tax (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_aircraft_deleted_at ON aircraft (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_airline_deleted_at ON airline (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_airplane_deleted_at ON airplane (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_airport_deleted_at ON airport (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_city_deleted_at ON city (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_country_deleted_at ON country (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Upstream ids of purged rows, so that imports keep them deleted.
+CREATE TABLE tombstones (
+  resource varchar(16) NOT NULL,
+  upstream_key TEXT NOT NULL,
+  deleted_at TIMESTAMPTZ NOT NULL,
+  purged_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+  PRIMARY KEY (resource, upstream_key)
+);
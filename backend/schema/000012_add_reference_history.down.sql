@@ -0,0 +1,11 @@
+DROP TRIGGER IF EXISTS country_history ON country;
+DROP TRIGGER IF EXISTS city_history ON city;
+DROP TRIGGER IF EXISTS airport_history ON airport;
+DROP TRIGGER IF EXISTS airplane_history ON airplane;
+DROP TRIGGER IF EXISTS airline_history ON airline;
+DROP TRIGGER IF EXISTS aircraft_history ON aircraft;
+DROP TRIGGER IF EXISTS tax_history ON tax;
+
+DROP FUNCTION IF EXISTS record_reference_history ();
+
+DROP TABLE IF EXISTS reference_history;
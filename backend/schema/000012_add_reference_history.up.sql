@@ -0,0 +1,71 @@
+-- Every version of the reference rows, written by a trigger so that single
+-- writes, bulk merges, soft deletes and purges are all recorded. before is
+-- NULL on create and after is NULL on purge.
+CREATE TABLE reference_history (
+  id BIGSERIAL PRIMARY KEY,
+  resource varchar(16) NOT NULL,
+  row_id UUID NOT NULL,
+  operation varchar(8) NOT NULL,
+  before JSONB NULL,
+  after JSONB NULL,
+  changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+);
+
+CREATE INDEX idx_reference_history_row ON reference_history (resource, row_id, changed_at);
+
+CREATE FUNCTION record_reference_history () RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    INSERT INTO reference_history (resource, row_id, operation, after)
+    VALUES (TG_TABLE_NAME, NEW.id, 'create', to_jsonb(NEW));
+  ELSIF TG_OP = 'UPDATE' THEN
+    -- Only a changed updated_at is not a new version.
+    IF to_jsonb(OLD) - 'updated_at' = to_jsonb(NEW) - 'updated_at' THEN
+      RETURN NULL;
+    END IF;
+    INSERT INTO reference_history (resource, row_id, operation, before, after)
+    VALUES (TG_TABLE_NAME, NEW.id,
+      CASE
+        WHEN OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN 'delete'
+        WHEN OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN 'restore'
+        ELSE 'update'
+      END,
+      to_jsonb(OLD), to_jsonb(NEW));
+  ELSE
+    INSERT INTO reference_history (resource, row_id, operation, before)
+    VALUES (TG_TABLE_NAME, OLD.id, 'purge', to_jsonb(OLD));
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tax_history AFTER INSERT OR UPDATE OR DELETE ON tax
+  FOR EACH ROW EXECUTE FUNCTION record_reference_history ();
+CREATE TRIGGER aircraft_history AFTER INSERT OR UPDATE OR DELETE ON aircraft
+  FOR EACH ROW EXECUTE FUNCTION record_reference_history ();
+CREATE TRIGGER airline_history AFTER INSERT OR UPDATE OR DELETE ON airline
+  FOR EACH ROW EXECUTE FUNCTION record_reference_history ();
+CREATE TRIGGER airplane_history AFTER INSERT OR UPDATE OR DELETE ON airplane
+  FOR EACH ROW EXECUTE FUNCTION record_reference_history ();
+CREATE TRIGGER airport_history AFTER INSERT OR UPDATE OR DELETE ON airport
+  FOR EACH ROW EXECUTE FUNCTION record_reference_history ();
+CREATE TRIGGER city_history AFTER INSERT OR UPDATE OR DELETE ON city
+  FOR EACH ROW EXECUTE FUNCTION record_reference_history ();
+CREATE TRIGGER country_history AFTER INSERT OR UPDATE OR DELETE ON country
+  FOR EACH ROW EXECUTE FUNCTION record_reference_history ();
+
+-- The rows already there start with a snapshot of their current version.
+INSERT INTO reference_history (resource, row_id, operation, after, changed_at)
+SELECT 'tax', id, 'snapshot', to_jsonb(t), COALESCE(updated_at, created_at, NOW()) FROM tax t;
+INSERT INTO reference_history (resource, row_id, operation, after, changed_at)
+SELECT 'aircraft', id, 'snapshot', to_jsonb(t), COALESCE(updated_at, created_at, NOW()) FROM aircraft t;
+INSERT INTO reference_history (resource, row_id, operation, after, changed_at)
+SELECT 'airline', id, 'snapshot', to_jsonb(t), COALESCE(updated_at, created_at, NOW()) FROM airline t;
+INSERT INTO reference_history (resource, row_id, operation, after, changed_at)
+SELECT 'airplane', id, 'snapshot', to_jsonb(t), COALESCE(updated_at, created_at, NOW()) FROM airplane t;
+INSERT INTO reference_history (resource, row_id, operation, after, changed_at)
+SELECT 'airport', id, 'snapshot', to_jsonb(t), COALESCE(updated_at, created_at, NOW()) FROM airport t;
+INSERT INTO reference_history (resource, row_id, operation, after, changed_at)
+SELECT 'city', id, 'snapshot', to_jsonb(t), COALESCE(updated_at, created_at, NOW()) FROM city t;
+INSERT INTO reference_history (resource, row_id, operation, after, changed_at)
+SELECT 'country', id, 'snapshot', to_jsonb(t), COALESCE(updated_at, created_at, NOW()) FROM country t;
@@ -0,0 +1,11 @@
+DROP TRIGGER IF EXISTS country_outbox ON country;
+DROP TRIGGER IF EXISTS city_outbox ON city;
+DROP TRIGGER IF EXISTS airport_outbox ON airport;
+DROP TRIGGER IF EXISTS airplane_outbox ON airplane;
+DROP TRIGGER IF EXISTS airline_outbox ON airline;
+DROP TRIGGER IF EXISTS aircraft_outbox ON aircraft;
+DROP TRIGGER IF EXISTS tax_outbox ON tax;
+
+DROP FUNCTION IF EXISTS record_outbox_event ();
+
+DROP TABLE IF EXISTS outbox;
@@ -0,0 +1,66 @@
+-- Change events of the reference tables for the relay to publish. A
+-- trigger writes them in the transaction of the change, and the relay
+-- deletes each one once every sink took it.
+CREATE TABLE outbox (
+  id BIGSERIAL PRIMARY KEY,
+  entity varchar(16) NOT NULL,
+  entity_id UUID NOT NULL,
+  operation varchar(8) NOT NULL,
+  fields TEXT[] NOT NULL DEFAULT '{}',
+  attempts INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW (),
+  last_error TEXT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW ()
+);
+
+CREATE INDEX idx_outbox_entity ON outbox (entity, entity_id, id);
+CREATE INDEX idx_outbox_due ON outbox (next_attempt_at);
+
+-- fields are the columns the change set: all of them on create, the ones
+-- whose value changed otherwise, updated_at aside.
+CREATE FUNCTION record_outbox_event () RETURNS TRIGGER AS $$
+DECLARE
+  changed TEXT[];
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    SELECT COALESCE(array_agg(n.key ORDER BY n.key), '{}') INTO changed
+    FROM jsonb_each(to_jsonb(NEW)) n WHERE n.value <> 'null';
+    INSERT INTO outbox (entity, entity_id, operation, fields)
+    VALUES (TG_TABLE_NAME, NEW.id, 'create', changed);
+  ELSIF TG_OP = 'UPDATE' THEN
+    SELECT COALESCE(array_agg(n.key ORDER BY n.key), '{}') INTO changed
+    FROM jsonb_each(to_jsonb(NEW)) n
+    WHERE n.key <> 'updated_at' AND n.value IS DISTINCT FROM to_jsonb(OLD) -> n.key;
+    IF cardinality(changed) = 0 THEN
+      RETURN NULL;
+    END IF;
+    INSERT INTO outbox (entity, entity_id, operation, fields)
+    VALUES (TG_TABLE_NAME, NEW.id,
+      CASE
+        WHEN OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN 'delete'
+        WHEN OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN 'restore'
+        ELSE 'update'
+      END,
+      changed);
+  ELSE
+    INSERT INTO outbox (entity, entity_id, operation)
+    VALUES (TG_TABLE_NAME, OLD.id, 'purge');
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tax_outbox AFTER INSERT OR UPDATE OR DELETE ON tax
+  FOR EACH ROW EXECUTE FUNCTION record_outbox_event ();
+CREATE TRIGGER aircraft_outbox AFTER INSERT OR UPDATE OR DELETE ON aircraft
+  FOR EACH ROW EXECUTE FUNCTION record_outbox_event ();
+CREATE TRIGGER airline_outbox AFTER INSERT OR UPDATE OR DELETE ON airline
+  FOR EACH ROW EXECUTE FUNCTION record_outbox_event ();
+CREATE TRIGGER airplane_outbox AFTER INSERT OR UPDATE OR DELETE ON airplane
+  FOR EACH ROW EXECUTE FUNCTION record_outbox_event ();
+CREATE TRIGGER airport_outbox AFTER INSERT OR UPDATE OR DELETE ON airport
+  FOR EACH ROW EXECUTE FUNCTION record_outbox_event ();
+CREATE TRIGGER city_outbox AFTER INSERT OR UPDATE OR DELETE ON city
+  FOR EACH ROW EXECUTE FUNCTION record_outbox_event ();
+CREATE TRIGGER country_outbox AFTER INSERT OR UPDATE OR DELETE ON country
+  FOR EACH ROW EXECUTE FUNCTION record_outbox_event ();
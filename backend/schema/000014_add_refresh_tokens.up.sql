@@ -0,0 +1,3 @@
+-- Access tokens expire; the refresh token handed out with them renews them.
+-- Only a SHA-256 of the current refresh token of a user is kept.
+ALTER TABLE users ADD COLUMN refresh_token_hash varchar(64) UNIQUE;
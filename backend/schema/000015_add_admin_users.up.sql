@@ -0,0 +1,3 @@
+-- Administrators may read and restore soft deleted rows, purge them and
+-- write in bulk. Grant it with UPDATE users SET admin = true WHERE email = ...
+ALTER TABLE users ADD COLUMN admin BOOLEAN NOT NULL DEFAULT false;
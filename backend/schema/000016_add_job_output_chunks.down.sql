@@ -0,0 +1,2 @@
+ALTER TABLE jobs ADD COLUMN output BYTEA NULL;
+DROP TABLE IF EXISTS job_output_chunks;
@@ -0,0 +1,10 @@
+-- Files built by jobs, such as exports, are stored in chunks as they are
+-- written, so neither the worker nor a download holds a whole file.
+CREATE TABLE job_output_chunks (
+  job_id UUID NOT NULL REFERENCES jobs (id) ON DELETE CASCADE,
+  seq INT NOT NULL,
+  data BYTEA NOT NULL,
+  PRIMARY KEY (job_id, seq)
+);
+
+ALTER TABLE jobs DROP COLUMN output;
@@ -0,0 +1,10 @@
+// Package schema embeds the SQL migrations so the binary can apply them
+// without the migrate CLI or a checkout of this directory.
+package schema
+
+import "embed"
+
+// Migrations holds every NNNNNN_name.up.sql / NNNNNN_name.down.sql pair.
+//
+//go:embed *.sql
+var Migrations embed.FS